@@ -0,0 +1,431 @@
+package geodbtools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/afero"
+)
+
+var (
+	// ErrUnsupportedBundle indicates that a source is not a supported bundle (directory, tar, tar.gz or zip archive)
+	ErrUnsupportedBundle = errors.New("unsupported bundle")
+
+	// ErrBundleFileNotFound indicates that a bundle does not contain a file with the requested name
+	ErrBundleFileNotFound = errors.New("bundle file not found")
+)
+
+// Bundle represents a database source consisting of multiple named files, like a directory or an archive
+type Bundle interface {
+	// Files returns the names of all files contained in the bundle, in lexical order.
+	// Names use forward slashes as path separator and are relative to the bundle's root.
+	Files() []string
+
+	// Open returns a ReaderSource providing the contents of the file with the given name.
+	// Returned sources remain valid until the bundle is closed.
+	Open(name string) (s ReaderSource, err error)
+
+	// ModTime returns the modification time of the file with the given name
+	ModTime(name string) (modTime time.Time, err error)
+
+	// Close frees up resources used by the bundle and all sources opened from it
+	Close() error
+}
+
+// BundleFormat describes a database format whose databases span multiple files, read from a Bundle
+type BundleFormat interface {
+	Format
+
+	// NewBundleReader returns a new reader instance for the database contained in the given bundle
+	NewBundleReader(b Bundle) (reader Reader, meta Metadata, err error)
+
+	// DetectBundleFormat checks if the given bundle contains a database of the given format
+	DetectBundleFormat(b Bundle) (isFormat bool)
+}
+
+// bundleFileNames returns the sorted names of the given files
+func bundleFileNames(modTimes map[string]time.Time) (names []string) {
+	names = make([]string, 0, len(modTimes))
+	for name := range modTimes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+var _ Bundle = (*memoryBundle)(nil)
+
+// memoryBundle implements a bundle holding the contents of all files in memory
+type memoryBundle struct {
+	files    map[string][]byte
+	modTimes map[string]time.Time
+}
+
+func (b *memoryBundle) Files() []string {
+	return bundleFileNames(b.modTimes)
+}
+
+func (b *memoryBundle) Open(name string) (s ReaderSource, err error) {
+	data, ok := b.files[name]
+	if !ok {
+		err = ErrBundleFileNotFound
+		return
+	}
+
+	s = NewBytesReaderSource(data)
+	return
+}
+
+func (b *memoryBundle) ModTime(name string) (modTime time.Time, err error) {
+	var ok bool
+	if modTime, ok = b.modTimes[name]; !ok {
+		err = ErrBundleFileNotFound
+	}
+	return
+}
+
+// Close is a no-op, as memory bundles do not hold any resources besides memory
+func (b *memoryBundle) Close() error {
+	return nil
+}
+
+// NewTarBundle returns a new bundle holding the files of the tar archive read from the given io.Reader.
+// The archive is read into memory completely.
+func NewTarBundle(r io.Reader) (b Bundle, err error) {
+	bundle := &memoryBundle{
+		files:    make(map[string][]byte),
+		modTimes: make(map[string]time.Time),
+	}
+
+	tarReader := tar.NewReader(r)
+	for {
+		var header *tar.Header
+		if header, err = tarReader.Next(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+
+		if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(header.Name))
+		if bundle.files[name], err = ioutil.ReadAll(tarReader); err != nil {
+			return
+		}
+		bundle.modTimes[name] = header.ModTime
+	}
+
+	b = bundle
+	return
+}
+
+// NewTarGzBundle returns a new bundle holding the files of the gzip-compressed tar archive read from the given
+// io.Reader.
+// The archive is read into memory completely.
+func NewTarGzBundle(r io.Reader) (b Bundle, err error) {
+	var gzipReader *gzip.Reader
+	if gzipReader, err = gzip.NewReader(r); err != nil {
+		return
+	}
+	defer gzipReader.Close()
+
+	return NewTarBundle(gzipReader)
+}
+
+var _ Bundle = (*zipBundle)(nil)
+
+// zipBundle implements a bundle backed by a ZIP archive
+type zipBundle struct {
+	files    map[string]*zip.File
+	modTimes map[string]time.Time
+	closer   io.Closer
+}
+
+func (b *zipBundle) Files() []string {
+	return bundleFileNames(b.modTimes)
+}
+
+func (b *zipBundle) Open(name string) (s ReaderSource, err error) {
+	f, ok := b.files[name]
+	if !ok {
+		err = ErrBundleFileNotFound
+		return
+	}
+
+	var rc io.ReadCloser
+	if rc, err = f.Open(); err != nil {
+		return
+	}
+	defer rc.Close()
+
+	// ZIP archive members are compressed and thus do not allow random access
+	var data []byte
+	if data, err = ioutil.ReadAll(rc); err != nil {
+		return
+	}
+
+	s = NewBytesReaderSource(data)
+	return
+}
+
+func (b *zipBundle) ModTime(name string) (modTime time.Time, err error) {
+	var ok bool
+	if modTime, ok = b.modTimes[name]; !ok {
+		err = ErrBundleFileNotFound
+	}
+	return
+}
+
+func (b *zipBundle) Close() error {
+	if b.closer != nil {
+		return b.closer.Close()
+	}
+	return nil
+}
+
+// NewZipBundle returns a new bundle holding the files of the ZIP archive provided by the given ReaderSource.
+// The ReaderSource has to remain open while the bundle is in use.
+func NewZipBundle(r ReaderSource) (b Bundle, err error) {
+	var zipReader *zip.Reader
+	if zipReader, err = zip.NewReader(r, r.Size()); err != nil {
+		return
+	}
+
+	bundle := &zipBundle{
+		files:    make(map[string]*zip.File, len(zipReader.File)),
+		modTimes: make(map[string]time.Time, len(zipReader.File)),
+	}
+
+	for _, f := range zipReader.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+
+		name := filepath.ToSlash(filepath.Clean(f.Name))
+		bundle.files[name] = f
+		bundle.modTimes[name] = f.Modified
+	}
+
+	b = bundle
+	return
+}
+
+var _ Bundle = (*directoryBundle)(nil)
+
+// directoryBundle implements a bundle backed by a directory
+type directoryBundle struct {
+	path     string
+	modTimes map[string]time.Time
+
+	sourcesMu sync.Mutex
+	sources   []ReaderSource
+}
+
+func (b *directoryBundle) Files() []string {
+	return bundleFileNames(b.modTimes)
+}
+
+func (b *directoryBundle) Open(name string) (s ReaderSource, err error) {
+	if _, ok := b.modTimes[name]; !ok {
+		err = ErrBundleFileNotFound
+		return
+	}
+
+	if s, err = NewFileReaderSource(filepath.Join(b.path, filepath.FromSlash(name))); err != nil {
+		return
+	}
+
+	b.sourcesMu.Lock()
+	b.sources = append(b.sources, s)
+	b.sourcesMu.Unlock()
+	return
+}
+
+func (b *directoryBundle) ModTime(name string) (modTime time.Time, err error) {
+	var ok bool
+	if modTime, ok = b.modTimes[name]; !ok {
+		err = ErrBundleFileNotFound
+	}
+	return
+}
+
+func (b *directoryBundle) Close() (err error) {
+	b.sourcesMu.Lock()
+	defer b.sourcesMu.Unlock()
+
+	for _, s := range b.sources {
+		if closeErr := s.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	b.sources = nil
+	return
+}
+
+// NewDirectoryBundle returns a new bundle holding all files contained in the given directory and its
+// subdirectories
+func NewDirectoryBundle(path string) (b Bundle, err error) {
+	bundle := &directoryBundle{
+		path:     path,
+		modTimes: make(map[string]time.Time),
+	}
+
+	if err = afero.Walk(fs, path, func(filePath string, info os.FileInfo, walkErr error) (err error) {
+		if walkErr != nil {
+			return walkErr
+		} else if info.IsDir() {
+			return
+		}
+
+		var name string
+		if name, err = filepath.Rel(path, filePath); err != nil {
+			return
+		}
+		bundle.modTimes[filepath.ToSlash(name)] = info.ModTime()
+		return
+	}); err != nil {
+		return
+	}
+
+	b = bundle
+	return
+}
+
+// bundle archive signatures
+var (
+	gzipSignature      = []byte{0x1f, 0x8b}
+	zipSignature       = []byte("PK\x03\x04")
+	zipEmptySignature  = []byte("PK\x05\x06")
+	tarSignature       = []byte("ustar")
+	tarSignatureOffset = int64(257)
+)
+
+// hasSignature checks if the data provided by the given ReaderSource contains the given signature at the given offset
+func hasSignature(r ReaderSource, offset int64, signature []byte) bool {
+	if r.Size() < offset+int64(len(signature)) {
+		return false
+	}
+
+	b := make([]byte, len(signature))
+	if _, err := r.ReadAt(b, offset); err != nil {
+		return false
+	}
+	return bytes.Equal(b, signature)
+}
+
+// NewArchiveBundle returns a new bundle holding the files of the tar, tar.gz or ZIP archive provided by the given
+// ReaderSource. The archive type is detected from the archive's contents.
+// ErrUnsupportedBundle is returned if the data is not a supported archive.
+// The ReaderSource has to remain open while the bundle is in use.
+func NewArchiveBundle(r ReaderSource) (b Bundle, err error) {
+	switch {
+	case hasSignature(r, 0, gzipSignature):
+		return NewTarGzBundle(io.NewSectionReader(r, 0, r.Size()))
+	case hasSignature(r, 0, zipSignature), hasSignature(r, 0, zipEmptySignature):
+		return NewZipBundle(r)
+	case hasSignature(r, tarSignatureOffset, tarSignature):
+		return NewTarBundle(io.NewSectionReader(r, 0, r.Size()))
+	}
+
+	err = ErrUnsupportedBundle
+	return
+}
+
+// OpenBundle returns a new bundle for the given path, which may either be a directory or a tar, tar.gz or ZIP
+// archive.
+// ErrUnsupportedBundle is returned if the path refers to a file that is not a supported archive.
+func OpenBundle(path string) (b Bundle, err error) {
+	var info os.FileInfo
+	if info, err = fs.Stat(path); err != nil {
+		return
+	} else if info.IsDir() {
+		return NewDirectoryBundle(path)
+	}
+
+	var s ReaderSource
+	if s, err = NewFileReaderSource(path); err != nil {
+		return
+	}
+
+	if b, err = NewArchiveBundle(s); err != nil {
+		s.Close()
+		return
+	}
+
+	switch bundle := b.(type) {
+	case *memoryBundle:
+		// the archive has been read into memory completely
+		err = s.Close()
+	case *zipBundle:
+		bundle.closer = s
+	}
+	return
+}
+
+// DetectBundleFormat tries to detect the format of the database contained in the given bundle.
+// Formats implementing BundleFormat are checked first, followed by formats detecting any single file contained in
+// the bundle.
+func DetectBundleFormat(b Bundle) (f Format, err error) {
+	formatRegistryMu.RLock()
+	defer formatRegistryMu.RUnlock()
+
+	for _, format := range formatRegistry {
+		if bundleFormat, ok := format.(BundleFormat); ok && bundleFormat.DetectBundleFormat(b) {
+			f = format
+			return
+		}
+	}
+
+	for _, name := range b.Files() {
+		var s ReaderSource
+		if s, err = b.Open(name); err != nil {
+			return
+		}
+
+		for _, format := range formatRegistry {
+			if _, ok := format.(BundleFormat); !ok && format.DetectFormat(s) {
+				f = format
+				return
+			}
+		}
+	}
+
+	err = ErrFormatNotFound
+	return
+}
+
+// NewBundleReader returns a new reader for the database of the given format, contained in the given bundle.
+// Formats implementing BundleFormat read the bundle directly, all other formats read the first file contained in the
+// bundle they detect as their format.
+func NewBundleReader(f Format, b Bundle) (reader Reader, meta Metadata, err error) {
+	if bundleFormat, ok := f.(BundleFormat); ok {
+		return bundleFormat.NewBundleReader(b)
+	}
+
+	for _, name := range b.Files() {
+		var s ReaderSource
+		if s, err = b.Open(name); err != nil {
+			return
+		}
+
+		if f.DetectFormat(s) {
+			return f.NewReaderAt(s)
+		}
+	}
+
+	err = ErrFormatNotFound
+	return
+}
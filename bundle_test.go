@@ -0,0 +1,410 @@
+package geodbtools
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/spf13/afero"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:generate mockgen -package geodbtools -self_package github.com/anexia-it/geodbtools -destination mock_bundle_format_test.go github.com/anexia-it/geodbtools BundleFormat
+
+var testBundleModTime = time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC)
+
+var testBundleFiles = map[string]string{
+	"db/a.csv":     "a",
+	"db/sub/b.csv": "b",
+}
+
+// testTar returns a tar archive holding the test bundle files
+func testTar(t *testing.T) []byte {
+	buf := bytes.NewBufferString("")
+	tarWriter := tar.NewWriter(buf)
+
+	require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+		Name:     "db/",
+		Typeflag: tar.TypeDir,
+		Mode:     0755,
+		ModTime:  testBundleModTime,
+	}))
+
+	for _, name := range []string{"db/a.csv", "db/sub/b.csv"} {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(testBundleFiles[name])),
+			ModTime:  testBundleModTime,
+		}))
+		_, err := tarWriter.Write([]byte(testBundleFiles[name]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tarWriter.Close())
+	return buf.Bytes()
+}
+
+// testTarGz returns a gzip-compressed tar archive holding the test bundle files
+func testTarGz(t *testing.T) []byte {
+	buf := bytes.NewBufferString("")
+	gzipWriter := gzip.NewWriter(buf)
+	_, err := gzipWriter.Write(testTar(t))
+	require.NoError(t, err)
+	require.NoError(t, gzipWriter.Close())
+	return buf.Bytes()
+}
+
+// testZip returns a ZIP archive holding the test bundle files
+func testZip(t *testing.T) []byte {
+	buf := bytes.NewBufferString("")
+	zipWriter := zip.NewWriter(buf)
+
+	_, err := zipWriter.CreateHeader(&zip.FileHeader{
+		Name:     "db/",
+		Modified: testBundleModTime,
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"db/a.csv", "db/sub/b.csv"} {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Method:   zip.Deflate,
+			Modified: testBundleModTime,
+		})
+		require.NoError(t, err)
+		_, err = w.Write([]byte(testBundleFiles[name]))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, zipWriter.Close())
+	return buf.Bytes()
+}
+
+func testBytesSource(b []byte) ReaderSource {
+	return NewReaderSourceWrapper(bytes.NewReader(b), int64(len(b)))
+}
+
+// testMemFS replaces the filesystem by an in-memory filesystem holding the test bundle files below /bundle
+// and the test archives below /archives
+func testMemFS(t *testing.T) (restore func()) {
+	memFS := afero.NewMemMapFs()
+	for name, data := range testBundleFiles {
+		require.NoError(t, afero.WriteFile(memFS, "/bundle/"+name, []byte(data), 0644))
+		require.NoError(t, memFS.Chtimes("/bundle/"+name, testBundleModTime, testBundleModTime))
+	}
+
+	require.NoError(t, afero.WriteFile(memFS, "/archives/test.tar", testTar(t), 0644))
+	require.NoError(t, afero.WriteFile(memFS, "/archives/test.tar.gz", testTarGz(t), 0644))
+	require.NoError(t, afero.WriteFile(memFS, "/archives/test.zip", testZip(t), 0644))
+	require.NoError(t, afero.WriteFile(memFS, "/archives/test.txt", []byte("test"), 0644))
+
+	origFS := fs
+	fs = memFS
+	return func() {
+		fs = origFS
+	}
+}
+
+// assertTestBundle checks that the given bundle holds the test bundle files
+func assertTestBundle(t *testing.T, b Bundle) {
+	assert.EqualValues(t, []string{"db/a.csv", "db/sub/b.csv"}, b.Files())
+
+	for name, expected := range testBundleFiles {
+		s, err := b.Open(name)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(io.NewSectionReader(s, 0, s.Size()))
+		require.NoError(t, err)
+		assert.EqualValues(t, expected, string(data))
+
+		modTime, err := b.ModTime(name)
+		assert.NoError(t, err)
+		assert.EqualValues(t, testBundleModTime.Unix(), modTime.Unix())
+	}
+
+	s, err := b.Open("db")
+	assert.Nil(t, s)
+	assert.EqualError(t, err, ErrBundleFileNotFound.Error())
+
+	_, err = b.ModTime("db/c.csv")
+	assert.EqualError(t, err, ErrBundleFileNotFound.Error())
+
+	assert.NoError(t, b.Close())
+}
+
+func TestNewTarBundle(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		b, err := NewTarBundle(bytes.NewReader(testTar(t)))
+		require.NoError(t, err)
+		assertTestBundle(t, b)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data := testTar(t)
+		b, err := NewTarBundle(bytes.NewReader(data[:600]))
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewTarGzBundle(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		b, err := NewTarGzBundle(bytes.NewReader(testTarGz(t)))
+		require.NoError(t, err)
+		assertTestBundle(t, b)
+	})
+
+	t.Run("NotGzip", func(t *testing.T) {
+		b, err := NewTarGzBundle(bytes.NewReader(testTar(t)))
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewZipBundle(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		b, err := NewZipBundle(testBytesSource(testZip(t)))
+		require.NoError(t, err)
+		assertTestBundle(t, b)
+	})
+
+	t.Run("NotZip", func(t *testing.T) {
+		b, err := NewZipBundle(testBytesSource([]byte("test")))
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	})
+
+	t.Run("CloseSource", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		closer := NewMockCloser(ctrl)
+		closer.EXPECT().Close().Return(testErr)
+
+		b := &zipBundle{
+			closer: closer,
+		}
+		assert.EqualError(t, b.Close(), testErr.Error())
+	})
+}
+
+func TestNewDirectoryBundle(t *testing.T) {
+	defer testMemFS(t)()
+
+	t.Run("OK", func(t *testing.T) {
+		b, err := NewDirectoryBundle("/bundle")
+		require.NoError(t, err)
+		assertTestBundle(t, b)
+		assert.Empty(t, b.(*directoryBundle).sources)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		b, err := NewDirectoryBundle("/missing")
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	})
+
+	t.Run("OpenError", func(t *testing.T) {
+		b, err := NewDirectoryBundle("/bundle")
+		require.NoError(t, err)
+		require.NoError(t, fs.Remove("/bundle/db/a.csv"))
+		defer afero.WriteFile(fs, "/bundle/db/a.csv", []byte(testBundleFiles["db/a.csv"]), 0644)
+
+		s, err := b.Open("db/a.csv")
+		assert.Nil(t, s)
+		assert.Error(t, err)
+	})
+}
+
+func TestNewArchiveBundle(t *testing.T) {
+	testCases := map[string][]byte{
+		"Tar":   testTar(t),
+		"TarGz": testTarGz(t),
+		"Zip":   testZip(t),
+	}
+
+	for name, data := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b, err := NewArchiveBundle(testBytesSource(data))
+			require.NoError(t, err)
+			assertTestBundle(t, b)
+		})
+	}
+
+	t.Run("EmptyZip", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		require.NoError(t, zip.NewWriter(buf).Close())
+
+		b, err := NewArchiveBundle(testBytesSource(buf.Bytes()))
+		require.NoError(t, err)
+		assert.Empty(t, b.Files())
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		b, err := NewArchiveBundle(testBytesSource([]byte("test")))
+		assert.Nil(t, b)
+		assert.EqualError(t, err, ErrUnsupportedBundle.Error())
+	})
+}
+
+func TestOpenBundle(t *testing.T) {
+	defer testMemFS(t)()
+
+	for _, path := range []string{"/bundle", "/archives/test.tar", "/archives/test.tar.gz", "/archives/test.zip"} {
+		t.Run(path, func(t *testing.T) {
+			b, err := OpenBundle(path)
+			require.NoError(t, err)
+			assertTestBundle(t, b)
+		})
+	}
+
+	t.Run("NotFound", func(t *testing.T) {
+		b, err := OpenBundle("/missing")
+		assert.Nil(t, b)
+		assert.Error(t, err)
+	})
+
+	t.Run("Unsupported", func(t *testing.T) {
+		b, err := OpenBundle("/archives/test.txt")
+		assert.Nil(t, b)
+		assert.EqualError(t, err, ErrUnsupportedBundle.Error())
+	})
+}
+
+// withFormatRegistry replaces the format registry by one holding the given formats
+func withFormatRegistry(formats map[string]Format) (restore func()) {
+	formatRegistryMu.Lock()
+	originalFormatRegistry := formatRegistry
+	formatRegistry = formats
+	formatRegistryMu.Unlock()
+
+	return func() {
+		formatRegistryMu.Lock()
+		defer formatRegistryMu.Unlock()
+		formatRegistry = originalFormatRegistry
+	}
+}
+
+func TestDetectBundleFormat(t *testing.T) {
+	b, err := NewTarBundle(bytes.NewReader(testTar(t)))
+	require.NoError(t, err)
+
+	t.Run("BundleFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		bundleFormat := NewMockBundleFormat(ctrl)
+		bundleFormat.EXPECT().DetectBundleFormat(b).Return(true)
+		defer withFormatRegistry(map[string]Format{
+			"bundle": bundleFormat,
+		})()
+
+		f, err := DetectBundleFormat(b)
+		assert.NoError(t, err)
+		assert.EqualValues(t, bundleFormat, f)
+	})
+
+	t.Run("SingleFileFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		bundleFormat := NewMockBundleFormat(ctrl)
+		bundleFormat.EXPECT().DetectBundleFormat(b).Return(false)
+		singleFileFormat := NewMockFormat(ctrl)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(false)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(true)
+		defer withFormatRegistry(map[string]Format{
+			"bundle": bundleFormat,
+			"single": singleFileFormat,
+		})()
+
+		f, err := DetectBundleFormat(b)
+		assert.NoError(t, err)
+		assert.EqualValues(t, singleFileFormat, f)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		singleFileFormat := NewMockFormat(ctrl)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(false).Times(2)
+		defer withFormatRegistry(map[string]Format{
+			"single": singleFileFormat,
+		})()
+
+		f, err := DetectBundleFormat(b)
+		assert.Nil(t, f)
+		assert.EqualError(t, err, ErrFormatNotFound.Error())
+	})
+}
+
+func TestNewBundleReader(t *testing.T) {
+	b, err := NewTarBundle(bytes.NewReader(testTar(t)))
+	require.NoError(t, err)
+
+	t.Run("BundleFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedReader := NewMockReader(ctrl)
+		expectedMeta := Metadata{
+			Description: "test",
+		}
+
+		bundleFormat := NewMockBundleFormat(ctrl)
+		bundleFormat.EXPECT().NewBundleReader(b).Return(expectedReader, expectedMeta, nil)
+
+		reader, meta, err := NewBundleReader(bundleFormat, b)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expectedReader, reader)
+		assert.EqualValues(t, expectedMeta, meta)
+	})
+
+	t.Run("SingleFileFormat", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expectedReader := NewMockReader(ctrl)
+		expectedMeta := Metadata{
+			Description: "test",
+		}
+
+		singleFileFormat := NewMockFormat(ctrl)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(false)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(true)
+		singleFileFormat.EXPECT().NewReaderAt(gomock.Any()).DoAndReturn(func(s ReaderSource) (Reader, Metadata, error) {
+			data, err := ioutil.ReadAll(io.NewSectionReader(s, 0, s.Size()))
+			require.NoError(t, err)
+			assert.EqualValues(t, "b", string(data))
+			return expectedReader, expectedMeta, nil
+		})
+
+		reader, meta, err := NewBundleReader(singleFileFormat, b)
+		assert.NoError(t, err)
+		assert.EqualValues(t, expectedReader, reader)
+		assert.EqualValues(t, expectedMeta, meta)
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		singleFileFormat := NewMockFormat(ctrl)
+		singleFileFormat.EXPECT().DetectFormat(gomock.Any()).Return(false).Times(2)
+
+		reader, _, err := NewBundleReader(singleFileFormat, b)
+		assert.Nil(t, reader)
+		assert.EqualError(t, err, ErrFormatNotFound.Error())
+	})
+}
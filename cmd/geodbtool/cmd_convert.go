@@ -7,29 +7,46 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"strings"
 	"time"
 
 	"github.com/anexia-it/geodbtools"
 	"github.com/cheggaaa/pb"
 	"github.com/spf13/cobra"
-	"go.uber.org/multierr"
 )
 
 var cmdConvert = &cobra.Command{
 	Use:   "convert <database> <target>",
 	Short: `Convert a GeoIP database from one format to another`,
-	Args:  cobra.ExactArgs(2),
+	Long: `Convert a GeoIP database from one format to another.
+
+The database may either be a single database file or a bundle holding the database files,
+i.e. a directory or a tar, tar.gz or ZIP archive.`,
+	Args: cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		var inputFormatName, outputFormatName string
 		var ipVersionInt8 int8
-		var verify, force bool
+		var verify, verifyRanges, force, compact bool
+		var verifyWorkers, maxFailures, samples int
+		var reportOutputName string
 
 		inputFormatName, _ = cmd.Flags().GetString("in-format")
 		outputFormatName, _ = cmd.Flags().GetString("out-format")
 		ipVersionInt8, _ = cmd.Flags().GetInt8("ip-version")
 		verify, _ = cmd.Flags().GetBool("verify")
+		verifyRanges, _ = cmd.Flags().GetBool("verify-ranges")
+		verifyWorkers, _ = cmd.Flags().GetInt("verify-workers")
+		maxFailures, _ = cmd.Flags().GetInt("max-failures")
+		samples, _ = cmd.Flags().GetInt("samples")
+		reportOutputName, _ = cmd.Flags().GetString("report")
 		force, _ = cmd.Flags().GetBool("force")
+		compact, _ = cmd.Flags().GetBool("compact")
+
+		if reportOutputName != "text" && reportOutputName != "json" {
+			err = fmt.Errorf("unsupported report format: %s", reportOutputName)
+			return
+		}
 
 		inputPath := args[0]
 		outputPath := args[1]
@@ -54,26 +71,15 @@ var cmdConvert = &cobra.Command{
 			return
 		}
 
-		var inputReaderSource geodbtools.ReaderSource
-		if inputReaderSource, err = geodbtools.NewFileReaderSource(inputPath); err != nil {
+		var input *database
+		if input, err = openDatabase(inputPath, inputFormatName); err != nil {
 			return
 		}
-		defer inputReaderSource.Close()
+		defer input.Close()
 
-		var inputFormat geodbtools.Format
+		inputFormat, inputReader, meta := input.format, input.reader, input.meta
 		if inputFormatName == "auto" {
-			if inputFormat, err = geodbtools.DetectFormat(inputReaderSource); err != nil {
-				return
-			}
 			cmd.Printf("detected input format: %s\n", inputFormat.FormatName())
-		} else if inputFormat, err = geodbtools.LookupFormat(inputFormatName); err != nil {
-			return
-		}
-
-		var inputReader geodbtools.Reader
-		var meta geodbtools.Metadata
-		if inputReader, meta, err = inputFormat.NewReaderAt(inputReaderSource); err != nil {
-			return
 		}
 
 		var recordTree *geodbtools.RecordTree
@@ -93,38 +99,41 @@ var cmdConvert = &cobra.Command{
 
 		cmd.Printf("starting conversion from %s format to %s format...\n", inputFormat.FormatName(), outputFormat.FormatName())
 		convertStartAt := time.Now()
-		if err = outputWriter.WriteDatabase(meta, recordTree); err != nil {
+		var compactionStats *geodbtools.CompactionStats
+		if compact {
+			// the tree may be cached by the reader, which is used for verification
+			compactedTree := recordTree.Clone()
+			stats := compactedTree.Compact()
+			compactionStats = &stats
+			err = outputWriter.WriteDatabase(meta, compactedTree)
+		} else {
+			compactionStats, err = geodbtools.WriteDatabase(outputWriter, meta, recordTree)
+		}
+		if err != nil {
 			return
 		}
+
+		if compactionStats != nil {
+			cmd.Printf("record tree compacted from %d to %d nodes\n", compactionStats.NodesBefore, compactionStats.NodesAfter)
+		}
 		cmd.Printf("conversion finished after %s\n", time.Since(convertStartAt))
 
-		if verify {
+		if verify || verifyRanges {
 
 			var verifyReader geodbtools.Reader
 
-			if verifyReader, _, err = outputFormat.NewReaderAt(geodbtools.NewReaderSourceWrapper(bytes.NewReader(outputBuffer.Bytes()), int64(outputBuffer.Len()))); err != nil {
+			if verifyReader, _, err = outputFormat.NewReaderAt(geodbtools.NewBytesReaderSource(outputBuffer.Bytes())); err != nil {
 				return
 			}
 
 			cmd.Println("starting verification...")
 
-			var progress *pb.ProgressBar
-
 			progressReports := make(chan *geodbtools.VerificationProgress, 8)
-			defer func() {
-				if progressReports != nil {
-					close(progressReports)
-				}
-
-				if progress != nil {
-					progress.Finish()
-				}
-			}()
-
 			progressDoneCtx, progressDone := context.WithCancel(context.Background())
 			go func() {
 				defer progressDone()
 
+				var progress *pb.ProgressBar
 				for report := range progressReports {
 					if progress == nil {
 						progress = pb.StartNew(report.TotalRecords)
@@ -133,21 +142,45 @@ var cmdConvert = &cobra.Command{
 
 					progress.SetCurrent(int64(report.CheckedRecords))
 				}
-				progress.Finish()
-				progress = nil
+
+				if progress != nil {
+					progress.Finish()
+				}
 			}()
 
+			verifyCtx, stopVerification := signal.NotifyContext(context.Background(), os.Interrupt)
+			defer stopVerification()
+
+			verifyOptions := geodbtools.VerifyOptions{
+				Workers:     verifyWorkers,
+				MaxFailures: maxFailures,
+				MaxSamples:  samples,
+				Progress:    progressReports,
+			}
+
 			verifyStartAt := time.Now()
-			err = geodbtools.Verify(verifyReader, recordTree, progressReports)
+			var report *geodbtools.VerificationReport
+			if verifyRanges {
+				report, err = geodbtools.VerifyRangesContext(verifyCtx, verifyReader, recordTree, ipVersion, verifyOptions)
+			} else {
+				report, err = geodbtools.VerifyContext(verifyCtx, verifyReader, recordTree, verifyOptions)
+			}
 			close(progressReports)
 			<-progressDoneCtx.Done()
-			progressReports = nil
 			if err != nil {
-				verificationErrors := multierr.Errors(err)
-				for i, verificationErr := range verificationErrors {
-					cmd.Printf("error #%d: %s\n", i+1, verificationErr.Error())
-				}
-				err = fmt.Errorf("verification failed with %d errors", len(verificationErrors))
+				return
+			}
+
+			reportWriter := cmd.OutOrStderr()
+			if reportOutputName == "json" {
+				reportWriter = cmd.OutOrStdout()
+			}
+			if err = writeVerificationReport(reportWriter, report, reportOutputName); err != nil {
+				return
+			}
+
+			if report.Failures > 0 {
+				err = fmt.Errorf("verification failed with %d errors", report.Failures)
 				return
 			}
 			cmd.Printf("verification finished after %s\n", time.Since(verifyStartAt))
@@ -174,6 +207,12 @@ func init() {
 	cmdConvert.Flags().StringP("out-format", "O", "", fmt.Sprintf("output format (%s)", strings.Join(geodbtools.FormatNames(), "|")))
 	cmdConvert.Flags().Int8P("ip-version", "i", 4, "IP version (4|6)")
 	cmdConvert.Flags().BoolP("verify", "V", false, "enables verification of the conversion by checking all records")
+	cmdConvert.Flags().BoolP("verify-ranges", "R", false, "enables verification of the conversion by comparing both databases across the whole address space, implies --verify")
+	cmdConvert.Flags().Int("verify-workers", 0, "number of concurrent lookups during verification, defaults to the number of CPUs")
+	cmdConvert.Flags().Int("max-failures", 0, "stops the verification after the given number of failures, 0 for no limit")
+	cmdConvert.Flags().Int("samples", geodbtools.DefaultVerificationSamples, "number of failures included in the verification report as examples")
+	cmdConvert.Flags().String("report", "text", "output format of the verification report (text|json)")
 	cmdConvert.Flags().BoolP("force", "f", false, "overwrites existing output files")
+	cmdConvert.Flags().BoolP("compact", "c", false, "compacts the record tree before writing, even if the output format does not opt in")
 	cmdRoot.AddCommand(cmdConvert)
 }
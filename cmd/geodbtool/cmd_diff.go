@@ -0,0 +1,289 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/spf13/cobra"
+)
+
+// diffOutput renders the ranges and the summary of a diff
+type diffOutput interface {
+	// writeRange writes a single differing range
+	writeRange(r geodbtools.DiffRange) error
+
+	// finish writes the summary, along with anything else pending
+	finish(summary *geodbtools.DiffSummary) error
+}
+
+// diffRangeJSON represents a differing range in JSON output
+type diffRangeJSON struct {
+	First    string                 `json:"first"`
+	Last     string                 `json:"last"`
+	Networks []string               `json:"networks"`
+	Old      map[string]interface{} `json:"old"`
+	New      map[string]interface{} `json:"new"`
+}
+
+// diffRangeNetworks returns the networks covering the given range, as strings
+func diffRangeNetworks(r geodbtools.DiffRange) (networks []string) {
+	for _, network := range r.Networks() {
+		networks = append(networks, network.String())
+	}
+	return
+}
+
+// diffRecordFields returns the fields of the given record for JSON output, nil if the record is nil
+func diffRecordFields(rec geodbtools.Record) map[string]interface{} {
+	if rec == nil {
+		return nil
+	}
+	return geodbtools.RecordFields(rec)
+}
+
+// sortedCountryCodes returns the country codes of the given summary in alphabetical order
+func sortedCountryCodes(summary *geodbtools.DiffSummary) []string {
+	countryCodes := make([]string, 0, len(summary.Countries))
+	for countryCode := range summary.Countries {
+		countryCodes = append(countryCodes, countryCode)
+	}
+	sort.Strings(countryCodes)
+	return countryCodes
+}
+
+// textDiffOutput renders a diff as human-readable text
+type textDiffOutput struct {
+	w           io.Writer
+	summaryOnly bool
+}
+
+func (o *textDiffOutput) writeRange(r geodbtools.DiffRange) (err error) {
+	if o.summaryOnly {
+		return
+	}
+
+	_, err = fmt.Fprintf(o.w, "%s-%s (%s): %s -> %s\n", r.First, r.Last, strings.Join(diffRangeNetworks(r), ", "), recordSummary(r.Old), recordSummary(r.New))
+	return
+}
+
+func (o *textDiffOutput) finish(summary *geodbtools.DiffSummary) (err error) {
+	if !o.summaryOnly && summary.Ranges > 0 {
+		if _, err = fmt.Fprintln(o.w); err != nil {
+			return
+		}
+	}
+
+	if _, err = fmt.Fprintf(o.w, "%d differing ranges\n", summary.Ranges); err != nil {
+		return
+	}
+
+	for _, countryCode := range sortedCountryCodes(summary) {
+		country := summary.Countries[countryCode]
+		if _, err = fmt.Fprintf(o.w, "%s: gained %d ranges (%s addresses), lost %d ranges (%s addresses), changed %d ranges\n",
+			countryCode, country.RangesGained, country.AddressesGained, country.RangesLost, country.AddressesLost, country.RangesChanged); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// jsonDiffOutput renders a diff as a single JSON object, streaming the ranges
+type jsonDiffOutput struct {
+	w           io.Writer
+	summaryOnly bool
+	ranges      int
+}
+
+func (o *jsonDiffOutput) writeRange(r geodbtools.DiffRange) (err error) {
+	if o.summaryOnly {
+		return
+	}
+
+	prefix := ","
+	if o.ranges == 0 {
+		prefix = `{"ranges":[`
+	}
+	o.ranges++
+
+	var b []byte
+	if b, err = json.Marshal(diffRangeJSON{
+		First:    r.First.String(),
+		Last:     r.Last.String(),
+		Networks: diffRangeNetworks(r),
+		Old:      diffRecordFields(r.Old),
+		New:      diffRecordFields(r.New),
+	}); err != nil {
+		return
+	}
+
+	_, err = io.WriteString(o.w, prefix+string(b))
+	return
+}
+
+func (o *jsonDiffOutput) finish(summary *geodbtools.DiffSummary) (err error) {
+	var b []byte
+	if b, err = json.Marshal(summary); err != nil {
+		return
+	}
+
+	switch {
+	case o.summaryOnly:
+		_, err = fmt.Fprintf(o.w, "%s\n", b)
+	case o.ranges == 0:
+		_, err = fmt.Fprintf(o.w, `{"ranges":[],"summary":%s}`+"\n", b)
+	default:
+		_, err = fmt.Fprintf(o.w, `],"summary":%s}`+"\n", b)
+	}
+	return
+}
+
+// csvDiffOutput renders either the ranges or the summary of a diff as CSV
+type csvDiffOutput struct {
+	w           *csv.Writer
+	summaryOnly bool
+	ranges      int
+}
+
+func (o *csvDiffOutput) writeRange(r geodbtools.DiffRange) (err error) {
+	if o.summaryOnly {
+		return
+	}
+
+	if o.ranges == 0 {
+		if err = o.w.Write([]string{"first", "last", "networks", "old", "new"}); err != nil {
+			return
+		}
+	}
+	o.ranges++
+
+	err = o.w.Write([]string{r.First.String(), r.Last.String(), strings.Join(diffRangeNetworks(r), " "), recordSummary(r.Old), recordSummary(r.New)})
+	return
+}
+
+func (o *csvDiffOutput) finish(summary *geodbtools.DiffSummary) (err error) {
+	if o.summaryOnly {
+		if err = o.w.Write([]string{"country_code", "ranges_gained", "ranges_lost", "ranges_changed", "addresses_gained", "addresses_lost"}); err != nil {
+			return
+		}
+
+		for _, countryCode := range sortedCountryCodes(summary) {
+			country := summary.Countries[countryCode]
+			if err = o.w.Write([]string{
+				countryCode,
+				fmt.Sprint(country.RangesGained),
+				fmt.Sprint(country.RangesLost),
+				fmt.Sprint(country.RangesChanged),
+				country.AddressesGained.String(),
+				country.AddressesLost.String(),
+			}); err != nil {
+				return
+			}
+		}
+	} else if o.ranges == 0 {
+		if err = o.w.Write([]string{"first", "last", "networks", "old", "new"}); err != nil {
+			return
+		}
+	}
+
+	o.w.Flush()
+	return o.w.Error()
+}
+
+// newDiffOutput returns the diff output of the given name
+func newDiffOutput(name string, w io.Writer, summaryOnly bool) (output diffOutput, err error) {
+	switch name {
+	case "text":
+		output = &textDiffOutput{w: w, summaryOnly: summaryOnly}
+	case "json":
+		output = &jsonDiffOutput{w: w, summaryOnly: summaryOnly}
+	case "csv":
+		output = &csvDiffOutput{w: csv.NewWriter(w), summaryOnly: summaryOnly}
+	default:
+		err = fmt.Errorf("unsupported output format: %s", name)
+	}
+	return
+}
+
+var cmdDiff = &cobra.Command{
+	Use:   "diff <old database> <new database>",
+	Short: "Show the address ranges whose records differ between two GeoIP databases",
+	Long: `Show the address ranges whose records differ between two GeoIP databases.
+
+The databases may be of different formats. Each range is reported along with its old and new record,
+followed by a summary of the changes per country.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var oldFormatName, newFormatName, outputName, prefixString string
+		var ipVersionInt8 int8
+		var summaryOnly bool
+
+		oldFormatName, _ = cmd.Flags().GetString("old-format")
+		newFormatName, _ = cmd.Flags().GetString("new-format")
+		outputName, _ = cmd.Flags().GetString("output")
+		prefixString, _ = cmd.Flags().GetString("prefix")
+		ipVersionInt8, _ = cmd.Flags().GetInt8("ip-version")
+		summaryOnly, _ = cmd.Flags().GetBool("summary")
+
+		filter := geodbtools.NetworkFilter{
+			IPVersion: geodbtools.IPVersion(ipVersionInt8),
+		}
+		if filter.IPVersion != geodbtools.IPVersion4 && filter.IPVersion != geodbtools.IPVersion6 {
+			err = geodbtools.ErrUnsupportedIPVersion
+			return
+		}
+
+		if prefixString != "" {
+			if filter.Prefix, err = netip.ParsePrefix(prefixString); err != nil {
+				return
+			}
+			filter.IPVersion = geodbtools.IPVersionUndefined
+		}
+
+		var output diffOutput
+		if output, err = newDiffOutput(outputName, cmd.OutOrStdout(), summaryOnly); err != nil {
+			return
+		}
+
+		var oldDB, newDB *database
+		if oldDB, err = openDatabase(args[0], oldFormatName); err != nil {
+			return
+		}
+		defer oldDB.Close()
+
+		if newDB, err = openDatabase(args[1], newFormatName); err != nil {
+			return
+		}
+		defer newDB.Close()
+
+		summary := geodbtools.NewDiffSummary()
+		var outputErr error
+		if err = geodbtools.Diff(oldDB.reader, newDB.reader, filter, func(r geodbtools.DiffRange) bool {
+			summary.Add(r)
+			outputErr = output.writeRange(r)
+			return outputErr == nil
+		}); err != nil {
+			return
+		} else if err = outputErr; err != nil {
+			return
+		}
+
+		err = output.finish(summary)
+		return
+	},
+}
+
+func init() {
+	cmdDiff.Flags().String("old-format", "auto", fmt.Sprintf("format of the old database (auto|%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdDiff.Flags().String("new-format", "auto", fmt.Sprintf("format of the new database (auto|%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdDiff.Flags().Int8P("ip-version", "i", 4, "IP version (4|6)")
+	cmdDiff.Flags().StringP("prefix", "p", "", "restricts the comparison to the given prefix, overriding the IP version")
+	cmdDiff.Flags().StringP("output", "o", "text", "output format (text|json|csv)")
+	cmdDiff.Flags().BoolP("summary", "s", false, "only outputs the per-country summary")
+	cmdRoot.AddCommand(cmdDiff)
+}
@@ -11,32 +11,22 @@ import (
 var cmdInfo = &cobra.Command{
 	Use:   "info <database>",
 	Short: `Print information about a GeoIP database file`,
-	Args:  cobra.ExactArgs(1),
+	Long: `Print information about a GeoIP database.
+
+The database may either be a single database file or a bundle holding the database files,
+i.e. a directory or a tar, tar.gz or ZIP archive.`,
+	Args: cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) (err error) {
 		dbPath := args[0]
 		formatName, _ := cmd.Flags().GetString("format")
 
-		var source geodbtools.ReaderSource
-
-		if source, err = geodbtools.NewFileReaderSource(dbPath); err != nil {
-			return
-		}
-		defer source.Close()
-
-		var format geodbtools.Format
-
-		if formatName == "auto" {
-			if format, err = geodbtools.DetectFormat(source); err != nil {
-				return
-			}
-		} else if format, err = geodbtools.LookupFormat(formatName); err != nil {
+		var db *database
+		if db, err = openDatabase(dbPath, formatName); err != nil {
 			return
 		}
+		defer db.Close()
 
-		var meta geodbtools.Metadata
-		if _, meta, err = format.NewReaderAt(source); err != nil {
-			return
-		}
+		format, meta := db.format, db.meta
 
 		cmd.Printf("format         : %s\n", format.FormatName())
 		cmd.Printf("type           : %s\n", meta.Type)
@@ -1,64 +1,359 @@
 package main
 
 import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"net"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
 	"strings"
 
 	"github.com/anexia-it/geodbtools"
 	"github.com/spf13/cobra"
 )
 
-var cmdLookup = &cobra.Command{
-	Use:   "lookup <ip address>",
-	Short: "Look up GeoIP information for an IP address",
-	Args:  cobra.ExactArgs(1),
-	RunE: func(cmd *cobra.Command, args []string) (err error) {
-		ip := net.ParseIP(args[0])
-		if ip == nil {
-			err = errors.New("invalid IP address")
+// errInvalidIPAddress indicates that an address to look up could not be parsed
+var errInvalidIPAddress = errors.New("invalid IP address")
+
+// lookupResult holds the result of looking up a single address
+type lookupResult struct {
+	// input holds the address as given
+	input string
+
+	// record holds the record found
+	record geodbtools.Record
+
+	// prefix holds the network the address has been found in
+	prefix netip.Prefix
+
+	// err holds the error encountered while parsing or looking up the address
+	err error
+}
+
+// lookupResultJSON represents a lookup result in JSON output
+type lookupResultJSON struct {
+	IP      string                 `json:"ip"`
+	Network string                 `json:"network,omitempty"`
+	Record  map[string]interface{} `json:"record"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// newLookupResultJSON returns the JSON representation of the given result
+func newLookupResultJSON(result lookupResult) (resultJSON lookupResultJSON) {
+	resultJSON = lookupResultJSON{
+		IP:     result.input,
+		Record: diffRecordFields(result.record),
+	}
+	if result.prefix.IsValid() {
+		resultJSON.Network = result.prefix.String()
+	}
+	if result.err != nil {
+		resultJSON.Error = result.err.Error()
+	}
+	return
+}
+
+// lookupColumns holds the record fields written as columns of CSV and TSV output, as named by geodbtools.RecordFields.
+// Localized city names are not included.
+var lookupColumns = []string{
+	"country_code",
+	"country_name",
+	"registered_country_code",
+	"represented_country_code",
+	"represented_country_type",
+	"continent_code",
+	"subdivision_codes",
+	"region_code",
+	"city_name",
+	"postal_code",
+	"latitude",
+	"longitude",
+	"accuracy_radius",
+	"time_zone",
+	"metro_code",
+	"area_code",
+	"as_number",
+	"organization",
+}
+
+// lookupOutput renders the results of a lookup
+type lookupOutput interface {
+	// writeResult writes the result of a single address
+	writeResult(result lookupResult) error
+
+	// finish writes anything pending
+	finish() error
+}
+
+// textLookupOutput renders lookup results as human-readable text
+type textLookupOutput struct {
+	cmd     *cobra.Command
+	verbose bool
+	batch   bool
+	results int
+}
+
+func (o *textLookupOutput) writeResult(result lookupResult) (err error) {
+	if o.batch {
+		if o.results > 0 {
+			o.cmd.Println()
+		}
+		o.cmd.Printf("address          : %s\n", result.input)
+	}
+	o.results++
+
+	if result.err != nil {
+		o.cmd.Printf("error            : %s\n", result.err)
+		return
+	}
+
+	printRecord(o.cmd, result.record, o.verbose)
+	return
+}
+
+func (o *textLookupOutput) finish() error {
+	return nil
+}
+
+// jsonLookupOutput renders lookup results as a JSON array, or as JSON Lines if lines is set
+type jsonLookupOutput struct {
+	w       io.Writer
+	lines   bool
+	results int
+}
+
+func (o *jsonLookupOutput) writeResult(result lookupResult) (err error) {
+	var b []byte
+	if b, err = json.Marshal(newLookupResultJSON(result)); err != nil {
+		return
+	}
+
+	prefix := ","
+	switch {
+	case o.lines:
+		prefix = ""
+		b = append(b, '\n')
+	case o.results == 0:
+		prefix = "["
+	}
+	o.results++
+
+	_, err = io.WriteString(o.w, prefix+string(b))
+	return
+}
+
+func (o *jsonLookupOutput) finish() (err error) {
+	switch {
+	case o.lines:
+	case o.results == 0:
+		_, err = io.WriteString(o.w, "[]\n")
+	default:
+		_, err = io.WriteString(o.w, "]\n")
+	}
+	return
+}
+
+// csvLookupOutput renders lookup results as CSV, or TSV if the writer's delimiter is set accordingly
+type csvLookupOutput struct {
+	w       *csv.Writer
+	results int
+}
+
+// writeHeader writes the header line
+func (o *csvLookupOutput) writeHeader() error {
+	header := append([]string{"ip", "network"}, lookupColumns...)
+	return o.w.Write(append(header, "error"))
+}
+
+func (o *csvLookupOutput) writeResult(result lookupResult) (err error) {
+	if o.results == 0 {
+		if err = o.writeHeader(); err != nil {
+			return
+		}
+	}
+	o.results++
+
+	row := make([]string, 0, len(lookupColumns)+3)
+	row = append(row, result.input, "")
+	if result.prefix.IsValid() {
+		row[1] = result.prefix.String()
+	}
+
+	fields := geodbtools.RecordFields(result.record)
+	for _, column := range lookupColumns {
+		value, ok := fields[column]
+		switch {
+		case !ok:
+			row = append(row, "")
+		case column == "subdivision_codes":
+			row = append(row, strings.Join(value.([]string), "/"))
+		default:
+			row = append(row, fmt.Sprint(value))
+		}
+	}
+
+	errString := ""
+	if result.err != nil {
+		errString = result.err.Error()
+	}
+
+	err = o.w.Write(append(row, errString))
+	return
+}
+
+func (o *csvLookupOutput) finish() (err error) {
+	if o.results == 0 {
+		if err = o.writeHeader(); err != nil {
 			return
 		}
+	}
+
+	o.w.Flush()
+	return o.w.Error()
+}
 
-		var formatName string
-		var dbPath string
+// newLookupOutput returns the lookup output of the given name
+func newLookupOutput(name string, cmd *cobra.Command, verbose, batch bool) (output lookupOutput, err error) {
+	switch name {
+	case "text":
+		output = &textLookupOutput{cmd: cmd, verbose: verbose, batch: batch}
+	case "json":
+		output = &jsonLookupOutput{w: cmd.OutOrStdout()}
+	case "jsonl":
+		output = &jsonLookupOutput{w: cmd.OutOrStdout(), lines: true}
+	case "csv":
+		output = &csvLookupOutput{w: csv.NewWriter(cmd.OutOrStdout())}
+	case "tsv":
+		w := csv.NewWriter(cmd.OutOrStdout())
+		w.Comma = '\t'
+		output = &csvLookupOutput{w: w}
+	default:
+		err = fmt.Errorf("unsupported output format: %s", name)
+	}
+	return
+}
+
+// readLookupAddresses calls the given function for each address read from the given reader, one address per line.
+// Empty lines and lines starting with "#" are skipped.
+func readLookupAddresses(r io.Reader, fn func(address string) error) (err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		address := strings.TrimSpace(scanner.Text())
+		if address == "" || strings.HasPrefix(address, "#") {
+			continue
+		}
+
+		if err = fn(address); err != nil {
+			return
+		}
+	}
+	return scanner.Err()
+}
+
+// lookupAddress looks up the given address using the given reader
+func lookupAddress(reader geodbtools.Reader, address string) (result lookupResult) {
+	result.input = address
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		result.err = errInvalidIPAddress
+		return
+	}
+
+	result.record, result.prefix, result.err = geodbtools.LookupNetwork(reader, addr)
+	return
+}
+
+var cmdLookup = &cobra.Command{
+	Use:   "lookup [<ip address>...]",
+	Short: "Look up GeoIP information for IP addresses",
+	Long: `Look up GeoIP information for IP addresses.
+
+The addresses are taken from the arguments or, if an input file is given or no arguments are present, read from the
+input, one address per line. The database is opened once for all addresses.
+Structured output formats (json|jsonl|csv|tsv) are written to standard output and report the matched network,
+all record fields and the error encountered, if any, for each address.`,
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var formatName, dbPath, inputPath, outputName string
 		var verbose bool
 		dbPath, _ = cmd.Flags().GetString("db")
 		formatName, _ = cmd.Flags().GetString("format")
+		inputPath, _ = cmd.Flags().GetString("input")
+		outputName, _ = cmd.Flags().GetString("output")
 		verbose, _ = cmd.Flags().GetBool("verbose")
 
-		var source geodbtools.ReaderSource
+		// a single address given as argument retains the plain output and exit status of a single lookup
+		single := len(args) == 1 && inputPath == ""
+		if single && outputName == "text" {
+			if _, err = netip.ParseAddr(args[0]); err != nil {
+				err = errInvalidIPAddress
+				return
+			}
+		}
+
+		var output lookupOutput
+		if output, err = newLookupOutput(outputName, cmd, verbose, !single); err != nil {
+			return
+		}
 
-		if source, err = geodbtools.NewFileReaderSource(dbPath); err != nil {
+		var input io.Reader
+		switch {
+		case inputPath == "-" || (inputPath == "" && len(args) == 0):
+			input = os.Stdin
+		case inputPath != "":
+			var inputFile *os.File
+			if inputFile, err = os.Open(inputPath); err != nil {
+				return
+			}
+			defer inputFile.Close()
+			input = inputFile
+		}
+
+		var db *database
+		if db, err = openDatabase(dbPath, formatName); err != nil {
 			return
 		}
-		defer source.Close()
+		defer db.Close()
 
-		var format geodbtools.Format
+		if verbose && formatName == "auto" {
+			cmd.Printf("detected format: %s\n", db.format.FormatName())
+		}
 
-		if formatName == "auto" {
-			if format, err = geodbtools.DetectFormat(source); err != nil {
-				return
+		var failed int
+		lookup := func(address string) error {
+			result := lookupAddress(db.reader, address)
+			if result.err != nil {
+				failed++
+				if single && outputName == "text" {
+					return result.err
+				}
 			}
-			if verbose {
-				cmd.Printf("detected format: %s\n", format.FormatName())
+			return output.writeResult(result)
+		}
+
+		for _, address := range args {
+			if err = lookup(address); err != nil {
+				return
 			}
-		} else if format, err = geodbtools.LookupFormat(formatName); err != nil {
-			return
 		}
 
-		var reader geodbtools.Reader
-		if reader, _, err = format.NewReaderAt(source); err != nil {
-			return
+		if input != nil {
+			if err = readLookupAddresses(input, lookup); err != nil {
+				return
+			}
 		}
 
-		var record geodbtools.Record
-		if record, err = reader.LookupIP(ip); err != nil {
+		if err = output.finish(); err != nil {
 			return
 		}
 
-		printRecord(cmd, record, verbose)
+		if failed > 0 && outputName == "text" {
+			err = fmt.Errorf("%d lookups failed", failed)
+		}
 		return
 	},
 }
@@ -70,6 +365,76 @@ func printRecord(cmd *cobra.Command, rec geodbtools.Record, verbose bool) {
 		cmd.Printf("country          : %s\n", t.GetCountryCode())
 	}
 
+	if t, ok := rec.(geodbtools.RegisteredCountryRecord); ok && t.GetRegisteredCountryCode() != "" {
+		cmd.Printf("registered in    : %s\n", t.GetRegisteredCountryCode())
+	}
+
+	if t, ok := rec.(geodbtools.RepresentedCountryRecord); ok && t.GetRepresentedCountryCode() != "" {
+		cmd.Printf("represents       : %s (%s)\n", t.GetRepresentedCountryCode(), t.GetRepresentedCountryType())
+	}
+
+	if t, ok := rec.(geodbtools.ContinentRecord); ok && t.GetContinentCode() != "" {
+		cmd.Printf("continent        : %s\n", t.GetContinentCode())
+	}
+
+	if t, ok := rec.(geodbtools.SubdivisionRecord); ok {
+		if subdivisionCodes := t.GetSubdivisionCodes(); len(subdivisionCodes) > 0 {
+			cmd.Printf("subdivisions     : %s\n", strings.Join(subdivisionCodes, ", "))
+		}
+	} else if t, ok := rec.(geodbtools.RegionRecord); ok && t.GetRegionCode() != "" {
+		cmd.Printf("region           : %s\n", t.GetRegionCode())
+	}
+
+	if t, ok := rec.(geodbtools.CityRecord); ok && t.GetCityName() != "" {
+		cmd.Printf("city             : %s\n", t.GetCityName())
+	}
+
+	if t, ok := rec.(geodbtools.LocalizedCityRecord); ok && verbose {
+		cityNames := t.GetCityNames()
+		locales := make([]string, 0, len(cityNames))
+		for locale := range cityNames {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+
+		for _, locale := range locales {
+			cmd.Printf("city name %-6s : %s\n", "("+locale+")", cityNames[locale])
+		}
+	}
+
+	if t, ok := rec.(geodbtools.PostalCodeRecord); ok && t.GetPostalCode() != "" {
+		cmd.Printf("postal code      : %s\n", t.GetPostalCode())
+	}
+
+	if t, ok := rec.(geodbtools.LocationRecord); ok {
+		cmd.Printf("location         : %.4f, %.4f\n", t.GetLatitude(), t.GetLongitude())
+	}
+
+	if t, ok := rec.(geodbtools.AccuracyRadiusRecord); ok && t.GetAccuracyRadius() > 0 {
+		cmd.Printf("accuracy radius  : %d km\n", t.GetAccuracyRadius())
+	}
+
+	if t, ok := rec.(geodbtools.TimeZoneRecord); ok && t.GetTimeZone() != "" {
+		cmd.Printf("time zone        : %s\n", t.GetTimeZone())
+	}
+
+	if t, ok := rec.(geodbtools.MetroCodeRecord); ok {
+		if t.GetMetroCode() > 0 {
+			cmd.Printf("metro code       : %d\n", t.GetMetroCode())
+		}
+		if t.GetAreaCode() > 0 {
+			cmd.Printf("area code        : %d\n", t.GetAreaCode())
+		}
+	}
+
+	if t, ok := rec.(geodbtools.ASNRecord); ok && t.GetASNumber() > 0 {
+		cmd.Printf("AS number        : %d\n", t.GetASNumber())
+	}
+
+	if t, ok := rec.(geodbtools.OrganizationRecord); ok && t.GetOrganization() != "" {
+		cmd.Printf("organization     : %s\n", t.GetOrganization())
+	}
+
 	if verbose {
 		if rec.GetNetwork() != nil {
 			cmd.Printf("matching network : %s\n", rec.GetNetwork())
@@ -79,8 +444,10 @@ func printRecord(cmd *cobra.Command, rec geodbtools.Record, verbose bool) {
 
 func init() {
 	cmdLookup.Flags().BoolP("verbose", "v", false, "enables verbose output")
-	cmdLookup.Flags().StringP("db", "d", "", "database file")
+	cmdLookup.Flags().StringP("db", "d", "", "database file or bundle (directory, tar, tar.gz or ZIP archive)")
 	cmdLookup.Flags().StringP("format", "f", "auto", fmt.Sprintf("database format (auto|%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdLookup.Flags().StringP("input", "i", "", "reads addresses from the given file, one per line (- for standard input)")
+	cmdLookup.Flags().StringP("output", "o", "text", "output format (text|json|jsonl|csv|tsv)")
 
 	cmdRoot.AddCommand(cmdLookup)
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/spf13/cobra"
+)
+
+var cmdMerge = &cobra.Command{
+	Use:   "merge <target> <database> [<database>...]",
+	Short: "Merge multiple GeoIP databases into one",
+	Long: `Merge multiple GeoIP databases into one.
+
+The databases are overlaid in the order given, each database taking precedence over all databases before it.
+The databases may be of different formats, the type and description of the target database are taken from
+the first database. The input format applies to all databases if given once, or to the databases in order if
+given once per database, e.g. "-I mmdat -I mmdb".`,
+	Args: cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var inputFormatNames []string
+		var outputFormatName string
+		var ipVersionInt8 int8
+		var force, showConflicts bool
+
+		inputFormatNames, _ = cmd.Flags().GetStringArray("in-format")
+		outputFormatName, _ = cmd.Flags().GetString("out-format")
+		ipVersionInt8, _ = cmd.Flags().GetInt8("ip-version")
+		force, _ = cmd.Flags().GetBool("force")
+		showConflicts, _ = cmd.Flags().GetBool("conflicts")
+
+		outputPath := args[0]
+		inputPaths := args[1:]
+
+		if len(inputFormatNames) != 1 && len(inputFormatNames) != len(inputPaths) {
+			err = fmt.Errorf("%d input formats given for %d databases", len(inputFormatNames), len(inputPaths))
+			return
+		}
+
+		if outputFileInfo, statErr := os.Stat(outputPath); statErr == nil && !force {
+			err = errors.New("target file exists")
+			return
+		} else if statErr == nil && outputFileInfo.IsDir() {
+			err = errors.New("target is a directory")
+			return
+		}
+
+		ipVersion := geodbtools.IPVersion(ipVersionInt8)
+
+		if ipVersion != geodbtools.IPVersion4 && ipVersion != geodbtools.IPVersion6 {
+			err = geodbtools.ErrUnsupportedIPVersion
+			return
+		}
+
+		var outputFormat geodbtools.Format
+		if outputFormat, err = geodbtools.LookupFormat(outputFormatName); err != nil {
+			return
+		}
+
+		var meta geodbtools.Metadata
+		sources := make([]geodbtools.MergeSource, 0, len(inputPaths))
+		for i, inputPath := range inputPaths {
+			inputFormatName := inputFormatNames[0]
+			if len(inputFormatNames) > 1 {
+				inputFormatName = inputFormatNames[i]
+			}
+
+			var input *database
+			if input, err = openDatabase(inputPath, inputFormatName); err != nil {
+				return
+			}
+			defer input.Close()
+
+			if i == 0 {
+				meta = input.meta
+			}
+			cmd.Printf("opened %s (%s format, %s database)\n", inputPath, input.format.FormatName(), input.meta.Type)
+
+			sources = append(sources, geodbtools.MergeSource{
+				Name:   inputPath,
+				Reader: input.reader,
+			})
+		}
+
+		cmd.Println("starting merge...")
+		mergeStartAt := time.Now()
+		var conflicts int
+		var recordTree *geodbtools.RecordTree
+		if recordTree, err = geodbtools.Merge(sources, ipVersion, func(conflict geodbtools.MergeConflict) {
+			conflicts++
+			if showConflicts {
+				cmd.Printf("%s: %s (%s) overrides %s (%s)\n", conflict.Prefix, conflict.Source, recordSummary(conflict.Record),
+					conflict.OverriddenSource, recordSummary(conflict.OverriddenRecord))
+			}
+		}); err != nil {
+			return
+		}
+		cmd.Printf("merge finished after %s with %d conflicts\n", time.Since(mergeStartAt), conflicts)
+
+		meta.BuildTime = time.Now()
+		meta.IPVersion = ipVersion
+
+		outputBuffer := bytes.NewBufferString("")
+		var outputWriter geodbtools.Writer
+		if outputWriter, err = outputFormat.NewWriter(outputBuffer, meta.Type, ipVersion); err != nil {
+			return
+		}
+
+		cmd.Printf("starting write of %s database...\n", outputFormat.FormatName())
+		var compactionStats *geodbtools.CompactionStats
+		if compactionStats, err = geodbtools.WriteDatabase(outputWriter, meta, recordTree); err != nil {
+			return
+		}
+
+		if compactionStats != nil {
+			cmd.Printf("record tree compacted from %d to %d nodes\n", compactionStats.NodesBefore, compactionStats.NodesAfter)
+		}
+
+		var outputFile *os.File
+		if outputFile, err = os.OpenFile(outputPath, os.O_WRONLY|os.O_TRUNC|os.O_CREATE, 0600); err != nil {
+			return
+		}
+		defer outputFile.Close()
+
+		writeStartAt := time.Now()
+		if _, err = io.Copy(outputFile, outputBuffer); err != nil {
+			return
+		}
+		cmd.Printf("write finished after %s\n", time.Since(writeStartAt))
+		return
+	},
+}
+
+func init() {
+	cmdMerge.Flags().StringArrayP("in-format", "I", []string{"auto"}, fmt.Sprintf("format of all databases, or of each database if repeated (auto|%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdMerge.Flags().StringP("out-format", "O", "", fmt.Sprintf("output format (%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdMerge.Flags().Int8P("ip-version", "i", 4, "IP version (4|6)")
+	cmdMerge.Flags().BoolP("force", "f", false, "overwrites existing output files")
+	cmdMerge.Flags().Bool("conflicts", false, "prints each network overridden by a database of higher precedence")
+	cmdRoot.AddCommand(cmdMerge)
+}
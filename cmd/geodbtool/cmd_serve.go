@@ -0,0 +1,260 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/netip"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/anexia-it/geodbtools/httpapi"
+	"github.com/anexia-it/geodbtools/metrics"
+	"github.com/spf13/cobra"
+)
+
+// serveShutdownTimeout limits the time spent waiting for pending requests when shutting down
+const serveShutdownTimeout = 10 * time.Second
+
+// parseTrustedProxies parses the given networks or addresses of trusted proxies
+func parseTrustedProxies(values []string) (trustedProxies []netip.Prefix, err error) {
+	for _, value := range values {
+		var prefix netip.Prefix
+		if !strings.Contains(value, "/") {
+			var addr netip.Addr
+			if addr, err = netip.ParseAddr(value); err != nil {
+				return
+			}
+			addr = addr.Unmap()
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		} else if prefix, err = netip.ParsePrefix(value); err != nil {
+			return
+		}
+
+		trustedProxies = append(trustedProxies, prefix.Masked())
+	}
+	return
+}
+
+// openServedDatabase opens the database at the given path for serving, naming it after its type.
+// If events is not nil, the database is opened using a ReloadingReader, reloading it on SIGHUP and, if watchInterval
+// is set, whenever the file changes.
+func openServedDatabase(path, formatName string, watchInterval time.Duration, events chan<- *geodbtools.ReloadEvent) (db httpapi.Database, closer io.Closer, err error) {
+	if events == nil {
+		var opened *database
+		if opened, err = openDatabase(path, formatName); err != nil {
+			return
+		}
+
+		db = httpapi.Database{
+			Name:     string(opened.meta.Type),
+			Format:   opened.format.FormatName(),
+			Reader:   opened.reader,
+			Metadata: opened.meta,
+		}
+		closer = opened
+		return
+	}
+
+	// the format is determined once, so that reloads are rejected if the format changes
+	var format geodbtools.Format
+	if formatName != "auto" {
+		if format, err = geodbtools.LookupFormat(formatName); err != nil {
+			return
+		}
+	} else {
+		var source geodbtools.ReaderSource
+		if source, err = geodbtools.NewMmapReaderSource(path); err != nil {
+			return
+		}
+		format, err = geodbtools.DetectFormat(source)
+		source.Close()
+		if err != nil {
+			return
+		}
+	}
+
+	var reader *geodbtools.ReloadingReader
+	if reader, err = geodbtools.NewReloadingReader(path, geodbtools.ReloadOptions{
+		Format:       format,
+		PollInterval: watchInterval,
+		Signals:      []os.Signal{syscall.SIGHUP},
+		Events:       events,
+	}); err != nil {
+		return
+	}
+
+	db = httpapi.Database{
+		Name:     string(reader.Metadata().Type),
+		Format:   format.FormatName(),
+		Reader:   reader,
+		Metadata: reader.Metadata(),
+	}
+	closer = reader
+	return
+}
+
+var cmdServe = &cobra.Command{
+	Use:   "serve [<name>=]<database> [[<name>=]<database>...]",
+	Short: "Serve lookups in GeoIP databases over a JSON HTTP API",
+	Long: `Serve lookups in GeoIP databases over a JSON HTTP API.
+
+Each address is looked up in all databases, with results keyed by database name. Unless given explicitly, the name
+of a database is its type (e.g. country, city or asn).
+
+The following endpoints are provided:
+
+  GET  /lookup/<ip>  looks up the given address
+  POST /lookup       looks up the addresses held by the JSON array of strings passed as request body
+  GET  /me           looks up the address of the requester, honoring X-Forwarded-For headers of trusted proxies
+  GET  /health       reports the health of the service
+  GET  /metadata     returns the metadata of the databases
+
+With --reload or --watch, database files are reloaded on SIGHUP or when they change on disk, respectively. Lookups
+are served from the previous version until the new one has been opened and validated.
+
+Database files need to be updated by renaming a new file over the old one (e.g. using mv), rather than overwriting
+them in place (e.g. using cp or shell redirection). Without --reload or --watch, files are memory-mapped and an
+in-place overwrite corrupts lookups or crashes the server. With --reload or --watch, files are read into memory, but
+a file overwritten in place might be loaded while only partially written.
+
+With --metrics, lookup and reload metrics along with the build time and age of each database are exposed in the
+Prometheus text format at /metrics.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) (err error) {
+		var listenAddr, formatName string
+		var trustedProxyValues []string
+		var maxBatchSize int
+		var reload, exposeMetrics bool
+		var watchInterval time.Duration
+
+		listenAddr, _ = cmd.Flags().GetString("listen")
+		formatName, _ = cmd.Flags().GetString("format")
+		trustedProxyValues, _ = cmd.Flags().GetStringSlice("trusted-proxy")
+		maxBatchSize, _ = cmd.Flags().GetInt("max-batch")
+		reload, _ = cmd.Flags().GetBool("reload")
+		watchInterval, _ = cmd.Flags().GetDuration("watch")
+		exposeMetrics, _ = cmd.Flags().GetBool("metrics")
+
+		options := httpapi.Options{
+			MaxBatchSize: maxBatchSize,
+		}
+		if options.TrustedProxies, err = parseTrustedProxies(trustedProxyValues); err != nil {
+			return
+		}
+
+		var events chan *geodbtools.ReloadEvent
+		if reload || watchInterval > 0 {
+			events = make(chan *geodbtools.ReloadEvent)
+		}
+
+		var registry *metrics.Registry
+		if exposeMetrics {
+			registry = metrics.NewRegistry()
+		}
+		// instrumented holds the instrumented readers of each database file, recording its reloads
+		instrumented := make(map[string][]*metrics.Reader)
+
+		databases := make([]httpapi.Database, 0, len(args))
+		for _, arg := range args {
+			name, path := "", arg
+			if i := strings.Index(arg, "="); i >= 0 {
+				name, path = arg[:i], arg[i+1:]
+			}
+
+			var db httpapi.Database
+			var closer io.Closer
+			if db, closer, err = openServedDatabase(path, formatName, watchInterval, events); err != nil {
+				return
+			}
+			defer closer.Close()
+
+			if name != "" {
+				db.Name = name
+			}
+			cmd.Printf("opened %s as %s (%s format, %s database)\n", path, db.Name, db.Format, db.Metadata.Type)
+
+			if registry != nil {
+				reader := registry.Instrument(db.Reader, db.Name, db.Format, db.Metadata)
+				instrumented[path] = append(instrumented[path], reader)
+				db.Reader = reader
+			}
+			databases = append(databases, db)
+		}
+
+		if events != nil {
+			go func() {
+				for event := range events {
+					for _, reader := range instrumented[event.Path] {
+						reader.ObserveReload(event)
+					}
+
+					if event.Err != nil {
+						cmd.Printf("reloading %s failed: %s\n", event.Path, event.Err)
+						continue
+					}
+					cmd.Printf("reloaded %s, build time %s -> %s\n", event.Path, event.OldMetadata.BuildTime, event.NewMetadata.BuildTime)
+				}
+			}()
+		}
+
+		var handler http.Handler
+		if handler, err = httpapi.NewHandler(databases, options); errors.Is(err, httpapi.ErrDuplicateDatabase) {
+			err = errors.New("duplicate database name, use <name>=<database> to name databases explicitly")
+			return
+		} else if err != nil {
+			return
+		}
+
+		if registry != nil {
+			mux := http.NewServeMux()
+			mux.Handle("/", handler)
+			mux.Handle("/metrics", registry)
+			handler = mux
+		}
+
+		server := &http.Server{
+			Addr:              listenAddr,
+			Handler:           handler,
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		serveErr := make(chan error, 1)
+		go func() {
+			serveErr <- server.ListenAndServe()
+		}()
+		cmd.Printf("listening on %s\n", listenAddr)
+
+		select {
+		case err = <-serveErr:
+			return
+		case <-ctx.Done():
+		}
+
+		cmd.Println("shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), serveShutdownTimeout)
+		defer cancel()
+		err = server.Shutdown(shutdownCtx)
+		return
+	},
+}
+
+func init() {
+	cmdServe.Flags().StringP("listen", "l", "127.0.0.1:8080", "address to listen on")
+	cmdServe.Flags().StringP("format", "f", "auto", fmt.Sprintf("format of the databases (auto|%s)", strings.Join(geodbtools.FormatNames(), "|")))
+	cmdServe.Flags().StringSlice("trusted-proxy", nil, "networks or addresses of proxies whose X-Forwarded-For headers are trusted")
+	cmdServe.Flags().Int("max-batch", httpapi.DefaultMaxBatchSize, "maximum number of addresses per batch lookup")
+	cmdServe.Flags().Bool("reload", false, "reloads database files on SIGHUP (bundles are not supported)")
+	cmdServe.Flags().Duration("watch", 0, "interval at which database files are checked for changes, implies --reload")
+	cmdServe.Flags().Bool("metrics", false, "exposes metrics in Prometheus text format at /metrics")
+	cmdRoot.AddCommand(cmdServe)
+}
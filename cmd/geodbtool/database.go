@@ -0,0 +1,93 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// database holds an opened database, along with the resources backing it
+type database struct {
+	format  geodbtools.Format
+	reader  geodbtools.Reader
+	meta    geodbtools.Metadata
+	closers []io.Closer
+}
+
+// Close frees up the resources backing the database
+func (db *database) Close() (err error) {
+	for i := len(db.closers) - 1; i >= 0; i-- {
+		if closeErr := db.closers[i].Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	db.closers = nil
+	return
+}
+
+// openBundleDatabase opens the database contained in the given bundle
+func openBundleDatabase(db *database, b geodbtools.Bundle, formatName string) (err error) {
+	db.closers = append(db.closers, b)
+
+	if formatName == "auto" {
+		if db.format, err = geodbtools.DetectBundleFormat(b); err != nil {
+			return
+		}
+	} else if db.format, err = geodbtools.LookupFormat(formatName); err != nil {
+		return
+	}
+
+	db.reader, db.meta, err = geodbtools.NewBundleReader(db.format, b)
+	return
+}
+
+// openDatabase opens the database at the given path, using the format with the given name or detecting the format if
+// "auto" is passed.
+// The path may either refer to a single database file or to a bundle (directory, tar, tar.gz or ZIP archive) holding
+// the database files.
+func openDatabase(path, formatName string) (db *database, err error) {
+	db = &database{}
+	defer func() {
+		if err != nil {
+			db.Close()
+			db = nil
+		}
+	}()
+
+	var info os.FileInfo
+	if info, err = os.Stat(path); err != nil {
+		return
+	} else if info.IsDir() {
+		var b geodbtools.Bundle
+		if b, err = geodbtools.NewDirectoryBundle(path); err != nil {
+			return
+		}
+
+		err = openBundleDatabase(db, b, formatName)
+		return
+	}
+
+	var source geodbtools.ReaderSource
+	if source, err = geodbtools.NewMmapReaderSource(path); err != nil {
+		return
+	}
+	db.closers = append(db.closers, source)
+
+	// archives are always treated as bundles, as their members might be detected as database files otherwise
+	if b, bundleErr := geodbtools.NewArchiveBundle(source); bundleErr == nil {
+		err = openBundleDatabase(db, b, formatName)
+		return
+	}
+
+	if formatName == "auto" {
+		if db.format, err = geodbtools.DetectFormat(source); err != nil {
+			return
+		}
+	} else if db.format, err = geodbtools.LookupFormat(formatName); err != nil {
+		return
+	}
+
+	db.reader, db.meta, err = db.format.NewReaderAt(source)
+	return
+}
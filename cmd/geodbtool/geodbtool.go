@@ -1,6 +1,8 @@
 package main
 
 import (
+	_ "github.com/anexia-it/geodbtools/geoip2csvformat"
+	_ "github.com/anexia-it/geodbtools/legacycsvformat"
 	_ "github.com/anexia-it/geodbtools/mmdatformat"
 	_ "github.com/anexia-it/geodbtools/mmdbformat"
 )
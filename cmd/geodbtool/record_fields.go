@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// recordSummary returns a single-line representation of the fields of the given record.
+// "-" is returned if the record is nil and "(empty)" if it has no fields.
+func recordSummary(rec geodbtools.Record) string {
+	if rec == nil {
+		return "-"
+	}
+
+	fields := geodbtools.RecordFields(rec)
+	delete(fields, "city_names")
+	if len(fields) == 0 {
+		return "(empty)"
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		value := fields[key]
+		if subdivisionCodes, ok := value.([]string); ok {
+			value = strings.Join(subdivisionCodes, "/")
+		}
+		values = append(values, fmt.Sprintf("%s=%v", key, value))
+	}
+	return strings.Join(values, " ")
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// verificationSampleJSON represents an example of a verification failure in JSON output
+type verificationSampleJSON struct {
+	First       string                 `json:"first"`
+	Last        string                 `json:"last"`
+	Expected    map[string]interface{} `json:"expected"`
+	Actual      map[string]interface{} `json:"actual"`
+	LookupError string                 `json:"lookup_error,omitempty"`
+	Message     string                 `json:"message"`
+}
+
+// verificationReportJSON represents a verification report in JSON output
+type verificationReportJSON struct {
+	*geodbtools.VerificationReport
+	Samples []verificationSampleJSON `json:"samples"`
+}
+
+// verificationSampleRecords returns the expected record, the record found and the lookup error of the given sample
+func verificationSampleRecords(sample *geodbtools.VerificationSample) (expectedRecord, record geodbtools.Record, lookupErr error) {
+	switch err := sample.Err.(type) {
+	case *geodbtools.VerificationError:
+		return err.ExpectedRecord, err.Record, err.LookupError
+	case *geodbtools.RangeVerificationError:
+		return err.ExpectedRecord, err.Record, nil
+	}
+	return
+}
+
+// verificationCountryCode returns the given country code for text output
+func verificationCountryCode(countryCode string) string {
+	if countryCode == "" {
+		return "-"
+	}
+	return countryCode
+}
+
+// writeVerificationReport writes the given report in the given output format (text|json)
+func writeVerificationReport(w io.Writer, report *geodbtools.VerificationReport, outputName string) (err error) {
+	switch outputName {
+	case "text":
+		return writeVerificationReportText(w, report)
+	case "json":
+		out := verificationReportJSON{
+			VerificationReport: report,
+			Samples:            make([]verificationSampleJSON, 0, len(report.Samples)),
+		}
+
+		for _, sample := range report.Samples {
+			expectedRecord, record, lookupErr := verificationSampleRecords(sample)
+			sampleJSON := verificationSampleJSON{
+				First:    sample.First.String(),
+				Last:     sample.Last.String(),
+				Expected: diffRecordFields(expectedRecord),
+				Actual:   diffRecordFields(record),
+				Message:  sample.Err.Error(),
+			}
+			if lookupErr != nil {
+				sampleJSON.LookupError = lookupErr.Error()
+			}
+			out.Samples = append(out.Samples, sampleJSON)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(out)
+	}
+
+	return fmt.Errorf("unsupported output format: %s", outputName)
+}
+
+// writeVerificationReportText writes the given report as human-readable text
+func writeVerificationReportText(w io.Writer, report *geodbtools.VerificationReport) (err error) {
+	if _, err = fmt.Fprintf(w, "checked %d of %d records, found %d failures (%d lookup errors, %d missing and %d extra ranges)\n",
+		report.CheckedRecords, report.TotalRecords, report.Failures, report.LookupErrors, report.MissingRanges, report.ExtraRanges); err != nil {
+		return
+	}
+
+	if report.Stopped {
+		if _, err = fmt.Fprintln(w, "verification stopped after reaching the failure limit"); err != nil {
+			return
+		}
+	}
+
+	if len(report.Mismatches) > 0 {
+		if _, err = fmt.Fprintln(w, "mismatches by expected and actual country:"); err != nil {
+			return
+		}
+
+		for _, mismatch := range report.Mismatches {
+			if _, err = fmt.Fprintf(w, "  %s -> %s: %d\n", verificationCountryCode(mismatch.ExpectedCountryCode), verificationCountryCode(mismatch.CountryCode), mismatch.Count); err != nil {
+				return
+			}
+		}
+	}
+
+	if len(report.Samples) > 0 {
+		if _, err = fmt.Fprintln(w, "examples:"); err != nil {
+			return
+		}
+
+		for _, sample := range report.Samples {
+			if _, err = fmt.Fprintf(w, "  %s-%s: %s\n", sample.First, sample.Last, sample.Err); err != nil {
+				return
+			}
+		}
+	}
+	return
+}
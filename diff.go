@@ -0,0 +1,402 @@
+package geodbtools
+
+import (
+	"math/big"
+	"net/netip"
+)
+
+// DiffRange describes an address range whose records differ between two databases
+type DiffRange struct {
+	// First holds the first address of the range
+	First netip.Addr
+
+	// Last holds the last address of the range
+	Last netip.Addr
+
+	// Old holds the record of the range inside the old database, nil if the range is not covered by it
+	Old Record
+
+	// New holds the record of the range inside the new database, nil if the range is not covered by it
+	New Record
+}
+
+// Networks returns the smallest list of networks covering the range
+func (r DiffRange) Networks() (networks []netip.Prefix) {
+	ipNetworks, err := RangeNetworks(r.First.AsSlice(), r.Last.AsSlice())
+	if err != nil {
+		return
+	}
+
+	networks = make([]netip.Prefix, 0, len(ipNetworks))
+	for _, network := range ipNetworks {
+		networks = append(networks, PrefixFromIPNet(network))
+	}
+	return
+}
+
+// Size returns the number of addresses inside the range
+func (r DiffRange) Size() *big.Int {
+	size := new(big.Int).Sub(new(big.Int).SetBytes(r.Last.AsSlice()), new(big.Int).SetBytes(r.First.AsSlice()))
+	return size.Add(size, big.NewInt(1))
+}
+
+// prefixLastAddr returns the last address of the given prefix
+func prefixLastAddr(prefix netip.Prefix) netip.Addr {
+	b := prefix.Masked().Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(b)*8; bit++ {
+		b[bit>>3] |= 0x80 >> uint(bit&7)
+	}
+
+	addr, _ := netip.AddrFromSlice(b)
+	return addr
+}
+
+// networkSource provides the networks of a database to Diff, in address order and without overlaps
+type networkSource interface {
+	// next returns the next network and its record, ok is false if there are no more networks
+	next() (prefix netip.Prefix, record Record, ok bool)
+
+	// err returns the error that stopped the source, if any
+	err() error
+}
+
+// iteratorNetworkSource provides the networks reported by a NetworkIterator
+type iteratorNetworkSource struct {
+	it NetworkIterator
+}
+
+func (s *iteratorNetworkSource) next() (prefix netip.Prefix, record Record, ok bool) {
+	for s.it.Next() {
+		if prefix = RecordPrefix(s.it.Record()); prefix.IsValid() {
+			record = s.it.Record()
+			ok = true
+			return
+		}
+	}
+	return
+}
+
+func (s *iteratorNetworkSource) err() error {
+	return s.it.Err()
+}
+
+// treeNetwork holds a network reported by RecordTree.WalkNetworks
+type treeNetwork struct {
+	prefix netip.Prefix
+	record Record
+}
+
+// treeNetworkSource provides the networks of a RecordTree, as reported by RecordTree.WalkNetworks
+type treeNetworkSource struct {
+	networks []treeNetwork
+	index    int
+	walkErr  error
+
+	// progress optionally receives a report for every network provided
+	progress chan<- *VerificationProgress
+}
+
+// newTreeNetworkSource returns a source for the networks of the given tree overlapping the given prefix, if valid
+func newTreeNetworkSource(tree *RecordTree, ipVersion IPVersion, prefix netip.Prefix) (s *treeNetworkSource) {
+	s = &treeNetworkSource{}
+	s.walkErr = tree.WalkNetworks(ipVersion, func(network netip.Prefix, record Record) bool {
+		if ipVersion == IPVersion4 && network.Addr().Is4In6() && network.Bits() >= 96 {
+			// IPv4 records of IPv6 databases may carry IPv4-mapped networks
+			network = netip.PrefixFrom(network.Addr().Unmap(), network.Bits()-96)
+		} else if ipVersion == IPVersion6 && network.Addr().Is4() {
+			// IPv4 networks of IPv6 trees are located inside ::/96
+			b := network.Addr().As4()
+			network = netip.PrefixFrom(netip.AddrFrom16([16]byte{12: b[0], 13: b[1], 14: b[2], 15: b[3]}), network.Bits()+96)
+		}
+
+		if network.IsValid() && (!prefix.IsValid() || network.Overlaps(prefix)) {
+			s.networks = append(s.networks, treeNetwork{
+				prefix: network,
+				record: record,
+			})
+		}
+		return true
+	})
+	return
+}
+
+func (s *treeNetworkSource) next() (prefix netip.Prefix, record Record, ok bool) {
+	if s.progress != nil {
+		s.progress <- &VerificationProgress{
+			TotalRecords:   len(s.networks),
+			CheckedRecords: s.index,
+		}
+	}
+
+	if s.walkErr != nil || s.index >= len(s.networks) {
+		return
+	}
+
+	network := s.networks[s.index]
+	s.index++
+	return network.prefix, network.record, true
+}
+
+func (s *treeNetworkSource) err() error {
+	return s.walkErr
+}
+
+// readerNetworkSource returns a source for the networks of the given reader matching the given filter.
+// Readers implementing IterableReader are iterated directly, the RecordTree of all other readers is walked using
+// RecordTree.WalkNetworks, as its records may overlap.
+func readerNetworkSource(r Reader, filter NetworkFilter) networkSource {
+	if iterableReader, ok := r.(IterableReader); ok {
+		return &iteratorNetworkSource{
+			it: iterableReader.Networks(filter),
+		}
+	}
+
+	ipVersion, prefix, _ := filter.normalize()
+	tree, err := r.RecordTree(ipVersion)
+	if err != nil {
+		return &treeNetworkSource{
+			walkErr: err,
+		}
+	}
+	return newTreeNetworkSource(tree, ipVersion, prefix)
+}
+
+// diffCursor holds the current network of one of the databases compared by Diff
+type diffCursor struct {
+	source networkSource
+	record Record
+	first  netip.Addr
+	last   netip.Addr
+	valid  bool
+}
+
+// next moves the cursor to the next network of its source
+func (c *diffCursor) next() (err error) {
+	var prefix netip.Prefix
+	if prefix, c.record, c.valid = c.source.next(); c.valid {
+		c.first = prefix.Masked().Addr()
+		c.last = prefixLastAddr(prefix)
+		return
+	}
+
+	err = c.source.err()
+	return
+}
+
+// advance moves the cursor to the next network ending at or after the given address
+func (c *diffCursor) advance(addr netip.Addr) (err error) {
+	for c.valid && c.last.Less(addr) {
+		if err = c.next(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// recordAt returns the record of the cursor's network if it contains the given address
+func (c *diffCursor) recordAt(addr netip.Addr) Record {
+	if c.valid && !addr.Less(c.first) {
+		return c.record
+	}
+	return nil
+}
+
+// segmentEnd returns the last address, starting at the given one, for which the cursor's record does not change
+func (c *diffCursor) segmentEnd(addr netip.Addr, end netip.Addr) netip.Addr {
+	if !c.valid {
+		return end
+	} else if addr.Less(c.first) {
+		if c.first.Prev().Less(end) {
+			return c.first.Prev()
+		}
+		return end
+	} else if c.last.Less(end) {
+		return c.last
+	}
+	return end
+}
+
+// diffRecordsEqual checks if two records, each of which may be nil, are equal
+func diffRecordsEqual(a, b Record) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a == b || RecordsEqual(a, b)
+}
+
+// diffFilter returns the IP version and the prefix to compare for the given filter
+func diffFilter(filter NetworkFilter) (ipVersion IPVersion, prefix netip.Prefix, err error) {
+	var ok bool
+	if ipVersion, prefix, ok = filter.normalize(); !ok {
+		// the filter cannot match any network
+		prefix = netip.Prefix{}
+		return
+	} else if prefix.IsValid() {
+		return
+	}
+
+	switch ipVersion {
+	case IPVersion4:
+		prefix = netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+	case IPVersion6:
+		prefix = netip.PrefixFrom(netip.IPv6Unspecified(), 0)
+	default:
+		err = ErrUnsupportedIPVersion
+	}
+	return
+}
+
+// Diff compares the networks of two databases, walking both in address order, and calls the given function for each
+// address range whose records differ, as determined by RecordsEqual. Adjacent ranges with equal changes are reported
+// as a single range. The walk is stopped as soon as the function returns false.
+// The filter has to specify the IP version to compare, either directly or by its prefix, and restricts the
+// comparison to its prefix, if valid.
+func Diff(oldReader, newReader Reader, filter NetworkFilter, fn func(r DiffRange) bool) (err error) {
+	var ipVersion IPVersion
+	var prefix netip.Prefix
+	if ipVersion, prefix, err = diffFilter(filter); err != nil || !prefix.IsValid() {
+		return
+	}
+
+	filter = NetworkFilter{
+		IPVersion: ipVersion,
+		Prefix:    prefix,
+	}
+	return diffSources(readerNetworkSource(oldReader, filter), readerNetworkSource(newReader, filter), prefix, fn)
+}
+
+// diffSources walks the networks of both sources inside the given prefix in address order, calling the given
+// function for each range whose records differ
+func diffSources(oldSource, newSource networkSource, prefix netip.Prefix, fn func(r DiffRange) bool) (err error) {
+	first, last := prefix.Addr(), prefixLastAddr(prefix)
+
+	cursors := [2]*diffCursor{
+		{source: oldSource},
+		{source: newSource},
+	}
+	for _, c := range cursors {
+		if err = c.next(); err != nil {
+			return
+		} else if err = c.advance(first); err != nil {
+			return
+		}
+	}
+
+	var pending *DiffRange
+	for addr := first; ; {
+		end := cursors[1].segmentEnd(addr, cursors[0].segmentEnd(addr, last))
+		oldRecord, newRecord := cursors[0].recordAt(addr), cursors[1].recordAt(addr)
+
+		if !diffRecordsEqual(oldRecord, newRecord) {
+			if pending != nil && pending.Last.Next() == addr && diffRecordsEqual(pending.Old, oldRecord) && diffRecordsEqual(pending.New, newRecord) {
+				pending.Last = end
+			} else {
+				if pending != nil && !fn(*pending) {
+					return
+				}
+				pending = &DiffRange{
+					First: addr,
+					Last:  end,
+					Old:   oldRecord,
+					New:   newRecord,
+				}
+			}
+		}
+
+		if end == last || (!cursors[0].valid && !cursors[1].valid) {
+			break
+		}
+
+		addr = end.Next()
+		for _, c := range cursors {
+			if err = c.advance(addr); err != nil {
+				return
+			}
+		}
+	}
+
+	if pending != nil {
+		fn(*pending)
+	}
+	return
+}
+
+// DiffCountrySummary holds the changes of a single country between two databases
+type DiffCountrySummary struct {
+	// RangesGained holds the number of ranges newly assigned to the country
+	RangesGained uint `json:"ranges_gained"`
+
+	// RangesLost holds the number of ranges no longer assigned to the country
+	RangesLost uint `json:"ranges_lost"`
+
+	// RangesChanged holds the number of ranges that stayed assigned to the country, with changed records
+	RangesChanged uint `json:"ranges_changed"`
+
+	// AddressesGained holds the number of addresses newly assigned to the country
+	AddressesGained *big.Int `json:"addresses_gained"`
+
+	// AddressesLost holds the number of addresses no longer assigned to the country
+	AddressesLost *big.Int `json:"addresses_lost"`
+}
+
+// DiffSummary summarizes the ranges reported by Diff
+type DiffSummary struct {
+	// Ranges holds the number of differing ranges
+	Ranges uint `json:"ranges"`
+
+	// Countries holds the per-country summary, keyed by country code.
+	// Ranges of records not implementing CountryRecord, or having no country code, are not included.
+	Countries map[string]*DiffCountrySummary `json:"countries"`
+}
+
+// NewDiffSummary returns a new, empty DiffSummary
+func NewDiffSummary() *DiffSummary {
+	return &DiffSummary{
+		Countries: make(map[string]*DiffCountrySummary),
+	}
+}
+
+// country returns the summary of the given country, creating it if necessary
+func (s *DiffSummary) country(countryCode string) *DiffCountrySummary {
+	summary, exists := s.Countries[countryCode]
+	if !exists {
+		summary = &DiffCountrySummary{
+			AddressesGained: new(big.Int),
+			AddressesLost:   new(big.Int),
+		}
+		s.Countries[countryCode] = summary
+	}
+	return summary
+}
+
+// Add adds the given range to the summary
+func (s *DiffSummary) Add(r DiffRange) {
+	s.Ranges++
+
+	oldCountryCode, newCountryCode := diffCountryCode(r.Old), diffCountryCode(r.New)
+	if oldCountryCode != "" && AreCountryCodesEqual(oldCountryCode, newCountryCode) {
+		s.country(oldCountryCode).RangesChanged++
+		return
+	}
+
+	size := r.Size()
+	if oldCountryCode != "" {
+		summary := s.country(oldCountryCode)
+		summary.RangesLost++
+		summary.AddressesLost.Add(summary.AddressesLost, size)
+	}
+
+	if newCountryCode != "" {
+		summary := s.country(newCountryCode)
+		summary.RangesGained++
+		summary.AddressesGained.Add(summary.AddressesGained, size)
+	}
+}
+
+// diffCountryCode returns the country code of the given record, if it is a CountryRecord
+func diffCountryCode(record Record) string {
+	if countryRecord, ok := record.(CountryRecord); ok {
+		return countryRecord.GetCountryCode()
+	}
+	return ""
+}
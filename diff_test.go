@@ -0,0 +1,231 @@
+package geodbtools
+
+import (
+	"errors"
+	"math/big"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDiffReader returns an IterableReader iterating over the given records
+func testDiffReader(t *testing.T, ctrl *gomock.Controller, records ...Record) Reader {
+	tree, err := NewPrefixRecordTree(records)
+	require.NoError(t, err)
+
+	return &testIterableReader{
+		MockReader: NewMockReader(ctrl),
+		it:         NewRecordTreeIterator(tree, NetworkFilter{}),
+	}
+}
+
+// testErrorIterator implements a NetworkIterator failing with the given error
+type testErrorIterator struct {
+	err error
+}
+
+func (it *testErrorIterator) Next() bool     { return false }
+func (it *testErrorIterator) Record() Record { return nil }
+func (it *testErrorIterator) Err() error     { return it.err }
+
+// diffRanges collects the ranges reported by Diff, formatted as "first-last old->new"
+func diffRanges(t *testing.T, oldReader, newReader Reader, filter NetworkFilter) (ranges []string) {
+	recordCode := func(record Record) string {
+		if record == nil {
+			return "-"
+		}
+		return diffCountryCode(record)
+	}
+
+	require.NoError(t, Diff(oldReader, newReader, filter, func(r DiffRange) bool {
+		ranges = append(ranges, r.First.String()+"-"+r.Last.String()+" "+recordCode(r.Old)+"->"+recordCode(r.New))
+		return true
+	}))
+	return
+}
+
+func TestDiff(t *testing.T) {
+	t.Run("Equal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+		newReader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "AT"),
+		)
+
+		assert.Empty(t, diffRanges(t, oldReader, newReader, NetworkFilter{IPVersion: IPVersion4}))
+	})
+
+	t.Run("Changes", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "12.0.0.0/8", "US"),
+			newTestCountryRecord(ctrl, "255.255.255.0/24", "DE"),
+		)
+		newReader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "DE"),
+			newTestCountryRecord(ctrl, "11.0.0.0/8", "DE"),
+			newTestCountryRecord(ctrl, "255.255.255.0/24", "DE"),
+		)
+
+		assert.EqualValues(t, []string{
+			"10.128.0.0-10.255.255.255 AT->DE",
+			"11.0.0.0-11.255.255.255 -->DE",
+			"12.0.0.0-12.255.255.255 US->-",
+		}, diffRanges(t, oldReader, newReader, NetworkFilter{IPVersion: IPVersion4}))
+	})
+
+	t.Run("AdjacentRanges", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "AT"),
+		)
+		newReader := testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "DE"))
+
+		assert.EqualValues(t, []string{
+			"10.0.0.0-10.255.255.255 AT->DE",
+		}, diffRanges(t, oldReader, newReader, NetworkFilter{IPVersion: IPVersion4}))
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+		newReader := testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "DE"))
+
+		assert.EqualValues(t, []string{
+			"10.1.0.0-10.1.255.255 AT->DE",
+		}, diffRanges(t, oldReader, newReader, NetworkFilter{Prefix: netip.MustParsePrefix("10.1.0.0/16")}))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "2001:db8::/32", "AT"))
+		newReader := testDiffReader(t, ctrl)
+
+		assert.EqualValues(t, []string{
+			"2001:db8::-2001:db8:ffff:ffff:ffff:ffff:ffff:ffff AT->-",
+		}, diffRanges(t, oldReader, newReader, NetworkFilter{IPVersion: IPVersion6}))
+	})
+
+	t.Run("RecordTreeReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree, err := NewPrefixRecordTree([]Record{newTestCountryRecord(ctrl, "10.0.0.0/8", "AT")})
+		require.NoError(t, err)
+		require.NoError(t, tree.Insert(netip.MustParsePrefix("10.1.0.0/16"), newTestCountryRecord(ctrl, "10.1.0.0/16", "DE")))
+
+		newReader := NewMockReader(ctrl)
+		newReader.EXPECT().RecordTree(IPVersion4).Return(tree, nil)
+
+		assert.EqualValues(t, []string{
+			"10.1.0.0-10.1.255.255 AT->DE",
+		}, diffRanges(t, testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT")), newReader, NetworkFilter{IPVersion: IPVersion4}))
+	})
+
+	t.Run("Stop", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		oldReader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "12.0.0.0/8", "AT"),
+		)
+		newReader := testDiffReader(t, ctrl)
+
+		var count int
+		assert.NoError(t, Diff(oldReader, newReader, NetworkFilter{IPVersion: IPVersion4}, func(r DiffRange) bool {
+			count++
+			return false
+		}))
+		assert.EqualValues(t, 1, count)
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		assert.EqualError(t, Diff(testDiffReader(t, ctrl), testDiffReader(t, ctrl), NetworkFilter{}, nil), ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IteratorError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		newReader := &testIterableReader{
+			MockReader: NewMockReader(ctrl),
+			it:         &testErrorIterator{err: testErr},
+		}
+
+		assert.EqualError(t, Diff(testDiffReader(t, ctrl), newReader, NetworkFilter{IPVersion: IPVersion4}, nil), testErr.Error())
+	})
+}
+
+func TestDiffRange(t *testing.T) {
+	r := DiffRange{
+		First: netip.MustParseAddr("10.0.0.0"),
+		Last:  netip.MustParseAddr("10.1.0.255"),
+	}
+
+	assert.EqualValues(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/16"),
+		netip.MustParsePrefix("10.1.0.0/24"),
+	}, r.Networks())
+	assert.EqualValues(t, big.NewInt(65536+256), r.Size())
+}
+
+func TestDiffSummary_Add(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	summary := NewDiffSummary()
+	summary.Add(DiffRange{
+		First: netip.MustParseAddr("10.0.0.0"),
+		Last:  netip.MustParseAddr("10.0.0.255"),
+		Old:   newTestCountryRecord(ctrl, "10.0.0.0/24", "AT"),
+		New:   newTestCountryRecord(ctrl, "10.0.0.0/24", "DE"),
+	})
+	summary.Add(DiffRange{
+		First: netip.MustParseAddr("10.0.1.0"),
+		Last:  netip.MustParseAddr("10.0.1.255"),
+		New:   newTestCountryRecord(ctrl, "10.0.1.0/24", "DE"),
+	})
+	summary.Add(DiffRange{
+		First: netip.MustParseAddr("10.0.2.0"),
+		Last:  netip.MustParseAddr("10.0.2.255"),
+		Old:   newTestCountryRecord(ctrl, "10.0.2.0/24", "AT"),
+		New:   newTestCountryRecord(ctrl, "10.0.2.0/24", "AT"),
+	})
+
+	assert.EqualValues(t, 3, summary.Ranges)
+	assert.EqualValues(t, map[string]*DiffCountrySummary{
+		"AT": {
+			RangesLost:      1,
+			RangesChanged:   1,
+			AddressesGained: big.NewInt(0),
+			AddressesLost:   big.NewInt(256),
+		},
+		"DE": {
+			RangesGained:    2,
+			AddressesGained: big.NewInt(512),
+			AddressesLost:   big.NewInt(0),
+		},
+	}, summary.Countries)
+}
@@ -12,4 +12,11 @@ var (
 	ErrRecordNotFound = errors.New("record not found")
 	// ErrUnsupportedIPVersion indicates that the desired IP version is not supported by the database
 	ErrUnsupportedIPVersion = errors.New("requested IP version not supported by database")
+	// ErrUnsupportedRecordType indicates that a record type is not supported by the database type
+	ErrUnsupportedRecordType = errors.New("unsupported record type")
+
+	// ErrReaderClosed indicates that the reader has already been closed
+	ErrReaderClosed = errors.New("reader closed")
+	// ErrDatabaseTypeMismatch indicates that a reloaded database is of a different type than the one it replaces
+	ErrDatabaseTypeMismatch = errors.New("database type mismatch")
 )
@@ -0,0 +1,91 @@
+package geoip2csvformat
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// csvTable provides access to the values of a CSV file with a header line by column name
+type csvTable struct {
+	r       *csv.Reader
+	columns map[string]int
+	row     []string
+}
+
+// newCSVTable returns a new csvTable reading from the given io.Reader.
+// The header line is consumed immediately.
+func newCSVTable(r io.Reader) (t *csvTable, err error) {
+	csvReader := csv.NewReader(r)
+
+	var header []string
+	if header, err = csvReader.Read(); err != nil {
+		return
+	}
+
+	t = &csvTable{
+		r:       csvReader,
+		columns: make(map[string]int, len(header)),
+	}
+
+	for i, column := range header {
+		t.columns[column] = i
+	}
+	return
+}
+
+// hasColumn checks if the table contains a column with the given name
+func (t *csvTable) hasColumn(column string) bool {
+	_, ok := t.columns[column]
+	return ok
+}
+
+// next advances to the next row of the table.
+// io.EOF is returned once all rows have been read.
+func (t *csvTable) next() (err error) {
+	t.row, err = t.r.Read()
+	return
+}
+
+// value returns the value of the given column in the current row, or an empty string if the column does not exist
+func (t *csvTable) value(column string) string {
+	i, ok := t.columns[column]
+	if !ok || i >= len(t.row) {
+		return ""
+	}
+	return t.row[i]
+}
+
+// csvWriter wraps a csv.Writer, writing rows of a fixed set of columns
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+// newCSVWriter returns a new csvWriter, writing the header line immediately
+func newCSVWriter(w io.Writer, columns []string) (cw *csvWriter, err error) {
+	cw = &csvWriter{
+		w:       csv.NewWriter(w),
+		columns: columns,
+	}
+
+	if err = cw.w.Write(columns); err != nil {
+		cw = nil
+	}
+	return
+}
+
+// write writes a single row, given the values keyed by column name
+func (cw *csvWriter) write(values map[string]string) (err error) {
+	row := make([]string, len(cw.columns))
+	for i, column := range cw.columns {
+		row[i] = values[column]
+	}
+
+	return cw.w.Write(row)
+}
+
+// flush flushes all buffered rows to the underlying io.Writer
+func (cw *csvWriter) flush() (err error) {
+	cw.w.Flush()
+	return cw.w.Error()
+}
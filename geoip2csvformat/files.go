@@ -0,0 +1,87 @@
+package geoip2csvformat
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// databaseFiles holds the names of the files a single database consists of
+type databaseFiles struct {
+	// name holds the name of the database, as used as prefix of all file names
+	name string
+	// blocks holds the name of the blocks file, keyed by IP version
+	blocks map[geodbtools.IPVersion]string
+	// locations holds the name of the locations file, keyed by locale
+	locations map[string]string
+}
+
+// locales returns the locales of all locations files, starting with the default locale
+func (f databaseFiles) locales() (locales []string) {
+	locales = make([]string, 0, len(f.locations))
+	for locale := range f.locations {
+		locales = append(locales, locale)
+	}
+
+	sort.Slice(locales, func(i, j int) bool {
+		if locales[i] == defaultLocale || locales[j] == defaultLocale {
+			return locales[i] == defaultLocale
+		}
+		return locales[i] < locales[j]
+	})
+	return
+}
+
+// findDatabaseFiles looks up the blocks and locations files of a database, given a list of file names.
+// The database name is derived from the first blocks file found.
+func findDatabaseFiles(names []string) (files databaseFiles, err error) {
+	sortedNames := append([]string(nil), names...)
+	sort.Strings(sortedNames)
+
+	files.blocks = make(map[geodbtools.IPVersion]string)
+	files.locations = make(map[string]string)
+
+	for _, name := range sortedNames {
+		baseName := path.Base(name)
+
+		var dbName string
+		var ipVersion geodbtools.IPVersion
+		switch {
+		case strings.HasSuffix(baseName, blocksIPv4Suffix):
+			dbName, ipVersion = strings.TrimSuffix(baseName, blocksIPv4Suffix), geodbtools.IPVersion4
+		case strings.HasSuffix(baseName, blocksIPv6Suffix):
+			dbName, ipVersion = strings.TrimSuffix(baseName, blocksIPv6Suffix), geodbtools.IPVersion6
+		default:
+			continue
+		}
+
+		if files.name == "" {
+			files.name = dbName
+		}
+		if dbName == files.name {
+			files.blocks[ipVersion] = name
+		}
+	}
+
+	if len(files.blocks) == 0 {
+		err = ErrBlocksNotFound
+		return
+	}
+
+	locationsPrefix := files.name + locationsInfix
+	for _, name := range sortedNames {
+		baseName := path.Base(name)
+		if !strings.HasPrefix(baseName, locationsPrefix) || !strings.HasSuffix(baseName, csvExtension) {
+			continue
+		}
+
+		locale := strings.TrimSuffix(strings.TrimPrefix(baseName, locationsPrefix), csvExtension)
+		if locale != "" {
+			files.locations[locale] = name
+		}
+	}
+
+	return
+}
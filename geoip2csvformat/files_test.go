@@ -0,0 +1,44 @@
+package geoip2csvformat
+
+import (
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDatabaseFiles(t *testing.T) {
+	t.Run("BlocksNotFound", func(t *testing.T) {
+		_, err := findDatabaseFiles([]string{
+			"GeoLite2-City-Locations-en.csv",
+			"README.txt",
+		})
+		assert.EqualError(t, err, ErrBlocksNotFound.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		files, err := findDatabaseFiles([]string{
+			"GeoLite2-City-CSV_20190101/COPYRIGHT.txt",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Blocks-IPv6.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-de.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Blocks-IPv4.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-en.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-Country-Locations-fr.csv",
+			"GeoLite2-City-CSV_20190101/GeoLite2-Country-Blocks-IPv4.csv",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, databaseFiles{
+			name: "GeoLite2-City",
+			blocks: map[geodbtools.IPVersion]string{
+				geodbtools.IPVersion4: "GeoLite2-City-CSV_20190101/GeoLite2-City-Blocks-IPv4.csv",
+				geodbtools.IPVersion6: "GeoLite2-City-CSV_20190101/GeoLite2-City-Blocks-IPv6.csv",
+			},
+			locations: map[string]string{
+				"de": "GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-de.csv",
+				"en": "GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-en.csv",
+			},
+		}, files)
+		assert.EqualValues(t, []string{"en", "de"}, files.locales())
+	})
+}
@@ -0,0 +1,70 @@
+// Package geoip2csvformat implements the MaxMind GeoIP2 CSV format, consisting of blocks and locations files
+package geoip2csvformat
+
+import (
+	"errors"
+	"io"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+var (
+	// ErrBlocksNotFound indicates that the database does not contain any blocks file
+	ErrBlocksNotFound = errors.New("blocks file not found")
+	// ErrLocationNotFound indicates that a block references a location which is not contained in the locations file
+	ErrLocationNotFound = errors.New("location not found")
+)
+
+const (
+	// blocksIPv4Suffix defines the file name suffix of IPv4 blocks files
+	blocksIPv4Suffix = "-Blocks-IPv4.csv"
+	// blocksIPv6Suffix defines the file name suffix of IPv6 blocks files
+	blocksIPv6Suffix = "-Blocks-IPv6.csv"
+	// locationsInfix separates the database name from the locale inside locations file names
+	locationsInfix = "-Locations-"
+	// csvExtension defines the file name extension of CSV files
+	csvExtension = ".csv"
+
+	// defaultLocale defines the locale names are taken from if available
+	defaultLocale = "en"
+)
+
+var _ geodbtools.BundleFormat = format{}
+
+type format struct{}
+
+func (format) FormatName() string {
+	return "geoip2csv"
+}
+
+func (format) NewReaderAt(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	return NewReader(r)
+}
+
+func (format) NewBundleReader(b geodbtools.Bundle) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	return NewBundleReader(b)
+}
+
+func (format) NewWriter(w io.Writer, dbType geodbtools.DatabaseType, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	return NewWriter(w, dbType, ipVersion)
+}
+
+// DetectFormat checks if the given source is a ZIP archive holding a blocks file
+func (f format) DetectFormat(r geodbtools.ReaderSource) (isFormat bool) {
+	b, err := geodbtools.NewZipBundle(r)
+	if err != nil {
+		return
+	}
+
+	return f.DetectBundleFormat(b)
+}
+
+// DetectBundleFormat checks if the given bundle holds a blocks file
+func (format) DetectBundleFormat(b geodbtools.Bundle) (isFormat bool) {
+	_, err := findDatabaseFiles(b.Files())
+	return err == nil
+}
+
+func init() {
+	geodbtools.MustRegisterFormat(format{})
+}
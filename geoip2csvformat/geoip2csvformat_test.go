@@ -0,0 +1,178 @@
+package geoip2csvformat
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCountryLocations = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union
+2782113,en,EU,Europe,AT,Austria,1
+2921044,en,EU,Europe,DE,Germany,1
+6252001,en,NA,North America,US,United States,0
+`
+
+const testCountryBlocksIPv4 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.0.0.0/24,2782113,2782113,,0,0
+1.0.1.0/24,2921044,2782113,,0,0
+10.0.0.0/8,,6252001,6252001,1,0
+`
+
+const testCountryBlocksIPv6 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+::ffff:192.0.2.0/120,2921044,2782113,,0,0
+2001:db8::/32,2782113,2782113,,0,0
+`
+
+const testCityLocationsEN = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union
+2761369,en,EU,Europe,AT,Austria,9,Vienna,,,Vienna,,Europe/Vienna,1
+2782113,en,EU,Europe,AT,Austria,,,,,,,,1
+5803556,en,NA,"North America",US,"United States",WA,Washington,,,Milton,819,America/Los_Angeles,0
+`
+
+const testCityLocationsDE = `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union
+2761369,de,EU,Europa,AT,Österreich,9,Wien,,,Wien,,Europe/Vienna,1
+2782113,de,EU,Europa,AT,Österreich,,,,,,,,1
+5803556,de,NA,Nordamerika,US,USA,WA,Washington,,,,819,America/Los_Angeles,0
+`
+
+const testCityBlocksIPv4 = `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius
+1.0.0.0/24,2761369,2782113,,0,0,1010,48.2,16.3667,20
+1.0.1.0/24,5803556,,,0,0,98354,47.2513,-122.3149,22
+`
+
+// testZipSource returns a ReaderSource providing a ZIP archive containing the given files
+func testZipSource(t *testing.T, files map[string]string) geodbtools.ReaderSource {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBufferString("")
+	zipWriter := zip.NewWriter(buf)
+	for _, name := range names {
+		w, err := zipWriter.CreateHeader(&zip.FileHeader{
+			Name:     name,
+			Modified: time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC),
+		})
+		require.NoError(t, err)
+		_, err = w.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zipWriter.Close())
+
+	return geodbtools.NewReaderSourceWrapper(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+}
+
+// testTarBundle returns a bundle holding the given files, as read from a tar archive
+func testTarBundle(t *testing.T, files map[string]string) geodbtools.Bundle {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	buf := bytes.NewBufferString("")
+	tarWriter := tar.NewWriter(buf)
+	for _, name := range names {
+		require.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Typeflag: tar.TypeReg,
+			Mode:     0644,
+			Size:     int64(len(files[name])),
+			ModTime:  time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC),
+		}))
+		_, err := tarWriter.Write([]byte(files[name]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tarWriter.Close())
+
+	b, err := geodbtools.NewTarBundle(buf)
+	require.NoError(t, err)
+	return b
+}
+
+func TestFormat_FormatName(t *testing.T) {
+	assert.EqualValues(t, "geoip2csv", format{}.FormatName())
+}
+
+func TestFormat_NewReaderAt(t *testing.T) {
+	reader, meta, err := format{}.NewReaderAt(testZipSource(t, map[string]string{
+		"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+		"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+	}))
+	assert.NoError(t, err)
+	assert.NotNil(t, reader)
+	assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+}
+
+func TestFormat_NewBundleReader(t *testing.T) {
+	reader, meta, err := format{}.NewBundleReader(testTarBundle(t, map[string]string{
+		"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+		"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Locations-en.csv": testCountryLocations,
+	}))
+	assert.NoError(t, err)
+	assert.NotNil(t, reader)
+	assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+}
+
+func TestFormat_NewWriter(t *testing.T) {
+	t.Run("UnsupportedDatabaseType", func(t *testing.T) {
+		w, err := format{}.NewWriter(nil, geodbtools.DatabaseTypeASN, geodbtools.IPVersion4)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := format{}.NewWriter(buf, geodbtools.DatabaseTypeCity, geodbtools.IPVersion6)
+		assert.NoError(t, err)
+		assert.EqualValues(t, &writer{
+			w:         buf,
+			dbType:    geodbtools.DatabaseTypeCity,
+			ipVersion: geodbtools.IPVersion6,
+		}, w)
+	})
+}
+
+func TestFormat_DetectFormat(t *testing.T) {
+	t.Run("NoZipArchive", func(t *testing.T) {
+		data := []byte("network,geoname_id\n")
+		assert.False(t, format{}.DetectFormat(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data)))))
+	})
+
+	t.Run("NoBlocksFile", func(t *testing.T) {
+		assert.False(t, format{}.DetectFormat(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		})))
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		assert.True(t, format{}.DetectFormat(testZipSource(t, map[string]string{
+			"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		})))
+	})
+}
+
+func TestFormat_DetectBundleFormat(t *testing.T) {
+	t.Run("NoBlocksFile", func(t *testing.T) {
+		assert.False(t, format{}.DetectBundleFormat(testTarBundle(t, map[string]string{
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		})))
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		assert.True(t, format{}.DetectBundleFormat(testTarBundle(t, map[string]string{
+			"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-CSV_20190101/GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		})))
+	})
+}
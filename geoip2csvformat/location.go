@@ -0,0 +1,101 @@
+package geoip2csvformat
+
+import (
+	"sort"
+	"strconv"
+)
+
+// locationNames holds the names of a location in a single locale
+type locationNames struct {
+	continent    string
+	country      string
+	subdivision1 string
+	subdivision2 string
+	city         string
+}
+
+// location represents a single entry of the locations files, identified by its GeoName ID
+type location struct {
+	geoNameID           uint32
+	continentCode       string
+	countryISOCode      string
+	subdivision1ISOCode string
+	subdivision2ISOCode string
+	metroCode           int
+	timeZone            string
+	isInEuropeanUnion   bool
+
+	// names holds the names of the location, keyed by locale
+	names map[string]locationNames
+}
+
+// locales returns the locales names are available in, starting with the default locale
+func (l *location) locales() (locales []string) {
+	locales = make([]string, 0, len(l.names))
+	for locale := range l.names {
+		locales = append(locales, locale)
+	}
+
+	sort.Slice(locales, func(i, j int) bool {
+		if locales[i] == defaultLocale || locales[j] == defaultLocale {
+			return locales[i] == defaultLocale
+		}
+		return locales[i] < locales[j]
+	})
+	return
+}
+
+// name returns a name of the location, preferring the default locale.
+// If the name is not available in the default locale, the first locale providing the name is used.
+func (l *location) name(get func(names locationNames) string) string {
+	if l == nil {
+		return ""
+	}
+
+	for _, locale := range l.locales() {
+		if name := get(l.names[locale]); name != "" {
+			return name
+		}
+	}
+	return ""
+}
+
+// localizedNames returns a name of the location in all available locales
+func (l *location) localizedNames(get func(names locationNames) string) (names map[string]string) {
+	if l == nil {
+		return
+	}
+
+	for locale, localeNames := range l.names {
+		if name := get(localeNames); name != "" {
+			if names == nil {
+				names = make(map[string]string)
+			}
+			names[locale] = name
+		}
+	}
+	return
+}
+
+// key returns a string uniquely identifying the location's contents, disregarding its GeoName ID
+func (l *location) key() string {
+	b := []byte(l.continentCode + "\x00" + l.countryISOCode + "\x00" + l.subdivision1ISOCode + "\x00" +
+		l.subdivision2ISOCode + "\x00" + l.timeZone + "\x00")
+	b = strconv.AppendInt(b, int64(l.metroCode), 10)
+	b = strconv.AppendBool(b, l.isInEuropeanUnion)
+
+	for _, locale := range l.locales() {
+		names := l.names[locale]
+		b = append(b, "\x00"+locale+"\x00"+names.continent+"\x00"+names.country+"\x00"+names.subdivision1+"\x00"+
+			names.subdivision2+"\x00"+names.city...)
+	}
+	return string(b)
+}
+
+func countryName(names locationNames) string {
+	return names.country
+}
+
+func cityName(names locationNames) string {
+	return names.city
+}
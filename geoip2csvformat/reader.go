@@ -0,0 +1,323 @@
+package geoip2csvformat
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+var _ geodbtools.Reader = (*blocksReader)(nil)
+
+type blocksReader struct {
+	// records holds the records of each blocks file, sorted by network address
+	records map[geodbtools.IPVersion][]geodbtools.Record
+}
+
+func (r *blocksReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
+	records, ok := r.records[ipVersion]
+	if !ok {
+		err = geodbtools.ErrUnsupportedIPVersion
+		return
+	}
+
+	if ipVersion == geodbtools.IPVersion6 {
+		// IPv4 blocks are mapped into ::ffff:0:0/96, IPv6 blocks for the same networks take precedence
+		records = append(mapIPv4Records(r.records[geodbtools.IPVersion4]), records...)
+	}
+
+	tree, err = geodbtools.NewPrefixRecordTree(records)
+	return
+}
+
+// mapIPv4Records returns copies of the given IPv4 records, representing the corresponding IPv4-mapped IPv6 networks
+func mapIPv4Records(records []geodbtools.Record) (mapped []geodbtools.Record) {
+	mapped = make([]geodbtools.Record, 0, len(records))
+	for _, record := range records {
+		ones, _ := record.GetNetwork().Mask.Size()
+		mapped = append(mapped, record.(blocksRecord).withNetwork(&net.IPNet{
+			IP:   record.GetNetwork().IP.To16(),
+			Mask: net.CIDRMask(ones+96, 128),
+		}))
+	}
+	return
+}
+
+func (r *blocksReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	ipVersion := geodbtools.IPVersion6
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+		ipVersion = geodbtools.IPVersion4
+	} else if ip = ip.To16(); ip == nil {
+		err = geodbtools.ErrRecordNotFound
+		return
+	}
+
+	if record = lookupRecords(r.records[ipVersion], ip); record == nil && ipVersion == geodbtools.IPVersion4 {
+		// IPv4 addresses may be covered by IPv4-mapped networks of the IPv6 blocks
+		record = lookupRecords(r.records[geodbtools.IPVersion6], ip.To16())
+	}
+
+	if record == nil {
+		err = geodbtools.ErrRecordNotFound
+	}
+	return
+}
+
+// lookupRecords returns the record of the given records sorted by network address that contains the given IP address,
+// or nil if there is none
+func lookupRecords(records []geodbtools.Record, ip net.IP) geodbtools.Record {
+	i := sort.Search(len(records), func(i int) bool {
+		return bytes.Compare(records[i].GetNetwork().IP, ip) > 0
+	})
+
+	if i == 0 || !records[i-1].GetNetwork().Contains(ip) {
+		return nil
+	}
+	return records[i-1]
+}
+
+// parseGeoNameID parses a GeoName ID column value and looks up the corresponding location.
+// Empty values result in a nil location.
+func parseGeoNameID(value string, locations map[uint32]*location) (loc *location, err error) {
+	if value == "" {
+		return
+	}
+
+	var id uint64
+	if id, err = strconv.ParseUint(value, 10, 32); err != nil {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	var ok bool
+	if loc, ok = locations[uint32(id)]; !ok {
+		err = ErrLocationNotFound
+	}
+	return
+}
+
+// parseFloat parses a floating-point column value, treating empty values as 0
+func parseFloat(value string) (f float64, err error) {
+	if value == "" {
+		return
+	}
+
+	if f, err = strconv.ParseFloat(value, 64); err != nil {
+		err = geodbtools.ErrDatabaseInvalid
+	}
+	return
+}
+
+// parseUint parses an unsigned integer column value, treating empty values as 0
+func parseUint(value string) (u uint64, err error) {
+	if value == "" {
+		return
+	}
+
+	if u, err = strconv.ParseUint(value, 10, 32); err != nil {
+		err = geodbtools.ErrDatabaseInvalid
+	}
+	return
+}
+
+// readLocations reads the rows of a locations file in the given locale into the given location map
+func readLocations(r io.Reader, locale string, locations map[uint32]*location) (err error) {
+	var t *csvTable
+	if t, err = newCSVTable(r); err != nil {
+		return
+	}
+
+	for {
+		if err = t.next(); err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			return
+		}
+
+		var id uint64
+		if id, err = strconv.ParseUint(t.value("geoname_id"), 10, 32); err != nil {
+			err = geodbtools.ErrDatabaseInvalid
+			return
+		}
+
+		var metroCode uint64
+		if metroCode, err = parseUint(t.value("metro_code")); err != nil {
+			return
+		}
+
+		loc, exists := locations[uint32(id)]
+		if !exists {
+			loc = &location{
+				geoNameID: uint32(id),
+				names:     make(map[string]locationNames),
+			}
+			locations[loc.geoNameID] = loc
+		}
+
+		loc.continentCode = t.value("continent_code")
+		loc.countryISOCode = t.value("country_iso_code")
+		loc.subdivision1ISOCode = t.value("subdivision_1_iso_code")
+		loc.subdivision2ISOCode = t.value("subdivision_2_iso_code")
+		loc.metroCode = int(metroCode)
+		loc.timeZone = t.value("time_zone")
+		loc.isInEuropeanUnion = t.value("is_in_european_union") == "1"
+		loc.names[locale] = locationNames{
+			continent:    t.value("continent_name"),
+			country:      t.value("country_name"),
+			subdivision1: t.value("subdivision_1_name"),
+			subdivision2: t.value("subdivision_2_name"),
+			city:         t.value("city_name"),
+		}
+	}
+}
+
+// readBlocks reads the rows of a blocks file of the given IP version, joining them with the given locations.
+// The returned records are sorted by network address.
+func readBlocks(r io.Reader, ipVersion geodbtools.IPVersion, locations map[uint32]*location) (records []geodbtools.Record, isCity bool, err error) {
+	var t *csvTable
+	if t, err = newCSVTable(r); err != nil {
+		return
+	}
+
+	isCity = t.hasColumn("latitude")
+
+	for {
+		if err = t.next(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+
+		rec := countryRecord{
+			isAnonymousProxy:    t.value("is_anonymous_proxy") == "1",
+			isSatelliteProvider: t.value("is_satellite_provider") == "1",
+		}
+
+		// IPv4-mapped networks (::ffff:a.b.c.d/n) are part of the IPv6 address space, so the IP version is
+		// determined by the length of the network mask
+		if _, rec.network, err = net.ParseCIDR(t.value("network")); err != nil {
+			err = geodbtools.ErrDatabaseInvalid
+			return
+		} else if _, bits := rec.network.Mask.Size(); (bits == 8*net.IPv4len) != (ipVersion == geodbtools.IPVersion4) {
+			err = geodbtools.ErrDatabaseInvalid
+			return
+		}
+
+		if rec.location, err = parseGeoNameID(t.value("geoname_id"), locations); err != nil {
+			return
+		} else if rec.registeredCountry, err = parseGeoNameID(t.value("registered_country_geoname_id"), locations); err != nil {
+			return
+		} else if rec.representedCountry, err = parseGeoNameID(t.value("represented_country_geoname_id"), locations); err != nil {
+			return
+		}
+
+		if !isCity {
+			records = append(records, &rec)
+			continue
+		}
+
+		cityRec := &cityRecord{
+			countryRecord: rec,
+			postalCode:    t.value("postal_code"),
+		}
+
+		var accuracyRadius uint64
+		if cityRec.latitude, err = parseFloat(t.value("latitude")); err != nil {
+			return
+		} else if cityRec.longitude, err = parseFloat(t.value("longitude")); err != nil {
+			return
+		} else if accuracyRadius, err = parseUint(t.value("accuracy_radius")); err != nil {
+			return
+		}
+		cityRec.accuracyRadius = uint(accuracyRadius)
+
+		records = append(records, cityRec)
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return bytes.Compare(records[i].GetNetwork().IP, records[j].GetNetwork().IP) < 0
+	})
+	return
+}
+
+// readBundleFile opens a file inside the bundle and passes its contents to the given function
+func readBundleFile(b geodbtools.Bundle, name string, fn func(r io.Reader) error) (err error) {
+	var s geodbtools.ReaderSource
+	if s, err = b.Open(name); err != nil {
+		return
+	}
+
+	return fn(io.NewSectionReader(s, 0, s.Size()))
+}
+
+// NewBundleReader returns a new reader for the GeoIP2 CSV database contained in the given bundle
+func NewBundleReader(b geodbtools.Bundle) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	var files databaseFiles
+	if files, err = findDatabaseFiles(b.Files()); err != nil {
+		return
+	}
+
+	locations := make(map[uint32]*location)
+	for _, locale := range files.locales() {
+		if err = readBundleFile(b, files.locations[locale], func(r io.Reader) error {
+			return readLocations(r, locale, locations)
+		}); err != nil {
+			return
+		}
+	}
+
+	r := &blocksReader{
+		records: make(map[geodbtools.IPVersion][]geodbtools.Record, len(files.blocks)),
+	}
+
+	meta = geodbtools.Metadata{
+		Type:               geodbtools.DatabaseTypeCountry,
+		Description:        files.name,
+		MajorFormatVersion: 2,
+		MinorFormatVersion: 0,
+		IPVersion:          geodbtools.IPVersion4,
+	}
+
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		name, ok := files.blocks[ipVersion]
+		if !ok {
+			continue
+		}
+
+		var isCity bool
+		if err = readBundleFile(b, name, func(br io.Reader) (readErr error) {
+			r.records[ipVersion], isCity, readErr = readBlocks(br, ipVersion, locations)
+			return
+		}); err != nil {
+			return
+		}
+
+		if isCity {
+			meta.Type = geodbtools.DatabaseTypeCity
+		}
+		if meta.BuildTime, err = b.ModTime(name); err != nil {
+			return
+		}
+		meta.IPVersion = ipVersion
+	}
+
+	reader = r
+	return
+}
+
+// NewReader returns a new reader for the GeoIP2 CSV database contained in the ZIP archive provided by the
+// given ReaderSource
+func NewReader(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	var b geodbtools.Bundle
+	if b, err = geodbtools.NewZipBundle(r); err != nil {
+		return
+	}
+
+	return NewBundleReader(b)
+}
@@ -0,0 +1,318 @@
+package geoip2csvformat
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewReader(t *testing.T) {
+	t.Run("NoZipArchive", func(t *testing.T) {
+		data := []byte("test")
+		reader, _, err := NewReader(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data))))
+		assert.Nil(t, reader)
+		assert.Error(t, err)
+	})
+
+	t.Run("BlocksNotFound", func(t *testing.T) {
+		reader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		assert.Nil(t, reader)
+		assert.EqualError(t, err, ErrBlocksNotFound.Error())
+	})
+
+	t.Run("LocationNotFound", func(t *testing.T) {
+		reader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv": testCountryBlocksIPv4,
+		}))
+		assert.Nil(t, reader)
+		assert.EqualError(t, err, ErrLocationNotFound.Error())
+	})
+
+	t.Run("InvalidBlocks", func(t *testing.T) {
+		for name, blocks := range map[string]string{
+			"InvalidNetwork":   "network,geoname_id\n1.0.0.0/33,2782113\n",
+			"WrongIPVersion":   "network,geoname_id\n2001:db8::/32,2782113\n",
+			"IPv4Mapped":       "network,geoname_id\n::ffff:1.0.0.0/120,2782113\n",
+			"InvalidGeoNameID": "network,geoname_id\n1.0.0.0/24,test\n",
+			"InvalidLatitude":  "network,geoname_id,latitude\n1.0.0.0/24,2782113,test\n",
+			"InvalidRadius":    "network,geoname_id,latitude,accuracy_radius\n1.0.0.0/24,2782113,1,-1\n",
+		} {
+			t.Run(name, func(t *testing.T) {
+				reader, _, err := NewReader(testZipSource(t, map[string]string{
+					"GeoLite2-Country-Blocks-IPv4.csv":  blocks,
+					"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+				}))
+				assert.Nil(t, reader)
+				assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+			})
+		}
+	})
+
+	t.Run("InvalidLocations", func(t *testing.T) {
+		for name, locations := range map[string]string{
+			"InvalidGeoNameID":  "geoname_id,country_iso_code\ntest,AT\n",
+			"InvalidMetroCode":  "geoname_id,metro_code\n2782113,test\n",
+			"MalformedCSVInput": "geoname_id,country_iso_code\n2782113,\"AT\n",
+		} {
+			t.Run(name, func(t *testing.T) {
+				reader, _, err := NewReader(testZipSource(t, map[string]string{
+					"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+					"GeoLite2-Country-Locations-en.csv": locations,
+				}))
+				assert.Nil(t, reader)
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Country", func(t *testing.T) {
+		reader, meta, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Blocks-IPv6.csv":  testCountryBlocksIPv6,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+		assert.EqualValues(t, "GeoLite2-Country", meta.Description)
+		assert.EqualValues(t, 2, meta.MajorFormatVersion)
+		assert.EqualValues(t, 0, meta.MinorFormatVersion)
+		assert.EqualValues(t, geodbtools.IPVersion6, meta.IPVersion)
+		assert.EqualValues(t, time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC).Unix(), meta.BuildTime.Unix())
+
+		if assert.IsType(t, &blocksReader{}, reader) {
+			r := reader.(*blocksReader)
+			assert.Len(t, r.records[geodbtools.IPVersion4], 3)
+			assert.Len(t, r.records[geodbtools.IPVersion6], 2)
+		}
+	})
+
+	t.Run("City", func(t *testing.T) {
+		reader, meta, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-City-Blocks-IPv4.csv":  testCityBlocksIPv4,
+			"GeoLite2-City-Locations-de.csv": testCityLocationsDE,
+			"GeoLite2-City-Locations-en.csv": testCityLocationsEN,
+		}))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCity, meta.Type)
+		assert.EqualValues(t, "GeoLite2-City", meta.Description)
+		assert.EqualValues(t, geodbtools.IPVersion4, meta.IPVersion)
+
+		record, err := reader.LookupIP(net.ParseIP("1.0.0.1"))
+		require.NoError(t, err)
+		if assert.IsType(t, &cityRecord{}, record) {
+			rec := record.(*cityRecord)
+			assert.EqualValues(t, "1.0.0.0/24", rec.GetNetwork().String())
+			assert.EqualValues(t, "AT", rec.GetCountryCode())
+			assert.EqualValues(t, "EU", rec.GetContinentCode())
+			assert.EqualValues(t, "AT", rec.GetRegisteredCountryCode())
+			assert.EqualValues(t, "Vienna", rec.GetCityName())
+			assert.EqualValues(t, map[string]string{"en": "Vienna", "de": "Wien"}, rec.GetCityNames())
+			assert.EqualValues(t, "9", rec.GetRegionCode())
+			assert.EqualValues(t, "1010", rec.GetPostalCode())
+			assert.EqualValues(t, 48.2, rec.GetLatitude())
+			assert.EqualValues(t, 16.3667, rec.GetLongitude())
+			assert.EqualValues(t, 20, rec.GetAccuracyRadius())
+			assert.EqualValues(t, "Europe/Vienna", rec.GetTimeZone())
+		}
+	})
+}
+
+func TestNewBundleReader(t *testing.T) {
+	t.Run("BlocksNotFound", func(t *testing.T) {
+		reader, _, err := NewBundleReader(testTarBundle(t, map[string]string{
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-en.csv": testCityLocationsEN,
+		}))
+		assert.Nil(t, reader)
+		assert.EqualError(t, err, ErrBlocksNotFound.Error())
+	})
+
+	t.Run("City", func(t *testing.T) {
+		reader, meta, err := NewBundleReader(testTarBundle(t, map[string]string{
+			"GeoLite2-City-CSV_20190101/COPYRIGHT.txt":                  "test",
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Blocks-IPv4.csv":  testCityBlocksIPv4,
+			"GeoLite2-City-CSV_20190101/GeoLite2-City-Locations-en.csv": testCityLocationsEN,
+		}))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCity, meta.Type)
+		assert.EqualValues(t, "GeoLite2-City", meta.Description)
+		assert.EqualValues(t, time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC).Unix(), meta.BuildTime.Unix())
+
+		record, err := reader.LookupIP(net.ParseIP("1.0.1.1"))
+		require.NoError(t, err)
+		assert.EqualValues(t, "US", record.(geodbtools.CountryRecord).GetCountryCode())
+	})
+}
+
+func TestBlocksReader_RecordTree(t *testing.T) {
+	reader, _, err := NewReader(testZipSource(t, map[string]string{
+		"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+		"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+	}))
+	require.NoError(t, err)
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersionUndefined)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		reader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Blocks-IPv6.csv":  testCountryBlocksIPv6,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		require.NoError(t, err)
+
+		tree, err := reader.RecordTree(geodbtools.IPVersion6)
+		require.NoError(t, err)
+
+		// the IPv4 blocks are mapped into ::ffff:0:0/96
+		networks := make(map[string]string)
+		require.NoError(t, tree.WalkNetworks(geodbtools.IPVersion6, func(network netip.Prefix, record geodbtools.Record) bool {
+			assert.EqualValues(t, network, geodbtools.RecordPrefix(record), network.String())
+			networks[network.String()] = record.(geodbtools.CountryRecord).GetCountryCode()
+			return true
+		}))
+		assert.EqualValues(t, map[string]string{
+			"::ffff:1.0.0.0/120":   "AT",
+			"::ffff:1.0.1.0/120":   "DE",
+			"::ffff:10.0.0.0/104":  "",
+			"::ffff:192.0.2.0/120": "DE",
+			"2001:db8::/32":        "AT",
+		}, networks)
+
+		// the records of the IPv4 blocks are left untouched
+		ipv4Tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+		for _, record := range ipv4Tree.Records() {
+			assert.True(t, geodbtools.RecordPrefix(record).Addr().Is4(), record.String())
+		}
+	})
+
+	t.Run("IPVersionNotContained", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion6)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			var treeRecords []string
+			for _, record := range tree.Records() {
+				treeRecords = append(treeRecords, record.String())
+			}
+
+			assert.EqualValues(t, []string{
+				"1.0.0.0/24: country code AT",
+				"1.0.1.0/24: country code DE",
+				"10.0.0.0/8: country code ",
+			}, treeRecords)
+
+			// the networks of the tree match the networks of the blocks
+			var networks []string
+			require.NoError(t, tree.WalkNetworks(geodbtools.IPVersion4, func(network netip.Prefix, record geodbtools.Record) bool {
+				networks = append(networks, network.String())
+				return true
+			}))
+			assert.EqualValues(t, []string{"1.0.0.0/24", "1.0.1.0/24", "10.0.0.0/8"}, networks)
+		}
+	})
+}
+
+func TestBlocksReader_LookupIP(t *testing.T) {
+	reader, _, err := NewReader(testZipSource(t, map[string]string{
+		"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+		"GeoLite2-Country-Blocks-IPv6.csv":  testCountryBlocksIPv6,
+		"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+	}))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		IP              string
+		ExpectedNetwork string
+		ExpectedCountry string
+	}{
+		{"1.0.0.0", "1.0.0.0/24", "AT"},
+		{"1.0.1.255", "1.0.1.0/24", "DE"},
+		{"::ffff:10.1.2.3", "10.0.0.0/8", ""},
+		{"2001:db8:1::1", "2001:db8::/32", "AT"},
+		{"192.0.2.1", "::ffff:192.0.2.0/120", "DE"},
+		{"0.0.0.1", "", ""},
+		{"1.0.2.0", "", ""},
+		{"2001:db9::", "", ""},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.IP, func(t *testing.T) {
+			record, err := reader.LookupIP(net.ParseIP(testCase.IP))
+			if testCase.ExpectedNetwork == "" {
+				assert.Nil(t, record)
+				assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+				return
+			}
+
+			assert.NoError(t, err)
+			if assert.NotNil(t, record) {
+				assert.EqualValues(t, testCase.ExpectedNetwork, geodbtools.PrefixFromIPNet(record.GetNetwork()).String())
+				assert.EqualValues(t, testCase.ExpectedCountry, record.(geodbtools.CountryRecord).GetCountryCode())
+			}
+		})
+	}
+
+	t.Run("InvalidIP", func(t *testing.T) {
+		record, err := reader.LookupIP(nil)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+
+	t.Run("RepresentedCountry", func(t *testing.T) {
+		record, err := reader.LookupIP(net.ParseIP("10.0.0.1"))
+		require.NoError(t, err)
+		rec := record.(*countryRecord)
+		assert.EqualValues(t, "US", rec.GetRegisteredCountryCode())
+		assert.EqualValues(t, "US", rec.GetRepresentedCountryCode())
+		assert.True(t, rec.isAnonymousProxy)
+		assert.False(t, rec.isSatelliteProvider)
+	})
+}
+
+func TestReadLocations(t *testing.T) {
+	locations := make(map[uint32]*location)
+	require.NoError(t, readLocations(strings.NewReader(testCityLocationsEN), "en", locations))
+	require.NoError(t, readLocations(strings.NewReader(testCityLocationsDE), "de", locations))
+
+	assert.Len(t, locations, 3)
+	assert.EqualValues(t, &location{
+		geoNameID:           5803556,
+		continentCode:       "NA",
+		countryISOCode:      "US",
+		subdivision1ISOCode: "WA",
+		metroCode:           819,
+		timeZone:            "America/Los_Angeles",
+		names: map[string]locationNames{
+			"en": {
+				continent:    "North America",
+				country:      "United States",
+				subdivision1: "Washington",
+				city:         "Milton",
+			},
+			"de": {
+				continent:    "Nordamerika",
+				country:      "USA",
+				subdivision1: "Washington",
+			},
+		},
+	}, locations[5803556])
+}
@@ -0,0 +1,176 @@
+package geoip2csvformat
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// blocksRecord describes a record read from a blocks file
+type blocksRecord interface {
+	geodbtools.Record
+
+	// withNetwork returns a copy of the record, representing the given network
+	withNetwork(network *net.IPNet) geodbtools.Record
+}
+
+var _ blocksRecord = (*countryRecord)(nil)
+var _ geodbtools.CountryNameRecord = (*countryRecord)(nil)
+var _ geodbtools.ContinentRecord = (*countryRecord)(nil)
+var _ geodbtools.RegisteredCountryRecord = (*countryRecord)(nil)
+var _ geodbtools.RepresentedCountryRecord = (*countryRecord)(nil)
+
+// countryRecord represents a single line of a country blocks file
+type countryRecord struct {
+	network             *net.IPNet
+	location            *location
+	registeredCountry   *location
+	representedCountry  *location
+	isAnonymousProxy    bool
+	isSatelliteProvider bool
+}
+
+func (r *countryRecord) withNetwork(network *net.IPNet) geodbtools.Record {
+	rec := *r
+	rec.network = network
+	return &rec
+}
+
+func (r *countryRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *countryRecord) GetCountryCode() string {
+	if r.location == nil {
+		return ""
+	}
+	return r.location.countryISOCode
+}
+
+func (r *countryRecord) GetCountryName() string {
+	return r.location.name(countryName)
+}
+
+func (r *countryRecord) GetContinentCode() string {
+	if r.location == nil {
+		return ""
+	}
+	return r.location.continentCode
+}
+
+func (r *countryRecord) GetRegisteredCountryCode() string {
+	if r.registeredCountry == nil {
+		return ""
+	}
+	return r.registeredCountry.countryISOCode
+}
+
+func (r *countryRecord) GetRepresentedCountryCode() string {
+	if r.representedCountry == nil {
+		return ""
+	}
+	return r.representedCountry.countryISOCode
+}
+
+// GetRepresentedCountryType always returns an empty string, as the type is not part of the CSV format
+func (r *countryRecord) GetRepresentedCountryType() string {
+	return ""
+}
+
+func (r *countryRecord) String() string {
+	return fmt.Sprintf("%s: country code %s", r.network, r.GetCountryCode())
+}
+
+var _ blocksRecord = (*cityRecord)(nil)
+var _ geodbtools.LocalizedCityRecord = (*cityRecord)(nil)
+var _ geodbtools.RegionRecord = (*cityRecord)(nil)
+var _ geodbtools.SubdivisionRecord = (*cityRecord)(nil)
+var _ geodbtools.PostalCodeRecord = (*cityRecord)(nil)
+var _ geodbtools.LocationRecord = (*cityRecord)(nil)
+var _ geodbtools.AccuracyRadiusRecord = (*cityRecord)(nil)
+var _ geodbtools.TimeZoneRecord = (*cityRecord)(nil)
+var _ geodbtools.MetroCodeRecord = (*cityRecord)(nil)
+
+// cityRecord represents a single line of a city blocks file
+type cityRecord struct {
+	countryRecord
+	postalCode     string
+	latitude       float64
+	longitude      float64
+	accuracyRadius uint
+}
+
+func (r *cityRecord) withNetwork(network *net.IPNet) geodbtools.Record {
+	rec := *r
+	rec.network = network
+	return &rec
+}
+
+func (r *cityRecord) GetCityName() string {
+	return r.location.name(cityName)
+}
+
+func (r *cityRecord) GetCityNames() map[string]string {
+	return r.location.localizedNames(cityName)
+}
+
+func (r *cityRecord) GetRegionCode() string {
+	if r.location == nil {
+		return ""
+	}
+	return r.location.subdivision1ISOCode
+}
+
+func (r *cityRecord) GetSubdivisionCodes() (codes []string) {
+	if r.location == nil {
+		return
+	}
+
+	for _, code := range []string{r.location.subdivision1ISOCode, r.location.subdivision2ISOCode} {
+		if code != "" {
+			codes = append(codes, code)
+		}
+	}
+	return
+}
+
+func (r *cityRecord) GetPostalCode() string {
+	return r.postalCode
+}
+
+func (r *cityRecord) GetLatitude() float64 {
+	return r.latitude
+}
+
+func (r *cityRecord) GetLongitude() float64 {
+	return r.longitude
+}
+
+func (r *cityRecord) GetAccuracyRadius() uint {
+	return r.accuracyRadius
+}
+
+func (r *cityRecord) GetTimeZone() string {
+	if r.location == nil {
+		return ""
+	}
+	return r.location.timeZone
+}
+
+func (r *cityRecord) GetMetroCode() int {
+	if r.location == nil {
+		return 0
+	}
+	return r.location.metroCode
+}
+
+// GetAreaCode always returns 0, as area codes are not part of the CSV format
+func (r *cityRecord) GetAreaCode() int {
+	return 0
+}
+
+func (r *cityRecord) String() string {
+	return fmt.Sprintf("%s: country code %s, region %s, city %s, postal code %s, location %.4f,%.4f",
+		r.network, r.GetCountryCode(), r.GetRegionCode(), r.GetCityName(), r.postalCode, r.latitude, r.longitude)
+}
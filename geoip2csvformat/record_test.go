@@ -0,0 +1,133 @@
+package geoip2csvformat
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountryRecord_Getters(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+
+	t.Run("NoLocations", func(t *testing.T) {
+		rec := &countryRecord{
+			network: network,
+		}
+
+		assert.EqualValues(t, network, rec.GetNetwork())
+		assert.EqualValues(t, "", rec.GetCountryCode())
+		assert.EqualValues(t, "", rec.GetCountryName())
+		assert.EqualValues(t, "", rec.GetContinentCode())
+		assert.EqualValues(t, "", rec.GetRegisteredCountryCode())
+		assert.EqualValues(t, "", rec.GetRepresentedCountryCode())
+		assert.EqualValues(t, "", rec.GetRepresentedCountryType())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		rec := &countryRecord{
+			network: network,
+			location: &location{
+				continentCode:  "EU",
+				countryISOCode: "AT",
+				names: map[string]locationNames{
+					"de": {country: "Österreich"},
+					"en": {country: "Austria"},
+				},
+			},
+			registeredCountry:  &location{countryISOCode: "DE"},
+			representedCountry: &location{countryISOCode: "US"},
+		}
+
+		assert.EqualValues(t, "AT", rec.GetCountryCode())
+		assert.EqualValues(t, "Austria", rec.GetCountryName())
+		assert.EqualValues(t, "EU", rec.GetContinentCode())
+		assert.EqualValues(t, "DE", rec.GetRegisteredCountryCode())
+		assert.EqualValues(t, "US", rec.GetRepresentedCountryCode())
+	})
+}
+
+func TestCountryRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+	rec := &countryRecord{
+		network:  network,
+		location: &location{countryISOCode: "XX"},
+	}
+
+	assert.EqualValues(t, "127.0.0.127/32: country code XX", rec.String())
+}
+
+func TestCityRecord_Getters(t *testing.T) {
+	t.Run("NoLocation", func(t *testing.T) {
+		rec := &cityRecord{}
+
+		assert.EqualValues(t, "", rec.GetCityName())
+		assert.Nil(t, rec.GetCityNames())
+		assert.EqualValues(t, "", rec.GetRegionCode())
+		assert.Nil(t, rec.GetSubdivisionCodes())
+		assert.EqualValues(t, "", rec.GetTimeZone())
+		assert.EqualValues(t, 0, rec.GetMetroCode())
+		assert.EqualValues(t, 0, rec.GetAreaCode())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		rec := &cityRecord{
+			countryRecord: countryRecord{
+				location: &location{
+					subdivision1ISOCode: "ENG",
+					subdivision2ISOCode: "WBK",
+					metroCode:           42,
+					timeZone:            "Europe/London",
+					names: map[string]locationNames{
+						"de": {city: "Boxford (de)"},
+						"fr": {city: "Boxford (fr)"},
+						"ru": {},
+					},
+				},
+			},
+			postalCode:     "OX1",
+			latitude:       51.75,
+			longitude:      -1.25,
+			accuracyRadius: 100,
+		}
+
+		assert.EqualValues(t, "Boxford (de)", rec.GetCityName())
+		assert.EqualValues(t, map[string]string{"de": "Boxford (de)", "fr": "Boxford (fr)"}, rec.GetCityNames())
+		assert.EqualValues(t, "ENG", rec.GetRegionCode())
+		assert.EqualValues(t, []string{"ENG", "WBK"}, rec.GetSubdivisionCodes())
+		assert.EqualValues(t, "OX1", rec.GetPostalCode())
+		assert.EqualValues(t, 51.75, rec.GetLatitude())
+		assert.EqualValues(t, -1.25, rec.GetLongitude())
+		assert.EqualValues(t, 100, rec.GetAccuracyRadius())
+		assert.EqualValues(t, "Europe/London", rec.GetTimeZone())
+		assert.EqualValues(t, 42, rec.GetMetroCode())
+
+		rec.location.names["en"] = locationNames{city: "Boxford"}
+		assert.EqualValues(t, "Boxford", rec.GetCityName())
+	})
+}
+
+func TestCityRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+	rec := &cityRecord{
+		countryRecord: countryRecord{
+			network: network,
+			location: &location{
+				countryISOCode:      "AT",
+				subdivision1ISOCode: "9",
+				names: map[string]locationNames{
+					"en": {city: "Vienna"},
+				},
+			},
+		},
+		postalCode: "1010",
+		latitude:   48.2,
+		longitude:  16.3667,
+	}
+
+	assert.EqualValues(t, "127.0.0.127/32: country code AT, region 9, city Vienna, postal code 1010, location 48.2000,16.3667", rec.String())
+}
@@ -0,0 +1,496 @@
+package geoip2csvformat
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/netip"
+	"sort"
+	"strconv"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+var (
+	countryBlocksColumns = []string{
+		"network", "geoname_id", "registered_country_geoname_id", "represented_country_geoname_id",
+		"is_anonymous_proxy", "is_satellite_provider",
+	}
+
+	cityBlocksColumns = []string{
+		"network", "geoname_id", "registered_country_geoname_id", "represented_country_geoname_id",
+		"is_anonymous_proxy", "is_satellite_provider", "postal_code", "latitude", "longitude", "accuracy_radius",
+	}
+
+	countryLocationsColumns = []string{
+		"geoname_id", "locale_code", "continent_code", "continent_name", "country_iso_code", "country_name",
+		"is_in_european_union",
+	}
+
+	cityLocationsColumns = []string{
+		"geoname_id", "locale_code", "continent_code", "continent_name", "country_iso_code", "country_name",
+		"subdivision_1_iso_code", "subdivision_1_name", "subdivision_2_iso_code", "subdivision_2_name", "city_name",
+		"metro_code", "time_zone", "is_in_european_union",
+	}
+)
+
+// databaseNames maps the supported database types to the database name used as file name prefix
+var databaseNames = map[geodbtools.DatabaseType]string{
+	geodbtools.DatabaseTypeCountry: "GeoLite2-Country",
+	geodbtools.DatabaseTypeCity:    "GeoLite2-City",
+}
+
+// locationSet holds the distinct locations referenced by the written blocks
+type locationSet struct {
+	byKey     map[string]*location
+	ids       map[uint32]bool
+	locations []*location
+}
+
+func newLocationSet() *locationSet {
+	return &locationSet{
+		byKey: make(map[string]*location),
+		ids:   make(map[uint32]bool),
+	}
+}
+
+// add returns the location of the set equal to the given location, adding a copy of it if necessary.
+// GeoName IDs of added locations are retained, unless they are already used by a different location.
+// Locations without a GeoName ID take over the ID of equal locations added later on.
+func (s *locationSet) add(loc *location) *location {
+	if loc == nil {
+		return nil
+	}
+
+	key := loc.key()
+	if existing, ok := s.byKey[key]; ok {
+		if existing.geoNameID == 0 && loc.geoNameID != 0 && !s.ids[loc.geoNameID] {
+			existing.geoNameID = loc.geoNameID
+			s.ids[loc.geoNameID] = true
+		}
+		return existing
+	}
+
+	added := *loc
+	if s.ids[added.geoNameID] {
+		added.geoNameID = 0
+	} else if added.geoNameID != 0 {
+		s.ids[added.geoNameID] = true
+	}
+
+	s.byKey[key] = &added
+	s.locations = append(s.locations, &added)
+	return &added
+}
+
+// assignIDs assigns GeoName IDs to all locations without one and sorts the locations by their ID
+func (s *locationSet) assignIDs() {
+	var nextID uint32
+	for id := range s.ids {
+		if id > nextID {
+			nextID = id
+		}
+	}
+
+	for _, loc := range s.locations {
+		if loc.geoNameID == 0 {
+			nextID++
+			loc.geoNameID = nextID
+		}
+	}
+
+	sort.Slice(s.locations, func(i, j int) bool {
+		return s.locations[i].geoNameID < s.locations[j].geoNameID
+	})
+}
+
+// locales returns the locales of all locations, starting with the default locale
+func (s *locationSet) locales() (locales []string) {
+	all := &location{
+		names: map[string]locationNames{
+			defaultLocale: {},
+		},
+	}
+
+	for _, loc := range s.locations {
+		for locale := range loc.names {
+			all.names[locale] = locationNames{}
+		}
+	}
+	return all.locales()
+}
+
+// countryLevel returns a copy of the location reduced to country-level information.
+// The GeoName ID is only retained if the location does not describe a subdivision or city.
+func (l *location) countryLevel() *location {
+	if l == nil {
+		return nil
+	}
+
+	c := &location{
+		geoNameID:         l.geoNameID,
+		continentCode:     l.continentCode,
+		countryISOCode:    l.countryISOCode,
+		isInEuropeanUnion: l.isInEuropeanUnion,
+		names:             make(map[string]locationNames, len(l.names)),
+	}
+
+	for locale, names := range l.names {
+		if names.subdivision1 != "" || names.subdivision2 != "" || names.city != "" {
+			c.geoNameID = 0
+		}
+
+		c.names[locale] = locationNames{
+			continent: names.continent,
+			country:   names.country,
+		}
+	}
+
+	if l.subdivision1ISOCode != "" || l.subdivision2ISOCode != "" || l.metroCode != 0 || l.timeZone != "" {
+		c.geoNameID = 0
+	}
+	return c
+}
+
+// countryCodeLocation returns a location consisting of the given country code only
+func countryCodeLocation(countryCode string) *location {
+	if countryCode == "" {
+		return nil
+	}
+
+	return &location{
+		countryISOCode: countryCode,
+	}
+}
+
+// recordLocation builds the location of a record not read from a GeoIP2 CSV database
+func recordLocation(record geodbtools.Record, cityLevel bool) (loc *location) {
+	loc = &location{
+		names: make(map[string]locationNames),
+	}
+
+	if countryRecord, ok := record.(geodbtools.CountryRecord); ok {
+		loc.countryISOCode = countryRecord.GetCountryCode()
+	}
+
+	if countryNameRecord, ok := record.(geodbtools.CountryNameRecord); ok && countryNameRecord.GetCountryName() != "" {
+		loc.names[defaultLocale] = locationNames{
+			country: countryNameRecord.GetCountryName(),
+		}
+	}
+
+	if continentRecord, ok := record.(geodbtools.ContinentRecord); ok {
+		loc.continentCode = continentRecord.GetContinentCode()
+	}
+
+	if cityLevel {
+		if subdivisionRecord, ok := record.(geodbtools.SubdivisionRecord); ok {
+			codes := subdivisionRecord.GetSubdivisionCodes()
+			if len(codes) > 0 {
+				loc.subdivision1ISOCode = codes[0]
+			}
+			if len(codes) > 1 {
+				loc.subdivision2ISOCode = codes[1]
+			}
+		} else if regionRecord, ok := record.(geodbtools.RegionRecord); ok {
+			loc.subdivision1ISOCode = regionRecord.GetRegionCode()
+		}
+
+		if localizedCityRecord, ok := record.(geodbtools.LocalizedCityRecord); ok {
+			for locale, name := range localizedCityRecord.GetCityNames() {
+				names := loc.names[locale]
+				names.city = name
+				loc.names[locale] = names
+			}
+		}
+
+		if cityRecord, ok := record.(geodbtools.CityRecord); ok && cityRecord.GetCityName() != "" && loc.names[defaultLocale].city == "" {
+			names := loc.names[defaultLocale]
+			names.city = cityRecord.GetCityName()
+			loc.names[defaultLocale] = names
+		}
+
+		if metroCodeRecord, ok := record.(geodbtools.MetroCodeRecord); ok {
+			loc.metroCode = metroCodeRecord.GetMetroCode()
+		}
+
+		if timeZoneRecord, ok := record.(geodbtools.TimeZoneRecord); ok {
+			loc.timeZone = timeZoneRecord.GetTimeZone()
+		}
+	}
+
+	if loc.key() == (&location{}).key() {
+		loc = nil
+	}
+	return
+}
+
+// blockRecord converts a record into a record of this format, whose locations are part of the given location set
+func blockRecord(record geodbtools.Record, dbType geodbtools.DatabaseType, locations *locationSet) (block *cityRecord, err error) {
+	cityLevel := dbType == geodbtools.DatabaseTypeCity
+
+	if cityLevel {
+		if _, ok := record.(geodbtools.CityRecord); !ok {
+			err = geodbtools.ErrUnsupportedRecordType
+			return
+		}
+	} else if _, ok := record.(geodbtools.CountryRecord); !ok {
+		err = geodbtools.ErrUnsupportedRecordType
+		return
+	}
+
+	block = &cityRecord{}
+
+	switch rec := record.(type) {
+	case *cityRecord:
+		*block = *rec
+	case *countryRecord:
+		block.countryRecord = *rec
+	default:
+		block.network = record.GetNetwork()
+		block.location = recordLocation(record, cityLevel)
+
+		if registeredCountryRecord, ok := record.(geodbtools.RegisteredCountryRecord); ok {
+			block.registeredCountry = countryCodeLocation(registeredCountryRecord.GetRegisteredCountryCode())
+		}
+
+		if representedCountryRecord, ok := record.(geodbtools.RepresentedCountryRecord); ok {
+			block.representedCountry = countryCodeLocation(representedCountryRecord.GetRepresentedCountryCode())
+		}
+
+		if postalCodeRecord, ok := record.(geodbtools.PostalCodeRecord); ok {
+			block.postalCode = postalCodeRecord.GetPostalCode()
+		}
+
+		if locationRecord, ok := record.(geodbtools.LocationRecord); ok {
+			block.latitude = locationRecord.GetLatitude()
+			block.longitude = locationRecord.GetLongitude()
+		}
+
+		if accuracyRadiusRecord, ok := record.(geodbtools.AccuracyRadiusRecord); ok {
+			block.accuracyRadius = accuracyRadiusRecord.GetAccuracyRadius()
+		}
+	}
+
+	if !cityLevel {
+		block.location = block.location.countryLevel()
+		block.postalCode = ""
+		block.latitude = 0
+		block.longitude = 0
+		block.accuracyRadius = 0
+	}
+
+	block.location = locations.add(block.location)
+	block.registeredCountry = locations.add(block.registeredCountry.countryLevel())
+	block.representedCountry = locations.add(block.representedCountry.countryLevel())
+	return
+}
+
+func formatGeoNameID(loc *location) string {
+	if loc == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(loc.geoNameID), 10)
+}
+
+func formatBool(b bool) string {
+	if b {
+		return "1"
+	}
+	return "0"
+}
+
+var _ geodbtools.Writer = (*writer)(nil)
+
+type writer struct {
+	w         io.Writer
+	dbType    geodbtools.DatabaseType
+	ipVersion geodbtools.IPVersion
+}
+
+func (w *writer) writeBlocks(out io.Writer, blocks []*cityRecord) (err error) {
+	columns := countryBlocksColumns
+	if w.dbType == geodbtools.DatabaseTypeCity {
+		columns = cityBlocksColumns
+	}
+
+	var cw *csvWriter
+	if cw, err = newCSVWriter(out, columns); err != nil {
+		return
+	}
+
+	for _, block := range blocks {
+		values := map[string]string{
+			"network":                        block.network.String(),
+			"geoname_id":                     formatGeoNameID(block.location),
+			"registered_country_geoname_id":  formatGeoNameID(block.registeredCountry),
+			"represented_country_geoname_id": formatGeoNameID(block.representedCountry),
+			"is_anonymous_proxy":             formatBool(block.isAnonymousProxy),
+			"is_satellite_provider":          formatBool(block.isSatelliteProvider),
+			"postal_code":                    block.postalCode,
+		}
+
+		if block.latitude != 0 || block.longitude != 0 {
+			values["latitude"] = strconv.FormatFloat(block.latitude, 'f', -1, 64)
+			values["longitude"] = strconv.FormatFloat(block.longitude, 'f', -1, 64)
+		}
+
+		if block.accuracyRadius != 0 {
+			values["accuracy_radius"] = strconv.FormatUint(uint64(block.accuracyRadius), 10)
+		}
+
+		if err = cw.write(values); err != nil {
+			return
+		}
+	}
+
+	return cw.flush()
+}
+
+func (w *writer) writeLocations(out io.Writer, locale string, locations []*location) (err error) {
+	columns := countryLocationsColumns
+	if w.dbType == geodbtools.DatabaseTypeCity {
+		columns = cityLocationsColumns
+	}
+
+	var cw *csvWriter
+	if cw, err = newCSVWriter(out, columns); err != nil {
+		return
+	}
+
+	for _, loc := range locations {
+		names := loc.names[locale]
+		values := map[string]string{
+			"geoname_id":             formatGeoNameID(loc),
+			"locale_code":            locale,
+			"continent_code":         loc.continentCode,
+			"continent_name":         names.continent,
+			"country_iso_code":       loc.countryISOCode,
+			"country_name":           names.country,
+			"subdivision_1_iso_code": loc.subdivision1ISOCode,
+			"subdivision_1_name":     names.subdivision1,
+			"subdivision_2_iso_code": loc.subdivision2ISOCode,
+			"subdivision_2_name":     names.subdivision2,
+			"city_name":              names.city,
+			"time_zone":              loc.timeZone,
+			"is_in_european_union":   formatBool(loc.isInEuropeanUnion),
+		}
+
+		if loc.metroCode != 0 {
+			values["metro_code"] = strconv.Itoa(loc.metroCode)
+		}
+
+		if err = cw.write(values); err != nil {
+			return
+		}
+	}
+
+	return cw.flush()
+}
+
+func (w *writer) WriteDatabase(meta geodbtools.Metadata, tree *geodbtools.RecordTree) (err error) {
+	locations := newLocationSet()
+	blocks := make(map[geodbtools.IPVersion][]*cityRecord)
+	blocks[w.ipVersion] = nil
+
+	if walkErr := tree.WalkNetworks(w.ipVersion, func(network netip.Prefix, record geodbtools.Record) bool {
+		if !network.IsValid() {
+			// ignore record without a network
+			return true
+		}
+
+		var block *cityRecord
+		if block, err = blockRecord(record, w.dbType, locations); err != nil {
+			return false
+		}
+
+		if network.Addr().Is4In6() && network.Bits() >= 96 {
+			// IPv4-mapped networks are written to the IPv4 blocks file
+			network = netip.PrefixFrom(network.Addr().Unmap(), network.Bits()-96)
+			block.network = geodbtools.IPNetFromPrefix(network)
+		} else if network != geodbtools.RecordPrefix(record) {
+			// the record has been inherited from a covering network
+			block.network = geodbtools.IPNetFromPrefix(network)
+		}
+
+		ipVersion := geodbtools.IPVersion6
+		if network.Addr().Is4() {
+			ipVersion = geodbtools.IPVersion4
+		}
+		blocks[ipVersion] = append(blocks[ipVersion], block)
+		return true
+	}); err != nil {
+		return
+	} else if err = walkErr; err != nil {
+		return
+	}
+
+	locations.assignIDs()
+
+	zipWriter := zip.NewWriter(w.w)
+	createFile := func(name string) (io.Writer, error) {
+		header := &zip.FileHeader{
+			Name:   name,
+			Method: zip.Deflate,
+		}
+
+		if !meta.BuildTime.IsZero() {
+			header.Modified = meta.BuildTime
+		}
+
+		return zipWriter.CreateHeader(header)
+	}
+
+	dbName := databaseNames[w.dbType]
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		ipBlocks, ok := blocks[ipVersion]
+		if !ok {
+			continue
+		}
+
+		sort.Slice(ipBlocks, func(i, j int) bool {
+			return bytes.Compare(ipBlocks[i].network.IP, ipBlocks[j].network.IP) < 0
+		})
+
+		suffix := blocksIPv4Suffix
+		if ipVersion == geodbtools.IPVersion6 {
+			suffix = blocksIPv6Suffix
+		}
+
+		var out io.Writer
+		if out, err = createFile(dbName + suffix); err != nil {
+			return
+		} else if err = w.writeBlocks(out, ipBlocks); err != nil {
+			return
+		}
+	}
+
+	for _, locale := range locations.locales() {
+		var out io.Writer
+		if out, err = createFile(dbName + locationsInfix + locale + csvExtension); err != nil {
+			return
+		} else if err = w.writeLocations(out, locale, locations.locations); err != nil {
+			return
+		}
+	}
+
+	return zipWriter.Close()
+}
+
+// NewWriter returns a new writer, writing a ZIP archive holding the blocks and locations files of the given
+// database type
+func NewWriter(w io.Writer, dbType geodbtools.DatabaseType, ipVersion geodbtools.IPVersion) (geodbtools.Writer, error) {
+	if _, ok := databaseNames[dbType]; !ok {
+		return nil, geodbtools.ErrUnsupportedDatabaseType
+	}
+
+	if ipVersion != geodbtools.IPVersion4 && ipVersion != geodbtools.IPVersion6 {
+		return nil, geodbtools.ErrUnsupportedIPVersion
+	}
+
+	return &writer{
+		w:         w,
+		dbType:    dbType,
+		ipVersion: ipVersion,
+	}, nil
+}
@@ -0,0 +1,293 @@
+package geoip2csvformat
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	_ "github.com/anexia-it/geodbtools/mmdbformat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyCityRecord implements a city record as provided by legacy (DAT) databases
+type legacyCityRecord struct {
+	network     *net.IPNet
+	countryCode string
+	regionCode  string
+	cityName    string
+	postalCode  string
+	latitude    float64
+	longitude   float64
+	metroCode   int
+}
+
+func (r *legacyCityRecord) String() string         { return r.network.String() }
+func (r *legacyCityRecord) GetNetwork() *net.IPNet { return r.network }
+func (r *legacyCityRecord) GetCountryCode() string { return r.countryCode }
+func (r *legacyCityRecord) GetRegionCode() string  { return r.regionCode }
+func (r *legacyCityRecord) GetCityName() string    { return r.cityName }
+func (r *legacyCityRecord) GetPostalCode() string  { return r.postalCode }
+func (r *legacyCityRecord) GetLatitude() float64   { return r.latitude }
+func (r *legacyCityRecord) GetLongitude() float64  { return r.longitude }
+func (r *legacyCityRecord) GetMetroCode() int      { return r.metroCode }
+func (r *legacyCityRecord) GetAreaCode() int       { return 0 }
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+// readZipFiles returns the contents of all files contained in the given ZIP archive
+func readZipFiles(t *testing.T, b []byte) (files map[string]string) {
+	bundle, err := geodbtools.NewZipBundle(geodbtools.NewReaderSourceWrapper(bytes.NewReader(b), int64(len(b))))
+	require.NoError(t, err)
+
+	files = make(map[string]string)
+	for _, name := range bundle.Files() {
+		s, err := bundle.Open(name)
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(io.NewSectionReader(s, 0, s.Size()))
+		require.NoError(t, err)
+		files[name] = string(data)
+	}
+	return
+}
+
+func testRecordTree(t *testing.T, records ...geodbtools.Record) *geodbtools.RecordTree {
+	tree, err := geodbtools.NewRecordTree(31, records, bitmap.IsSet)
+	require.NoError(t, err)
+	return tree
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("UnsupportedDatabaseType", func(t *testing.T) {
+		w, err := NewWriter(nil, geodbtools.DatabaseTypeISP, geodbtools.IPVersion4)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := NewWriter(nil, geodbtools.DatabaseTypeCountry, geodbtools.IPVersionUndefined)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+}
+
+func TestWriter_WriteDatabase(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		sourceReader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		require.NoError(t, err)
+		tree, err := sourceReader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+
+		w, err := NewWriter(bytes.NewBufferString(""), geodbtools.DatabaseTypeCity, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(geodbtools.Metadata{}, tree), geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		testErr := errors.New("test error")
+		w, err := NewWriter(&failingWriter{err: testErr}, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(geodbtools.Metadata{}, testRecordTree(t)), testErr.Error())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, testRecordTree(t)))
+
+		assert.EqualValues(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv6.csv":  "network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider\n",
+			"GeoLite2-Country-Locations-en.csv": "geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union\n",
+		}, readZipFiles(t, buf.Bytes()))
+	})
+
+	t.Run("CountryRecords", func(t *testing.T) {
+		sourceReader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		require.NoError(t, err)
+		tree, err := sourceReader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}, readZipFiles(t, buf.Bytes()))
+	})
+
+	t.Run("IPv4MappedRecords", func(t *testing.T) {
+		sourceReader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4,
+			"GeoLite2-Country-Blocks-IPv6.csv":  testCountryBlocksIPv6,
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}))
+		require.NoError(t, err)
+		tree, err := sourceReader.RecordTree(geodbtools.IPVersion6)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		// IPv4-mapped networks are written to the IPv4 blocks file
+		assert.EqualValues(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv":  testCountryBlocksIPv4 + "192.0.2.0/24,2921044,2782113,,0,0\n",
+			"GeoLite2-Country-Blocks-IPv6.csv":  "network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider\n2001:db8::/32,2782113,2782113,,0,0\n",
+			"GeoLite2-Country-Locations-en.csv": testCountryLocations,
+		}, readZipFiles(t, buf.Bytes()))
+	})
+
+	t.Run("CityRecordsToCountry", func(t *testing.T) {
+		sourceReader, _, err := NewReader(testZipSource(t, map[string]string{
+			"GeoLite2-City-Blocks-IPv4.csv":  testCityBlocksIPv4,
+			"GeoLite2-City-Locations-en.csv": testCityLocationsEN,
+		}))
+		require.NoError(t, err)
+		tree, err := sourceReader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, map[string]string{
+			"GeoLite2-Country-Blocks-IPv4.csv": `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.0.0.0/24,2782113,2782113,,0,0
+1.0.1.0/24,2782114,,,0,0
+`,
+			"GeoLite2-Country-Locations-en.csv": `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union
+2782113,en,EU,Europe,AT,Austria,1
+2782114,en,NA,North America,US,United States,0
+`,
+		}, readZipFiles(t, buf.Bytes()))
+	})
+
+	t.Run("ForeignCityRecords", func(t *testing.T) {
+		_, network1, _ := net.ParseCIDR("1.0.1.0/24")
+		_, network2, _ := net.ParseCIDR("1.0.0.0/24")
+		_, network3, _ := net.ParseCIDR("1.0.2.0/24")
+
+		tree := testRecordTree(t,
+			&legacyCityRecord{
+				network:     network1,
+				countryCode: "US",
+				regionCode:  "WA",
+				cityName:    "Milton",
+				postalCode:  "98354",
+				latitude:    47.2513,
+				longitude:   -122.3149,
+				metroCode:   819,
+			},
+			&legacyCityRecord{
+				network:     network2,
+				countryCode: "AT",
+				regionCode:  "09",
+				cityName:    "Vienna",
+				latitude:    48.2,
+				longitude:   16.3667,
+			},
+			&legacyCityRecord{
+				network:     network3,
+				countryCode: "US",
+				regionCode:  "WA",
+				cityName:    "Milton",
+				postalCode:  "98354",
+				latitude:    47.2513,
+				longitude:   -122.3149,
+				metroCode:   819,
+			},
+		)
+
+		buildTime := time.Date(2019, 1, 2, 3, 4, 6, 0, time.UTC)
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCity, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{BuildTime: buildTime}, tree))
+
+		assert.EqualValues(t, map[string]string{
+			"GeoLite2-City-Blocks-IPv4.csv": `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider,postal_code,latitude,longitude,accuracy_radius
+1.0.0.0/24,1,,,0,0,,48.2,16.3667,
+1.0.1.0/24,2,,,0,0,98354,47.2513,-122.3149,
+1.0.2.0/24,2,,,0,0,98354,47.2513,-122.3149,
+`,
+			"GeoLite2-City-Locations-en.csv": `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,subdivision_1_iso_code,subdivision_1_name,subdivision_2_iso_code,subdivision_2_name,city_name,metro_code,time_zone,is_in_european_union
+1,en,,,AT,,09,,,,Vienna,,,0
+2,en,,,US,,WA,,,,Milton,819,,0
+`,
+		}, readZipFiles(t, buf.Bytes()))
+
+		reader, meta, err := NewReader(geodbtools.NewReaderSourceWrapper(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+		require.NoError(t, err)
+		assert.EqualValues(t, buildTime.Unix(), meta.BuildTime.Unix())
+		assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+	})
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	testPath := filepath.Join(filepath.Dir(testFilename), "..", "mmdbformat", "test-data", "test-data", "GeoIP2-City-Test.mmdb")
+	source, err := geodbtools.NewFileReaderSource(testPath)
+	require.NoError(t, err)
+	defer source.Close()
+
+	mmdbFormat, err := geodbtools.LookupFormat("mmdb")
+	require.NoError(t, err)
+	sourceReader, sourceMeta, err := mmdbFormat.NewReaderAt(source)
+	require.NoError(t, err)
+
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		tree, err := sourceReader.RecordTree(ipVersion)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := format{}.NewWriter(buf, sourceMeta.Type, ipVersion)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(sourceMeta, tree))
+
+		reader, meta, err := format{}.NewReaderAt(geodbtools.NewReaderSourceWrapper(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCity, meta.Type)
+		assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+
+		if ipVersion != geodbtools.IPVersion4 {
+			continue
+		}
+
+		// rewriting the database retains all information
+		rewriteTree, err := reader.RecordTree(meta.IPVersion)
+		require.NoError(t, err)
+		rewriteBuf := bytes.NewBufferString("")
+		w, err = format{}.NewWriter(rewriteBuf, meta.Type, meta.IPVersion)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(meta, rewriteTree))
+		assert.EqualValues(t, readZipFiles(t, buf.Bytes()), readZipFiles(t, rewriteBuf.Bytes()))
+	}
+}
@@ -0,0 +1,57 @@
+package httpapi
+
+import (
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// trusted returns whether the given address is contained in any of the given networks
+func trusted(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientAddr returns the address of the client that issued the given request.
+// The X-Forwarded-For headers of the request are only considered if the request has been received from one of the
+// given trusted proxies. In that case, the addresses listed are walked from the last to the first one, returning the
+// first address not belonging to a trusted proxy. If an entry cannot be parsed, the last trusted address is returned.
+func ClientAddr(r *http.Request, trustedProxies []netip.Prefix) (addr netip.Addr, ok bool) {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+
+	if addr, err = netip.ParseAddr(host); err != nil {
+		return
+	}
+	addr = addr.WithZone("").Unmap()
+	ok = true
+
+	if !trusted(addr, trustedProxies) {
+		return
+	}
+
+	var forwarded []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		forwardedAddr, parseErr := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if parseErr != nil {
+			return
+		}
+
+		addr = forwardedAddr.WithZone("").Unmap()
+		if !trusted(addr, trustedProxies) {
+			return
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package httpapi
+
+import (
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientAddr(t *testing.T) {
+	trustedProxies := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+
+	testCases := []struct {
+		name          string
+		remoteAddr    string
+		forwardedFor  []string
+		expectedAddr  string
+		expectedFound bool
+	}{
+		{"Direct", "192.0.2.1:1234", nil, "192.0.2.1", true},
+		{"DirectIPv6", "[2001:db8::1]:1234", nil, "2001:db8::1", true},
+		{"UntrustedForwarded", "192.0.2.1:1234", []string{"198.51.100.1"}, "192.0.2.1", true},
+		{"TrustedForwarded", "10.0.0.1:1234", []string{"198.51.100.1"}, "198.51.100.1", true},
+		{"TrustedForwardedIPv6", "[fd00::1]:1234", []string{"2001:db8::2"}, "2001:db8::2", true},
+		{"TrustedChain", "10.0.0.1:1234", []string{"203.0.113.5, 198.51.100.1", "10.0.0.2"}, "198.51.100.1", true},
+		{"AllTrusted", "10.0.0.1:1234", []string{"10.0.0.3, 10.0.0.2"}, "10.0.0.3", true},
+		{"InvalidForwarded", "10.0.0.1:1234", []string{"invalid, 10.0.0.2"}, "10.0.0.2", true},
+		{"TrustedWithoutHeader", "10.0.0.1:1234", nil, "10.0.0.1", true},
+		{"InvalidRemoteAddr", "invalid", nil, "", false},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/me", nil)
+			r.RemoteAddr = testCase.remoteAddr
+			for _, forwardedFor := range testCase.forwardedFor {
+				r.Header.Add("X-Forwarded-For", forwardedFor)
+			}
+
+			addr, ok := ClientAddr(r, trustedProxies)
+			if assert.EqualValues(t, testCase.expectedFound, ok) && ok {
+				assert.EqualValues(t, testCase.expectedAddr, addr.String())
+			}
+		})
+	}
+}
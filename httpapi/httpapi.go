@@ -0,0 +1,270 @@
+// Package httpapi provides a JSON HTTP API for looking up IP addresses in GeoIP databases
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// DefaultMaxBatchSize defines the maximum number of addresses accepted by a batch lookup, unless configured otherwise
+const DefaultMaxBatchSize = 1000
+
+// maxBatchBodySize limits the size of batch lookup request bodies per address
+const maxBatchBodySize = 64
+
+// ErrNoDatabases indicates that a handler has been created without any database
+var ErrNoDatabases = errors.New("no databases")
+
+// ErrDuplicateDatabase indicates that multiple databases share the same name
+var ErrDuplicateDatabase = errors.New("duplicate database name")
+
+// Database holds a database served by the API
+type Database struct {
+	// Name identifies the database in responses
+	Name string
+
+	// Format holds the name of the database format
+	Format string
+
+	// Reader holds the reader used for lookups
+	Reader geodbtools.Reader
+
+	// Metadata holds the metadata of the database.
+	// Readers implementing geodbtools.MetadataReader report the metadata themselves.
+	Metadata geodbtools.Metadata
+}
+
+// metadata returns the current metadata of the database
+func (db Database) metadata() geodbtools.Metadata {
+	if metadataReader, ok := db.Reader.(geodbtools.MetadataReader); ok {
+		return metadataReader.Metadata()
+	}
+	return db.Metadata
+}
+
+// Options holds the options of a handler
+type Options struct {
+	// TrustedProxies holds the networks of proxies whose X-Forwarded-For headers are trusted when determining the
+	// address of the requester
+	TrustedProxies []netip.Prefix
+
+	// MaxBatchSize limits the number of addresses accepted by a batch lookup, DefaultMaxBatchSize is used if zero
+	MaxBatchSize int
+}
+
+// DatabaseResult represents the result of looking up an address in a single database
+type DatabaseResult struct {
+	Network string                 `json:"network,omitempty"`
+	Record  map[string]interface{} `json:"record,omitempty"`
+	Error   string                 `json:"error,omitempty"`
+}
+
+// LookupResult represents the result of looking up an address in all databases
+type LookupResult struct {
+	IP        string                     `json:"ip"`
+	Databases map[string]*DatabaseResult `json:"databases,omitempty"`
+	Error     string                     `json:"error,omitempty"`
+}
+
+// DatabaseMetadata represents the metadata of a database
+type DatabaseMetadata struct {
+	Name          string    `json:"name"`
+	Format        string    `json:"format"`
+	Type          string    `json:"type"`
+	Description   string    `json:"description"`
+	FormatVersion string    `json:"format_version"`
+	BuildTime     time.Time `json:"build_time"`
+	IPVersion     uint      `json:"ip_version"`
+}
+
+// Health represents the health of the API
+type Health struct {
+	Status    string `json:"status"`
+	Databases int    `json:"databases"`
+}
+
+// errorResponse represents an error in responses
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// handler implements the API
+type handler struct {
+	mux       *http.ServeMux
+	databases []Database
+	options   Options
+}
+
+var _ http.Handler = (*handler)(nil)
+
+// NewHandler returns a new http.Handler serving the API for the given databases.
+//
+// The following endpoints are provided:
+//
+//	GET  /lookup/{ip}  looks up the given address
+//	POST /lookup       looks up the addresses held by the JSON array of strings passed as request body
+//	GET  /me           looks up the address of the requester
+//	GET  /health       reports the health of the API
+//	GET  /metadata     returns the metadata of the databases
+func NewHandler(databases []Database, options Options) (h http.Handler, err error) {
+	if len(databases) == 0 {
+		err = ErrNoDatabases
+		return
+	}
+
+	names := make(map[string]bool, len(databases))
+	for _, db := range databases {
+		if names[db.Name] {
+			err = ErrDuplicateDatabase
+			return
+		}
+		names[db.Name] = true
+	}
+
+	if options.MaxBatchSize <= 0 {
+		options.MaxBatchSize = DefaultMaxBatchSize
+	}
+
+	apiHandler := &handler{
+		mux:       http.NewServeMux(),
+		databases: databases,
+		options:   options,
+	}
+	apiHandler.mux.HandleFunc("/lookup/", allowMethod(http.MethodGet, apiHandler.handleLookup))
+	apiHandler.mux.HandleFunc("/lookup", allowMethod(http.MethodPost, apiHandler.handleBatchLookup))
+	apiHandler.mux.HandleFunc("/me", allowMethod(http.MethodGet, apiHandler.handleLookupRequester))
+	apiHandler.mux.HandleFunc("/health", allowMethod(http.MethodGet, apiHandler.handleHealth))
+	apiHandler.mux.HandleFunc("/metadata", allowMethod(http.MethodGet, apiHandler.handleMetadata))
+
+	h = apiHandler
+	return
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
+}
+
+// allowMethod restricts the given handler function to requests of the given method
+func allowMethod(method string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != method {
+			w.Header().Set("Allow", method)
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+			return
+		}
+		fn(w, r)
+	}
+}
+
+// writeJSON writes the given value as JSON response with the given status code
+func writeJSON(w http.ResponseWriter, statusCode int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the given error message as JSON response with the given status code
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, errorResponse{Error: message})
+}
+
+// lookup looks up the given address in all databases
+func (h *handler) lookup(address string) (result *LookupResult) {
+	result = &LookupResult{
+		IP: address,
+	}
+
+	addr, err := netip.ParseAddr(address)
+	if err != nil {
+		result.Error = "invalid IP address"
+		return
+	}
+	addr = addr.WithZone("")
+	result.IP = addr.String()
+
+	result.Databases = make(map[string]*DatabaseResult, len(h.databases))
+	for _, db := range h.databases {
+		dbResult := &DatabaseResult{}
+		if record, prefix, lookupErr := geodbtools.LookupNetwork(db.Reader, addr); lookupErr != nil {
+			dbResult.Error = lookupErr.Error()
+		} else {
+			dbResult.Record = geodbtools.RecordFields(record)
+			if prefix.IsValid() {
+				dbResult.Network = prefix.String()
+			}
+		}
+		result.Databases[db.Name] = dbResult
+	}
+	return
+}
+
+func (h *handler) handleLookup(w http.ResponseWriter, r *http.Request) {
+	result := h.lookup(strings.TrimPrefix(r.URL.Path, "/lookup/"))
+	if result.Error != "" {
+		writeJSON(w, http.StatusBadRequest, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *handler) handleBatchLookup(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(h.options.MaxBatchSize)*maxBatchBodySize)
+
+	var addresses []string
+	if err := json.NewDecoder(r.Body).Decode(&addresses); err != nil {
+		writeError(w, http.StatusBadRequest, "request body must be a JSON array of IP addresses")
+		return
+	}
+
+	if len(addresses) > h.options.MaxBatchSize {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("at most %d addresses may be looked up at once", h.options.MaxBatchSize))
+		return
+	}
+
+	results := make([]*LookupResult, 0, len(addresses))
+	for _, address := range addresses {
+		results = append(results, h.lookup(address))
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+func (h *handler) handleLookupRequester(w http.ResponseWriter, r *http.Request) {
+	addr, ok := ClientAddr(r, h.options.TrustedProxies)
+	if !ok {
+		writeError(w, http.StatusBadRequest, "unable to determine client address")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, h.lookup(addr.String()))
+}
+
+func (h *handler) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, Health{
+		Status:    "ok",
+		Databases: len(h.databases),
+	})
+}
+
+func (h *handler) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	metadata := make([]DatabaseMetadata, 0, len(h.databases))
+	for _, db := range h.databases {
+		meta := db.metadata()
+		metadata = append(metadata, DatabaseMetadata{
+			Name:          db.Name,
+			Format:        db.Format,
+			Type:          string(meta.Type),
+			Description:   meta.Description,
+			FormatVersion: fmt.Sprintf("%d.%d", meta.MajorFormatVersion, meta.MinorFormatVersion),
+			BuildTime:     meta.BuildTime,
+			IPVersion:     uint(meta.IPVersion),
+		})
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
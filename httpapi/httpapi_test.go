@@ -0,0 +1,222 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	_ "github.com/anexia-it/geodbtools/mmdbformat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabase opens the mmdb test database with the given file name
+func testDatabase(t *testing.T, name, fileName string) Database {
+	_, testFilename, _, _ := runtime.Caller(0)
+	testPath := filepath.Join(filepath.Dir(testFilename), "..", "mmdbformat", "test-data", "test-data", fileName)
+
+	source, err := geodbtools.NewFileReaderSource(testPath)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		source.Close()
+	})
+
+	format, err := geodbtools.LookupFormat("mmdb")
+	require.NoError(t, err)
+
+	reader, meta, err := format.NewReaderAt(source)
+	require.NoError(t, err)
+
+	return Database{
+		Name:     name,
+		Format:   format.FormatName(),
+		Reader:   reader,
+		Metadata: meta,
+	}
+}
+
+// testHandler returns a handler serving the country and ASN test databases
+func testHandler(t *testing.T, options Options) http.Handler {
+	h, err := NewHandler([]Database{
+		testDatabase(t, "country", "GeoIP2-Country-Test.mmdb"),
+		testDatabase(t, "asn", "GeoLite2-ASN-Test.mmdb"),
+	}, options)
+	require.NoError(t, err)
+	return h
+}
+
+// serve runs the given request against the given handler, decoding the JSON response into v
+func serve(t *testing.T, h http.Handler, r *http.Request, v interface{}) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	assert.EqualValues(t, "application/json", w.Header().Get("Content-Type"))
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), v))
+	return w
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Run("NoDatabases", func(t *testing.T) {
+		_, err := NewHandler(nil, Options{})
+		assert.EqualError(t, err, ErrNoDatabases.Error())
+	})
+
+	t.Run("DuplicateDatabase", func(t *testing.T) {
+		_, err := NewHandler([]Database{{Name: "country"}, {Name: "country"}}, Options{})
+		assert.EqualError(t, err, ErrDuplicateDatabase.Error())
+	})
+}
+
+func TestHandler_Lookup(t *testing.T) {
+	h := testHandler(t, Options{})
+
+	t.Run("OK", func(t *testing.T) {
+		var result LookupResult
+		w := serve(t, h, httptest.NewRequest("GET", "/lookup/81.2.69.142", nil), &result)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+
+		assert.EqualValues(t, "81.2.69.142", result.IP)
+		assert.Empty(t, result.Error)
+		if assert.Contains(t, result.Databases, "country") {
+			assert.EqualValues(t, "81.2.69.142/31", result.Databases["country"].Network)
+			assert.EqualValues(t, "GB", result.Databases["country"].Record["country_code"])
+			assert.Empty(t, result.Databases["country"].Error)
+		}
+		assert.Contains(t, result.Databases, "asn")
+	})
+
+	t.Run("ASN", func(t *testing.T) {
+		var result LookupResult
+		w := serve(t, h, httptest.NewRequest("GET", "/lookup/1.128.0.1", nil), &result)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+		if assert.Contains(t, result.Databases, "asn") {
+			assert.EqualValues(t, "1.128.0.0/11", result.Databases["asn"].Network)
+			assert.EqualValues(t, 1221, result.Databases["asn"].Record["as_number"])
+			assert.EqualValues(t, "Telstra Pty Ltd", result.Databases["asn"].Record["organization"])
+		}
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		var result LookupResult
+		w := serve(t, h, httptest.NewRequest("GET", "/lookup/2001:218::1", nil), &result)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+		if assert.Contains(t, result.Databases, "country") {
+			assert.EqualValues(t, "JP", result.Databases["country"].Record["country_code"])
+		}
+	})
+
+	t.Run("InvalidAddress", func(t *testing.T) {
+		var result LookupResult
+		w := serve(t, h, httptest.NewRequest("GET", "/lookup/invalid", nil), &result)
+		assert.EqualValues(t, http.StatusBadRequest, w.Code)
+		assert.EqualValues(t, "invalid IP address", result.Error)
+		assert.Empty(t, result.Databases)
+	})
+}
+
+func TestHandler_BatchLookup(t *testing.T) {
+	h := testHandler(t, Options{MaxBatchSize: 2})
+
+	t.Run("OK", func(t *testing.T) {
+		var results []LookupResult
+		w := serve(t, h, httptest.NewRequest("POST", "/lookup", strings.NewReader(`["81.2.69.142", "invalid"]`)), &results)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+
+		if assert.Len(t, results, 2) {
+			assert.EqualValues(t, "81.2.69.142", results[0].IP)
+			assert.EqualValues(t, "GB", results[0].Databases["country"].Record["country_code"])
+			assert.EqualValues(t, "invalid", results[1].IP)
+			assert.EqualValues(t, "invalid IP address", results[1].Error)
+		}
+	})
+
+	t.Run("InvalidBody", func(t *testing.T) {
+		var result errorResponse
+		w := serve(t, h, httptest.NewRequest("POST", "/lookup", strings.NewReader(`{}`)), &result)
+		assert.EqualValues(t, http.StatusBadRequest, w.Code)
+		assert.NotEmpty(t, result.Error)
+	})
+
+	t.Run("TooManyAddresses", func(t *testing.T) {
+		var result errorResponse
+		w := serve(t, h, httptest.NewRequest("POST", "/lookup", strings.NewReader(`["81.2.69.142", "81.2.69.142", "81.2.69.142"]`)), &result)
+		assert.EqualValues(t, http.StatusRequestEntityTooLarge, w.Code)
+		assert.NotEmpty(t, result.Error)
+	})
+}
+
+func TestHandler_LookupRequester(t *testing.T) {
+	h := testHandler(t, Options{
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	})
+
+	t.Run("Direct", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/me", nil)
+		r.RemoteAddr = "81.2.69.142:1234"
+		r.Header.Set("X-Forwarded-For", "2001:218::1")
+
+		var result LookupResult
+		w := serve(t, h, r, &result)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+		assert.EqualValues(t, "81.2.69.142", result.IP)
+		assert.EqualValues(t, "GB", result.Databases["country"].Record["country_code"])
+	})
+
+	t.Run("TrustedProxy", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/me", nil)
+		r.RemoteAddr = "10.0.0.1:1234"
+		r.Header.Set("X-Forwarded-For", "2001:218::1")
+
+		var result LookupResult
+		w := serve(t, h, r, &result)
+		assert.EqualValues(t, http.StatusOK, w.Code)
+		assert.EqualValues(t, "2001:218::1", result.IP)
+		assert.EqualValues(t, "JP", result.Databases["country"].Record["country_code"])
+	})
+
+	t.Run("InvalidRemoteAddr", func(t *testing.T) {
+		r := httptest.NewRequest("GET", "/me", nil)
+		r.RemoteAddr = "invalid"
+
+		var result errorResponse
+		w := serve(t, h, r, &result)
+		assert.EqualValues(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestHandler_Health(t *testing.T) {
+	var health Health
+	w := serve(t, testHandler(t, Options{}), httptest.NewRequest("GET", "/health", nil), &health)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, Health{Status: "ok", Databases: 2}, health)
+}
+
+func TestHandler_Metadata(t *testing.T) {
+	var metadata []DatabaseMetadata
+	w := serve(t, testHandler(t, Options{}), httptest.NewRequest("GET", "/metadata", nil), &metadata)
+	assert.EqualValues(t, http.StatusOK, w.Code)
+
+	if assert.Len(t, metadata, 2) {
+		assert.EqualValues(t, "country", metadata[0].Name)
+		assert.EqualValues(t, "mmdb", metadata[0].Format)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCountry, metadata[0].Type)
+		assert.EqualValues(t, 6, metadata[0].IPVersion)
+		assert.False(t, metadata[0].BuildTime.IsZero())
+
+		assert.EqualValues(t, "asn", metadata[1].Name)
+		assert.EqualValues(t, geodbtools.DatabaseTypeASN, metadata[1].Type)
+	}
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	var result errorResponse
+	w := serve(t, testHandler(t, Options{}), httptest.NewRequest("GET", "/lookup", nil), &result)
+	assert.EqualValues(t, http.StatusMethodNotAllowed, w.Code)
+	assert.EqualValues(t, http.MethodPost, w.Header().Get("Allow"))
+}
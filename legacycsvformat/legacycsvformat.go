@@ -0,0 +1,66 @@
+// Package legacycsvformat implements the legacy MaxMind GeoIP country CSV format (GeoIPCountryWhois.csv and
+// GeoIPv6.csv), consisting of IP address ranges mapped to countries
+package legacycsvformat
+
+import (
+	"bufio"
+	"encoding/csv"
+	"io"
+	"strings"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+const (
+	// fieldCount defines the number of fields of a single line
+	fieldCount = 6
+
+	// detectMaxLineLength defines the maximum number of bytes read for format detection
+	detectMaxLineLength = 1024
+)
+
+var _ geodbtools.Format = format{}
+
+type format struct{}
+
+func (format) FormatName() string {
+	return "legacycsv"
+}
+
+func (format) NewReaderAt(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	return NewReader(r)
+}
+
+func (format) NewWriter(w io.Writer, dbType geodbtools.DatabaseType, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	return NewWriter(w, dbType, ipVersion)
+}
+
+// DetectFormat checks if the first line of the given source is a valid range definition
+func (format) DetectFormat(r geodbtools.ReaderSource) (isFormat bool) {
+	line, err := bufio.NewReaderSize(io.NewSectionReader(r, 0, r.Size()), detectMaxLineLength).ReadSlice('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+
+	csvReader := newCSVReader(strings.NewReader(string(line)))
+	var row []string
+	if row, err = csvReader.Read(); err != nil {
+		return
+	}
+
+	_, err = parseRow(row)
+	return err == nil
+}
+
+// newCSVReader returns a new csv.Reader configured for the legacy CSV format
+func newCSVReader(r io.Reader) (csvReader *csv.Reader) {
+	csvReader = csv.NewReader(r)
+	csvReader.FieldsPerRecord = fieldCount
+	csvReader.TrimLeadingSpace = true
+	csvReader.ReuseRecord = true
+	return
+}
+
+func init() {
+	geodbtools.MustRegisterFormat(format{})
+}
@@ -0,0 +1,76 @@
+package legacycsvformat
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testIPv4Database = `"1.0.0.0","1.0.0.255","16777216","16777471","AU","Australia"
+"1.0.1.0","1.0.3.255","16777472","16778239","CN","China"
+"1.0.4.0","1.0.4.255","16778240","16778495","AU","Australia"
+`
+
+const testIPv6Database = `"2001:200::", "2001:200:ffff:ffff:ffff:ffff:ffff:ffff", "42540528726795050063891204319802818560", "42540528806023212578155541913346768895", "JP", "Japan"
+"2001:208::", "2001:208:ffff:ffff:ffff:ffff:ffff:ffff", "42540529360620350178005905068154421248", "42540529439848512692270242661698371583", "SG", "Singapore"
+`
+
+// testSource returns a ReaderSource providing the given data
+func testSource(data string) geodbtools.ReaderSource {
+	return geodbtools.NewReaderSourceWrapper(bytes.NewReader([]byte(data)), int64(len(data)))
+}
+
+func TestFormat_FormatName(t *testing.T) {
+	assert.EqualValues(t, "legacycsv", format{}.FormatName())
+}
+
+func TestFormat_NewReaderAt(t *testing.T) {
+	reader, meta, err := format{}.NewReaderAt(testSource(testIPv4Database))
+	assert.NoError(t, err)
+	assert.NotNil(t, reader)
+	assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+}
+
+func TestFormat_NewWriter(t *testing.T) {
+	w, err := format{}.NewWriter(bytes.NewBufferString(""), geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+	assert.NoError(t, err)
+	assert.NotNil(t, w)
+}
+
+func TestFormat_DetectFormat(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		assert.True(t, format{}.DetectFormat(testSource(testIPv4Database)))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		assert.True(t, format{}.DetectFormat(testSource(testIPv6Database)))
+	})
+
+	t.Run("SingleLineWithoutNewline", func(t *testing.T) {
+		assert.True(t, format{}.DetectFormat(testSource(`"1.0.0.0","1.0.0.255","16777216","16777471","AU","Australia"`)))
+	})
+
+	testCases := map[string]string{
+		"Empty":            "",
+		"Binary":           "\x00\x01\x02\x03",
+		"GeoIP2CSV":        "network,geoname_id,registered_country_geoname_id\n1.0.0.0/24,2077456,2077456\n",
+		"InvalidIntegers":  `"1.0.0.0","1.0.0.255","1","2","AU","Australia"` + "\n",
+		"LineTooLong":      `"1.0.0.0","1.0.0.255","16777216","16777471","AU","` + string(bytes.Repeat([]byte("A"), detectMaxLineLength)) + `"` + "\n",
+		"MissingCountries": `"1.0.0.0","1.0.0.255","16777216","16777471"` + "\n",
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.False(t, format{}.DetectFormat(testSource(testCase)))
+		})
+	}
+}
+
+func TestFormatRegistered(t *testing.T) {
+	f, err := geodbtools.LookupFormat("legacycsv")
+	require.NoError(t, err)
+	assert.EqualValues(t, format{}, f)
+}
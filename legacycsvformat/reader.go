@@ -0,0 +1,178 @@
+package legacycsvformat
+
+import (
+	"bytes"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// countryRange represents a single line of the CSV file
+type countryRange struct {
+	first       net.IP
+	last        net.IP
+	countryCode string
+	countryName string
+}
+
+// parseIP parses an IP address and checks that it matches its integer representation
+func parseIP(ipString, intString string) (ip net.IP, err error) {
+	if ip = net.ParseIP(ipString); ip == nil {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	} else if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+
+	ipInt, ok := new(big.Int).SetString(intString, 10)
+	if !ok || ipInt.Cmp(new(big.Int).SetBytes(ip)) != 0 {
+		ip = nil
+		err = geodbtools.ErrDatabaseInvalid
+	}
+	return
+}
+
+// parseRow parses the fields of a single line
+func parseRow(row []string) (r countryRange, err error) {
+	if len(row) != fieldCount {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	if r.first, err = parseIP(row[0], row[2]); err != nil {
+		return
+	} else if r.last, err = parseIP(row[1], row[3]); err != nil {
+		return
+	} else if len(r.first) != len(r.last) || bytes.Compare(r.first, r.last) > 0 {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	r.countryCode = row[4]
+	r.countryName = row[5]
+	return
+}
+
+var _ geodbtools.Reader = (*rangeReader)(nil)
+
+type rangeReader struct {
+	// records holds the records of each IP version, sorted by network address
+	records map[geodbtools.IPVersion][]geodbtools.Record
+}
+
+func (r *rangeReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
+	records, ok := r.records[ipVersion]
+	if !ok {
+		err = geodbtools.ErrUnsupportedIPVersion
+		return
+	}
+
+	tree, err = geodbtools.NewPrefixRecordTree(records)
+	return
+}
+
+func (r *rangeReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	ipVersion := geodbtools.IPVersion6
+	if ipv4 := ip.To4(); ipv4 != nil {
+		ip = ipv4
+		ipVersion = geodbtools.IPVersion4
+	} else if ip = ip.To16(); ip == nil {
+		err = geodbtools.ErrRecordNotFound
+		return
+	}
+
+	records := r.records[ipVersion]
+	i := sort.Search(len(records), func(i int) bool {
+		return bytes.Compare(records[i].GetNetwork().IP, ip) > 0
+	})
+
+	if i == 0 || !records[i-1].GetNetwork().Contains(ip) {
+		err = geodbtools.ErrRecordNotFound
+		return
+	}
+
+	record = records[i-1]
+	return
+}
+
+// readRecords reads all lines from the given io.Reader, splitting the ranges into networks
+func readRecords(r io.Reader) (records map[geodbtools.IPVersion][]geodbtools.Record, err error) {
+	records = make(map[geodbtools.IPVersion][]geodbtools.Record)
+	csvReader := newCSVReader(r)
+
+	for {
+		var row []string
+		if row, err = csvReader.Read(); err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			records = nil
+			return
+		}
+
+		var countryRange countryRange
+		if countryRange, err = parseRow(row); err != nil {
+			records = nil
+			return
+		}
+
+		var networks []*net.IPNet
+		if networks, err = geodbtools.RangeNetworks(countryRange.first, countryRange.last); err != nil {
+			records = nil
+			return
+		}
+
+		ipVersion := geodbtools.IPVersion6
+		if len(countryRange.first) == net.IPv4len {
+			ipVersion = geodbtools.IPVersion4
+		}
+
+		for _, network := range networks {
+			records[ipVersion] = append(records[ipVersion], &countryRecord{
+				network:     network,
+				countryCode: countryRange.countryCode,
+				countryName: countryRange.countryName,
+			})
+		}
+	}
+
+	for _, ipRecords := range records {
+		sort.Slice(ipRecords, func(i, j int) bool {
+			return bytes.Compare(ipRecords[i].GetNetwork().IP, ipRecords[j].GetNetwork().IP) < 0
+		})
+	}
+	return
+}
+
+// NewReader returns a new reader for the legacy CSV database provided by the given ReaderSource
+func NewReader(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	var records map[geodbtools.IPVersion][]geodbtools.Record
+	if records, err = readRecords(io.NewSectionReader(r, 0, r.Size())); err != nil {
+		return
+	}
+
+	meta = geodbtools.Metadata{
+		Type:               geodbtools.DatabaseTypeCountry,
+		BuildTime:          time.Now(),
+		Description:        "GeoIP Country CSV",
+		MajorFormatVersion: 1,
+		MinorFormatVersion: 0,
+		IPVersion:          geodbtools.IPVersion4,
+	}
+
+	if _, ok := records[geodbtools.IPVersion6]; ok {
+		meta.IPVersion = geodbtools.IPVersion6
+	} else if _, ok := records[geodbtools.IPVersion4]; !ok {
+		// files without any lines are considered IPv4 databases
+		records[geodbtools.IPVersion4] = nil
+	}
+
+	reader = &rangeReader{
+		records: records,
+	}
+	return
+}
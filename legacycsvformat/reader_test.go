@@ -0,0 +1,170 @@
+package legacycsvformat
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRow(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		r, err := parseRow([]string{"1.0.0.0", "1.0.0.255", "16777216", "16777471", "AU", "Australia"})
+		assert.NoError(t, err)
+		assert.EqualValues(t, net.IP{1, 0, 0, 0}, r.first)
+		assert.EqualValues(t, net.IP{1, 0, 0, 255}, r.last)
+		assert.EqualValues(t, "AU", r.countryCode)
+		assert.EqualValues(t, "Australia", r.countryName)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		r, err := parseRow([]string{"2001:200::", "2001:200:ffff:ffff:ffff:ffff:ffff:ffff",
+			"42540528726795050063891204319802818560", "42540528806023212578155541913346768895", "JP", "Japan"})
+		assert.NoError(t, err)
+		assert.EqualValues(t, net.ParseIP("2001:200::"), r.first)
+		assert.EqualValues(t, net.ParseIP("2001:200:ffff:ffff:ffff:ffff:ffff:ffff"), r.last)
+		assert.EqualValues(t, "JP", r.countryCode)
+		assert.EqualValues(t, "Japan", r.countryName)
+	})
+
+	testCases := map[string][]string{
+		"FieldCount":          {"1.0.0.0", "1.0.0.255", "16777216", "16777471", "AU"},
+		"InvalidFirstIP":      {"1.0.0", "1.0.0.255", "16777216", "16777471", "AU", "Australia"},
+		"InvalidLastIP":       {"1.0.0.0", "1.0.0.x", "16777216", "16777471", "AU", "Australia"},
+		"InvalidInteger":      {"1.0.0.0", "1.0.0.255", "abc", "16777471", "AU", "Australia"},
+		"MismatchingInteger":  {"1.0.0.0", "1.0.0.255", "16777216", "16777472", "AU", "Australia"},
+		"MixedVersions":       {"1.0.0.0", "::ffff", "16777216", "65535", "AU", "Australia"},
+		"FirstAfterLast":      {"1.0.0.255", "1.0.0.0", "16777471", "16777216", "AU", "Australia"},
+		"IPv6IntegerMismatch": {"2001:200::", "2001:200::1", "1", "2", "JP", "Japan"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			_, err := parseRow(testCase)
+			assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		reader, meta, err := NewReader(testSource(testIPv4Database))
+		require.NoError(t, err)
+		require.NotNil(t, reader)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+		assert.EqualValues(t, "GeoIP Country CSV", meta.Description)
+		assert.EqualValues(t, 1, meta.MajorFormatVersion)
+		assert.EqualValues(t, 0, meta.MinorFormatVersion)
+		assert.EqualValues(t, geodbtools.IPVersion4, meta.IPVersion)
+
+		// the second range is split into two networks
+		rangeReader := reader.(*rangeReader)
+		networks := make([]string, len(rangeReader.records[geodbtools.IPVersion4]))
+		for i, record := range rangeReader.records[geodbtools.IPVersion4] {
+			networks[i] = record.GetNetwork().String()
+		}
+		assert.EqualValues(t, []string{"1.0.0.0/24", "1.0.1.0/24", "1.0.2.0/23", "1.0.4.0/24"}, networks)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		_, meta, err := NewReader(testSource(testIPv6Database))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.IPVersion6, meta.IPVersion)
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		reader, meta, err := NewReader(testSource(""))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.IPVersion4, meta.IPVersion)
+
+		tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		assert.NotNil(t, tree)
+	})
+
+	t.Run("CSVError", func(t *testing.T) {
+		reader, _, err := NewReader(testSource(`"1.0.0.0","1.0.0.255"` + "\n"))
+		assert.Error(t, err)
+		assert.Nil(t, reader)
+	})
+
+	t.Run("InvalidRow", func(t *testing.T) {
+		reader, _, err := NewReader(testSource(`"1.0.0.0","1.0.0.255","1","2","AU","Australia"` + "\n"))
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+		assert.Nil(t, reader)
+	})
+}
+
+func TestRangeReader_RecordTree(t *testing.T) {
+	reader, _, err := NewReader(testSource(testIPv4Database))
+	require.NoError(t, err)
+
+	t.Run("IPv4", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.Len(t, tree.Records(), 4)
+
+		// the networks of the tree match the networks of the ranges
+		var networks []string
+		require.NoError(t, tree.WalkNetworks(geodbtools.IPVersion4, func(network netip.Prefix, record geodbtools.Record) bool {
+			networks = append(networks, network.String())
+			return true
+		}))
+		assert.EqualValues(t, []string{"1.0.0.0/24", "1.0.1.0/24", "1.0.2.0/23", "1.0.4.0/24"}, networks)
+	})
+
+	t.Run("IPv6Missing", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion6)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+		assert.Nil(t, tree)
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion(5))
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+		assert.Nil(t, tree)
+	})
+}
+
+func TestRangeReader_LookupIP(t *testing.T) {
+	reader, _, err := NewReader(testSource(testIPv4Database + testIPv6Database))
+	require.NoError(t, err)
+
+	testCases := []struct {
+		IP          string
+		Network     string
+		CountryCode string
+	}{
+		{"1.0.0.1", "1.0.0.0/24", "AU"},
+		{"1.0.3.255", "1.0.2.0/23", "CN"},
+		{"1.0.4.0", "1.0.4.0/24", "AU"},
+		{"2001:200::1", "2001:200::/32", "JP"},
+		{"2001:208:1::", "2001:208::/32", "SG"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.IP, func(t *testing.T) {
+			record, err := reader.LookupIP(net.ParseIP(testCase.IP))
+			require.NoError(t, err)
+			assert.EqualValues(t, testCase.Network, record.GetNetwork().String())
+			assert.EqualValues(t, testCase.CountryCode, record.(geodbtools.CountryRecord).GetCountryCode())
+		})
+	}
+
+	for _, ip := range []string{"0.255.255.255", "1.0.5.0", "2001:1ff::", "2001:209::"} {
+		t.Run("NotFound"+ip, func(t *testing.T) {
+			record, err := reader.LookupIP(net.ParseIP(ip))
+			assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+			assert.Nil(t, record)
+		})
+	}
+
+	t.Run("InvalidIP", func(t *testing.T) {
+		record, err := reader.LookupIP(net.IP{1, 2, 3})
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+		assert.Nil(t, record)
+	})
+}
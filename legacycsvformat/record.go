@@ -0,0 +1,32 @@
+package legacycsvformat
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+var _ geodbtools.CountryNameRecord = (*countryRecord)(nil)
+
+type countryRecord struct {
+	network     *net.IPNet
+	countryCode string
+	countryName string
+}
+
+func (r *countryRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *countryRecord) GetCountryCode() string {
+	return r.countryCode
+}
+
+func (r *countryRecord) GetCountryName() string {
+	return r.countryName
+}
+
+func (r *countryRecord) String() string {
+	return fmt.Sprintf("%s: country code %s", r.network, r.countryCode)
+}
@@ -0,0 +1,22 @@
+package legacycsvformat
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCountryRecord(t *testing.T) {
+	_, network, _ := net.ParseCIDR("1.0.0.0/24")
+	record := &countryRecord{
+		network:     network,
+		countryCode: "AU",
+		countryName: "Australia",
+	}
+
+	assert.EqualValues(t, network, record.GetNetwork())
+	assert.EqualValues(t, "AU", record.GetCountryCode())
+	assert.EqualValues(t, "Australia", record.GetCountryName())
+	assert.EqualValues(t, "1.0.0.0/24: country code AU", record.String())
+}
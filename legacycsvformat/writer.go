@@ -0,0 +1,148 @@
+package legacycsvformat
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/big"
+	"net"
+	"net/netip"
+	"sort"
+	"strings"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// formatField returns the quoted representation of a single field
+func formatField(s string) string {
+	return `"` + strings.Replace(s, `"`, `""`, -1) + `"`
+}
+
+// rangeFields returns the fields of a single line describing the given range
+// IPv4-mapped IPv6 addresses are written as IPv4 addresses.
+func rangeFields(r countryRange) []string {
+	first, last := r.first, r.last
+	if first4, last4 := first.To4(), last.To4(); first4 != nil && last4 != nil {
+		first, last = first4, last4
+	}
+
+	return []string{
+		formatField(first.String()),
+		formatField(last.String()),
+		formatField(new(big.Int).SetBytes(first).String()),
+		formatField(new(big.Int).SetBytes(last).String()),
+		formatField(r.countryCode),
+		formatField(r.countryName),
+	}
+}
+
+var _ geodbtools.Writer = (*writer)(nil)
+
+type writer struct {
+	w         io.Writer
+	ipVersion geodbtools.IPVersion
+}
+
+// recordRange converts a record into the range it covers, given the network it represents
+func (w *writer) recordRange(record geodbtools.Record, prefix netip.Prefix) (r countryRange, err error) {
+	countryRecord, ok := record.(geodbtools.CountryRecord)
+	if !ok {
+		err = geodbtools.ErrUnsupportedRecordType
+		return
+	}
+
+	network := geodbtools.IPNetFromPrefix(prefix)
+
+	switch w.ipVersion {
+	case geodbtools.IPVersion4:
+		if network.IP = network.IP.To4(); network.IP == nil {
+			err = geodbtools.ErrUnsupportedIPVersion
+			return
+		}
+		if len(network.Mask) == net.IPv6len {
+			network.Mask = network.Mask[12:]
+		}
+	default:
+		network.IP = network.IP.To16()
+		if len(network.Mask) == net.IPv4len {
+			ones, _ := network.Mask.Size()
+			network.Mask = net.CIDRMask(ones+96, 128)
+		}
+	}
+
+	r.first = network.IP.Mask(network.Mask)
+	r.last = geodbtools.NetworkLastIP(network)
+	r.countryCode = countryRecord.GetCountryCode()
+
+	if countryNameRecord, ok := record.(geodbtools.CountryNameRecord); ok {
+		r.countryName = countryNameRecord.GetCountryName()
+	}
+	return
+}
+
+func (w *writer) WriteDatabase(meta geodbtools.Metadata, tree *geodbtools.RecordTree) (err error) {
+	var ranges []countryRange
+
+	if walkErr := tree.WalkNetworks(w.ipVersion, func(network netip.Prefix, record geodbtools.Record) bool {
+		if !network.IsValid() {
+			// ignore record without a network
+			return true
+		}
+
+		var r countryRange
+		if r, err = w.recordRange(record, network); err != nil {
+			return false
+		}
+		ranges = append(ranges, r)
+		return true
+	}); err != nil {
+		return
+	} else if err = walkErr; err != nil {
+		return
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return bytes.Compare(ranges[i].first, ranges[j].first) < 0
+	})
+
+	// the IPv6 file uses a separator including a space
+	separator := ","
+	if w.ipVersion == geodbtools.IPVersion6 {
+		separator = ", "
+	}
+
+	bufferedWriter := bufio.NewWriter(w.w)
+	for i := 0; i < len(ranges); i++ {
+		r := ranges[i]
+
+		// adjacent ranges of the same country are merged
+		for i+1 < len(ranges) && ranges[i+1].countryCode == r.countryCode && ranges[i+1].countryName == r.countryName &&
+			bytes.Equal(geodbtools.NextIP(r.last), ranges[i+1].first) {
+			i++
+			r.last = ranges[i].last
+		}
+
+		if _, err = bufferedWriter.WriteString(strings.Join(rangeFields(r), separator) + "\n"); err != nil {
+			return
+		}
+	}
+
+	return bufferedWriter.Flush()
+}
+
+// NewWriter returns a new writer for the legacy CSV format.
+// Only country databases are supported.
+func NewWriter(w io.Writer, dbType geodbtools.DatabaseType, ipVersion geodbtools.IPVersion) (geodbtools.Writer, error) {
+	if dbType != geodbtools.DatabaseTypeCountry {
+		return nil, geodbtools.ErrUnsupportedDatabaseType
+	}
+
+	if ipVersion != geodbtools.IPVersion4 && ipVersion != geodbtools.IPVersion6 {
+		return nil, geodbtools.ErrUnsupportedIPVersion
+	}
+
+	return &writer{
+		w:         w,
+		ipVersion: ipVersion,
+	}, nil
+}
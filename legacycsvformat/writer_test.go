@@ -0,0 +1,222 @@
+package legacycsvformat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	_ "github.com/anexia-it/geodbtools/mmdbformat"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+// testRecord implements a record not holding any country information
+type testRecord struct {
+	network *net.IPNet
+}
+
+func (r *testRecord) String() string         { return r.network.String() }
+func (r *testRecord) GetNetwork() *net.IPNet { return r.network }
+
+func testNetwork(t *testing.T, cidr string) *net.IPNet {
+	_, network, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+	return network
+}
+
+func testRecordTree(t *testing.T, ipVersion geodbtools.IPVersion, records ...geodbtools.Record) *geodbtools.RecordTree {
+	maxDepth := uint(31)
+	belongsRightFunc := geodbtools.RecordBelongsRightFunc(bitmap.IsSet)
+	if ipVersion == geodbtools.IPVersion6 {
+		maxDepth = 127
+		belongsRightFunc = geodbtools.RecordBelongsRightIPv6
+	}
+
+	tree, err := geodbtools.NewRecordTree(maxDepth, records, belongsRightFunc)
+	require.NoError(t, err)
+	return tree
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("UnsupportedDatabaseType", func(t *testing.T) {
+		w, err := NewWriter(nil, geodbtools.DatabaseTypeCity, geodbtools.IPVersion4)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := NewWriter(nil, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion(5))
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion6)
+		assert.NoError(t, err)
+		assert.EqualValues(t, &writer{
+			w:         buf,
+			ipVersion: geodbtools.IPVersion6,
+		}, w)
+	})
+}
+
+func TestWriter_WriteDatabase(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion4,
+			&countryRecord{network: testNetwork(t, "1.0.0.0/24"), countryCode: "AU", countryName: "Australia"},
+			&countryRecord{network: testNetwork(t, "1.0.1.0/24"), countryCode: "CN", countryName: "China"},
+			&countryRecord{network: testNetwork(t, "1.0.2.0/23"), countryCode: "CN", countryName: "China"},
+			&countryRecord{network: testNetwork(t, "1.0.4.0/24"), countryCode: "AU", countryName: "Australia"},
+			&countryRecord{network: testNetwork(t, "1.0.6.0/24"), countryCode: "AU", countryName: "Australia"},
+		)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, testIPv4Database+`"1.0.6.0","1.0.6.255","16778752","16779007","AU","Australia"`+"\n", buf.String())
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion6,
+			&countryRecord{network: testNetwork(t, "2001:200::/32"), countryCode: "JP", countryName: "Japan"},
+			&countryRecord{network: testNetwork(t, "2001:208::/32"), countryCode: "SG", countryName: "Singapore"},
+		)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, testIPv6Database, buf.String())
+	})
+
+	t.Run("IPv6WithIPv4Networks", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion6,
+			&countryRecord{network: testNetwork(t, "::ffff:1.0.0.0/120"), countryCode: "AU", countryName: "Australia"},
+		)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, `"1.0.0.0", "1.0.0.255", "16777216", "16777471", "AU", "Australia"`+"\n", buf.String())
+	})
+
+	t.Run("PatchedTree", func(t *testing.T) {
+		tree := &geodbtools.RecordTree{}
+		vendorRecord := &countryRecord{network: testNetwork(t, "1.0.0.0/22"), countryCode: "AU", countryName: "Australia"}
+		require.NoError(t, tree.Insert(geodbtools.PrefixFromIPNet(vendorRecord.network), vendorRecord))
+
+		customerRecord := &countryRecord{network: testNetwork(t, "1.0.1.0/24"), countryCode: "AT", countryName: "Austria"}
+		require.NoError(t, tree.Override(geodbtools.PrefixFromIPNet(customerRecord.network), customerRecord))
+		require.NoError(t, tree.Remove(geodbtools.PrefixFromIPNet(testNetwork(t, "1.0.3.0/24"))))
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, `"1.0.0.0","1.0.0.255","16777216","16777471","AU","Australia"
+"1.0.1.0","1.0.1.255","16777472","16777727","AT","Austria"
+"1.0.2.0","1.0.2.255","16777728","16777983","AU","Australia"
+`, buf.String())
+	})
+
+	t.Run("QuotedCountryName", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion4,
+			&countryRecord{network: testNetwork(t, "1.0.0.0/24"), countryCode: "XX", countryName: `The "Country"`},
+		)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{}, tree))
+
+		assert.EqualValues(t, `"1.0.0.0","1.0.0.255","16777216","16777471","XX","The ""Country"""`+"\n", buf.String())
+
+		reader, _, err := NewReader(testSource(buf.String()))
+		require.NoError(t, err)
+		record, err := reader.LookupIP(net.ParseIP("1.0.0.1"))
+		require.NoError(t, err)
+		assert.EqualValues(t, `The "Country"`, record.(geodbtools.CountryNameRecord).GetCountryName())
+	})
+
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion4, &testRecord{network: testNetwork(t, "1.0.0.0/24")})
+
+		w, err := NewWriter(bytes.NewBufferString(""), geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(geodbtools.Metadata{}, tree), geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("IPv6RecordInIPv4Database", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion6,
+			&countryRecord{network: testNetwork(t, "2001:200::/32"), countryCode: "JP", countryName: "Japan"},
+		)
+
+		w, err := NewWriter(bytes.NewBufferString(""), geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(geodbtools.Metadata{}, tree), geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		tree := testRecordTree(t, geodbtools.IPVersion4,
+			&countryRecord{network: testNetwork(t, "1.0.0.0/24"), countryCode: "AU", countryName: "Australia"},
+		)
+
+		testErr := errors.New("test error")
+		w, err := NewWriter(&failingWriter{err: testErr}, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(geodbtools.Metadata{}, tree), testErr.Error())
+	})
+}
+
+func TestWriter_RoundTrip(t *testing.T) {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	testPath := filepath.Join(filepath.Dir(testFilename), "..", "mmdbformat", "test-data", "test-data", "GeoIP2-Country-Test.mmdb")
+	source, err := geodbtools.NewFileReaderSource(testPath)
+	require.NoError(t, err)
+	defer source.Close()
+
+	mmdbFormat, err := geodbtools.LookupFormat("mmdb")
+	require.NoError(t, err)
+	sourceReader, sourceMeta, err := mmdbFormat.NewReaderAt(source)
+	require.NoError(t, err)
+
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		t.Run(fmt.Sprintf("IPv%d", ipVersion), func(t *testing.T) {
+			tree, err := sourceReader.RecordTree(ipVersion)
+			require.NoError(t, err)
+
+			buf := bytes.NewBufferString("")
+			w, err := format{}.NewWriter(buf, sourceMeta.Type, ipVersion)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(sourceMeta, tree))
+
+			reader, meta, err := format{}.NewReaderAt(testSource(buf.String()))
+			require.NoError(t, err)
+			assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+			assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+		})
+	}
+}
@@ -0,0 +1,112 @@
+package geodbtools
+
+import (
+	"context"
+	"net"
+)
+
+// LookupStreamBatchSize defines the maximum number of IP addresses LookupIPStream looks up at once
+var LookupStreamBatchSize = 1024
+
+// LookupResult holds the result of looking up a single IP address as part of a batch or stream
+type LookupResult struct {
+	// IP holds the IP address that has been looked up
+	IP net.IP
+
+	// Record holds the record found for the IP address, if any
+	Record Record
+
+	// Err holds the error that occurred during the lookup, if any
+	Err error
+}
+
+// BatchReader describes a Reader that provides an optimized implementation for looking up multiple IP addresses
+// at once
+type BatchReader interface {
+	Reader
+
+	// LookupIPs retrieves the records for the given IP addresses.
+	// The returned results are in the same order as the given IP addresses.
+	LookupIPs(ips []net.IP) (results []LookupResult)
+}
+
+// LookupIPs retrieves the records for the given IP addresses, using the given reader.
+// The returned results are in the same order as the given IP addresses.
+// Readers implementing BatchReader run the lookups themselves, all other readers are queried one IP address at a
+// time.
+func LookupIPs(r Reader, ips []net.IP) (results []LookupResult) {
+	if batchReader, ok := r.(BatchReader); ok {
+		return batchReader.LookupIPs(ips)
+	}
+
+	results = make([]LookupResult, len(ips))
+	for i, ip := range ips {
+		results[i].IP = ip
+		results[i].Record, results[i].Err = r.LookupIP(ip)
+	}
+	return
+}
+
+// LookupIPStream looks up all IP addresses received from the given channel, using the given reader.
+// IP addresses that are available at once are looked up as a batch of up to LookupStreamBatchSize addresses, using
+// LookupIPs. Results are sent in the same order as the IP addresses are received.
+// The returned channel is closed after the given channel has been closed and all results have been sent, or as soon
+// as the given context is done.
+func LookupIPStream(ctx context.Context, r Reader, ips <-chan net.IP) <-chan LookupResult {
+	results := make(chan LookupResult)
+
+	go func() {
+		defer close(results)
+
+		batchSize := LookupStreamBatchSize
+		if batchSize < 1 {
+			batchSize = 1
+		}
+		batch := make([]net.IP, 0, batchSize)
+
+		for {
+			batch = batch[:0]
+
+			// wait for the first IP address of the batch
+			select {
+			case <-ctx.Done():
+				return
+			case ip, ok := <-ips:
+				if !ok {
+					return
+				}
+				batch = append(batch, ip)
+			}
+
+			// add all IP addresses that are available immediately
+			closed := false
+		collect:
+			for len(batch) < batchSize {
+				select {
+				case ip, ok := <-ips:
+					if !ok {
+						closed = true
+						break collect
+					}
+					batch = append(batch, ip)
+				default:
+					break collect
+				}
+			}
+
+			for _, result := range LookupIPs(r, batch) {
+				select {
+				case <-ctx.Done():
+					return
+				case results <- result:
+				}
+			}
+
+			if closed {
+				return
+			}
+		}
+	}()
+
+	return results
+}
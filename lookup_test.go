@@ -0,0 +1,140 @@
+package geodbtools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+//go:generate mockgen -package geodbtools -self_package github.com/anexia-it/geodbtools -destination mock_batch_reader_test.go github.com/anexia-it/geodbtools BatchReader
+
+func TestLookupIPs(t *testing.T) {
+	t.Run("Fallback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		record := NewMockRecord(ctrl)
+		ips := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+
+		reader := NewMockReader(ctrl)
+		gomock.InOrder(
+			reader.EXPECT().LookupIP(ips[0]).Return(record, nil),
+			reader.EXPECT().LookupIP(ips[1]).Return(nil, testErr),
+		)
+
+		assert.EqualValues(t, []LookupResult{
+			{IP: ips[0], Record: record},
+			{IP: ips[1], Err: testErr},
+		}, LookupIPs(reader, ips))
+	})
+
+	t.Run("BatchReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ips := []net.IP{net.ParseIP("127.0.0.1"), net.ParseIP("::1")}
+		results := []LookupResult{
+			{IP: ips[0], Record: NewMockRecord(ctrl)},
+			{IP: ips[1], Err: ErrRecordNotFound},
+		}
+
+		reader := NewMockBatchReader(ctrl)
+		reader.EXPECT().LookupIPs(ips).Return(results)
+
+		assert.EqualValues(t, results, LookupIPs(reader, ips))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		assert.Empty(t, LookupIPs(NewMockReader(ctrl), nil))
+	})
+}
+
+func TestLookupIPStream(t *testing.T) {
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		originalBatchSize := LookupStreamBatchSize
+		LookupStreamBatchSize = 2
+		defer func() {
+			LookupStreamBatchSize = originalBatchSize
+		}()
+
+		record := NewMockRecord(ctrl)
+		reader := NewMockReader(ctrl)
+
+		ips := make(chan net.IP, 5)
+		var expectedResults []LookupResult
+		for i := 1; i <= 5; i++ {
+			ip := net.IPv4(127, 0, 0, byte(i))
+			ips <- ip
+
+			if i%2 == 0 {
+				reader.EXPECT().LookupIP(ip).Return(nil, ErrRecordNotFound)
+				expectedResults = append(expectedResults, LookupResult{IP: ip, Err: ErrRecordNotFound})
+			} else {
+				reader.EXPECT().LookupIP(ip).Return(record, nil)
+				expectedResults = append(expectedResults, LookupResult{IP: ip, Record: record})
+			}
+		}
+		close(ips)
+
+		var results []LookupResult
+		for result := range LookupIPStream(context.Background(), reader, ips) {
+			results = append(results, result)
+		}
+		assert.EqualValues(t, expectedResults, results)
+	})
+
+	t.Run("BatchReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ip := net.ParseIP("127.0.0.1")
+		results := []LookupResult{{IP: ip, Err: ErrRecordNotFound}}
+
+		reader := NewMockBatchReader(ctrl)
+		reader.EXPECT().LookupIPs([]net.IP{ip}).Return(results)
+
+		ips := make(chan net.IP, 1)
+		ips <- ip
+		close(ips)
+
+		var streamResults []LookupResult
+		for result := range LookupIPStream(context.Background(), reader, ips) {
+			streamResults = append(streamResults, result)
+		}
+		assert.EqualValues(t, results, streamResults)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		ip := net.ParseIP("127.0.0.1")
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().LookupIP(ip).Return(nil, ErrRecordNotFound).Times(1)
+
+		// the input channel is never closed, the stream has to end due to the cancelled context
+		ips := make(chan net.IP, 1)
+		ips <- ip
+
+		results := LookupIPStream(ctx, reader, ips)
+		assert.EqualValues(t, LookupResult{IP: ip, Err: ErrRecordNotFound}, <-results)
+
+		cancel()
+		for range results {
+			t.Error("received unexpected result")
+		}
+	})
+}
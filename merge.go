@@ -0,0 +1,166 @@
+package geodbtools
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+)
+
+// MergeSource holds a database merged by Merge
+type MergeSource struct {
+	// Name identifies the source inside conflicts
+	Name string
+
+	// Reader holds the reader of the database
+	Reader Reader
+}
+
+// MergeConflict describes a network whose record has been overridden by a source of higher precedence
+type MergeConflict struct {
+	// Prefix holds the overridden network
+	Prefix netip.Prefix
+
+	// Source holds the name of the source that won
+	Source string
+
+	// Record holds the record of the source that won
+	Record Record
+
+	// OverriddenSource holds the name of the source whose record has been overridden
+	OverriddenSource string
+
+	// OverriddenRecord holds the record that has been overridden
+	OverriddenRecord Record
+}
+
+// mergeSourceRecord marks the networks of a source inside the tree tracking the origin of merged records
+type mergeSourceRecord struct {
+	index int
+}
+
+func (r *mergeSourceRecord) String() string {
+	return fmt.Sprintf("merge source #%d", r.index)
+}
+
+func (r *mergeSourceRecord) GetNetwork() *net.IPNet {
+	return nil
+}
+
+// Merge overlays the networks of the given sources, building a single RecordTree of the given IP version.
+// Sources are applied in order, each source taking precedence over all earlier ones: its networks override the
+// records of earlier sources, including the ones of more specific networks, while the parts of earlier networks
+// outside of them are kept.
+// The given function, if not nil, is called for each part of a network whose record is overridden by a differing
+// record, as determined by RecordsEqual.
+func Merge(sources []MergeSource, ipVersion IPVersion, fn func(conflict MergeConflict)) (tree *RecordTree, err error) {
+	var prefix netip.Prefix
+	if _, prefix, err = diffFilter(NetworkFilter{IPVersion: ipVersion}); err != nil {
+		return
+	}
+
+	filter := NetworkFilter{
+		IPVersion: ipVersion,
+		Prefix:    prefix,
+	}
+	bitCount := prefix.Addr().BitLen()
+
+	tree = &RecordTree{}
+	// origins tracks the source of each network of the tree
+	origins := &RecordTree{}
+	for index, source := range sources {
+		origin := &mergeSourceRecord{index: index}
+
+		networks := readerNetworkSource(source.Reader, filter)
+		for {
+			network, record, ok := networks.next()
+			if !ok {
+				break
+			}
+
+			if index > 0 && fn != nil {
+				if err = tree.walkPrefix(network, bitCount, func(overridden netip.Prefix, overriddenRecord Record) bool {
+					if !diffRecordsEqual(record, overriddenRecord) {
+						overriddenSource := ""
+						if overriddenOrigin, ok := origins.leafAt(overridden.Addr()).(*mergeSourceRecord); ok {
+							overriddenSource = sources[overriddenOrigin.index].Name
+						}
+
+						fn(MergeConflict{
+							Prefix:           overridden,
+							Source:           source.Name,
+							Record:           record,
+							OverriddenSource: overriddenSource,
+							OverriddenRecord: overriddenRecord,
+						})
+					}
+					return true
+				}); err != nil {
+					return
+				}
+			}
+
+			// the network reported may be more specific than the record's own network
+			if err = tree.override(network, record, RecordPrefix(record).Masked() != network.Masked()); err != nil {
+				return
+			} else if err = origins.Override(network, origin); err != nil {
+				return
+			}
+		}
+
+		if err = networks.err(); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// walkPrefix calls the given function for the networks of the tree overlapping the given prefix, as reported by
+// WalkNetworks. Networks covering the prefix are reported as the prefix itself.
+// The walk is stopped as soon as the function returns false.
+func (t *RecordTree) walkPrefix(prefix netip.Prefix, bitCount int, fn func(network netip.Prefix, record Record) bool) (err error) {
+	prefix = prefix.Masked()
+	b := treeAddrBytes(prefix.Addr())
+
+	node := t
+	for depth := 0; depth < prefix.Bits(); depth++ {
+		if node.left == nil && node.right == nil {
+			if node.record != nil {
+				fn(prefix, node.record)
+			}
+			return
+		}
+
+		if prefixBit(&b, depth) {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		if node == nil {
+			return
+		}
+	}
+
+	return node.walkNetworks(bitCount, b, prefix.Bits(), fn)
+}
+
+// leafAt returns the record of the leaf containing the given address, nil if the address is not covered
+func (t *RecordTree) leafAt(addr netip.Addr) Record {
+	b := treeAddrBytes(addr)
+
+	node := t
+	for depth := 0; node.left != nil || node.right != nil; depth++ {
+		if depth >= addr.BitLen() {
+			return nil
+		}
+
+		if prefixBit(&b, depth) {
+			node = node.right
+		} else {
+			node = node.left
+		}
+		if node == nil {
+			return nil
+		}
+	}
+	return node.record
+}
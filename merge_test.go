@@ -0,0 +1,168 @@
+package geodbtools
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mergeConflicts runs Merge for the given sources, returning the tree along with the conflicts, formatted as
+// "prefix source:code->overridden source:code"
+func mergeConflicts(t *testing.T, sources []MergeSource) (tree *RecordTree, conflicts []string) {
+	var err error
+	tree, err = Merge(sources, IPVersion4, func(conflict MergeConflict) {
+		conflicts = append(conflicts, conflict.Prefix.String()+" "+
+			conflict.Source+":"+diffCountryCode(conflict.Record)+"->"+
+			conflict.OverriddenSource+":"+diffCountryCode(conflict.OverriddenRecord))
+	})
+	require.NoError(t, err)
+	return
+}
+
+// treeNetworks returns the networks of the given tree, formatted as "prefix code"
+func treeNetworks(t *testing.T, tree *RecordTree) (networks []string) {
+	require.NoError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+		networks = append(networks, network.String()+" "+diffCountryCode(record))
+		return true
+	}))
+	return
+}
+
+func TestMerge(t *testing.T) {
+	t.Run("Overlay", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree, conflicts := mergeConflicts(t, []MergeSource{
+			{
+				Name: "base",
+				Reader: testDiffReader(t, ctrl,
+					newTestCountryRecord(ctrl, "10.0.0.0/14", "AT"),
+					newTestCountryRecord(ctrl, "11.0.0.0/8", "US"),
+				),
+			},
+			{
+				Name: "corrections",
+				Reader: testDiffReader(t, ctrl,
+					newTestCountryRecord(ctrl, "10.1.0.0/16", "DE"),
+					newTestCountryRecord(ctrl, "11.0.0.0/8", "US"),
+					newTestCountryRecord(ctrl, "12.0.0.0/8", "CA"),
+				),
+			},
+		})
+
+		assert.EqualValues(t, []string{
+			"10.1.0.0/16 corrections:DE->base:AT",
+		}, conflicts)
+		assert.EqualValues(t, []string{
+			"10.0.0.0/16 AT",
+			"10.1.0.0/16 DE",
+			"10.2.0.0/15 AT",
+			"11.0.0.0/8 US",
+			"12.0.0.0/8 CA",
+		}, treeNetworks(t, tree))
+	})
+
+	t.Run("CoveringNetwork", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestCountryRecord(ctrl, "10.0.0.0/8", "US")
+		tree, conflicts := mergeConflicts(t, []MergeSource{
+			{
+				Name: "base",
+				Reader: testDiffReader(t, ctrl,
+					newTestCountryRecord(ctrl, "10.1.0.0/16", "DE"),
+					newTestCountryRecord(ctrl, "10.2.0.0/16", "US"),
+					newTestCountryRecord(ctrl, "10.3.0.0/16", "AT"),
+				),
+			},
+			{
+				Name:   "internal",
+				Reader: testDiffReader(t, ctrl, covering),
+			},
+		})
+
+		assert.EqualValues(t, []string{
+			"10.1.0.0/16 internal:US->base:DE",
+			"10.3.0.0/16 internal:US->base:AT",
+		}, conflicts)
+		assert.EqualValues(t, []Record{covering}, tree.Records())
+	})
+
+	t.Run("Precedence", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, conflicts := mergeConflicts(t, []MergeSource{
+			{
+				Name:   "base",
+				Reader: testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT")),
+			},
+			{
+				Name:   "internal",
+				Reader: testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.1.0.0/16", "DE")),
+			},
+			{
+				Name:   "corrections",
+				Reader: testDiffReader(t, ctrl, newTestCountryRecord(ctrl, "10.1.0.0/17", "FR")),
+			},
+		})
+
+		assert.EqualValues(t, []string{
+			"10.1.0.0/16 internal:DE->base:AT",
+			"10.1.0.0/17 corrections:FR->internal:DE",
+		}, conflicts)
+	})
+
+	t.Run("RecordTreeReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		readerTree, err := NewPrefixRecordTree([]Record{newTestCountryRecord(ctrl, "10.0.0.0/8", "AT")})
+		require.NoError(t, err)
+		require.NoError(t, readerTree.Insert(netip.MustParsePrefix("10.0.0.0/9"), newTestCountryRecord(ctrl, "10.0.0.0/9", "DE")))
+
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().RecordTree(IPVersion4).Return(readerTree, nil)
+
+		tree, conflicts := mergeConflicts(t, []MergeSource{
+			{
+				Name:   "base",
+				Reader: reader,
+			},
+		})
+
+		assert.Empty(t, conflicts)
+		assert.EqualValues(t, []string{
+			"10.0.0.0/9 DE",
+			"10.128.0.0/9 AT",
+		}, treeNetworks(t, tree))
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		_, err := Merge(nil, IPVersionUndefined, nil)
+		assert.EqualError(t, err, ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IteratorError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		_, err := Merge([]MergeSource{
+			{
+				Name: "base",
+				Reader: &testIterableReader{
+					MockReader: NewMockReader(ctrl),
+					it:         &testErrorIterator{err: testErr},
+				},
+			},
+		}, IPVersion4, nil)
+		assert.EqualError(t, err, testErr.Error())
+	})
+}
@@ -8,6 +8,14 @@ type DatabaseType string
 const (
 	// DatabaseTypeCountry defines the country database type
 	DatabaseTypeCountry DatabaseType = "country"
+	// DatabaseTypeCity defines the city database type
+	DatabaseTypeCity DatabaseType = "city"
+	// DatabaseTypeASN defines the autonomous system number database type
+	DatabaseTypeASN DatabaseType = "asn"
+	// DatabaseTypeOrganization defines the organization database type
+	DatabaseTypeOrganization DatabaseType = "organization"
+	// DatabaseTypeISP defines the internet service provider database type
+	DatabaseTypeISP DatabaseType = "isp"
 )
 
 // IPVersion defines an IP version
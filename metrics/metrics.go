@@ -0,0 +1,153 @@
+// Package metrics provides instrumentation of GeoIP database readers, exposing metrics in the Prometheus text
+// exposition format
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// contentType holds the content type of the Prometheus text exposition format
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultBuckets holds the upper bounds of the lookup duration histogram buckets, in seconds
+var DefaultBuckets = []float64{0.000001, 0.0000025, 0.000005, 0.00001, 0.000025, 0.00005, 0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01}
+
+// Registry holds the instrumented readers whose metrics are exposed
+type Registry struct {
+	mu      sync.Mutex
+	readers []*Reader
+	buckets []float64
+	// now returns the current time, used for determining the age of databases
+	now func() time.Time
+}
+
+var _ http.Handler = (*Registry)(nil)
+
+// NewRegistry returns a new, empty registry using DefaultBuckets for lookup duration histograms
+func NewRegistry() *Registry {
+	return &Registry{
+		buckets: DefaultBuckets,
+		now:     time.Now,
+	}
+}
+
+// escapeLabelValue escapes the given label value for the text exposition format
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+// formatFloat formats the given value for the text exposition format
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// metricWriter writes metric families in the text exposition format
+type metricWriter struct {
+	w   *bufio.Writer
+	now time.Time
+}
+
+// family writes the header of a metric family
+func (w *metricWriter) family(name, metricType, help string) {
+	fmt.Fprintf(w.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// sample writes a single sample of the given reader, with the given additional labels
+func (w *metricWriter) sample(name string, r *Reader, value string, labels ...string) {
+	fmt.Fprintf(w.w, `%s{database="%s",format="%s"`, name, escapeLabelValue(r.name), escapeLabelValue(r.format))
+	for i := 0; i+1 < len(labels); i += 2 {
+		fmt.Fprintf(w.w, `,%s="%s"`, labels[i], escapeLabelValue(labels[i+1]))
+	}
+	fmt.Fprintf(w.w, "} %s\n", value)
+}
+
+// counter writes a counter metric family, taking the value of each reader from the given function
+func (w *metricWriter) counter(readers []*Reader, name, help string, value func(r *Reader) uint64) {
+	w.family(name, "counter", help)
+	for _, r := range readers {
+		w.sample(name, r, strconv.FormatUint(value(r), 10))
+	}
+}
+
+// WriteTo writes the metrics of all registered readers to the given writer, in the Prometheus text exposition
+// format
+func (reg *Registry) WriteTo(out io.Writer) (n int64, err error) {
+	reg.mu.Lock()
+	readers := append([]*Reader(nil), reg.readers...)
+	reg.mu.Unlock()
+
+	counter := &countingWriter{w: out}
+	w := &metricWriter{
+		w:   bufio.NewWriter(counter),
+		now: reg.now(),
+	}
+
+	w.counter(readers, "geodbtools_lookups_total", "Total number of lookups.", func(r *Reader) uint64 {
+		return r.lookups.Load()
+	})
+	w.counter(readers, "geodbtools_lookup_not_found_total", "Total number of lookups that did not find a record or found an empty one.", func(r *Reader) uint64 {
+		return r.notFound.Load()
+	})
+	w.counter(readers, "geodbtools_lookup_errors_total", "Total number of lookups that failed for reasons other than a missing record.", func(r *Reader) uint64 {
+		return r.errors.Load()
+	})
+
+	w.family("geodbtools_lookup_duration_seconds", "histogram", "Duration of lookups.")
+	for _, r := range readers {
+		var cumulative uint64
+		for i, upperBound := range r.histogram.upperBounds {
+			cumulative += r.histogram.counts[i].Load()
+			w.sample("geodbtools_lookup_duration_seconds_bucket", r, strconv.FormatUint(cumulative, 10), "le", formatFloat(upperBound))
+		}
+		cumulative += r.histogram.counts[len(r.histogram.upperBounds)].Load()
+		w.sample("geodbtools_lookup_duration_seconds_bucket", r, strconv.FormatUint(cumulative, 10), "le", "+Inf")
+		w.sample("geodbtools_lookup_duration_seconds_sum", r, formatFloat(time.Duration(r.histogram.sum.Load()).Seconds()))
+		w.sample("geodbtools_lookup_duration_seconds_count", r, strconv.FormatUint(cumulative, 10))
+	}
+
+	w.counter(readers, "geodbtools_reloads_total", "Total number of successful database reloads.", func(r *Reader) uint64 {
+		return r.reloads.Load()
+	})
+	w.counter(readers, "geodbtools_reload_failures_total", "Total number of failed database reloads.", func(r *Reader) uint64 {
+		return r.reloadFailures.Load()
+	})
+
+	w.family("geodbtools_database_build_timestamp_seconds", "gauge", "Build time of the database as Unix timestamp.")
+	for _, r := range readers {
+		w.sample("geodbtools_database_build_timestamp_seconds", r, strconv.FormatInt(r.Metadata().BuildTime.Unix(), 10))
+	}
+
+	w.family("geodbtools_database_age_seconds", "gauge", "Time elapsed since the database has been built.")
+	for _, r := range readers {
+		w.sample("geodbtools_database_age_seconds", r, formatFloat(w.now.Sub(r.Metadata().BuildTime).Seconds()))
+	}
+
+	err = w.w.Flush()
+	n = counter.n
+	return
+}
+
+// ServeHTTP writes the metrics of all registered readers as response
+func (reg *Registry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	reg.WriteTo(w)
+}
+
+// countingWriter counts the bytes written to the underlying writer
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (w *countingWriter) Write(b []byte) (n int, err error) {
+	n, err = w.w.Write(b)
+	w.n += int64(n)
+	return
+}
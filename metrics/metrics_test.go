@@ -0,0 +1,132 @@
+package metrics
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testRegistry returns a registry with a single histogram bucket, reporting the given time as current time
+func testRegistry(now time.Time) *Registry {
+	reg := NewRegistry()
+	reg.buckets = []float64{60}
+	reg.now = func() time.Time {
+		return now
+	}
+	return reg
+}
+
+// metricLines returns the lines of the metrics written by the given registry, omitting histogram sums
+func metricLines(t *testing.T, reg *Registry) (lines []string) {
+	var buf bytes.Buffer
+	n, err := reg.WriteTo(&buf)
+	require.NoError(t, err)
+	assert.EqualValues(t, buf.Len(), n)
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		if !strings.HasPrefix(line, "geodbtools_lookup_duration_seconds_sum") {
+			lines = append(lines, line)
+		}
+	}
+	return
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	assert.EqualValues(t, `a\\b\"c\nd`, escapeLabelValue("a\\b\"c\nd"))
+}
+
+func TestRegistry_WriteTo(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	buildTime := time.Date(2019, 1, 3, 21, 26, 19, 0, time.UTC)
+	reg := testRegistry(buildTime.Add(time.Hour))
+
+	reader := NewMockReader(ctrl)
+	gomock.InOrder(
+		reader.EXPECT().LookupIP(gomock.Any()).Return(nil, nil),
+		reader.EXPECT().LookupIP(gomock.Any()).Return(nil, geodbtools.ErrRecordNotFound),
+		reader.EXPECT().LookupIP(gomock.Any()).Return(nil, errors.New("test error")),
+	)
+
+	r := reg.Instrument(reader, "country", "mmdb", geodbtools.Metadata{BuildTime: buildTime})
+	for i := 0; i < 3; i++ {
+		r.LookupIP(nil)
+	}
+	r.ObserveReload(&geodbtools.ReloadEvent{})
+	r.ObserveReload(&geodbtools.ReloadEvent{Err: errors.New("test error")})
+	r.ObserveReload(&geodbtools.ReloadEvent{})
+
+	reg.Instrument(NewMockReader(ctrl), `a "b"`, "mmdat", geodbtools.Metadata{BuildTime: buildTime.Add(time.Minute)})
+
+	assert.EqualValues(t, []string{
+		"# HELP geodbtools_lookups_total Total number of lookups.",
+		"# TYPE geodbtools_lookups_total counter",
+		`geodbtools_lookups_total{database="country",format="mmdb"} 3`,
+		`geodbtools_lookups_total{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_lookup_not_found_total Total number of lookups that did not find a record or found an empty one.",
+		"# TYPE geodbtools_lookup_not_found_total counter",
+		`geodbtools_lookup_not_found_total{database="country",format="mmdb"} 2`,
+		`geodbtools_lookup_not_found_total{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_lookup_errors_total Total number of lookups that failed for reasons other than a missing record.",
+		"# TYPE geodbtools_lookup_errors_total counter",
+		`geodbtools_lookup_errors_total{database="country",format="mmdb"} 1`,
+		`geodbtools_lookup_errors_total{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_lookup_duration_seconds Duration of lookups.",
+		"# TYPE geodbtools_lookup_duration_seconds histogram",
+		`geodbtools_lookup_duration_seconds_bucket{database="country",format="mmdb",le="60"} 3`,
+		`geodbtools_lookup_duration_seconds_bucket{database="country",format="mmdb",le="+Inf"} 3`,
+		`geodbtools_lookup_duration_seconds_count{database="country",format="mmdb"} 3`,
+		`geodbtools_lookup_duration_seconds_bucket{database="a \"b\"",format="mmdat",le="60"} 0`,
+		`geodbtools_lookup_duration_seconds_bucket{database="a \"b\"",format="mmdat",le="+Inf"} 0`,
+		`geodbtools_lookup_duration_seconds_count{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_reloads_total Total number of successful database reloads.",
+		"# TYPE geodbtools_reloads_total counter",
+		`geodbtools_reloads_total{database="country",format="mmdb"} 2`,
+		`geodbtools_reloads_total{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_reload_failures_total Total number of failed database reloads.",
+		"# TYPE geodbtools_reload_failures_total counter",
+		`geodbtools_reload_failures_total{database="country",format="mmdb"} 1`,
+		`geodbtools_reload_failures_total{database="a \"b\"",format="mmdat"} 0`,
+		"# HELP geodbtools_database_build_timestamp_seconds Build time of the database as Unix timestamp.",
+		"# TYPE geodbtools_database_build_timestamp_seconds gauge",
+		`geodbtools_database_build_timestamp_seconds{database="country",format="mmdb"} 1546550779`,
+		`geodbtools_database_build_timestamp_seconds{database="a \"b\"",format="mmdat"} 1546550839`,
+		"# HELP geodbtools_database_age_seconds Time elapsed since the database has been built.",
+		"# TYPE geodbtools_database_age_seconds gauge",
+		`geodbtools_database_age_seconds{database="country",format="mmdb"} 3600`,
+		`geodbtools_database_age_seconds{database="a \"b\"",format="mmdat"} 3540`,
+	}, metricLines(t, reg))
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	reg := testRegistry(time.Now())
+
+	w := httptest.NewRecorder()
+	reg.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	assert.EqualValues(t, http.StatusOK, w.Code)
+	assert.EqualValues(t, contentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "# TYPE geodbtools_lookups_total counter\n")
+}
+
+func TestHistogram(t *testing.T) {
+	h := newHistogram([]float64{0.001, 0.01})
+	h.observe(500*time.Microsecond, 1)
+	h.observe(time.Millisecond, 2)
+	h.observe(5*time.Millisecond, 1)
+	h.observe(time.Second, 1)
+
+	for i, expected := range []uint64{3, 1, 1} {
+		assert.EqualValues(t, expected, h.counts[i].Load())
+	}
+	assert.EqualValues(t, 1007500*time.Microsecond, h.sum.Load())
+}
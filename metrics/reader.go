@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// histogram implements a lock-free histogram of durations
+type histogram struct {
+	upperBounds []float64
+	// counts holds the (non-cumulative) count of each bucket, followed by the count of the +Inf bucket
+	counts []atomic.Uint64
+	// sum holds the sum of all observations in nanoseconds
+	sum atomic.Int64
+}
+
+// newHistogram returns a new histogram with the given bucket upper bounds, in seconds
+func newHistogram(upperBounds []float64) *histogram {
+	return &histogram{
+		upperBounds: upperBounds,
+		counts:      make([]atomic.Uint64, len(upperBounds)+1),
+	}
+}
+
+// observe adds the given duration to the histogram the given number of times
+func (h *histogram) observe(d time.Duration, n uint64) {
+	seconds := d.Seconds()
+	i := sort.SearchFloat64s(h.upperBounds, seconds)
+	h.counts[i].Add(n)
+	h.sum.Add(int64(d) * int64(n))
+}
+
+var _ geodbtools.AddrReader = (*Reader)(nil)
+var _ geodbtools.NetworkReader = (*Reader)(nil)
+var _ geodbtools.BatchReader = (*Reader)(nil)
+var _ geodbtools.MetadataReader = (*Reader)(nil)
+
+// Reader implements a geodbtools.Reader decorator recording metrics of the lookups run against the decorated reader
+type Reader struct {
+	reader geodbtools.Reader
+	name   string
+	format string
+	meta   geodbtools.Metadata
+
+	lookups        atomic.Uint64
+	notFound       atomic.Uint64
+	errors         atomic.Uint64
+	reloads        atomic.Uint64
+	reloadFailures atomic.Uint64
+	histogram      *histogram
+}
+
+// Instrument returns a new Reader decorating the given reader, registering its metrics labeled with the given
+// database and format names.
+// The given metadata is used for the database build time, unless the reader implements geodbtools.MetadataReader.
+func (reg *Registry) Instrument(reader geodbtools.Reader, name, format string, meta geodbtools.Metadata) *Reader {
+	r := &Reader{
+		reader:    reader,
+		name:      name,
+		format:    format,
+		meta:      meta,
+		histogram: newHistogram(reg.buckets),
+	}
+
+	reg.mu.Lock()
+	reg.readers = append(reg.readers, r)
+	reg.mu.Unlock()
+	return r
+}
+
+// observe records a lookup of the given number of addresses, started at the given time
+func (r *Reader) observe(startedAt time.Time, n uint64) {
+	r.lookups.Add(n)
+	r.histogram.observe(time.Since(startedAt), n)
+}
+
+// observeResult records the outcome of a lookup returning the given record and error.
+// Lookups returning a record without any fields are counted as not found, as MMDB readers report addresses in
+// unassigned space using an empty record rather than geodbtools.ErrRecordNotFound.
+func (r *Reader) observeResult(record geodbtools.Record, err error) {
+	switch {
+	case errors.Is(err, geodbtools.ErrRecordNotFound):
+		r.notFound.Add(1)
+	case err != nil:
+		r.errors.Add(1)
+	case len(geodbtools.RecordFields(record)) == 0:
+		r.notFound.Add(1)
+	}
+}
+
+// ObserveReload records the given reload of the underlying database
+func (r *Reader) ObserveReload(event *geodbtools.ReloadEvent) {
+	if event.Err != nil {
+		r.reloadFailures.Add(1)
+		return
+	}
+	r.reloads.Add(1)
+}
+
+// Metadata returns the metadata of the underlying database
+func (r *Reader) Metadata() geodbtools.Metadata {
+	if metadataReader, ok := r.reader.(geodbtools.MetadataReader); ok {
+		return metadataReader.Metadata()
+	}
+	return r.meta
+}
+
+// RecordTree returns the RecordTree of the underlying reader. Calls are not recorded as lookups.
+func (r *Reader) RecordTree(ipVersion geodbtools.IPVersion) (*geodbtools.RecordTree, error) {
+	return r.reader.RecordTree(ipVersion)
+}
+
+// LookupIP retrieves the record for the given IP address
+func (r *Reader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	defer r.observe(time.Now(), 1)
+	record, err = r.reader.LookupIP(ip)
+	r.observeResult(record, err)
+	return
+}
+
+// LookupAddr retrieves the record for the given IP address
+func (r *Reader) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	defer r.observe(time.Now(), 1)
+	record, err = geodbtools.LookupAddr(r.reader, addr)
+	r.observeResult(record, err)
+	return
+}
+
+// LookupNetwork retrieves the record for the given IP address, along with the prefix of the network containing the
+// address
+func (r *Reader) LookupNetwork(addr netip.Addr) (record geodbtools.Record, prefix netip.Prefix, err error) {
+	defer r.observe(time.Now(), 1)
+	record, prefix, err = geodbtools.LookupNetwork(r.reader, addr)
+	r.observeResult(record, err)
+	return
+}
+
+// LookupIPs retrieves the records for the given IP addresses.
+// Each address is recorded as a single lookup, with the average duration of the lookups of the batch.
+func (r *Reader) LookupIPs(ips []net.IP) (results []geodbtools.LookupResult) {
+	if len(ips) == 0 {
+		return geodbtools.LookupIPs(r.reader, ips)
+	}
+
+	startedAt := time.Now()
+	results = geodbtools.LookupIPs(r.reader, ips)
+	r.lookups.Add(uint64(len(ips)))
+	r.histogram.observe(time.Since(startedAt)/time.Duration(len(ips)), uint64(len(ips)))
+
+	for _, result := range results {
+		r.observeResult(result.Record, result.Err)
+	}
+	return
+}
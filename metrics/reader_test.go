@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// testMetadataReader implements a geodbtools.MetadataReader
+type testMetadataReader struct {
+	*MockReader
+	meta geodbtools.Metadata
+}
+
+func (r *testMetadataReader) Metadata() geodbtools.Metadata {
+	return r.meta
+}
+
+// testCountryRecord implements a geodbtools.CountryRecord
+type testCountryRecord struct {
+	*MockRecord
+	countryCode string
+}
+
+func (r *testCountryRecord) GetCountryCode() string {
+	return r.countryCode
+}
+
+func TestReader(t *testing.T) {
+	t.Run("Lookups", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		mockRecord := NewMockRecord(ctrl)
+		mockRecord.EXPECT().GetNetwork().AnyTimes().Return(&net.IPNet{IP: net.IPv4(10, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)})
+		record := &testCountryRecord{MockRecord: mockRecord, countryCode: "AT"}
+		// MMDB readers return empty records for addresses in unassigned space
+		emptyRecord := &testCountryRecord{MockRecord: NewMockRecord(ctrl)}
+
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().LookupIP(net.IP(net.IPv4(10, 0, 0, 1).To4())).AnyTimes().Return(record, nil)
+		reader.EXPECT().LookupIP(net.IP(net.IPv4(11, 0, 0, 1).To4())).AnyTimes().Return(nil, geodbtools.ErrRecordNotFound)
+		reader.EXPECT().LookupIP(net.IP(net.IPv4(12, 0, 0, 1).To4())).AnyTimes().Return(nil, errors.New("test error"))
+		reader.EXPECT().LookupIP(net.IP(net.IPv4(13, 0, 0, 1).To4())).AnyTimes().Return(emptyRecord, nil)
+
+		r := NewRegistry().Instrument(reader, "country", "mmdb", geodbtools.Metadata{})
+
+		found, err := r.LookupIP(net.IPv4(10, 0, 0, 1).To4())
+		assert.NoError(t, err)
+		assert.True(t, found == record)
+
+		found, err = r.LookupAddr(netip.MustParseAddr("10.0.0.1"))
+		assert.NoError(t, err)
+		assert.True(t, found == record)
+
+		found, prefix, err := r.LookupNetwork(netip.MustParseAddr("10.0.0.1"))
+		assert.NoError(t, err)
+		assert.True(t, found == record)
+		assert.EqualValues(t, netip.MustParsePrefix("10.0.0.0/8"), prefix)
+
+		_, err = r.LookupAddr(netip.MustParseAddr("11.0.0.1"))
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+
+		found, err = r.LookupIP(net.IPv4(13, 0, 0, 1).To4())
+		assert.NoError(t, err)
+		assert.True(t, found == emptyRecord)
+
+		results := r.LookupIPs([]net.IP{
+			net.IPv4(10, 0, 0, 1).To4(),
+			net.IPv4(11, 0, 0, 1).To4(),
+			net.IPv4(12, 0, 0, 1).To4(),
+			net.IPv4(13, 0, 0, 1).To4(),
+		})
+		if assert.Len(t, results, 4) {
+			assert.True(t, results[0].Record == record)
+			assert.EqualError(t, results[1].Err, geodbtools.ErrRecordNotFound.Error())
+			assert.EqualError(t, results[2].Err, "test error")
+			assert.True(t, results[3].Record == emptyRecord)
+		}
+
+		assert.EqualValues(t, 9, r.lookups.Load())
+		assert.EqualValues(t, 4, r.notFound.Load())
+		assert.EqualValues(t, 1, r.errors.Load())
+
+		var observed uint64
+		for i := range r.histogram.counts {
+			observed += r.histogram.counts[i].Load()
+		}
+		assert.EqualValues(t, 9, observed)
+	})
+
+	t.Run("RecordTree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree := &geodbtools.RecordTree{}
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().RecordTree(geodbtools.IPVersion4).Return(tree, nil)
+
+		r := NewRegistry().Instrument(reader, "country", "mmdb", geodbtools.Metadata{})
+		result, err := r.RecordTree(geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		assert.True(t, result == tree)
+		assert.EqualValues(t, 0, r.lookups.Load())
+	})
+
+	t.Run("Metadata", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		meta := geodbtools.Metadata{BuildTime: time.Date(2019, 1, 3, 21, 26, 19, 0, time.UTC)}
+		reg := NewRegistry()
+		assert.EqualValues(t, meta, reg.Instrument(NewMockReader(ctrl), "country", "mmdb", meta).Metadata())
+
+		reloadedMeta := geodbtools.Metadata{BuildTime: meta.BuildTime.Add(7 * 24 * time.Hour)}
+		r := reg.Instrument(&testMetadataReader{MockReader: NewMockReader(ctrl), meta: reloadedMeta}, "city", "mmdb", meta)
+		assert.EqualValues(t, reloadedMeta, r.Metadata())
+	})
+}
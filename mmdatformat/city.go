@@ -0,0 +1,241 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+const (
+	// cityRecordMaxLength defines the maximum number of bytes read for a single city record
+	cityRecordMaxLength = 256
+
+	// cityMetroAreaCodeCountry defines the country whose revision 1 records hold the metro and area code
+	cityMetroAreaCodeCountry = "US"
+)
+
+// decodeCityRecord returns a segmentRecordDecoder for city records.
+// Revision 1 records of US networks additionally hold the combined metro and area code.
+func decodeCityRecord(rev1 bool) segmentRecordDecoder {
+	return func(source geodbtools.ReaderSource, offset int64) (record segmentRecord, err error) {
+		var b []byte
+		if b, err = readSegmentData(source, offset, cityRecordMaxLength); err != nil {
+			return
+		}
+
+		rec := &cityRecord{}
+		rec.countryCode, _ = GetISO2CountryCodeString(int(b[0]))
+		b = b[1:]
+
+		var fields [3]string
+		for i := range fields {
+			end := bytes.IndexByte(b, 0x00)
+			if end < 0 {
+				err = geodbtools.ErrDatabaseInvalid
+				return
+			}
+
+			fields[i] = DecodeLatin1(b[:end])
+			b = b[end+1:]
+		}
+		rec.regionCode, rec.cityName, rec.postalCode = fields[0], fields[1], fields[2]
+
+		hasMetroAreaCode := rev1 && rec.countryCode == cityMetroAreaCodeCountry
+
+		coordinatesLength := 6
+		if hasMetroAreaCode {
+			coordinatesLength = 9
+		}
+
+		if len(b) < coordinatesLength {
+			err = geodbtools.ErrDatabaseInvalid
+			return
+		}
+
+		var latitude, longitude uint32
+		if latitude, err = DecodeRecordUint32(b, 3); err != nil {
+			return
+		} else if longitude, err = DecodeRecordUint32(b[3:], 3); err != nil {
+			return
+		}
+		rec.latitude = float64(latitude)/10000 - 180
+		rec.longitude = float64(longitude)/10000 - 180
+
+		if hasMetroAreaCode {
+			var metroAreaCombo uint32
+			if metroAreaCombo, err = DecodeRecordUint32(b[6:], 3); err != nil {
+				return
+			}
+			rec.metroCode = int(metroAreaCombo / 1000)
+			rec.areaCode = int(metroAreaCombo % 1000)
+		}
+
+		record = rec
+		return
+	}
+}
+
+// encodeCityRecord returns a segmentRecordEncoder for city records.
+// Revision 1 records of US networks additionally hold the combined metro and area code.
+func encodeCityRecord(rev1 bool) segmentRecordEncoder {
+	return func(record geodbtools.Record) (b []byte, err error) {
+		cityRecord, ok := record.(geodbtools.CityRecord)
+		if !ok {
+			err = geodbtools.ErrUnsupportedRecordType
+			return
+		}
+
+		var countryIdx int
+		if countryIdx, err = GetISO2CountryCodeIndex(cityRecord.GetCountryCode()); err != nil {
+			return
+		}
+
+		var regionCode, postalCode string
+		var latitude, longitude float64
+		var metroCode, areaCode int
+
+		if regionRecord, ok := record.(geodbtools.RegionRecord); ok {
+			regionCode = regionRecord.GetRegionCode()
+		}
+
+		if postalCodeRecord, ok := record.(geodbtools.PostalCodeRecord); ok {
+			postalCode = postalCodeRecord.GetPostalCode()
+		}
+
+		if locationRecord, ok := record.(geodbtools.LocationRecord); ok {
+			latitude = locationRecord.GetLatitude()
+			longitude = locationRecord.GetLongitude()
+		}
+
+		if metroCodeRecord, ok := record.(geodbtools.MetroCodeRecord); ok {
+			metroCode = metroCodeRecord.GetMetroCode()
+			areaCode = metroCodeRecord.GetAreaCode()
+		}
+
+		b = []byte{byte(countryIdx)}
+		for _, field := range []string{regionCode, cityRecord.GetCityName(), postalCode} {
+			b = append(b, EncodeLatin1(strings.Replace(field, "\x00", "", -1))...)
+			b = append(b, 0x00)
+		}
+
+		values := []uint32{
+			uint32(math.Round((latitude + 180) * 10000)),
+			uint32(math.Round((longitude + 180) * 10000)),
+		}
+		if countryCode, _ := GetISO2CountryCodeString(countryIdx); rev1 && countryCode == cityMetroAreaCodeCountry {
+			values = append(values, uint32(metroCode*1000+areaCode))
+		}
+
+		for _, value := range values {
+			var rec []byte
+			if rec, err = EncodeRecord(value, 3); err != nil {
+				return
+			}
+			b = append(b, rec...)
+		}
+
+		return
+	}
+}
+
+var _ Type = cityType{}
+
+type cityType struct{}
+
+func (cityType) DatabaseType() geodbtools.DatabaseType {
+	return geodbtools.DatabaseTypeCity
+}
+
+// EncodeTreeNode is not supported by city databases, as their records are stored in the record segments
+func (cityType) EncodeTreeNode(position *uint32, node *geodbtools.RecordTree) (b []byte, additionalNodes []*geodbtools.RecordTree, err error) {
+	err = geodbtools.ErrUnsupportedRecordType
+	return
+}
+
+func (cityType) NewReader(source geodbtools.ReaderSource, dbType DatabaseTypeID, dbInfo string, buildTime *time.Time) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	var ipVersion geodbtools.IPVersion
+	var rev1 bool
+
+	switch dbType {
+	case DatabaseTypeIDCityEditionRev0:
+		ipVersion = geodbtools.IPVersion4
+	case DatabaseTypeIDCityEditionRev1:
+		ipVersion = geodbtools.IPVersion4
+		rev1 = true
+	case DatabaseTypeIDCityEditionRev0V6:
+		ipVersion = geodbtools.IPVersion6
+	case DatabaseTypeIDCityEditionRev1V6:
+		ipVersion = geodbtools.IPVersion6
+		rev1 = true
+	default:
+		err = geodbtools.ErrUnsupportedDatabaseType
+		return
+	}
+
+	var segments uint32
+	if segments, err = readDatabaseSegments(source); err != nil {
+		return
+	}
+
+	if buildTime == nil {
+		now := time.Now()
+		buildTime = &now
+	}
+
+	meta = geodbtools.Metadata{
+		Type:               geodbtools.DatabaseTypeCity,
+		BuildTime:          *buildTime,
+		Description:        dbInfo,
+		MajorFormatVersion: 1,
+		MinorFormatVersion: 0,
+		IPVersion:          ipVersion,
+	}
+
+	bitCount := uint(32)
+	if ipVersion == geodbtools.IPVersion6 {
+		bitCount = 128
+	}
+
+	reader = &segmentReader{
+		source:       source,
+		dbType:       dbType,
+		segments:     segments,
+		recordLength: standardRecordLength,
+		bitCount:     bitCount,
+		decodeRecord: decodeCityRecord(rev1),
+	}
+	return
+}
+
+func (cityType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	var typeID DatabaseTypeID
+
+	switch ipVersion {
+	case geodbtools.IPVersion4:
+		typeID = DatabaseTypeIDCityEditionRev1
+	case geodbtools.IPVersion6:
+		typeID = DatabaseTypeIDCityEditionRev1V6
+	default:
+		err = geodbtools.ErrUnsupportedDatabaseType
+		return
+	}
+
+	writer = &segmentWriter{
+		w:            w,
+		typeID:       typeID,
+		recordLength: standardRecordLength,
+		encodeRecord: encodeCityRecord(true),
+	}
+	return
+}
+
+func init() {
+	MustRegisterType(DatabaseTypeIDCityEditionRev0, cityType{})
+	MustRegisterType(DatabaseTypeIDCityEditionRev1, cityType{})
+	MustRegisterType(DatabaseTypeIDCityEditionRev0V6, cityType{})
+	MustRegisterType(DatabaseTypeIDCityEditionRev1V6, cityType{})
+}
@@ -0,0 +1,474 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCityRecord(t *testing.T, cidr string, countryCode string, cityName string) *cityRecord {
+	_, network, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+
+	return &cityRecord{
+		network:     network,
+		countryCode: countryCode,
+		regionCode:  "09",
+		cityName:    cityName,
+		postalCode:  "1010",
+		latitude:    48.2,
+		longitude:   16.3667,
+	}
+}
+
+func testUSCityRecord(t *testing.T, cidr string, cityName string, metroCode int, areaCode int) *cityRecord {
+	rec := testCityRecord(t, cidr, "US", cityName)
+	rec.metroCode = metroCode
+	rec.areaCode = areaCode
+	return rec
+}
+
+func TestCityType_DatabaseType(t *testing.T) {
+	assert.EqualValues(t, geodbtools.DatabaseTypeCity, cityType{}.DatabaseType())
+}
+
+func TestCityType_EncodeTreeNode(t *testing.T) {
+	var position uint32
+	b, additionalNodes, err := cityType{}.EncodeTreeNode(&position, &geodbtools.RecordTree{})
+	assert.Nil(t, b)
+	assert.Nil(t, additionalNodes)
+	assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	assert.EqualValues(t, 0, position)
+}
+
+func TestCityType_NewReader(t *testing.T) {
+	t.Run("UnsupportedDBType", func(t *testing.T) {
+		reader, meta, err := cityType{}.NewReader(nil, DatabaseTypeIDCountryEdition, "test", nil)
+		assert.Nil(t, reader)
+		assert.EqualValues(t, geodbtools.Metadata{}, meta)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("SegmentsError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().Size().Return(int64(0))
+
+		reader, meta, err := cityType{}.NewReader(source, DatabaseTypeIDCityEditionRev1, "test", nil)
+		assert.Nil(t, reader)
+		assert.EqualValues(t, geodbtools.Metadata{}, meta)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	testCases := []struct {
+		Name      string
+		TypeID    DatabaseTypeID
+		IPVersion geodbtools.IPVersion
+		BitCount  uint
+	}{
+		{"Rev0", DatabaseTypeIDCityEditionRev0, geodbtools.IPVersion4, 32},
+		{"Rev1", DatabaseTypeIDCityEditionRev1, geodbtools.IPVersion4, 32},
+		{"Rev0V6", DatabaseTypeIDCityEditionRev0V6, geodbtools.IPVersion6, 128},
+		{"Rev1V6", DatabaseTypeIDCityEditionRev1V6, geodbtools.IPVersion6, 128},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			structureInfo := append(bytes.Repeat([]byte{0x00}, structureInfoMaxSize-7), 0xff, 0xff, 0xff, byte(testCase.TypeID), 0x2a, 0x00, 0x00)
+			source := &testReaderSource{
+				Reader: bytes.NewReader(structureInfo),
+				size:   int64(len(structureInfo)),
+			}
+
+			buildTime := time.Now()
+
+			reader, meta, err := cityType{}.NewReader(source, testCase.TypeID, "test", &buildTime)
+			assert.NoError(t, err)
+			assert.EqualValues(t, geodbtools.Metadata{
+				Type:               geodbtools.DatabaseTypeCity,
+				BuildTime:          buildTime,
+				Description:        "test",
+				MajorFormatVersion: 1,
+				MinorFormatVersion: 0,
+				IPVersion:          testCase.IPVersion,
+			}, meta)
+			if assert.NotNil(t, reader) && assert.IsType(t, &segmentReader{}, reader) {
+				r := reader.(*segmentReader)
+				assert.EqualValues(t, source, r.source)
+				assert.EqualValues(t, testCase.TypeID, r.dbType)
+				assert.EqualValues(t, 42, r.segments)
+				assert.EqualValues(t, standardRecordLength, r.recordLength)
+				assert.EqualValues(t, testCase.BitCount, r.bitCount)
+			}
+		})
+	}
+}
+
+func TestCityType_NewWriter(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		writer, err := cityType{}.NewWriter(nil, geodbtools.IPVersionUndefined)
+		assert.Nil(t, writer)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := cityType{}.NewWriter(buf, geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		if assert.NotNil(t, w) && assert.IsType(t, &segmentWriter{}, w) {
+			wr := w.(*segmentWriter)
+			assert.EqualValues(t, DatabaseTypeIDCityEditionRev1, wr.typeID)
+			assert.EqualValues(t, standardRecordLength, wr.recordLength)
+			assert.EqualValues(t, buf, wr.w)
+		}
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := cityType{}.NewWriter(buf, geodbtools.IPVersion6)
+		assert.NoError(t, err)
+		if assert.NotNil(t, w) && assert.IsType(t, &segmentWriter{}, w) {
+			wr := w.(*segmentWriter)
+			assert.EqualValues(t, DatabaseTypeIDCityEditionRev1V6, wr.typeID)
+			assert.EqualValues(t, standardRecordLength, wr.recordLength)
+			assert.EqualValues(t, buf, wr.w)
+		}
+	})
+}
+
+func TestEncodeCityRecord(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		b, err := encodeCityRecord(true)(&countryRecord{})
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("InvalidCountryCode", func(t *testing.T) {
+		b, err := encodeCityRecord(true)(&cityRecord{countryCode: "XXX"})
+		assert.Nil(t, b)
+		assert.EqualError(t, err, ErrCountryNotFound.Error())
+	})
+
+	t.Run("Rev0", func(t *testing.T) {
+		b, err := encodeCityRecord(false)(&cityRecord{
+			countryCode: "AT",
+			regionCode:  "09",
+			cityName:    "Wien",
+			postalCode:  "1010",
+			latitude:    48.2,
+			longitude:   16.3667,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{
+			0x0f,
+			'0', '9', 0x00,
+			'W', 'i', 'e', 'n', 0x00,
+			'1', '0', '1', '0', 0x00,
+			0x10, 0xd2, 0x22,
+			0x93, 0xf6, 0x1d,
+		}, b)
+	})
+
+	t.Run("Rev1", func(t *testing.T) {
+		b, err := encodeCityRecord(true)(&cityRecord{
+			countryCode: "US",
+			cityName:    "Zürich",
+			metroCode:   807,
+			areaCode:    415,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{
+			0xe1,
+			0x00,
+			'Z', 0xfc, 'r', 'i', 'c', 'h', 0x00,
+			0x00,
+			0x40, 0x77, 0x1b,
+			0x40, 0x77, 0x1b,
+			0xf7, 0x51, 0x0c,
+		}, b)
+	})
+
+	t.Run("Rev1NonUS", func(t *testing.T) {
+		b, err := encodeCityRecord(true)(&cityRecord{
+			countryCode: "AT",
+			regionCode:  "09",
+			cityName:    "Wien",
+			postalCode:  "1010",
+			latitude:    48.2,
+			longitude:   16.3667,
+			metroCode:   807,
+			areaCode:    415,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{
+			0x0f,
+			'0', '9', 0x00,
+			'W', 'i', 'e', 'n', 0x00,
+			'1', '0', '1', '0', 0x00,
+			0x10, 0xd2, 0x22,
+			0x93, 0xf6, 0x1d,
+		}, b)
+	})
+}
+
+func TestDecodeCityRecord(t *testing.T) {
+	t.Run("OffsetOutOfBounds", func(t *testing.T) {
+		source := &testReaderSource{
+			Reader: bytes.NewReader([]byte{0x00}),
+			size:   1,
+		}
+
+		record, err := decodeCityRecord(true)(source, 1)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("MissingStringTerminator", func(t *testing.T) {
+		data := []byte{0x0f, '0', '9', 0x00, 'W', 'i', 'e', 'n'}
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeCityRecord(true)(source, 0)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		data := []byte{0xe1, 0x00, 0x00, 0x00, 0x50, 0xd4, 0x22, 0xfb, 0x69, 0x1d}
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeCityRecord(true)(source, 0)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("Rev0", func(t *testing.T) {
+		data := []byte{
+			0xff,
+			0x0f,
+			'0', '9', 0x00,
+			'W', 'i', 'e', 'n', 0x00,
+			'1', '0', '1', '0', 0x00,
+			0x10, 0xd2, 0x22,
+			0x93, 0xf6, 0x1d,
+		}
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeCityRecord(false)(source, 1)
+		assert.NoError(t, err)
+		if assert.IsType(t, &cityRecord{}, record) {
+			rec := record.(*cityRecord)
+			assert.EqualValues(t, "AT", rec.countryCode)
+			assert.EqualValues(t, "09", rec.regionCode)
+			assert.EqualValues(t, "Wien", rec.cityName)
+			assert.EqualValues(t, "1010", rec.postalCode)
+			assert.InDelta(t, 48.2, rec.latitude, 0.00001)
+			assert.InDelta(t, 16.3667, rec.longitude, 0.00001)
+			assert.EqualValues(t, 0, rec.metroCode)
+			assert.EqualValues(t, 0, rec.areaCode)
+		}
+	})
+
+	t.Run("Rev1", func(t *testing.T) {
+		data := []byte{
+			0xe1,
+			0x00,
+			'Z', 0xfc, 'r', 'i', 'c', 'h', 0x00,
+			0x00,
+			0x40, 0x77, 0x1b,
+			0x40, 0x77, 0x1b,
+			0xf7, 0x51, 0x0c,
+		}
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeCityRecord(true)(source, 0)
+		assert.NoError(t, err)
+		if assert.IsType(t, &cityRecord{}, record) {
+			rec := record.(*cityRecord)
+			assert.EqualValues(t, "US", rec.countryCode)
+			assert.EqualValues(t, "", rec.regionCode)
+			assert.EqualValues(t, "Zürich", rec.cityName)
+			assert.EqualValues(t, "", rec.postalCode)
+			assert.InDelta(t, 0, rec.latitude, 0.00001)
+			assert.InDelta(t, 0, rec.longitude, 0.00001)
+			assert.EqualValues(t, 807, rec.metroCode)
+			assert.EqualValues(t, 415, rec.areaCode)
+		}
+	})
+
+	t.Run("Rev1NonUS", func(t *testing.T) {
+		data := []byte{
+			0x0f,
+			'0', '9', 0x00,
+			'W', 'i', 'e', 'n', 0x00,
+			'1', '0', '1', '0', 0x00,
+			0x10, 0xd2, 0x22,
+			0x93, 0xf6, 0x1d,
+		}
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeCityRecord(true)(source, 0)
+		assert.NoError(t, err)
+		if assert.IsType(t, &cityRecord{}, record) {
+			rec := record.(*cityRecord)
+			assert.EqualValues(t, "AT", rec.countryCode)
+			assert.EqualValues(t, "09", rec.regionCode)
+			assert.EqualValues(t, "Wien", rec.cityName)
+			assert.EqualValues(t, "1010", rec.postalCode)
+			assert.InDelta(t, 48.2, rec.latitude, 0.00001)
+			assert.InDelta(t, 16.3667, rec.longitude, 0.00001)
+			assert.EqualValues(t, 0, rec.metroCode)
+			assert.EqualValues(t, 0, rec.areaCode)
+		}
+	})
+}
+
+func TestCityType_RoundTrip(t *testing.T) {
+	buildTime := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+	meta := geodbtools.Metadata{
+		Type:        geodbtools.DatabaseTypeCity,
+		BuildTime:   buildTime,
+		Description: "test city database",
+	}
+
+	testCases := []struct {
+		Name           string
+		IPVersion      geodbtools.IPVersion
+		MaxDepth       uint
+		BelongsRightFn geodbtools.RecordBelongsRightFunc
+		Records        []geodbtools.Record
+		Lookups        map[string]string
+		MetroCodes     map[string][2]int
+		NotFound       []string
+	}{
+		{
+			Name:           "IPv4",
+			IPVersion:      geodbtools.IPVersion4,
+			MaxDepth:       31,
+			BelongsRightFn: bitmap.IsSet,
+			Records: []geodbtools.Record{
+				testCityRecord(t, "1.0.0.0/8", "AT", "Wien"),
+				testCityRecord(t, "2.0.0.0/8", "DE", "Berlin"),
+				testCityRecord(t, "3.0.0.0/8", "AT", "Wien"),
+				testCityRecord(t, "4.4.4.0/24", "CH", "Zürich"),
+				testUSCityRecord(t, "8.0.0.0/8", "San Francisco", 807, 415),
+			},
+			Lookups: map[string]string{
+				"1.2.3.4": "0.0.0.0/7: country code AT, region 09, city Wien, postal code 1010, location 48.2000,16.3667",
+				"4.4.4.4": "4.0.0.0/6: country code CH, region 09, city Zürich, postal code 1010, location 48.2000,16.3667",
+			},
+			MetroCodes: map[string][2]int{
+				"1.2.3.4": {0, 0},
+				"8.8.8.8": {807, 415},
+			},
+			NotFound: []string{"128.0.0.1", "::1"},
+		},
+		{
+			Name:           "IPv6",
+			IPVersion:      geodbtools.IPVersion6,
+			MaxDepth:       127,
+			BelongsRightFn: geodbtools.RecordBelongsRightIPv6,
+			Records: []geodbtools.Record{
+				testCityRecord(t, "2001:db8::/32", "AT", "Wien"),
+				testCityRecord(t, "2a00::/16", "DE", "Berlin"),
+				testUSCityRecord(t, "4000::/16", "San Francisco", 807, 415),
+			},
+			Lookups: map[string]string{
+				"2001:db8::1": "2000::/5: country code AT, region 09, city Wien, postal code 1010, location 48.2000,16.3667",
+				"2a00:1::1":   "2800::/5: country code DE, region 09, city Berlin, postal code 1010, location 48.2000,16.3667",
+			},
+			MetroCodes: map[string][2]int{
+				"2001:db8::1": {0, 0},
+				"4000::1":     {807, 415},
+			},
+			NotFound: []string{"3000::1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			tree, err := geodbtools.NewRecordTree(testCase.MaxDepth, testCase.Records, testCase.BelongsRightFn)
+			require.NoError(t, err)
+
+			buf := bytes.NewBufferString("")
+			w, err := cityType{}.NewWriter(buf, testCase.IPVersion)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(meta, tree))
+
+			data := buf.Bytes()
+			reader, readerMeta, err := NewReader(&testReaderSource{
+				Reader: bytes.NewReader(data),
+				size:   int64(len(data)),
+			})
+			require.NoError(t, err)
+			assert.EqualValues(t, geodbtools.DatabaseTypeCity, readerMeta.Type)
+			assert.EqualValues(t, testCase.IPVersion, readerMeta.IPVersion)
+			assert.EqualValues(t, buildTime, readerMeta.BuildTime)
+
+			assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+
+			for ip, expectedRecord := range testCase.Lookups {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				if assert.NoError(t, err, ip) {
+					assert.EqualValues(t, expectedRecord, record.String())
+				}
+
+				record, err = reader.(geodbtools.AddrReader).LookupAddr(netip.MustParseAddr(ip))
+				if assert.NoError(t, err, ip) {
+					assert.EqualValues(t, expectedRecord, record.String())
+					assert.EqualValues(t, geodbtools.IPNetFromPrefix(record.(geodbtools.PrefixRecord).GetPrefix()), record.GetNetwork())
+				}
+			}
+
+			for ip, expectedCodes := range testCase.MetroCodes {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				if assert.NoError(t, err, ip) && assert.Implements(t, (*geodbtools.MetroCodeRecord)(nil), record) {
+					metroCodeRecord := record.(geodbtools.MetroCodeRecord)
+					assert.EqualValues(t, expectedCodes[0], metroCodeRecord.GetMetroCode(), ip)
+					assert.EqualValues(t, expectedCodes[1], metroCodeRecord.GetAreaCode(), ip)
+				}
+			}
+
+			for _, ip := range testCase.NotFound {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				assert.Nil(t, record, ip)
+				assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error(), ip)
+			}
+
+			// writing the tree read back from the database results in the same database
+			readTree, err := reader.RecordTree(testCase.IPVersion)
+			require.NoError(t, err)
+
+			readMeta := meta
+			rewritten := bytes.NewBufferString("")
+			w, err = cityType{}.NewWriter(rewritten, testCase.IPVersion)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(readMeta, readTree))
+			assert.EqualValues(t, data, rewritten.Bytes())
+		})
+	}
+}
@@ -1,20 +1,20 @@
 package mmdatformat
 
 import (
+	"encoding/binary"
 	"io"
 	"net"
+	"net/netip"
+	"sort"
 	"sync"
 	"time"
 
-	"github.com/anexia-it/bitmap"
 	"github.com/anexia-it/geodbtools"
 )
 
-type readerCountryNode struct {
-	depth   uint
-	offset  int64
-	bitMask []byte
-}
+var _ geodbtools.BatchReader = (*readerCountry)(nil)
+var _ geodbtools.AddrReader = (*readerCountry)(nil)
+var _ geodbtools.IterableReader = (*readerCountry)(nil)
 
 type readerCountry struct {
 	source geodbtools.ReaderSource
@@ -22,95 +22,69 @@ type readerCountry struct {
 
 	recordTreeMu sync.Mutex
 	recordTree   *geodbtools.RecordTree
+
+	// recordCache holds the records returned by LookupIP, keyed by the offset of their search tree record
+	recordCacheMu sync.RWMutex
+	recordCache   map[int64]*countryRecord
 }
 
-func (r *readerCountry) buildTree() (err error) {
-	maxDepth := int(127)
-	recordBelongsRight := geodbtools.RecordBelongsRightIPv6
-	if r.dbType == DatabaseTypeIDCountryEdition {
-		maxDepth = 31
-		recordBelongsRight = bitmap.IsSet
+// bitCount returns the number of bits of the IP addresses stored in the database
+func (r *readerCountry) bitCount() uint {
+	if r.dbType == DatabaseTypeIDCountryEditionV6 {
+		return 128
 	}
+	return 32
+}
 
-	rootBitMask := make([]byte, (maxDepth+1)/8)
-	rootNode := &readerCountryNode{
-		depth:   0,
-		offset:  0,
-		bitMask: rootBitMask,
+func (r *readerCountry) buildTree() (err error) {
+	type pendingNode struct {
+		depth  uint
+		offset int64
+		addr   [16]byte
 	}
 
-	nodes := []*readerCountryNode{
-		rootNode,
-	}
+	bitCount := r.bitCount()
+	nodes := []pendingNode{{}}
 
 	var records []geodbtools.Record
 
-	curData := make([]byte, 6)
+	buf := make([]byte, 2*standardRecordLength)
 	for len(nodes) > 0 {
 		cur := nodes[0]
 		nodes = nodes[1:]
 
-		if _, err = r.source.ReadAt(curData, cur.offset); err != nil {
-			return
-		}
-
-		var left, right uint32
-		if left, err = DecodeRecordUint32(curData, 3); err != nil {
-			return
-		} else if right, err = DecodeRecordUint32(curData[3:], 3); err != nil {
+		var curData []byte
+		if curData, err = readSource(r.source, cur.offset, len(buf), buf); err != nil {
 			return
 		}
 
-		if left < countryBegin {
-			bitMask := make([]byte, len(cur.bitMask))
-			copy(bitMask, cur.bitMask)
-			bitmap.Clear(bitMask, uint(maxDepth)-(cur.depth+1))
-			nodes = append(nodes, &readerCountryNode{
-				depth:   cur.depth + 1,
-				offset:  int64(left) * 6,
-				bitMask: bitMask,
-			})
-		} else {
-			recordCountryCode, _ := GetISO2CountryCodeString(int(left - countryBegin))
-			ip := make([]byte, len(cur.bitMask))
-			copy(ip, cur.bitMask)
-			recordNet := &net.IPNet{
-				IP:   net.IP(ip),
-				Mask: net.CIDRMask(int(cur.depth+1), int(maxDepth+1)),
+		for i := 0; i < 2; i++ {
+			addr := cur.addr
+			if i == 1 {
+				addr[cur.depth>>3] |= 0x80 >> (cur.depth & 7)
 			}
-			records = append(records, &countryRecord{
-				network:     recordNet,
-				countryCode: recordCountryCode,
-			})
-		}
 
-		if right < countryBegin {
-			bitMask := make([]byte, len(cur.bitMask))
-			copy(bitMask, cur.bitMask)
-			bitmap.Set(bitMask, uint(maxDepth)-cur.depth)
-			nodes = append(nodes, &readerCountryNode{
-				depth:   cur.depth + 1,
-				offset:  int64(right) * 6,
-				bitMask: bitMask,
-			})
-		} else {
-			recordCountryCode, _ := GetISO2CountryCodeString(int(right - countryBegin))
-			bitMask := cur.bitMask[:]
-			bitmap.Set(bitMask, uint(maxDepth)-cur.depth)
-			ip := make([]byte, len(cur.bitMask))
-			copy(ip, cur.bitMask)
-			recordNet := &net.IPNet{
-				IP:   net.IP(ip),
-				Mask: net.CIDRMask(int(cur.depth+1), int(maxDepth+1)),
+			value := decodeStandardRecord(curData[i*standardRecordLength:])
+			if value < countryBegin {
+				if cur.depth+1 >= bitCount {
+					err = geodbtools.ErrDatabaseInvalid
+					return
+				}
+
+				nodes = append(nodes, pendingNode{
+					depth:  cur.depth + 1,
+					offset: int64(value) * 2 * standardRecordLength,
+					addr:   addr,
+				})
+				continue
 			}
-			records = append(records, &countryRecord{
-				network:     recordNet,
-				countryCode: recordCountryCode,
-			})
+
+			countryCode, _ := GetISO2CountryCodeString(int(value - countryBegin))
+			records = append(records, newCountryRecord(netip.PrefixFrom(bytesAddr(addr, bitCount), int(cur.depth+1)), countryCode))
 		}
 	}
 
-	r.recordTree, err = geodbtools.NewRecordTree(uint(maxDepth), records, recordBelongsRight)
+	r.recordTree, err = geodbtools.NewPrefixRecordTree(records)
 	return
 }
 
@@ -127,88 +101,195 @@ func (r *readerCountry) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbt
 	return
 }
 
+// Networks walks the search tree, reporting all networks matching the given filter
+func (r *readerCountry) Networks(filter geodbtools.NetworkFilter) geodbtools.NetworkIterator {
+	return geodbtools.NewSearchTreeIterator(&countrySearchTree{
+		r: r,
+	}, filter)
+}
+
+var _ geodbtools.SearchTree = (*countrySearchTree)(nil)
+
+// countrySearchTree provides the search tree of a country database to geodbtools.NewSearchTreeIterator
+type countrySearchTree struct {
+	r   *readerCountry
+	buf [standardRecordLength]byte
+}
+
+func (t *countrySearchTree) IPVersion() geodbtools.IPVersion {
+	if t.r.bitCount() == 128 {
+		return geodbtools.IPVersion6
+	}
+	return geodbtools.IPVersion4
+}
+
+func (t *countrySearchTree) NodeCount() uint {
+	return uint(countryBegin)
+}
+
+func (t *countrySearchTree) ReadNode(node uint, index uint) (value uint, err error) {
+	var b []byte
+	if b, err = readSource(t.r.source, int64(2*node+index)*standardRecordLength, standardRecordLength, t.buf[:]); err != nil {
+		return
+	}
+
+	value = uint(decodeStandardRecord(b))
+	return
+}
+
+func (t *countrySearchTree) ReadLeaf(value uint, prefix netip.Prefix) (record geodbtools.Record, err error) {
+	if value > uint(countryBegin)+255 {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	countryCode, _ := GetISO2CountryCodeString(int(value - uint(countryBegin)))
+	record = newCountryRecord(prefix, countryCode)
+	return
+}
+
+// lookupBufferPool holds the buffers used for reading search tree records from sources without a byte view
+var lookupBufferPool = sync.Pool{
+	New: func() interface{} {
+		return new([standardRecordLength]byte)
+	},
+}
+
+// cachedRecord returns the record for the search tree record at the given offset, which matched the given IP address
+// at the given prefix length.
+// Records are cached, as the network of a search tree record is the same for all IP addresses leading to it.
+func (r *readerCountry) cachedRecord(offset int64, addr netip.Addr, prefixLength int, countryIndex uint32) (record *countryRecord) {
+	r.recordCacheMu.RLock()
+	record = r.recordCache[offset]
+	r.recordCacheMu.RUnlock()
+
+	if record != nil && record.prefix.Contains(addr) {
+		return
+	}
+
+	countryCode, _ := GetISO2CountryCodeString(int(countryIndex))
+	record = newCountryRecord(netip.PrefixFrom(addr, prefixLength), countryCode)
+
+	r.recordCacheMu.Lock()
+	if r.recordCache == nil {
+		r.recordCache = make(map[int64]*countryRecord)
+	}
+	if _, exists := r.recordCache[offset]; !exists {
+		r.recordCache[offset] = record
+	}
+	r.recordCacheMu.Unlock()
+	return
+}
+
+// LookupIP looks up the given IP address using LookupAddr
 func (r *readerCountry) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
-	maxDepth := uint(31)
-	recordBelongsRight := bitmap.IsSet
+	addr, _ := netip.AddrFromSlice(ip)
+	return r.LookupAddr(addr)
+}
 
-	if r.dbType == DatabaseTypeIDCountryEditionV6 {
-		maxDepth = 127
-		recordBelongsRight = geodbtools.RecordBelongsRightIPv6
-		ip = ip.To16()
-	} else if ip = ip.To4(); ip == nil {
+// LookupAddr walks the search tree using stack-only state.
+// Once the record for a network has been looked up, subsequent lookups inside the same network do not allocate any
+// memory. Returned records are shared between lookups and must not be modified.
+func (r *readerCountry) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	bitCount := r.bitCount()
+	if addr = normalizeAddr(addr, bitCount); !addr.IsValid() {
 		// checking a non-v4 address in a v4 tree does not make any sense
 		err = geodbtools.ErrRecordNotFound
 		return
 	}
 
-	rootBitMask := make([]byte, (maxDepth+1)/8)
-	current := &readerCountryNode{
-		depth:   0,
-		offset:  0,
-		bitMask: rootBitMask,
+	// sources without a byte view are read into a pooled buffer
+	var buf *[standardRecordLength]byte
+	if _, ok := r.source.(geodbtools.BytesReaderSource); !ok {
+		buf = lookupBufferPool.Get().(*[standardRecordLength]byte)
+		defer lookupBufferPool.Put(buf)
 	}
 
-	memSize := uint32(r.source.Size())
-	for depth := int(maxDepth); depth >= 0; depth-- {
-		next := make([]byte, 3)
-		nextBitMask := make([]byte, len(current.bitMask))
-		copy(nextBitMask, current.bitMask)
+	ip := addrBytes(addr)
+	var offset int64
+	memSize := r.source.Size()
+	for depth := uint(0); depth < bitCount; depth++ {
+		recordOffset := offset
+		if ip[depth>>3]&(0x80>>(depth&7)) != 0 {
+			recordOffset += standardRecordLength
+		}
 
-		if recordBelongsRight(ip, uint(depth)) {
-			if _, err = r.source.ReadAt(next, current.offset+3); err != nil {
-				return
-			}
-			bitmap.Set(nextBitMask, current.depth)
+		var b []byte
+		if buf != nil {
+			b, err = readSource(r.source, recordOffset, standardRecordLength, buf[:])
 		} else {
-			if _, err = r.source.ReadAt(next, current.offset); err != nil {
-				return
-			}
-			bitmap.Clear(nextBitMask, current.depth)
+			b, err = readSource(r.source, recordOffset, standardRecordLength, nil)
 		}
-
-		var nextVal uint32
-		if nextVal, err = DecodeRecordUint32(next, 3); err != nil {
+		if err != nil {
 			return
 		}
 
-		if nextVal >= countryBegin && nextVal <= countryBegin+255 {
-			recordCountryCode, _ := GetISO2CountryCodeString(int(nextVal - countryBegin))
-
-			cidrMask := net.CIDRMask((int(maxDepth)-depth)+1, int(maxDepth)+1)
-
-			var maskedIP []byte
-			if maskedIP, err = bitmap.Mask(ip, cidrMask); err != nil {
-				return
-			}
-
-			matchingNetwork := &net.IPNet{
-				IP:   maskedIP,
-				Mask: cidrMask,
-			}
-
+		value := decodeStandardRecord(b)
+		if value >= countryBegin && value <= countryBegin+255 {
 			// country record found, report it back
-			record = &countryRecord{
-				network:     matchingNetwork,
-				countryCode: recordCountryCode,
-			}
+			record = r.cachedRecord(recordOffset, addr, int(depth+1), value-countryBegin)
 			return
 		}
 
-		nextOffset := int64(nextVal * 6)
-
-		if uint32(nextOffset+6) >= memSize {
+		offset = int64(value) * 2 * standardRecordLength
+		if offset+2*standardRecordLength >= memSize {
 			err = geodbtools.ErrDatabaseInvalid
 			return
 		}
+	}
+
+	err = geodbtools.ErrRecordNotFound
+	return
+}
+
+// lookupKey holds the 16-byte representation of an IP address as two integers, along with its index
+type lookupKey struct {
+	high, low uint64
+	index     int
+}
 
-		current = &readerCountryNode{
-			depth:   current.depth + 1,
-			offset:  nextOffset,
-			bitMask: nextBitMask,
+// lookupOrder sorts lookup keys by IP address
+type lookupOrder []lookupKey
+
+func (o lookupOrder) Len() int {
+	return len(o)
+}
+
+func (o lookupOrder) Less(i, j int) bool {
+	return o[i].high < o[j].high || (o[i].high == o[j].high && o[i].low < o[j].low)
+}
+
+func (o lookupOrder) Swap(i, j int) {
+	o[i], o[j] = o[j], o[i]
+}
+
+// LookupIPs looks up the given IP addresses in sorted order, which allows skipping the search tree walk for IP
+// addresses contained in the network of the previous IP address' record
+func (r *readerCountry) LookupIPs(ips []net.IP) (results []geodbtools.LookupResult) {
+	results = make([]geodbtools.LookupResult, len(ips))
+
+	order := make(lookupOrder, len(ips))
+	for i, ip := range ips {
+		order[i].index = i
+		if ip = ip.To16(); ip != nil {
+			order[i].high = binary.BigEndian.Uint64(ip[:8])
+			order[i].low = binary.BigEndian.Uint64(ip[8:])
 		}
 	}
+	sort.Sort(order)
+
+	var previous *countryRecord
+	for _, key := range order {
+		i := key.index
+		results[i].IP = ips[i]
+		if previous != nil && previous.network.Contains(ips[i]) {
+			results[i].Record = previous
+			continue
+		}
 
-	err = geodbtools.ErrRecordNotFound
+		results[i].Record, results[i].Err = r.LookupIP(ips[i])
+		previous, _ = results[i].Record.(*countryRecord)
+	}
 	return
 }
 
@@ -293,7 +374,7 @@ func encodeCountryRecord(position *uint32, b []byte, node *geodbtools.RecordTree
 		if leaf := node.Leaf(); leaf != nil {
 			countryRecord, ok := leaf.(geodbtools.CountryRecord)
 			if !ok {
-				err = ErrUnsupportedRecordType
+				err = geodbtools.ErrUnsupportedRecordType
 				return
 			}
 
@@ -3,7 +3,9 @@ package mmdatformat
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
+	"net/netip"
 	"testing"
 	"time"
 
@@ -445,6 +447,29 @@ func TestReaderCountry_LookupIP(t *testing.T) {
 		}
 	})
 
+	t.Run("TwoLevelsBytesReaderSource", func(t *testing.T) {
+		reader := &readerCountry{
+			source: geodbtools.NewBytesReaderSource([]byte{
+				0x01, 0x00, 0x00, 0x01, 0x00, 0x00,
+				0xff, 0xff, 0xff, 0xfd, 0xff, 0xff,
+				0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			}),
+			dbType: DatabaseTypeIDCountryEdition,
+		}
+
+		record, err := reader.LookupIP(net.ParseIP("127.0.0.1"))
+		assert.NoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.EqualValues(t, "64.0.0.0/2: country code BQ", record.String())
+		}
+
+		record, err = reader.LookupIP(net.ParseIP("128.0.0.1"))
+		assert.NoError(t, err)
+		if assert.NotNil(t, record) {
+			assert.EqualValues(t, "128.0.0.0/2: country code O1", record.String())
+		}
+	})
+
 	t.Run("RecordNotFound", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -540,7 +565,7 @@ func TestCountryType_EncodeTreeNode(t *testing.T) {
 			b, additionalNodes, err := countryType{}.EncodeTreeNode(&position, root)
 			assert.Nil(t, b)
 			assert.Nil(t, additionalNodes)
-			assert.EqualError(t, err, ErrUnsupportedRecordType.Error())
+			assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
 			assert.EqualValues(t, 0, position)
 		})
 
@@ -600,7 +625,7 @@ func TestCountryType_EncodeTreeNode(t *testing.T) {
 			b, additionalNodes, err := countryType{}.EncodeTreeNode(&position, root)
 			assert.Nil(t, b)
 			assert.Nil(t, additionalNodes)
-			assert.EqualError(t, err, ErrUnsupportedRecordType.Error())
+			assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
 			assert.EqualValues(t, 0, position)
 		})
 
@@ -719,3 +744,245 @@ func recordStrings(records []geodbtools.Record) (s []string) {
 
 	return
 }
+
+// testCountryDatabase returns an IPv4 country database holding a few networks
+func TestReaderCountry_Networks(t *testing.T) {
+	for name, reader := range testCountryReaders(t, testCountryDatabase(t)) {
+		t.Run(name, func(t *testing.T) {
+			recordTree, err := reader.RecordTree(geodbtools.IPVersion4)
+			require.NoError(t, err)
+
+			var expectedNetworks []string
+			for it := geodbtools.NewRecordTreeIterator(recordTree, geodbtools.NetworkFilter{}); it.Next(); {
+				expectedNetworks = append(expectedNetworks, it.Record().String())
+			}
+
+			var networks []string
+			it := geodbtools.Networks(reader, geodbtools.NetworkFilter{})
+			for it.Next() {
+				networks = append(networks, it.Record().String())
+			}
+			assert.NoError(t, it.Err())
+			assert.EqualValues(t, expectedNetworks, networks)
+
+			it = geodbtools.Networks(reader, geodbtools.NetworkFilter{
+				Prefix: netip.MustParsePrefix("10.0.0.0/12"),
+			})
+			var countryCodes []string
+			for it.Next() {
+				assert.True(t, netip.MustParsePrefix("10.0.0.0/12").Overlaps(geodbtools.RecordPrefix(it.Record())))
+				countryCodes = append(countryCodes, it.Record().(geodbtools.CountryRecord).GetCountryCode())
+			}
+			assert.NoError(t, it.Err())
+			assert.Len(t, countryCodes, 16)
+			assert.EqualValues(t, []string{"CH", "LI"}, countryCodes[:2])
+
+			it = geodbtools.Networks(reader, geodbtools.NetworkFilter{
+				IPVersion: geodbtools.IPVersion6,
+			})
+			assert.False(t, it.Next())
+			assert.EqualError(t, it.Err(), geodbtools.ErrUnsupportedIPVersion.Error())
+		})
+	}
+}
+
+func testCountryDatabase(tb testing.TB) []byte {
+	networks := map[string]string{
+		"1.0.0.0/8":   "AT",
+		"2.0.0.0/16":  "DE",
+		"4.4.4.0/24":  "CH",
+		"128.0.0.0/1": "US",
+	}
+
+	// the database has to be large enough to hold the database info
+	for i := 0; i < 16; i++ {
+		networks[fmt.Sprintf("10.%d.0.0/16", i)] = []string{"CH", "LI"}[i%2]
+	}
+
+	var records []geodbtools.Record
+	for network, countryCode := range networks {
+		_, ipNet, err := net.ParseCIDR(network)
+		require.NoError(tb, err)
+
+		records = append(records, &countryRecord{
+			network:     ipNet,
+			countryCode: countryCode,
+		})
+	}
+
+	tree, err := geodbtools.NewRecordTree(31, records, bitmap.IsSet)
+	require.NoError(tb, err)
+
+	buf := bytes.NewBufferString("")
+	w, err := countryType{}.NewWriter(buf, geodbtools.IPVersion4)
+	require.NoError(tb, err)
+	require.NoError(tb, w.WriteDatabase(geodbtools.Metadata{
+		Type:        geodbtools.DatabaseTypeCountry,
+		BuildTime:   time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC),
+		Description: "test country database",
+	}, tree))
+
+	return buf.Bytes()
+}
+
+// testCountryReaders returns readers for the given database, using different sources and cache modes
+func testCountryReaders(tb testing.TB, data []byte) map[string]geodbtools.Reader {
+	readers := make(map[string]geodbtools.Reader)
+	for name, newReader := range map[string]func() (geodbtools.Reader, geodbtools.Metadata, error){
+		"Standard": func() (geodbtools.Reader, geodbtools.Metadata, error) {
+			return NewReader(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data))))
+		},
+		"MemoryCache": func() (geodbtools.Reader, geodbtools.Metadata, error) {
+			return NewReaderWithCacheMode(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data))), CacheModeMemory)
+		},
+		"BytesReaderSource": func() (geodbtools.Reader, geodbtools.Metadata, error) {
+			return NewReader(geodbtools.NewBytesReaderSource(data))
+		},
+	} {
+		reader, _, err := newReader()
+		require.NoError(tb, err)
+		readers[name] = reader
+	}
+
+	return readers
+}
+
+func TestReaderCountry_LookupIP_Allocations(t *testing.T) {
+	lookups := map[string]string{
+		"1.2.3.4":   "AT",
+		"2.0.1.1":   "DE",
+		"4.4.4.4":   "CH",
+		"10.1.0.1":  "LI",
+		"192.0.2.1": "US",
+	}
+
+	for name, reader := range testCountryReaders(t, testCountryDatabase(t)) {
+		t.Run(name, func(t *testing.T) {
+			for ip, expectedCountryCode := range lookups {
+				parsedIP := net.ParseIP(ip)
+
+				record, err := reader.LookupIP(parsedIP)
+				require.NoError(t, err, ip)
+				if assert.Implements(t, (*geodbtools.CountryRecord)(nil), record, ip) {
+					assert.EqualValues(t, expectedCountryCode, record.(geodbtools.CountryRecord).GetCountryCode(), ip)
+				}
+
+				allocs := testing.AllocsPerRun(100, func() {
+					reader.LookupIP(parsedIP)
+				})
+				assert.EqualValues(t, 0, allocs, ip)
+			}
+		})
+	}
+}
+
+func TestReaderCountry_LookupAddr(t *testing.T) {
+	lookups := map[string]string{
+		"1.2.3.4":          "AT",
+		"::ffff:4.4.4.4":   "CH",
+		"10.1.0.1":         "LI",
+		"192.0.2.1":        "US",
+		"2001:db8::1":      "",
+		"::ffff:ffff:ffff": "US",
+	}
+
+	for name, reader := range testCountryReaders(t, testCountryDatabase(t)) {
+		t.Run(name, func(t *testing.T) {
+			addrReader := reader.(geodbtools.AddrReader)
+			for ip, expectedCountryCode := range lookups {
+				addr := netip.MustParseAddr(ip)
+
+				record, err := addrReader.LookupAddr(addr)
+				if expectedCountryCode == "" {
+					assert.Nil(t, record, ip)
+					assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error(), ip)
+					continue
+				}
+
+				require.NoError(t, err, ip)
+				assert.EqualValues(t, expectedCountryCode, record.(geodbtools.CountryRecord).GetCountryCode(), ip)
+
+				prefix := record.(geodbtools.PrefixRecord).GetPrefix()
+				assert.True(t, prefix.Contains(addr.Unmap()), ip)
+				assert.EqualValues(t, geodbtools.IPNetFromPrefix(prefix), record.GetNetwork(), ip)
+
+				// looking up the net.IP representation results in the same record
+				ipRecord, err := reader.LookupIP(net.ParseIP(ip))
+				assert.NoError(t, err, ip)
+				assert.EqualValues(t, record, ipRecord, ip)
+
+				allocs := testing.AllocsPerRun(100, func() {
+					addrReader.LookupAddr(addr)
+				})
+				assert.EqualValues(t, 0, allocs, ip)
+			}
+
+			record, err := addrReader.LookupAddr(netip.Addr{})
+			assert.Nil(t, record)
+			assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+		})
+	}
+}
+
+func TestReaderCountry_LookupIPs(t *testing.T) {
+	ips := []net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("1.2.3.4"),
+		net.ParseIP("2001:db8::1"),
+		net.ParseIP("10.1.0.1"),
+		net.ParseIP("1.2.3.5"),
+		net.ParseIP("10.0.0.1"),
+		net.ParseIP("192.0.2.2"),
+	}
+
+	for name, reader := range testCountryReaders(t, testCountryDatabase(t)) {
+		t.Run(name, func(t *testing.T) {
+			results := reader.(geodbtools.BatchReader).LookupIPs(ips)
+			if assert.Len(t, results, len(ips)) {
+				for i, ip := range ips {
+					record, err := reader.LookupIP(ip)
+					assert.EqualValues(t, geodbtools.LookupResult{IP: ip, Record: record, Err: err}, results[i], ip.String())
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkReaderCountry_LookupIPs(b *testing.B) {
+	ips := make([]net.IP, 1024)
+	for i := range ips {
+		ips[i] = net.IPv4(10, byte(i%16), byte(i/16), 1)
+	}
+
+	reader := testCountryReaders(b, testCountryDatabase(b))["BytesReaderSource"]
+	b.Run("Batch", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			reader.(geodbtools.BatchReader).LookupIPs(ips)
+		}
+	})
+
+	b.Run("Single", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, ip := range ips {
+				reader.LookupIP(ip)
+			}
+		}
+	})
+}
+
+func BenchmarkReaderCountry_LookupIP(b *testing.B) {
+	ip := net.ParseIP("192.0.2.1")
+
+	for name, reader := range testCountryReaders(b, testCountryDatabase(b)) {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := reader.LookupIP(ip); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -9,15 +9,24 @@ import (
 )
 
 var (
-	// ErrUnsupportedRecordType indicates that a record type is unsupported
-	ErrUnsupportedRecordType = errors.New("unsupported record type")
+	// ErrUnsupportedRecordType indicates that a record type is unsupported.
+	//
+	// Deprecated: use geodbtools.ErrUnsupportedRecordType, which this variable refers to.
+	ErrUnsupportedRecordType = geodbtools.ErrUnsupportedRecordType
 	// ErrDatabaseInfoNotFound indicates that the database information could not be found
 	ErrDatabaseInfoNotFound = errors.New("database information not found")
+	// ErrDatabaseTooLarge indicates that the database contents exceed the addressable size
+	ErrDatabaseTooLarge = errors.New("database too large")
 )
 
 const (
 	structureInfoMaxSize = 20
 	databaseInfoMaxSize  = 100
+
+	// standardRecordLength defines the length of a search tree record in bytes
+	standardRecordLength = 3
+	// organizationRecordLength defines the length of a search tree record in bytes for organization and ISP databases
+	organizationRecordLength = 4
 )
 
 var _ geodbtools.Format = format{}
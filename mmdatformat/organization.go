@@ -0,0 +1,216 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+const (
+	// organizationRecordMaxLength defines the maximum number of bytes read for a single organization record
+	organizationRecordMaxLength = 300
+)
+
+// parseASNumber splits an AS number database entry ("AS<number> <organization>") into its parts.
+// Entries not following this notation are returned as organization name.
+func parseASNumber(s string) (asNumber uint32, organization string) {
+	organization = s
+	if !strings.HasPrefix(s, "AS") {
+		return
+	}
+
+	numberString := s[2:]
+	var remainder string
+	if spaceIndex := strings.IndexByte(numberString, ' '); spaceIndex >= 0 {
+		numberString, remainder = numberString[:spaceIndex], numberString[spaceIndex+1:]
+	}
+
+	number, err := strconv.ParseUint(numberString, 10, 32)
+	if err != nil {
+		return
+	}
+
+	asNumber = uint32(number)
+	organization = remainder
+	return
+}
+
+// decodeOrganizationRecord returns a segmentRecordDecoder for organization records.
+// If asn is set, the AS number is parsed from the stored string.
+func decodeOrganizationRecord(asn bool) segmentRecordDecoder {
+	return func(source geodbtools.ReaderSource, offset int64) (record segmentRecord, err error) {
+		var b []byte
+		if b, err = readSegmentData(source, offset, organizationRecordMaxLength); err != nil {
+			return
+		}
+
+		end := bytes.IndexByte(b, 0x00)
+		if end < 0 {
+			err = geodbtools.ErrDatabaseInvalid
+			return
+		}
+
+		rec := &organizationRecord{
+			organization: DecodeLatin1(b[:end]),
+		}
+
+		if asn {
+			rec.asNumber, rec.organization = parseASNumber(rec.organization)
+		}
+
+		record = rec
+		return
+	}
+}
+
+// encodeOrganizationRecord returns a segmentRecordEncoder for organization records.
+// If asn is set, the AS number is prepended to the organization name.
+func encodeOrganizationRecord(asn bool) segmentRecordEncoder {
+	return func(record geodbtools.Record) (b []byte, err error) {
+		organizationRecord, ok := record.(geodbtools.OrganizationRecord)
+		if !ok {
+			err = geodbtools.ErrUnsupportedRecordType
+			return
+		}
+
+		s := organizationRecord.GetOrganization()
+		if asnRecord, ok := record.(geodbtools.ASNRecord); asn && ok && asnRecord.GetASNumber() != 0 {
+			s = strings.TrimSpace(fmt.Sprintf("AS%d %s", asnRecord.GetASNumber(), s))
+		}
+
+		s = strings.Replace(s, "\x00", "", -1)
+		if s == "" {
+			// records without any information are stored as empty records
+			return
+		}
+
+		b = append(EncodeLatin1(s), 0x00)
+		return
+	}
+}
+
+var _ Type = organizationType{}
+
+// organizationType implements the AS number, organization and ISP database types, which all store a single string
+// per record
+type organizationType struct {
+	dbType       geodbtools.DatabaseType
+	typeIDV4     DatabaseTypeID
+	typeIDV6     DatabaseTypeID
+	recordLength int
+	asn          bool
+}
+
+var (
+	asNumberType = organizationType{
+		dbType:       geodbtools.DatabaseTypeASN,
+		typeIDV4:     DatabaseTypeIDASNumEdition,
+		typeIDV6:     DatabaseTypeIDASNumEditionV6,
+		recordLength: standardRecordLength,
+		asn:          true,
+	}
+
+	orgType = organizationType{
+		dbType:       geodbtools.DatabaseTypeOrganization,
+		typeIDV4:     DatabaseTypeIDOrgEdition,
+		typeIDV6:     DatabaseTypeIDOrgEditionV6,
+		recordLength: organizationRecordLength,
+	}
+
+	ispType = organizationType{
+		dbType:       geodbtools.DatabaseTypeISP,
+		typeIDV4:     DatabaseTypeIDISPEdition,
+		typeIDV6:     DatabaseTypeIDISPEditionV6,
+		recordLength: organizationRecordLength,
+	}
+)
+
+func (t organizationType) DatabaseType() geodbtools.DatabaseType {
+	return t.dbType
+}
+
+// EncodeTreeNode is not supported by organization databases, as their records are stored in the record segments
+func (t organizationType) EncodeTreeNode(position *uint32, node *geodbtools.RecordTree) (b []byte, additionalNodes []*geodbtools.RecordTree, err error) {
+	err = geodbtools.ErrUnsupportedRecordType
+	return
+}
+
+func (t organizationType) NewReader(source geodbtools.ReaderSource, dbType DatabaseTypeID, dbInfo string, buildTime *time.Time) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	var ipVersion geodbtools.IPVersion
+	var bitCount uint
+
+	switch dbType {
+	case t.typeIDV4:
+		ipVersion = geodbtools.IPVersion4
+		bitCount = 32
+	case t.typeIDV6:
+		ipVersion = geodbtools.IPVersion6
+		bitCount = 128
+	default:
+		err = geodbtools.ErrUnsupportedDatabaseType
+		return
+	}
+
+	var segments uint32
+	if segments, err = readDatabaseSegments(source); err != nil {
+		return
+	}
+
+	if buildTime == nil {
+		now := time.Now()
+		buildTime = &now
+	}
+
+	meta = geodbtools.Metadata{
+		Type:               t.dbType,
+		BuildTime:          *buildTime,
+		Description:        dbInfo,
+		MajorFormatVersion: 1,
+		MinorFormatVersion: 0,
+		IPVersion:          ipVersion,
+	}
+
+	reader = &segmentReader{
+		source:       source,
+		dbType:       dbType,
+		segments:     segments,
+		recordLength: t.recordLength,
+		bitCount:     bitCount,
+		decodeRecord: decodeOrganizationRecord(t.asn),
+	}
+	return
+}
+
+func (t organizationType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	var typeID DatabaseTypeID
+
+	switch ipVersion {
+	case geodbtools.IPVersion4:
+		typeID = t.typeIDV4
+	case geodbtools.IPVersion6:
+		typeID = t.typeIDV6
+	default:
+		err = geodbtools.ErrUnsupportedDatabaseType
+		return
+	}
+
+	writer = &segmentWriter{
+		w:            w,
+		typeID:       typeID,
+		recordLength: t.recordLength,
+		encodeRecord: encodeOrganizationRecord(t.asn),
+	}
+	return
+}
+
+func init() {
+	for _, t := range []organizationType{asNumberType, orgType, ispType} {
+		MustRegisterType(t.typeIDV4, t)
+		MustRegisterType(t.typeIDV6, t)
+	}
+}
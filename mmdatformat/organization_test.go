@@ -0,0 +1,399 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testOrganizationRecord(t *testing.T, cidr string, asNumber uint32, organization string) *organizationRecord {
+	_, network, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+
+	return &organizationRecord{
+		network:      network,
+		asNumber:     asNumber,
+		organization: organization,
+	}
+}
+
+func TestParseASNumber(t *testing.T) {
+	testCases := []struct {
+		Name                 string
+		Input                string
+		ExpectedASNumber     uint32
+		ExpectedOrganization string
+	}{
+		{"Empty", "", 0, ""},
+		{"ASNumberOnly", "AS64512", 64512, ""},
+		{"ASNumberAndOrganization", "AS64512 Test Organization", 64512, "Test Organization"},
+		{"NoPrefix", "Test Organization", 0, "Test Organization"},
+		{"InvalidNumber", "ASX Test Organization", 0, "ASX Test Organization"},
+		{"NumberOverflow", "AS4294967296 Test", 0, "AS4294967296 Test"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			asNumber, organization := parseASNumber(testCase.Input)
+			assert.EqualValues(t, testCase.ExpectedASNumber, asNumber)
+			assert.EqualValues(t, testCase.ExpectedOrganization, organization)
+		})
+	}
+}
+
+func TestEncodeOrganizationRecord(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		b, err := encodeOrganizationRecord(false)(&countryRecord{})
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		b, err := encodeOrganizationRecord(true)(&organizationRecord{})
+		assert.NoError(t, err)
+		assert.Nil(t, b)
+	})
+
+	t.Run("Organization", func(t *testing.T) {
+		b, err := encodeOrganizationRecord(false)(&organizationRecord{
+			asNumber:     64512,
+			organization: "Zürich\x00",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{'Z', 0xfc, 'r', 'i', 'c', 'h', 0x00}, b)
+	})
+
+	t.Run("ASNumber", func(t *testing.T) {
+		b, err := encodeOrganizationRecord(true)(&organizationRecord{
+			asNumber:     64512,
+			organization: "Test",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte("AS64512 Test\x00"), b)
+	})
+
+	t.Run("ASNumberWithoutOrganization", func(t *testing.T) {
+		b, err := encodeOrganizationRecord(true)(&organizationRecord{
+			asNumber: 64512,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte("AS64512\x00"), b)
+	})
+}
+
+func TestDecodeOrganizationRecord(t *testing.T) {
+	t.Run("OffsetOutOfBounds", func(t *testing.T) {
+		source := &testReaderSource{
+			Reader: bytes.NewReader([]byte{0x00}),
+			size:   1,
+		}
+
+		record, err := decodeOrganizationRecord(false)(source, 1)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("MissingStringTerminator", func(t *testing.T) {
+		data := []byte("Test")
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		record, err := decodeOrganizationRecord(false)(source, 0)
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	data := []byte("\x00AS64512 Z\xfcrich\x00")
+	source := &testReaderSource{
+		Reader: bytes.NewReader(data),
+		size:   int64(len(data)),
+	}
+
+	t.Run("Organization", func(t *testing.T) {
+		record, err := decodeOrganizationRecord(false)(source, 1)
+		assert.NoError(t, err)
+		if assert.IsType(t, &organizationRecord{}, record) {
+			rec := record.(*organizationRecord)
+			assert.EqualValues(t, 0, rec.asNumber)
+			assert.EqualValues(t, "AS64512 Zürich", rec.organization)
+		}
+	})
+
+	t.Run("ASNumber", func(t *testing.T) {
+		record, err := decodeOrganizationRecord(true)(source, 1)
+		assert.NoError(t, err)
+		if assert.IsType(t, &organizationRecord{}, record) {
+			rec := record.(*organizationRecord)
+			assert.EqualValues(t, 64512, rec.asNumber)
+			assert.EqualValues(t, "Zürich", rec.organization)
+		}
+	})
+}
+
+func TestOrganizationType_DatabaseType(t *testing.T) {
+	assert.EqualValues(t, geodbtools.DatabaseTypeASN, asNumberType.DatabaseType())
+	assert.EqualValues(t, geodbtools.DatabaseTypeOrganization, orgType.DatabaseType())
+	assert.EqualValues(t, geodbtools.DatabaseTypeISP, ispType.DatabaseType())
+}
+
+func TestOrganizationType_EncodeTreeNode(t *testing.T) {
+	var position uint32
+	b, additionalNodes, err := organizationType{}.EncodeTreeNode(&position, &geodbtools.RecordTree{})
+	assert.Nil(t, b)
+	assert.Nil(t, additionalNodes)
+	assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	assert.EqualValues(t, 0, position)
+}
+
+func TestOrganizationType_NewReader(t *testing.T) {
+	t.Run("UnsupportedDBType", func(t *testing.T) {
+		reader, meta, err := asNumberType.NewReader(nil, DatabaseTypeIDOrgEdition, "test", nil)
+		assert.Nil(t, reader)
+		assert.EqualValues(t, geodbtools.Metadata{}, meta)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	t.Run("SegmentsError", func(t *testing.T) {
+		source := &testReaderSource{
+			Reader: bytes.NewReader(nil),
+			size:   0,
+		}
+
+		reader, meta, err := orgType.NewReader(source, DatabaseTypeIDOrgEdition, "test", nil)
+		assert.Nil(t, reader)
+		assert.EqualValues(t, geodbtools.Metadata{}, meta)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	testCases := []struct {
+		Name         string
+		Type         organizationType
+		TypeID       DatabaseTypeID
+		IPVersion    geodbtools.IPVersion
+		BitCount     uint
+		RecordLength int
+	}{
+		{"ASNum", asNumberType, DatabaseTypeIDASNumEdition, geodbtools.IPVersion4, 32, standardRecordLength},
+		{"ASNumV6", asNumberType, DatabaseTypeIDASNumEditionV6, geodbtools.IPVersion6, 128, standardRecordLength},
+		{"Org", orgType, DatabaseTypeIDOrgEdition, geodbtools.IPVersion4, 32, organizationRecordLength},
+		{"OrgV6", orgType, DatabaseTypeIDOrgEditionV6, geodbtools.IPVersion6, 128, organizationRecordLength},
+		{"ISP", ispType, DatabaseTypeIDISPEdition, geodbtools.IPVersion4, 32, organizationRecordLength},
+		{"ISPV6", ispType, DatabaseTypeIDISPEditionV6, geodbtools.IPVersion6, 128, organizationRecordLength},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			structureInfo := append(bytes.Repeat([]byte{0x00}, structureInfoMaxSize-7), 0xff, 0xff, 0xff, byte(testCase.TypeID), 0x2a, 0x00, 0x00)
+			source := &testReaderSource{
+				Reader: bytes.NewReader(structureInfo),
+				size:   int64(len(structureInfo)),
+			}
+
+			buildTime := time.Now()
+
+			reader, meta, err := testCase.Type.NewReader(source, testCase.TypeID, "test", &buildTime)
+			assert.NoError(t, err)
+			assert.EqualValues(t, geodbtools.Metadata{
+				Type:               testCase.Type.dbType,
+				BuildTime:          buildTime,
+				Description:        "test",
+				MajorFormatVersion: 1,
+				MinorFormatVersion: 0,
+				IPVersion:          testCase.IPVersion,
+			}, meta)
+			if assert.NotNil(t, reader) && assert.IsType(t, &segmentReader{}, reader) {
+				r := reader.(*segmentReader)
+				assert.EqualValues(t, source, r.source)
+				assert.EqualValues(t, testCase.TypeID, r.dbType)
+				assert.EqualValues(t, 42, r.segments)
+				assert.EqualValues(t, testCase.RecordLength, r.recordLength)
+				assert.EqualValues(t, testCase.BitCount, r.bitCount)
+			}
+		})
+	}
+}
+
+func TestOrganizationType_NewWriter(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		writer, err := ispType.NewWriter(nil, geodbtools.IPVersionUndefined)
+		assert.Nil(t, writer)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedDatabaseType.Error())
+	})
+
+	testCases := []struct {
+		Name           string
+		Type           organizationType
+		IPVersion      geodbtools.IPVersion
+		ExpectedTypeID DatabaseTypeID
+	}{
+		{"ASNum", asNumberType, geodbtools.IPVersion4, DatabaseTypeIDASNumEdition},
+		{"ASNumV6", asNumberType, geodbtools.IPVersion6, DatabaseTypeIDASNumEditionV6},
+		{"Org", orgType, geodbtools.IPVersion4, DatabaseTypeIDOrgEdition},
+		{"OrgV6", orgType, geodbtools.IPVersion6, DatabaseTypeIDOrgEditionV6},
+		{"ISP", ispType, geodbtools.IPVersion4, DatabaseTypeIDISPEdition},
+		{"ISPV6", ispType, geodbtools.IPVersion6, DatabaseTypeIDISPEditionV6},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			w, err := testCase.Type.NewWriter(buf, testCase.IPVersion)
+			assert.NoError(t, err)
+			if assert.NotNil(t, w) && assert.IsType(t, &segmentWriter{}, w) {
+				wr := w.(*segmentWriter)
+				assert.EqualValues(t, testCase.ExpectedTypeID, wr.typeID)
+				assert.EqualValues(t, testCase.Type.recordLength, wr.recordLength)
+				assert.EqualValues(t, buf, wr.w)
+			}
+		})
+	}
+}
+
+func TestOrganizationType_RoundTrip(t *testing.T) {
+	buildTime := time.Date(2019, 1, 2, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		Name           string
+		DatabaseType   geodbtools.DatabaseType
+		IPVersion      geodbtools.IPVersion
+		MaxDepth       uint
+		BelongsRightFn geodbtools.RecordBelongsRightFunc
+		Records        []geodbtools.Record
+		Lookups        map[string]string
+		NotFound       []string
+	}{
+		{
+			Name:           "ASNum",
+			DatabaseType:   geodbtools.DatabaseTypeASN,
+			IPVersion:      geodbtools.IPVersion4,
+			MaxDepth:       31,
+			BelongsRightFn: bitmap.IsSet,
+			Records: []geodbtools.Record{
+				testOrganizationRecord(t, "1.0.0.0/8", 64512, "Test Organization"),
+				testOrganizationRecord(t, "2.0.0.0/8", 64513, ""),
+				testOrganizationRecord(t, "3.0.0.0/8", 64512, "Test Organization"),
+			},
+			Lookups: map[string]string{
+				"1.2.3.4": "0.0.0.0/7: AS64512, organization Test Organization",
+				"2.2.3.4": "2.0.0.0/8: AS64513, organization ",
+			},
+			NotFound: []string{"128.0.0.1", "::1"},
+		},
+		{
+			Name:           "ASNumV6",
+			DatabaseType:   geodbtools.DatabaseTypeASN,
+			IPVersion:      geodbtools.IPVersion6,
+			MaxDepth:       127,
+			BelongsRightFn: geodbtools.RecordBelongsRightIPv6,
+			Records: []geodbtools.Record{
+				testOrganizationRecord(t, "2001:db8::/32", 64512, "Test Organization"),
+				testOrganizationRecord(t, "2a00::/16", 64513, "Other Organization"),
+			},
+			Lookups: map[string]string{
+				"2001:db8::1": "2000::/5: AS64512, organization Test Organization",
+				"2a00:1::1":   "2800::/5: AS64513, organization Other Organization",
+			},
+			NotFound: []string{"3000::1"},
+		},
+		{
+			Name:           "Org",
+			DatabaseType:   geodbtools.DatabaseTypeOrganization,
+			IPVersion:      geodbtools.IPVersion4,
+			MaxDepth:       31,
+			BelongsRightFn: bitmap.IsSet,
+			Records: []geodbtools.Record{
+				testOrganizationRecord(t, "1.0.0.0/8", 0, "Test Organization"),
+				testOrganizationRecord(t, "2.0.0.0/8", 0, "Zürich Organization"),
+			},
+			Lookups: map[string]string{
+				"1.2.3.4": "0.0.0.0/7: organization Test Organization",
+				"2.2.3.4": "2.0.0.0/7: organization Zürich Organization",
+			},
+			NotFound: []string{"128.0.0.1"},
+		},
+		{
+			Name:           "ISPV6",
+			DatabaseType:   geodbtools.DatabaseTypeISP,
+			IPVersion:      geodbtools.IPVersion6,
+			MaxDepth:       127,
+			BelongsRightFn: geodbtools.RecordBelongsRightIPv6,
+			Records: []geodbtools.Record{
+				testOrganizationRecord(t, "2001:db8::/32", 0, "Test ISP"),
+				testOrganizationRecord(t, "2a00::/16", 0, "Other ISP"),
+			},
+			Lookups: map[string]string{
+				"2001:db8::1": "2000::/5: organization Test ISP",
+				"2a00:1::1":   "2800::/5: organization Other ISP",
+			},
+			NotFound: []string{"8000::1"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			meta := geodbtools.Metadata{
+				Type:        testCase.DatabaseType,
+				BuildTime:   buildTime,
+				Description: "test database",
+			}
+
+			tree, err := geodbtools.NewRecordTree(testCase.MaxDepth, testCase.Records, testCase.BelongsRightFn)
+			require.NoError(t, err)
+
+			buf := bytes.NewBufferString("")
+			w, err := format{}.NewWriter(buf, testCase.DatabaseType, testCase.IPVersion)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(meta, tree))
+
+			data := buf.Bytes()
+			reader, readerMeta, err := NewReader(&testReaderSource{
+				Reader: bytes.NewReader(data),
+				size:   int64(len(data)),
+			})
+			require.NoError(t, err)
+			assert.EqualValues(t, testCase.DatabaseType, readerMeta.Type)
+			assert.EqualValues(t, testCase.IPVersion, readerMeta.IPVersion)
+			assert.EqualValues(t, buildTime, readerMeta.BuildTime)
+
+			assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+
+			for ip, expectedRecord := range testCase.Lookups {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				if assert.NoError(t, err, ip) {
+					assert.EqualValues(t, expectedRecord, record.String())
+				}
+			}
+
+			for _, ip := range testCase.NotFound {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				assert.Nil(t, record, ip)
+				assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error(), ip)
+			}
+
+			// walking the search tree reports the same networks as the record tree
+			recordTree, err := reader.RecordTree(testCase.IPVersion)
+			require.NoError(t, err)
+
+			var expectedNetworks []string
+			for it := geodbtools.NewRecordTreeIterator(recordTree, geodbtools.NetworkFilter{}); it.Next(); {
+				expectedNetworks = append(expectedNetworks, it.Record().String())
+			}
+
+			var networks []string
+			it := geodbtools.Networks(reader, geodbtools.NetworkFilter{})
+			for it.Next() {
+				networks = append(networks, it.Record().String())
+			}
+			assert.NoError(t, it.Err())
+			assert.EqualValues(t, expectedNetworks, networks)
+		})
+	}
+}
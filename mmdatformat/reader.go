@@ -105,8 +105,35 @@ func (mr *metaReader) setup() (reader geodbtools.Reader, meta geodbtools.Metadat
 	return
 }
 
-// NewReader initializes a new reader
+// CacheMode defines how a reader accesses the database source
+type CacheMode int
+
+const (
+	// CacheModeStandard reads from the database source on demand, like libGeoIP's GEOIP_STANDARD
+	CacheModeStandard CacheMode = iota
+	// CacheModeMemory reads the whole database source into memory when the reader is initialized, like libGeoIP's
+	// GEOIP_MEMORY_CACHE.
+	// Sources providing a byte view already (like memory-mapped files) are used as-is.
+	CacheModeMemory
+)
+
+// NewReader initializes a new reader, using CacheModeStandard
 func NewReader(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	return NewReaderWithCacheMode(r, CacheModeStandard)
+}
+
+// NewReaderWithCacheMode initializes a new reader, using the given cache mode
+func NewReaderWithCacheMode(r geodbtools.ReaderSource, mode CacheMode) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
+	if mode == CacheModeMemory {
+		if _, ok := r.(geodbtools.BytesReaderSource); !ok {
+			data := make([]byte, r.Size())
+			if _, err = r.ReadAt(data, 0); err != nil {
+				return
+			}
+			r = geodbtools.NewBytesReaderSource(data)
+		}
+	}
+
 	mr := &metaReader{
 		source: r,
 	}
@@ -349,3 +349,59 @@ func TestNewReader(t *testing.T) {
 		assert.EqualValues(t, reader, r)
 	})
 }
+
+func TestNewReaderWithCacheMode(t *testing.T) {
+	data := testCountryDatabase(t)
+
+	t.Run("Standard", func(t *testing.T) {
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		r, meta, err := NewReaderWithCacheMode(source, CacheModeStandard)
+		assert.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+		if assert.IsType(t, &readerCountry{}, r) {
+			assert.EqualValues(t, source, r.(*readerCountry).source)
+		}
+	})
+
+	t.Run("Memory", func(t *testing.T) {
+		source := &testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		}
+
+		r, meta, err := NewReaderWithCacheMode(source, CacheModeMemory)
+		assert.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+		if assert.IsType(t, &readerCountry{}, r) && assert.Implements(t, (*geodbtools.BytesReaderSource)(nil), r.(*readerCountry).source) {
+			assert.EqualValues(t, data, r.(*readerCountry).source.(geodbtools.BytesReaderSource).Bytes())
+		}
+	})
+
+	t.Run("MemoryBytesReaderSource", func(t *testing.T) {
+		source := geodbtools.NewBytesReaderSource(data)
+
+		r, _, err := NewReaderWithCacheMode(source, CacheModeMemory)
+		assert.NoError(t, err)
+		if assert.IsType(t, &readerCountry{}, r) {
+			assert.EqualValues(t, source, r.(*readerCountry).source)
+		}
+	})
+
+	t.Run("MemoryReadError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().Size().Return(int64(len(data)))
+		source.EXPECT().ReadAt(gomock.Any(), int64(0)).Return(0, testErr)
+
+		r, _, err := NewReaderWithCacheMode(source, CacheModeMemory)
+		assert.Nil(t, r)
+		assert.EqualError(t, err, testErr.Error())
+	})
+}
@@ -3,17 +3,30 @@ package mmdatformat
 import (
 	"fmt"
 	"net"
+	"net/netip"
 
 	"github.com/anexia-it/geodbtools"
 )
 
 var _ geodbtools.CountryRecord = (*countryRecord)(nil)
+var _ geodbtools.PrefixRecord = (*countryRecord)(nil)
 
 type countryRecord struct {
+	prefix      netip.Prefix
 	network     *net.IPNet
 	countryCode string
 }
 
+// newCountryRecord returns a new country record, representing the given prefix
+func newCountryRecord(prefix netip.Prefix, countryCode string) *countryRecord {
+	prefix = prefix.Masked()
+	return &countryRecord{
+		prefix:      prefix,
+		network:     geodbtools.IPNetFromPrefix(prefix),
+		countryCode: countryCode,
+	}
+}
+
 func (r *countryRecord) GetRecordKey() []byte {
 	return r.network.IP
 }
@@ -22,6 +35,13 @@ func (r *countryRecord) GetNetwork() *net.IPNet {
 	return r.network
 }
 
+func (r *countryRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
 func (r *countryRecord) GetCountryCode() string {
 	return r.countryCode
 }
@@ -29,3 +49,124 @@ func (r *countryRecord) GetCountryCode() string {
 func (r *countryRecord) String() string {
 	return fmt.Sprintf("%s: country code %s", r.network, r.countryCode)
 }
+
+var _ geodbtools.CityRecord = (*cityRecord)(nil)
+var _ geodbtools.RegionRecord = (*cityRecord)(nil)
+var _ geodbtools.PostalCodeRecord = (*cityRecord)(nil)
+var _ geodbtools.LocationRecord = (*cityRecord)(nil)
+var _ geodbtools.MetroCodeRecord = (*cityRecord)(nil)
+var _ geodbtools.PrefixRecord = (*cityRecord)(nil)
+var _ segmentRecord = (*cityRecord)(nil)
+
+type cityRecord struct {
+	prefix      netip.Prefix
+	network     *net.IPNet
+	countryCode string
+	regionCode  string
+	cityName    string
+	postalCode  string
+	latitude    float64
+	longitude   float64
+	metroCode   int
+	areaCode    int
+}
+
+func (r *cityRecord) withPrefix(prefix netip.Prefix) geodbtools.Record {
+	rec := *r
+	rec.prefix = prefix.Masked()
+	rec.network = geodbtools.IPNetFromPrefix(rec.prefix)
+	return &rec
+}
+
+func (r *cityRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *cityRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
+func (r *cityRecord) GetCountryCode() string {
+	return r.countryCode
+}
+
+func (r *cityRecord) GetRegionCode() string {
+	return r.regionCode
+}
+
+func (r *cityRecord) GetCityName() string {
+	return r.cityName
+}
+
+func (r *cityRecord) GetPostalCode() string {
+	return r.postalCode
+}
+
+func (r *cityRecord) GetLatitude() float64 {
+	return r.latitude
+}
+
+func (r *cityRecord) GetLongitude() float64 {
+	return r.longitude
+}
+
+func (r *cityRecord) GetMetroCode() int {
+	return r.metroCode
+}
+
+func (r *cityRecord) GetAreaCode() int {
+	return r.areaCode
+}
+
+func (r *cityRecord) String() string {
+	return fmt.Sprintf("%s: country code %s, region %s, city %s, postal code %s, location %.4f,%.4f",
+		r.network, r.countryCode, r.regionCode, r.cityName, r.postalCode, r.latitude, r.longitude)
+}
+
+var _ geodbtools.ASNRecord = (*organizationRecord)(nil)
+var _ geodbtools.PrefixRecord = (*organizationRecord)(nil)
+var _ segmentRecord = (*organizationRecord)(nil)
+
+type organizationRecord struct {
+	prefix       netip.Prefix
+	network      *net.IPNet
+	asNumber     uint32
+	organization string
+}
+
+func (r *organizationRecord) withPrefix(prefix netip.Prefix) geodbtools.Record {
+	rec := *r
+	rec.prefix = prefix.Masked()
+	rec.network = geodbtools.IPNetFromPrefix(rec.prefix)
+	return &rec
+}
+
+func (r *organizationRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *organizationRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
+func (r *organizationRecord) GetASNumber() uint32 {
+	return r.asNumber
+}
+
+func (r *organizationRecord) GetOrganization() string {
+	return r.organization
+}
+
+func (r *organizationRecord) String() string {
+	if r.asNumber != 0 {
+		return fmt.Sprintf("%s: AS%d, organization %s", r.network, r.asNumber, r.organization)
+	}
+
+	return fmt.Sprintf("%s: organization %s", r.network, r.organization)
+}
@@ -2,6 +2,7 @@ package mmdatformat
 
 import (
 	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -18,6 +19,24 @@ func TestCountryRecord_GetNetwork(t *testing.T) {
 	assert.EqualValues(t, network, rec.GetNetwork())
 }
 
+func TestCountryRecord_GetPrefix(t *testing.T) {
+	t.Run("Prefix", func(t *testing.T) {
+		rec := newCountryRecord(netip.MustParsePrefix("127.1.2.3/8"), "XX")
+		assert.EqualValues(t, netip.MustParsePrefix("127.0.0.0/8"), rec.GetPrefix())
+		assert.EqualValues(t, "127.0.0.0/8", rec.GetNetwork().String())
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		_, network, err := net.ParseCIDR("127.0.0.0/8")
+		require.NoError(t, err)
+		rec := &countryRecord{
+			network: network,
+		}
+
+		assert.EqualValues(t, netip.MustParsePrefix("127.0.0.0/8"), rec.GetPrefix())
+	})
+}
+
 func TestCountryRecord_GetRecordKey(t *testing.T) {
 	_, network, err := net.ParseCIDR("127.1.2.3/32")
 	require.NoError(t, err)
@@ -46,3 +65,123 @@ func TestCountryRecord_String(t *testing.T) {
 
 	assert.EqualValues(t, "127.0.0.127/32: country code XX", rec.String())
 }
+
+func TestCityRecord_Getters(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	rec := &cityRecord{
+		network:     network,
+		countryCode: "AT",
+		regionCode:  "09",
+		cityName:    "Wien",
+		postalCode:  "1010",
+		latitude:    48.2,
+		longitude:   16.3667,
+		metroCode:   807,
+		areaCode:    415,
+	}
+
+	assert.EqualValues(t, network, rec.GetNetwork())
+	assert.EqualValues(t, "AT", rec.GetCountryCode())
+	assert.EqualValues(t, "09", rec.GetRegionCode())
+	assert.EqualValues(t, "Wien", rec.GetCityName())
+	assert.EqualValues(t, "1010", rec.GetPostalCode())
+	assert.EqualValues(t, 48.2, rec.GetLatitude())
+	assert.EqualValues(t, 16.3667, rec.GetLongitude())
+	assert.EqualValues(t, 807, rec.GetMetroCode())
+	assert.EqualValues(t, 415, rec.GetAreaCode())
+}
+
+func TestCityRecord_withPrefix(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	_, otherNetwork, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	rec := &cityRecord{
+		network:  network,
+		cityName: "Wien",
+	}
+
+	other := rec.withPrefix(netip.MustParsePrefix("10.1.2.3/8"))
+	if assert.IsType(t, &cityRecord{}, other) {
+		assert.EqualValues(t, otherNetwork, other.GetNetwork())
+		assert.EqualValues(t, netip.MustParsePrefix("10.0.0.0/8"), other.(*cityRecord).GetPrefix())
+		assert.EqualValues(t, "Wien", other.(*cityRecord).cityName)
+	}
+	assert.EqualValues(t, network, rec.GetNetwork())
+}
+
+func TestCityRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+	rec := &cityRecord{
+		network:     network,
+		countryCode: "AT",
+		regionCode:  "09",
+		cityName:    "Wien",
+		postalCode:  "1010",
+		latitude:    48.2,
+		longitude:   16.3667,
+	}
+
+	assert.EqualValues(t, "127.0.0.127/32: country code AT, region 09, city Wien, postal code 1010, location 48.2000,16.3667", rec.String())
+}
+
+func TestOrganizationRecord_Getters(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	rec := &organizationRecord{
+		network:      network,
+		asNumber:     64512,
+		organization: "Test Organization",
+	}
+
+	assert.EqualValues(t, network, rec.GetNetwork())
+	assert.EqualValues(t, 64512, rec.GetASNumber())
+	assert.EqualValues(t, "Test Organization", rec.GetOrganization())
+}
+
+func TestOrganizationRecord_withPrefix(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+	_, otherNetwork, err := net.ParseCIDR("10.0.0.0/8")
+	require.NoError(t, err)
+
+	rec := &organizationRecord{
+		network:      network,
+		organization: "Test Organization",
+	}
+
+	other := rec.withPrefix(netip.MustParsePrefix("10.1.2.3/8"))
+	if assert.IsType(t, &organizationRecord{}, other) {
+		assert.EqualValues(t, otherNetwork, other.GetNetwork())
+		assert.EqualValues(t, netip.MustParsePrefix("10.0.0.0/8"), other.(*organizationRecord).GetPrefix())
+		assert.EqualValues(t, "Test Organization", other.(*organizationRecord).organization)
+	}
+	assert.EqualValues(t, network, rec.GetNetwork())
+}
+
+func TestOrganizationRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+
+	t.Run("Organization", func(t *testing.T) {
+		rec := &organizationRecord{
+			network:      network,
+			organization: "Test Organization",
+		}
+
+		assert.EqualValues(t, "127.0.0.127/32: organization Test Organization", rec.String())
+	})
+
+	t.Run("ASNumber", func(t *testing.T) {
+		rec := &organizationRecord{
+			network:      network,
+			asNumber:     64512,
+			organization: "Test Organization",
+		}
+
+		assert.EqualValues(t, "127.0.0.127/32: AS64512, organization Test Organization", rec.String())
+	})
+}
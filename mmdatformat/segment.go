@@ -0,0 +1,370 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+// segmentRecord describes a record that is stored inside the data segment following the search tree
+type segmentRecord interface {
+	geodbtools.Record
+
+	// withPrefix returns a copy of the record, representing the given prefix
+	withPrefix(prefix netip.Prefix) geodbtools.Record
+}
+
+// segmentRecordDecoder decodes the record stored at the given offset of the source
+type segmentRecordDecoder func(source geodbtools.ReaderSource, offset int64) (record segmentRecord, err error)
+
+// segmentRecordEncoder encodes a record for storage inside the data segment
+type segmentRecordEncoder func(record geodbtools.Record) (b []byte, err error)
+
+// readDatabaseSegments reads the number of database segments (the search tree's node count) from the structure info
+func readDatabaseSegments(source geodbtools.ReaderSource) (segments uint32, err error) {
+	dataSize := source.Size()
+	if dataSize < structureInfoMaxSize {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	structInfoBytes := make([]byte, structureInfoMaxSize)
+	if _, err = source.ReadAt(structInfoBytes, dataSize-structureInfoMaxSize); err != nil {
+		return
+	}
+
+	structInfoStart := bytes.LastIndex(structInfoBytes, []byte{0xff, 0xff, 0xff})
+	if structInfoStart < 0 || structInfoStart+7 > len(structInfoBytes) {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	return DecodeRecordUint32(structInfoBytes[structInfoStart+4:], 3)
+}
+
+// readSegmentData reads up to maxLength bytes of record data, starting at the given offset
+func readSegmentData(source geodbtools.ReaderSource, offset int64, maxLength int64) (b []byte, err error) {
+	size := source.Size()
+	if offset < 0 || offset >= size {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	if offset+maxLength > size {
+		maxLength = size - offset
+	}
+
+	return readSource(source, offset, int(maxLength), nil)
+}
+
+var _ geodbtools.AddrReader = (*segmentReader)(nil)
+var _ geodbtools.IterableReader = (*segmentReader)(nil)
+
+// segmentReader implements a reader for database types that store their records inside a data segment
+type segmentReader struct {
+	source       geodbtools.ReaderSource
+	dbType       DatabaseTypeID
+	segments     uint32
+	recordLength int
+	bitCount     uint
+	decodeRecord segmentRecordDecoder
+
+	recordTreeMu sync.Mutex
+	recordTree   *geodbtools.RecordTree
+}
+
+// dataOffset returns the position of the record referenced by the given search tree value inside the source
+func (r *segmentReader) dataOffset(value uint32) int64 {
+	return int64(value-r.segments) + int64(2*r.recordLength)*int64(r.segments)
+}
+
+// readNode reads both records of the search tree node with the given number
+func (r *segmentReader) readNode(node uint32, buf []byte) (left, right uint32, err error) {
+	var b []byte
+	if b, err = readSource(r.source, int64(node)*int64(len(buf)), len(buf), buf); err != nil {
+		return
+	}
+
+	if left, err = DecodeRecordUint32(b, r.recordLength); err != nil {
+		return
+	}
+	right, err = DecodeRecordUint32(b[r.recordLength:], r.recordLength)
+	return
+}
+
+func (r *segmentReader) buildTree() (err error) {
+	type pendingNode struct {
+		node  uint32
+		depth uint
+		addr  [16]byte
+	}
+
+	nodes := []pendingNode{{}}
+
+	var records []geodbtools.Record
+	decodedRecords := make(map[uint32]segmentRecord)
+
+	buf := make([]byte, 2*r.recordLength)
+	for len(nodes) > 0 {
+		cur := nodes[0]
+		nodes = nodes[1:]
+
+		var values [2]uint32
+		if values[0], values[1], err = r.readNode(cur.node, buf); err != nil {
+			return
+		}
+
+		for i, value := range values {
+			addr := cur.addr
+			if i == 1 {
+				addr[cur.depth>>3] |= 0x80 >> (cur.depth & 7)
+			}
+
+			if value < r.segments {
+				if cur.depth+1 >= r.bitCount {
+					err = geodbtools.ErrDatabaseInvalid
+					return
+				}
+
+				nodes = append(nodes, pendingNode{
+					node:  value,
+					depth: cur.depth + 1,
+					addr:  addr,
+				})
+				continue
+			} else if value == r.segments {
+				// no record for this network
+				continue
+			}
+
+			record, decoded := decodedRecords[value]
+			if !decoded {
+				if record, err = r.decodeRecord(r.source, r.dataOffset(value)); err != nil {
+					return
+				}
+				decodedRecords[value] = record
+			}
+
+			records = append(records, record.withPrefix(netip.PrefixFrom(bytesAddr(addr, r.bitCount), int(cur.depth+1))))
+		}
+	}
+
+	r.recordTree, err = geodbtools.NewPrefixRecordTree(records)
+	return
+}
+
+func (r *segmentReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
+	r.recordTreeMu.Lock()
+	defer r.recordTreeMu.Unlock()
+	if r.recordTree == nil {
+		err = r.buildTree()
+	}
+
+	if err == nil {
+		tree = r.recordTree
+	}
+	return
+}
+
+// Networks walks the search tree, reporting all networks matching the given filter
+func (r *segmentReader) Networks(filter geodbtools.NetworkFilter) geodbtools.NetworkIterator {
+	return geodbtools.NewSearchTreeIterator(&segmentSearchTree{
+		r:   r,
+		buf: make([]byte, r.recordLength),
+	}, filter)
+}
+
+var _ geodbtools.SearchTree = (*segmentSearchTree)(nil)
+
+// segmentSearchTree provides the search tree of a segment database to geodbtools.NewSearchTreeIterator
+type segmentSearchTree struct {
+	r   *segmentReader
+	buf []byte
+}
+
+func (t *segmentSearchTree) IPVersion() geodbtools.IPVersion {
+	if t.r.bitCount == 128 {
+		return geodbtools.IPVersion6
+	}
+	return geodbtools.IPVersion4
+}
+
+func (t *segmentSearchTree) NodeCount() uint {
+	return uint(t.r.segments)
+}
+
+func (t *segmentSearchTree) ReadNode(node uint, index uint) (value uint, err error) {
+	var b []byte
+	if b, err = readSource(t.r.source, int64(2*node+index)*int64(t.r.recordLength), t.r.recordLength, t.buf); err != nil {
+		return
+	}
+
+	// records are stored in little-endian byte order
+	for i := len(b) - 1; i >= 0; i-- {
+		value = value<<8 | uint(b[i])
+	}
+	return
+}
+
+func (t *segmentSearchTree) ReadLeaf(value uint, prefix netip.Prefix) (record geodbtools.Record, err error) {
+	if value == uint(t.r.segments) {
+		// no record for this network
+		return
+	}
+
+	var segRecord segmentRecord
+	if segRecord, err = t.r.decodeRecord(t.r.source, t.r.dataOffset(uint32(value))); err != nil {
+		return
+	}
+
+	record = segRecord.withPrefix(prefix)
+	return
+}
+
+// LookupIP looks up the given IP address using LookupAddr
+func (r *segmentReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	addr, _ := netip.AddrFromSlice(ip)
+	return r.LookupAddr(addr)
+}
+
+func (r *segmentReader) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	if addr = normalizeAddr(addr, r.bitCount); !addr.IsValid() {
+		err = geodbtools.ErrRecordNotFound
+		return
+	}
+
+	ip := addrBytes(addr)
+	var node uint32
+	buf := make([]byte, 2*r.recordLength)
+	for depth := uint(0); depth < r.bitCount; depth++ {
+		var left, right uint32
+		if left, right, err = r.readNode(node, buf); err != nil {
+			return
+		}
+
+		node = left
+		if ip[depth>>3]&(0x80>>(depth&7)) != 0 {
+			node = right
+		}
+
+		if node < r.segments {
+			continue
+		} else if node == r.segments {
+			break
+		}
+
+		var segRecord segmentRecord
+		if segRecord, err = r.decodeRecord(r.source, r.dataOffset(node)); err != nil {
+			return
+		}
+
+		record = segRecord.withPrefix(netip.PrefixFrom(addr, int(depth+1)))
+		return
+	}
+
+	err = geodbtools.ErrRecordNotFound
+	return
+}
+
+var _ geodbtools.CompactingWriter = (*segmentWriter)(nil)
+
+// segmentWriter implements a writer for database types that store their records inside a data segment
+type segmentWriter struct {
+	w            io.Writer
+	typeID       DatabaseTypeID
+	recordLength int
+	encodeRecord segmentRecordEncoder
+}
+
+// CompactTree opts in to compaction, as each node of the search tree is written
+func (w *segmentWriter) CompactTree() bool {
+	return true
+}
+
+func (w *segmentWriter) WriteDatabase(meta geodbtools.Metadata, tree *geodbtools.RecordTree) (err error) {
+	if tree == nil {
+		tree = &geodbtools.RecordTree{}
+	}
+
+	// number all nodes of the search tree, as the data segment starts right after the last node
+	nodes := []*geodbtools.RecordTree{tree}
+	nodeNumbers := map[*geodbtools.RecordTree]uint32{
+		tree: 0,
+	}
+
+	for i := 0; i < len(nodes); i++ {
+		for _, child := range []*geodbtools.RecordTree{nodes[i].Left(), nodes[i].Right()} {
+			if child != nil && child.Leaf() == nil {
+				nodeNumbers[child] = uint32(len(nodes))
+				nodes = append(nodes, child)
+			}
+		}
+	}
+
+	segments := uint32(len(nodes))
+	maxValue := uint64(1)<<uint(8*w.recordLength) - 1
+
+	// the first byte of the data segment is reserved, as a pointer to it would be equal to segments,
+	// which denotes an empty record
+	data := bytes.NewBuffer([]byte{0x00})
+	dataOffsets := make(map[string]uint32)
+
+	for _, node := range nodes {
+		var pair []byte
+
+		for _, child := range []*geodbtools.RecordTree{node.Left(), node.Right()} {
+			value := segments
+
+			if child != nil {
+				if leaf := child.Leaf(); leaf == nil {
+					value = nodeNumbers[child]
+				} else {
+					var b []byte
+					if b, err = w.encodeRecord(leaf); err != nil {
+						return
+					}
+
+					if len(b) > 0 {
+						offset, exists := dataOffsets[string(b)]
+						if !exists {
+							offset = uint32(data.Len())
+							dataOffsets[string(b)] = offset
+							data.Write(b)
+						}
+						value = segments + offset
+					}
+				}
+			}
+
+			if uint64(value) > maxValue {
+				err = ErrDatabaseTooLarge
+				return
+			}
+
+			var rec []byte
+			if rec, err = EncodeRecord(value, w.recordLength); err != nil {
+				return
+			}
+			pair = append(pair, rec...)
+		}
+
+		if _, err = w.w.Write(pair); err != nil {
+			return
+		}
+	}
+
+	if _, err = w.w.Write(data.Bytes()); err != nil {
+		return
+	}
+
+	var segmentsBytes []byte
+	if segmentsBytes, err = EncodeRecord(segments, 3); err != nil {
+		return
+	}
+
+	return writeDatabaseInfo(w.w, w.typeID, meta, segmentsBytes)
+}
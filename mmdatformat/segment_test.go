@@ -0,0 +1,360 @@
+package mmdatformat
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadDatabaseSegments(t *testing.T) {
+	t.Run("SourceTooSmall", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().Size().Return(int64(structureInfoMaxSize - 1))
+
+		segments, err := readDatabaseSegments(source)
+		assert.EqualValues(t, 0, segments)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("ReadError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().Size().Return(int64(structureInfoMaxSize))
+		source.EXPECT().ReadAt(gomock.Any(), int64(0)).Return(0, testErr)
+
+		segments, err := readDatabaseSegments(source)
+		assert.EqualValues(t, 0, segments)
+		assert.EqualError(t, err, testErr.Error())
+	})
+
+	t.Run("StructureInfoNotFound", func(t *testing.T) {
+		data := make([]byte, structureInfoMaxSize)
+		segments, err := readDatabaseSegments(&testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		})
+		assert.EqualValues(t, 0, segments)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("StructureInfoTruncated", func(t *testing.T) {
+		data := append(make([]byte, structureInfoMaxSize-5), 0xff, 0xff, 0xff, byte(DatabaseTypeIDCityEditionRev1), 0x01)
+		segments, err := readDatabaseSegments(&testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		})
+		assert.EqualValues(t, 0, segments)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		data := append(make([]byte, structureInfoMaxSize+10), 0xff, 0xff, 0xff, byte(DatabaseTypeIDCityEditionRev1), 0x01, 0x02, 0x03)
+		segments, err := readDatabaseSegments(&testReaderSource{
+			Reader: bytes.NewReader(data),
+			size:   int64(len(data)),
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0x030201, segments)
+	})
+}
+
+func TestReadSegmentData(t *testing.T) {
+	source := &testReaderSource{
+		Reader: bytes.NewReader([]byte{0x01, 0x02, 0x03, 0x04}),
+		size:   4,
+	}
+
+	t.Run("NegativeOffset", func(t *testing.T) {
+		b, err := readSegmentData(source, -1, 2)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("OffsetOutOfBounds", func(t *testing.T) {
+		b, err := readSegmentData(source, 4, 2)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("ReadError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().Size().Return(int64(4))
+		source.EXPECT().ReadAt(gomock.Any(), int64(1)).Return(0, testErr)
+
+		b, err := readSegmentData(source, 1, 2)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, testErr.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		b, err := readSegmentData(source, 1, 2)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{0x02, 0x03}, b)
+	})
+
+	t.Run("Truncated", func(t *testing.T) {
+		b, err := readSegmentData(source, 2, 10)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{0x03, 0x04}, b)
+	})
+}
+
+// testSegmentRecordEncoder encodes a record as its city name
+func testSegmentRecordEncoder(record geodbtools.Record) (b []byte, err error) {
+	return []byte(record.(geodbtools.CityRecord).GetCityName()), nil
+}
+
+func TestSegmentWriter_WriteDatabase(t *testing.T) {
+	meta := geodbtools.Metadata{
+		Description: "test",
+	}
+
+	t.Run("NilTree", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w := &segmentWriter{
+			w:            buf,
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: standardRecordLength,
+			encodeRecord: testSegmentRecordEncoder,
+		}
+
+		assert.NoError(t, w.WriteDatabase(meta, nil))
+		// a single node pointing to the empty record, followed by the reserved data byte
+		assert.EqualValues(t, []byte{0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00}, buf.Bytes()[:7])
+		assert.EqualValues(t, []byte{0xff, 0xff, 0xff, byte(DatabaseTypeIDCityEditionRev1), 0x01, 0x00, 0x00}, buf.Bytes()[buf.Len()-7:])
+	})
+
+	t.Run("EncodeError", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{
+			testCityRecord(t, "1.0.0.0/8", "AT", "Wien"),
+		}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		testErr := errors.New("test error")
+		w := &segmentWriter{
+			w:            bytes.NewBufferString(""),
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: standardRecordLength,
+			encodeRecord: func(geodbtools.Record) ([]byte, error) {
+				return nil, testErr
+			},
+		}
+
+		assert.EqualError(t, w.WriteDatabase(meta, tree), testErr.Error())
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		writer := NewMockWriter(ctrl)
+		writer.EXPECT().Write(gomock.Any()).Return(0, testErr)
+
+		w := &segmentWriter{
+			w:            writer,
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: standardRecordLength,
+			encodeRecord: testSegmentRecordEncoder,
+		}
+
+		assert.EqualError(t, w.WriteDatabase(meta, nil), testErr.Error())
+	})
+
+	t.Run("DataWriteError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		writer := NewMockWriter(ctrl)
+		gomock.InOrder(
+			writer.EXPECT().Write(gomock.Any()).Return(6, nil),
+			writer.EXPECT().Write([]byte{0x00}).Return(0, testErr),
+		)
+
+		w := &segmentWriter{
+			w:            writer,
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: standardRecordLength,
+			encodeRecord: testSegmentRecordEncoder,
+		}
+
+		assert.EqualError(t, w.WriteDatabase(meta, nil), testErr.Error())
+	})
+
+	t.Run("DatabaseTooLarge", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{
+			testCityRecord(t, "0.0.0.0/2", "AT", string(bytes.Repeat([]byte{'a'}, 300))),
+			testCityRecord(t, "64.0.0.0/2", "AT", string(bytes.Repeat([]byte{'b'}, 200))),
+		}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		w := &segmentWriter{
+			w:            bytes.NewBufferString(""),
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: 1,
+			encodeRecord: testSegmentRecordEncoder,
+		}
+
+		assert.EqualError(t, w.WriteDatabase(meta, tree), ErrDatabaseTooLarge.Error())
+	})
+
+	t.Run("DeduplicatesRecords", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{
+			testCityRecord(t, "0.0.0.0/2", "AT", "Wien"),
+			testCityRecord(t, "64.0.0.0/2", "AT", "Wien"),
+			testCityRecord(t, "128.0.0.0/1", "AT", ""),
+		}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w := &segmentWriter{
+			w:            buf,
+			typeID:       DatabaseTypeIDCityEditionRev1,
+			recordLength: standardRecordLength,
+			encodeRecord: testSegmentRecordEncoder,
+		}
+
+		require.NoError(t, w.WriteDatabase(meta, tree))
+		assert.EqualValues(t, []byte{
+			// node 0: left points to node 1, right record encodes to nothing
+			0x01, 0x00, 0x00, 0x02, 0x00, 0x00,
+			// node 1: both records point to the same data
+			0x03, 0x00, 0x00, 0x03, 0x00, 0x00,
+			// data segment
+			0x00, 'W', 'i', 'e', 'n',
+		}, buf.Bytes()[:17])
+	})
+}
+
+func TestSegmentReader_RecordTree(t *testing.T) {
+	t.Run("ReadError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().ReadAt(gomock.Any(), int64(0)).Return(0, testErr)
+
+		r := &segmentReader{
+			source:       source,
+			segments:     1,
+			recordLength: standardRecordLength,
+			bitCount:     32,
+			decodeRecord: decodeCityRecord(true),
+		}
+
+		tree, err := r.RecordTree(geodbtools.IPVersion4)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, testErr.Error())
+	})
+
+	t.Run("TreeTooDeep", func(t *testing.T) {
+		// node 0 points to itself on the left side
+		data := []byte{0x00, 0x00, 0x00, 0x01, 0x00, 0x00}
+		r := &segmentReader{
+			source: &testReaderSource{
+				Reader: bytes.NewReader(data),
+				size:   int64(len(data)),
+			},
+			segments:     1,
+			recordLength: standardRecordLength,
+			bitCount:     32,
+			decodeRecord: decodeCityRecord(true),
+		}
+
+		tree, err := r.RecordTree(geodbtools.IPVersion4)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("DecodeError", func(t *testing.T) {
+		data := []byte{0x01, 0x00, 0x00, 0x10, 0x00, 0x00}
+		r := &segmentReader{
+			source: &testReaderSource{
+				Reader: bytes.NewReader(data),
+				size:   int64(len(data)),
+			},
+			segments:     1,
+			recordLength: standardRecordLength,
+			bitCount:     32,
+			decodeRecord: decodeCityRecord(true),
+		}
+
+		tree, err := r.RecordTree(geodbtools.IPVersion4)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+}
+
+func TestSegmentReader_LookupIP(t *testing.T) {
+	t.Run("IPv6OnIPv4Database", func(t *testing.T) {
+		r := &segmentReader{
+			bitCount: 32,
+		}
+
+		record, err := r.LookupIP(net.ParseIP("::1"))
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+
+	t.Run("ReadError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().ReadAt(gomock.Any(), int64(0)).Return(0, testErr)
+
+		r := &segmentReader{
+			source:       source,
+			segments:     1,
+			recordLength: standardRecordLength,
+			bitCount:     32,
+			decodeRecord: decodeCityRecord(true),
+		}
+
+		record, err := r.LookupIP(net.ParseIP("127.0.0.1"))
+		assert.Nil(t, record)
+		assert.EqualError(t, err, testErr.Error())
+	})
+
+	t.Run("DecodeError", func(t *testing.T) {
+		data := []byte{0x10, 0x00, 0x00, 0x01, 0x00, 0x00}
+		r := &segmentReader{
+			source: &testReaderSource{
+				Reader: bytes.NewReader(data),
+				size:   int64(len(data)),
+			},
+			segments:     1,
+			recordLength: standardRecordLength,
+			bitCount:     32,
+			decodeRecord: decodeCityRecord(true),
+		}
+
+		record, err := r.LookupIP(net.ParseIP("127.0.0.1"))
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+}
+
+func TestSegmentWriter_CompactTree(t *testing.T) {
+	assert.True(t, (&segmentWriter{}).CompactTree())
+}
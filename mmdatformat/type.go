@@ -18,8 +18,28 @@ const (
 	DatabaseTypeIDBase DatabaseTypeID = 105
 	// DatabaseTypeIDCountryEdition is an IPv4 country database
 	DatabaseTypeIDCountryEdition = DatabaseTypeIDBase + 1
+	// DatabaseTypeIDCityEditionRev1 is an IPv4 city database, including metro and area codes
+	DatabaseTypeIDCityEditionRev1 = DatabaseTypeIDBase + 2
+	// DatabaseTypeIDISPEdition is an IPv4 ISP database
+	DatabaseTypeIDISPEdition = DatabaseTypeIDBase + 4
+	// DatabaseTypeIDOrgEdition is an IPv4 organization database
+	DatabaseTypeIDOrgEdition = DatabaseTypeIDBase + 5
+	// DatabaseTypeIDCityEditionRev0 is an IPv4 city database
+	DatabaseTypeIDCityEditionRev0 = DatabaseTypeIDBase + 6
+	// DatabaseTypeIDASNumEdition is an IPv4 AS number database
+	DatabaseTypeIDASNumEdition = DatabaseTypeIDBase + 9
 	// DatabaseTypeIDCountryEditionV6 is an IPv6 country database
 	DatabaseTypeIDCountryEditionV6 = DatabaseTypeIDBase + 12
+	// DatabaseTypeIDASNumEditionV6 is an IPv6 AS number database
+	DatabaseTypeIDASNumEditionV6 = DatabaseTypeIDBase + 21
+	// DatabaseTypeIDISPEditionV6 is an IPv6 ISP database
+	DatabaseTypeIDISPEditionV6 = DatabaseTypeIDBase + 22
+	// DatabaseTypeIDOrgEditionV6 is an IPv6 organization database
+	DatabaseTypeIDOrgEditionV6 = DatabaseTypeIDBase + 23
+	// DatabaseTypeIDCityEditionRev1V6 is an IPv6 city database, including metro and area codes
+	DatabaseTypeIDCityEditionRev1V6 = DatabaseTypeIDBase + 30
+	// DatabaseTypeIDCityEditionRev0V6 is an IPv6 city database
+	DatabaseTypeIDCityEditionRev0V6 = DatabaseTypeIDBase + 31
 )
 
 const (
@@ -3,7 +3,11 @@ package mmdatformat
 import (
 	"bytes"
 	"encoding/binary"
+	"io"
+	"net/netip"
 	"unicode"
+
+	"github.com/anexia-it/geodbtools"
 )
 
 // EncodeRecord encodes the given value as a record of the given length
@@ -38,6 +42,11 @@ func DecodeRecordUint32(b []byte, length int) (record uint32, err error) {
 	return
 }
 
+// decodeStandardRecord decodes a search tree record of standard length without allocating memory
+func decodeStandardRecord(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
 func reverseBytes(b []byte) (reversed []byte) {
 	reversed = make([]byte, len(b))
 	for i := 0; i < len(b); i++ {
@@ -58,3 +67,87 @@ func ContainsOnlyNumericCharacters(s string) bool {
 
 	return true
 }
+
+// DecodeLatin1 decodes an ISO-8859-1 encoded byte-slice
+func DecodeLatin1(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+
+	return string(runes)
+}
+
+// EncodeLatin1 encodes a string using ISO-8859-1.
+// Characters that cannot be represented are replaced by a question mark.
+func EncodeLatin1(s string) []byte {
+	b := make([]byte, 0, len(s))
+	for _, r := range s {
+		if r > 0xff {
+			r = '?'
+		}
+		b = append(b, byte(r))
+	}
+
+	return b
+}
+
+// readSource reads length bytes from the given source, starting at the given offset.
+// Sources providing a byte view are accessed directly, returning a slice of their data.
+// All other sources are read into buf, which is allocated if it is too small.
+func readSource(source geodbtools.ReaderSource, offset int64, length int, buf []byte) (b []byte, err error) {
+	if bytesSource, ok := source.(geodbtools.BytesReaderSource); ok {
+		data := bytesSource.Bytes()
+		end := offset + int64(length)
+		if data == nil {
+			err = geodbtools.ErrReaderClosed
+		} else if offset < 0 {
+			err = geodbtools.ErrInvalidOffset
+		} else if end > int64(len(data)) {
+			err = io.EOF
+		} else {
+			b = data[offset:end:end]
+		}
+		return
+	}
+
+	if len(buf) < length {
+		buf = make([]byte, length)
+	}
+
+	if _, err = source.ReadAt(buf[:length], offset); err == nil {
+		b = buf[:length]
+	}
+	return
+}
+
+// normalizeAddr returns the given address in the representation used by search trees of the given bit count.
+// The zero Addr is returned if the address cannot be looked up in such a search tree.
+func normalizeAddr(addr netip.Addr, bitCount uint) netip.Addr {
+	if !addr.IsValid() {
+		return netip.Addr{}
+	} else if bitCount == 128 {
+		return netip.AddrFrom16(addr.As16())
+	} else if addr = addr.Unmap(); !addr.Is4() {
+		return netip.Addr{}
+	}
+	return addr
+}
+
+// addrBytes returns the bytes of the given address, with IPv4 addresses occupying the first four bytes
+func addrBytes(addr netip.Addr) (b [16]byte) {
+	if addr.Is4() {
+		a4 := addr.As4()
+		copy(b[:], a4[:])
+		return
+	}
+	return addr.As16()
+}
+
+// bytesAddr returns the address stored in the given bytes, using the first four bytes for IPv4 addresses
+func bytesAddr(b [16]byte, bitCount uint) netip.Addr {
+	if bitCount == 32 {
+		return netip.AddrFrom4([4]byte{b[0], b[1], b[2], b[3]})
+	}
+	return netip.AddrFrom16(b)
+}
@@ -1,8 +1,13 @@
 package mmdatformat
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"testing"
 
+	"github.com/anexia-it/geodbtools"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -113,3 +118,80 @@ func TestContainsOnlyNumericCharacters(t *testing.T) {
 	})
 
 }
+
+func TestDecodeLatin1(t *testing.T) {
+	assert.EqualValues(t, "", DecodeLatin1(nil))
+	assert.EqualValues(t, "Wien", DecodeLatin1([]byte("Wien")))
+	assert.EqualValues(t, "Zürich", DecodeLatin1([]byte{'Z', 0xfc, 'r', 'i', 'c', 'h'}))
+}
+
+func TestEncodeLatin1(t *testing.T) {
+	assert.EqualValues(t, []byte{}, EncodeLatin1(""))
+	assert.EqualValues(t, []byte("Wien"), EncodeLatin1("Wien"))
+	assert.EqualValues(t, []byte{'Z', 0xfc, 'r', 'i', 'c', 'h'}, EncodeLatin1("Zürich"))
+	assert.EqualValues(t, []byte{'?', 'x'}, EncodeLatin1("€x"))
+}
+
+func TestReadSource(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04}
+
+	t.Run("BytesReaderSource", func(t *testing.T) {
+		source := geodbtools.NewBytesReaderSource(data)
+
+		b, err := readSource(source, 1, 2, nil)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{0x02, 0x03}, b)
+
+		// the returned slice is a view of the source's data
+		assert.True(t, &b[0] == &source.(geodbtools.BytesReaderSource).Bytes()[1])
+		assert.EqualValues(t, 2, cap(b))
+	})
+
+	t.Run("BytesReaderSourceInvalidOffset", func(t *testing.T) {
+		b, err := readSource(geodbtools.NewBytesReaderSource(data), -1, 2, nil)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrInvalidOffset.Error())
+	})
+
+	t.Run("BytesReaderSourceEOF", func(t *testing.T) {
+		b, err := readSource(geodbtools.NewBytesReaderSource(data), 3, 2, nil)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, io.EOF.Error())
+	})
+
+	t.Run("BytesReaderSourceClosed", func(t *testing.T) {
+		source := geodbtools.NewBytesReaderSource(data)
+		assert.NoError(t, source.Close())
+
+		b, err := readSource(source, 1, 2, nil)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, geodbtools.ErrReaderClosed.Error())
+	})
+
+	t.Run("ReaderSource", func(t *testing.T) {
+		buf := make([]byte, 8)
+		b, err := readSource(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data))), 1, 2, buf)
+		assert.NoError(t, err)
+		assert.EqualValues(t, []byte{0x02, 0x03}, b)
+		assert.True(t, &b[0] == &buf[0])
+	})
+
+	t.Run("ReaderSourceAllocatesBuffer", func(t *testing.T) {
+		b, err := readSource(geodbtools.NewReaderSourceWrapper(bytes.NewReader(data), int64(len(data))), 0, 4, nil)
+		assert.NoError(t, err)
+		assert.EqualValues(t, data, b)
+	})
+
+	t.Run("ReaderSourceError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		source := NewMockReaderSource(ctrl)
+		source.EXPECT().ReadAt(gomock.Any(), int64(1)).Return(0, testErr)
+
+		b, err := readSource(source, 1, 2, nil)
+		assert.Nil(t, b)
+		assert.EqualError(t, err, testErr.Error())
+	})
+}
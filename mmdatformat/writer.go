@@ -7,7 +7,7 @@ import (
 	"github.com/anexia-it/geodbtools"
 )
 
-var _ geodbtools.Writer = (*writer)(nil)
+var _ geodbtools.CompactingWriter = (*writer)(nil)
 
 type writer struct {
 	w      io.Writer
@@ -44,25 +44,36 @@ func (w *writer) WriteDatabase(meta geodbtools.Metadata, tree *geodbtools.Record
 		}
 	}
 
+	return writeDatabaseInfo(w.w, w.typeID, meta, nil)
+}
+
+// CompactTree opts in to compaction, as each node of the search tree is written
+func (w *writer) CompactTree() bool {
+	return true
+}
+
+// writeDatabaseInfo writes the database info and the structure info, including optional additional
+// structure info bytes
+func writeDatabaseInfo(w io.Writer, typeID DatabaseTypeID, meta geodbtools.Metadata, additionalStructureInfo []byte) (err error) {
 	// metadata
-	if _, err = w.w.Write([]byte{0x00, 0x00, 0x00}); err != nil {
+	if _, err = w.Write([]byte{0x00, 0x00, 0x00}); err != nil {
 		return
 	}
 
 	metaRecord := fmt.Sprintf("GEO-%d %04d%02d%02d %s",
-		w.typeID,
+		typeID,
 		meta.BuildTime.Year(),
 		meta.BuildTime.Month(),
 		meta.BuildTime.Day(),
 		meta.Description,
 	)
-	if _, err = w.w.Write([]byte(metaRecord)); err != nil {
+	if _, err = w.Write([]byte(metaRecord)); err != nil {
 		return
 	}
 
 	// structure info
-	if _, err = w.w.Write([]byte{0xff, 0xff, 0xff,
-		byte(w.typeID)}); err != nil {
+	if _, err = w.Write(append([]byte{0xff, 0xff, 0xff,
+		byte(typeID)}, additionalStructureInfo...)); err != nil {
 		return
 	}
 
@@ -5,11 +5,13 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"net/netip"
 	"testing"
 	"time"
 
 	"github.com/anexia-it/bitmap"
 	"github.com/anexia-it/geodbtools"
+	_ "github.com/anexia-it/geodbtools/mmdbformat"
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -44,7 +46,7 @@ func TestWriter_WriteDatabase(t *testing.T) {
 		}
 
 		err = w.WriteDatabase(geodbtools.Metadata{}, root)
-		assert.EqualError(t, err, ErrUnsupportedRecordType.Error())
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
 		assert.Empty(t, buf.Bytes())
 	})
 
@@ -341,3 +343,60 @@ func TestWriter_WriteDatabase(t *testing.T) {
 		assert.EqualValues(t, expectedContents, buf.Bytes())
 	})
 }
+
+func TestWriter_CompactTree(t *testing.T) {
+	assert.True(t, (&writer{}).CompactTree())
+}
+
+func TestWriteDatabase_SplitMMDBTree(t *testing.T) {
+	// MMDB search trees hold sibling networks sharing the same record, which compaction merges
+	tree := &geodbtools.RecordTree{}
+	for _, record := range []*countryRecord{
+		newCountryRecord(netip.MustParsePrefix("1.0.0.0/10"), "AT"),
+		newCountryRecord(netip.MustParsePrefix("1.64.0.0/10"), "AT"),
+		newCountryRecord(netip.MustParsePrefix("1.128.0.0/9"), "AT"),
+		newCountryRecord(netip.MustParsePrefix("2.0.0.0/9"), "DE"),
+		newCountryRecord(netip.MustParsePrefix("2.128.0.0/9"), "DE"),
+		newCountryRecord(netip.MustParsePrefix("3.0.0.0/8"), "US"),
+	} {
+		require.NoError(t, tree.Insert(record.GetPrefix(), record))
+	}
+
+	mmdbFormat, err := geodbtools.LookupFormat("mmdb")
+	require.NoError(t, err)
+
+	mmdbBuf := bytes.NewBufferString("")
+	mmdbWriter, err := mmdbFormat.NewWriter(mmdbBuf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+	require.NoError(t, err)
+	require.NoError(t, mmdbWriter.WriteDatabase(geodbtools.Metadata{
+		BuildTime:   time.Now(),
+		Description: "test country database with split siblings",
+	}, tree))
+
+	mmdbReader, meta, err := mmdbFormat.NewReaderAt(geodbtools.NewBytesReaderSource(mmdbBuf.Bytes()))
+	require.NoError(t, err)
+	mmdbTree, err := mmdbReader.RecordTree(geodbtools.IPVersion4)
+	require.NoError(t, err)
+	nodeCount := mmdbTree.NodeCount()
+
+	buf := bytes.NewBufferString("")
+	w, err := format{}.NewWriter(buf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+	require.NoError(t, err)
+	stats, err := geodbtools.WriteDatabase(w, meta, mmdbTree)
+	require.NoError(t, err)
+	if assert.NotNil(t, stats) {
+		assert.EqualValues(t, nodeCount, stats.NodesBefore)
+		assert.True(t, stats.NodesAfter < stats.NodesBefore, "%d nodes after compaction, %d before", stats.NodesAfter, stats.NodesBefore)
+	}
+	assert.EqualValues(t, nodeCount, mmdbTree.NodeCount())
+
+	uncompactedBuf := bytes.NewBufferString("")
+	w, err = format{}.NewWriter(uncompactedBuf, geodbtools.DatabaseTypeCountry, geodbtools.IPVersion4)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteDatabase(meta, mmdbTree))
+	assert.True(t, buf.Len() < uncompactedBuf.Len())
+
+	reader, _, err := format{}.NewReaderAt(geodbtools.NewBytesReaderSource(buf.Bytes()))
+	require.NoError(t, err)
+	assert.NoError(t, geodbtools.Verify(reader, mmdbTree, nil))
+}
@@ -0,0 +1,102 @@
+package mmdbformat
+
+import (
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var _ searchTreeReader = (*asnReader)(nil)
+
+type asnReader struct {
+	r    *maxminddb.Reader
+	tree *searchTree
+}
+
+func (r *asnReader) setSearchTree(tree *searchTree) {
+	r.tree = tree
+}
+
+func (r *asnReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
+	tree, err = BuildRecordTree(r.r, ipVersion, func() Record {
+		return &asnRecord{}
+	})
+
+	return
+}
+
+// Networks walks the search tree, reporting all networks matching the given filter
+func (r *asnReader) Networks(filter geodbtools.NetworkFilter) geodbtools.NetworkIterator {
+	return networks(r, r.r, r.tree, filter, func() Record {
+		return &asnRecord{}
+	})
+}
+
+// LookupIP looks up the given IP address using LookupNetwork
+func (r *asnReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	addr, _ := netip.AddrFromSlice(ip)
+	record, _, err = r.LookupNetwork(addr)
+	return
+}
+
+// LookupAddr looks up the given IP address using LookupNetwork
+func (r *asnReader) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	record, _, err = r.LookupNetwork(addr)
+	return
+}
+
+// LookupNetwork walks the search tree, reporting the network the record has been found in
+func (r *asnReader) LookupNetwork(addr netip.Addr) (record geodbtools.Record, prefix netip.Prefix, err error) {
+	return lookupRecord(r.r, r.tree, addr, &asnRecord{})
+}
+
+// asnType implements the ASN database types, writing databases of the type it has been registered for
+type asnType struct {
+	typeID DatabaseTypeID
+}
+
+func (asnType) DatabaseType() geodbtools.DatabaseType {
+	return geodbtools.DatabaseTypeASN
+}
+
+func (asnType) NewReader(dbReader *maxminddb.Reader) (reader geodbtools.Reader, err error) {
+	reader = &asnReader{
+		r: dbReader,
+	}
+	return
+}
+
+func (t asnType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	return NewWriter(w, t.typeID, ipVersion, RecordSizeAuto, asnRecordData)
+}
+
+// asnRecordData returns the data section value for an ASN record
+func asnRecordData(record geodbtools.Record) (data map[string]interface{}, err error) {
+	asnRecord, ok := record.(geodbtools.ASNRecord)
+	if !ok {
+		err = geodbtools.ErrUnsupportedRecordType
+		return
+	}
+
+	asNumber := asnRecord.GetASNumber()
+	organization := asnRecord.GetOrganization()
+	if asNumber == 0 && organization == "" {
+		return
+	}
+
+	data = map[string]interface{}{
+		"autonomous_system_number": asNumber,
+	}
+	if organization != "" {
+		data["autonomous_system_organization"] = organization
+	}
+	return
+}
+
+func init() {
+	MustRegisterType(DatabaseTypeIDGeoLite2ASN, asnType{DatabaseTypeIDGeoLite2ASN})
+	MustRegisterType(DatabaseTypeIDGeoIP2ASN, asnType{DatabaseTypeIDGeoIP2ASN})
+}
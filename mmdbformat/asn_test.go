@@ -0,0 +1,189 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func openASNTestDatabase(t *testing.T) *maxminddb.Reader {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	testPath := filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", "GeoLite2-ASN-Test.mmdb")
+
+	maxmindDB, err := maxminddb.Open(testPath)
+	require.NoError(t, err)
+	return maxmindDB
+}
+
+func TestASNType_DatabaseType(t *testing.T) {
+	assert.EqualValues(t, geodbtools.DatabaseTypeASN, asnType{}.DatabaseType())
+}
+
+func TestASNType_NewReader(t *testing.T) {
+	maxmindDB := openASNTestDatabase(t)
+	defer maxmindDB.Close()
+
+	reader, err := asnType{}.NewReader(maxmindDB)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &asnReader{
+		r: maxmindDB,
+	}, reader)
+}
+
+func TestASNType_NewWriter(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := asnType{}.NewWriter(nil, geodbtools.IPVersionUndefined)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	for _, typeID := range []DatabaseTypeID{DatabaseTypeIDGeoLite2ASN, DatabaseTypeIDGeoIP2ASN} {
+		t.Run(string(typeID), func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			w, err := asnType{typeID}.NewWriter(buf, geodbtools.IPVersion6)
+			assert.NoError(t, err)
+			if assert.NotNil(t, w) && assert.IsType(t, &writer{}, w) {
+				wr := w.(*writer)
+				assert.EqualValues(t, buf, wr.w)
+				assert.EqualValues(t, typeID, wr.typeID)
+				assert.EqualValues(t, geodbtools.IPVersion6, wr.ipVersion)
+				assert.EqualValues(t, RecordSizeAuto, wr.recordSize)
+			}
+		})
+	}
+}
+
+func TestASNRecordData(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		data, err := asnRecordData(&countryRecord{})
+		assert.Nil(t, data)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		data, err := asnRecordData(&asnRecord{})
+		assert.Nil(t, data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ASNumberOnly", func(t *testing.T) {
+		data, err := asnRecordData(&asnRecord{
+			ASNumber: 64512,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"autonomous_system_number": uint32(64512),
+		}, data)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		data, err := asnRecordData(&asnRecord{
+			ASNumber:     64512,
+			Organization: "Test Organization",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"autonomous_system_number":       uint32(64512),
+			"autonomous_system_organization": "Test Organization",
+		}, data)
+	})
+}
+
+func TestASNReader_RecordTree(t *testing.T) {
+	maxmindDB := openASNTestDatabase(t)
+	defer maxmindDB.Close()
+
+	reader := &asnReader{
+		r: maxmindDB,
+	}
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersionUndefined)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		expectedRecords := []string{
+			"1.128.0.0/11: AS1221, organization Telstra Pty Ltd",
+			"12.81.92.0/22: AS7018, organization AT&T Services",
+			"12.81.96.0/19: AS7018, organization ",
+		}
+
+		tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			var treeRecords []string
+			for _, record := range tree.Records() {
+				treeRecords = append(treeRecords, record.String())
+			}
+
+			assert.EqualValues(t, expectedRecords, treeRecords)
+		}
+	})
+}
+
+func TestASNReader_LookupIP(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "GeoLite2-ASN-Test.mmdb")
+	tree, err := newSearchTree(buf, mmdbReader.Metadata)
+	require.NoError(t, err)
+
+	reader := &asnReader{
+		r:    mmdbReader,
+		tree: tree,
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		expectedRecord := &asnRecord{
+			ASNumber:     1221,
+			Organization: "Telstra Pty Ltd",
+		}
+		expectedRecord.SetPrefix(netip.MustParsePrefix("1.128.0.0/11"))
+
+		record, err := reader.LookupIP(net.ParseIP("1.128.0.1"))
+		assert.NoError(t, err)
+		assert.EqualValues(t, expectedRecord, record)
+	})
+
+	t.Run("LookupFailure", func(t *testing.T) {
+		record, err := reader.LookupIP(nil)
+		assert.Nil(t, record)
+		assert.Error(t, err)
+	})
+}
+
+func TestASNType_RoundTrip(t *testing.T) {
+	maxmindDB := openASNTestDatabase(t)
+	defer maxmindDB.Close()
+
+	sourceReader := &asnReader{
+		r: maxmindDB,
+	}
+
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		tree, err := sourceReader.RecordTree(ipVersion)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := asnType{DatabaseTypeIDGeoLite2ASN}.NewWriter(buf, ipVersion)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{
+			Description: "test",
+		}, tree))
+
+		reader, meta, err := format{}.NewReaderAt(geodbtools.NewReaderSourceWrapper(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeASN, meta.Type)
+		assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+	}
+}
@@ -0,0 +1,212 @@
+package mmdbformat
+
+import (
+	"io"
+	"net"
+	"net/netip"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+var _ searchTreeReader = (*cityReader)(nil)
+
+type cityReader struct {
+	r    *maxminddb.Reader
+	tree *searchTree
+}
+
+func (r *cityReader) setSearchTree(tree *searchTree) {
+	r.tree = tree
+}
+
+func (r *cityReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
+	tree, err = BuildRecordTree(r.r, ipVersion, func() Record {
+		return &cityRecord{}
+	})
+
+	return
+}
+
+// Networks walks the search tree, reporting all networks matching the given filter
+func (r *cityReader) Networks(filter geodbtools.NetworkFilter) geodbtools.NetworkIterator {
+	return networks(r, r.r, r.tree, filter, func() Record {
+		return &cityRecord{}
+	})
+}
+
+// LookupIP looks up the given IP address using LookupNetwork
+func (r *cityReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	addr, _ := netip.AddrFromSlice(ip)
+	record, _, err = r.LookupNetwork(addr)
+	return
+}
+
+// LookupAddr looks up the given IP address using LookupNetwork
+func (r *cityReader) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	record, _, err = r.LookupNetwork(addr)
+	return
+}
+
+// LookupNetwork walks the search tree, reporting the network the record has been found in
+func (r *cityReader) LookupNetwork(addr netip.Addr) (record geodbtools.Record, prefix netip.Prefix, err error) {
+	return lookupRecord(r.r, r.tree, addr, &cityRecord{})
+}
+
+// cityType implements the city database types, writing databases of the type it has been registered for
+type cityType struct {
+	typeID DatabaseTypeID
+}
+
+func (cityType) DatabaseType() geodbtools.DatabaseType {
+	return geodbtools.DatabaseTypeCity
+}
+
+func (cityType) NewReader(dbReader *maxminddb.Reader) (reader geodbtools.Reader, err error) {
+	reader = &cityReader{
+		r: dbReader,
+	}
+	return
+}
+
+func (t cityType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	return NewWriter(w, t.typeID, ipVersion, RecordSizeAuto, cityRecordData)
+}
+
+// setDataValue sets a value inside the given section of the data map, creating the section if required.
+// Empty values are omitted.
+func setDataValue(data map[string]interface{}, section string, key string, value interface{}) {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return
+		}
+	case uint16:
+		if v == 0 {
+			return
+		}
+	case uint32:
+		if v == 0 {
+			return
+		}
+	case map[string]string:
+		if len(v) == 0 {
+			return
+		}
+	}
+
+	sectionData, ok := data[section].(map[string]interface{})
+	if !ok {
+		sectionData = make(map[string]interface{})
+		data[section] = sectionData
+	}
+	sectionData[key] = value
+}
+
+// geoNameEntityData returns the data section value of a geoNameEntity
+func geoNameEntityData(entity geoNameEntity) (data map[string]interface{}) {
+	data = make(map[string]interface{})
+	if entity.GeoNameID != 0 {
+		data["geoname_id"] = entity.GeoNameID
+	}
+	if entity.ISOCode != "" {
+		data["iso_code"] = entity.ISOCode
+	}
+	if len(entity.Names) > 0 {
+		data["names"] = entity.Names
+	}
+	return
+}
+
+// cityRecordData returns the data section value for a city record.
+// Additional information is taken from the optional record interfaces the record implements.
+func cityRecordData(record geodbtools.Record) (data map[string]interface{}, err error) {
+	geoCityRecord, ok := record.(geodbtools.CityRecord)
+	if !ok {
+		err = geodbtools.ErrUnsupportedRecordType
+		return
+	}
+
+	data = make(map[string]interface{})
+	setCountryData(data, record)
+
+	cityNames := make(map[string]string)
+	if localizedCityRecord, ok := record.(geodbtools.LocalizedCityRecord); ok {
+		for locale, name := range localizedCityRecord.GetCityNames() {
+			cityNames[locale] = name
+		}
+	}
+	if cityName := geoCityRecord.GetCityName(); cityName != "" && cityNames["en"] == "" {
+		cityNames["en"] = cityName
+	}
+	setDataValue(data, "city", "names", cityNames)
+
+	var subdivisionCodes []string
+	if subdivisionRecord, ok := record.(geodbtools.SubdivisionRecord); ok {
+		subdivisionCodes = subdivisionRecord.GetSubdivisionCodes()
+	} else if regionRecord, ok := record.(geodbtools.RegionRecord); ok && regionRecord.GetRegionCode() != "" {
+		subdivisionCodes = []string{regionRecord.GetRegionCode()}
+	}
+
+	if len(subdivisionCodes) > 0 {
+		subdivisions := make([]interface{}, len(subdivisionCodes))
+		for i, code := range subdivisionCodes {
+			subdivisions[i] = map[string]interface{}{
+				"iso_code": code,
+			}
+		}
+		data["subdivisions"] = subdivisions
+	}
+
+	if postalCodeRecord, ok := record.(geodbtools.PostalCodeRecord); ok {
+		setDataValue(data, "postal", "code", postalCodeRecord.GetPostalCode())
+	}
+
+	if locationRecord, ok := record.(geodbtools.LocationRecord); ok && (locationRecord.GetLatitude() != 0 || locationRecord.GetLongitude() != 0) {
+		setDataValue(data, "location", "latitude", locationRecord.GetLatitude())
+		setDataValue(data, "location", "longitude", locationRecord.GetLongitude())
+	}
+
+	if accuracyRadiusRecord, ok := record.(geodbtools.AccuracyRadiusRecord); ok {
+		setDataValue(data, "location", "accuracy_radius", uint16(accuracyRadiusRecord.GetAccuracyRadius()))
+	}
+
+	if timeZoneRecord, ok := record.(geodbtools.TimeZoneRecord); ok {
+		setDataValue(data, "location", "time_zone", timeZoneRecord.GetTimeZone())
+	}
+
+	if metroCodeRecord, ok := record.(geodbtools.MetroCodeRecord); ok {
+		setDataValue(data, "location", "metro_code", uint16(metroCodeRecord.GetMetroCode()))
+	}
+
+	// records read from MMDB databases additionally carry GeoName IDs and localized names
+	if rec, ok := record.(*cityRecord); ok {
+		setDataValue(data, "city", "geoname_id", rec.City.GeoNameID)
+		setDataValue(data, "continent", "geoname_id", rec.Continent.GeoNameID)
+		setDataValue(data, "continent", "names", rec.Continent.Names)
+		setDataValue(data, "country", "geoname_id", rec.Country.GeoNameID)
+		setDataValue(data, "country", "names", rec.Country.Names)
+		setDataValue(data, "registered_country", "geoname_id", rec.RegisteredCountry.GeoNameID)
+		setDataValue(data, "registered_country", "names", rec.RegisteredCountry.Names)
+		setDataValue(data, "represented_country", "geoname_id", rec.RepresentedCountry.GeoNameID)
+		setDataValue(data, "represented_country", "names", rec.RepresentedCountry.Names)
+
+		if len(rec.Subdivisions) > 0 {
+			subdivisions := make([]interface{}, len(rec.Subdivisions))
+			for i, subdivision := range rec.Subdivisions {
+				subdivisions[i] = geoNameEntityData(subdivision)
+			}
+			data["subdivisions"] = subdivisions
+		}
+	}
+
+	if len(data) == 0 {
+		data = nil
+	}
+	return
+}
+
+func init() {
+	MustRegisterType(DatabaseTypeIDGeoLite2City, cityType{DatabaseTypeIDGeoLite2City})
+	MustRegisterType(DatabaseTypeIDGeoIP2City, cityType{DatabaseTypeIDGeoIP2City})
+}
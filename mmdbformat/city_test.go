@@ -0,0 +1,302 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"net"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// legacyCityRecord implements a city record as provided by legacy (DAT) databases
+type legacyCityRecord struct {
+	networkRecord
+	countryCode string
+	regionCode  string
+	cityName    string
+	postalCode  string
+	latitude    float64
+	longitude   float64
+	metroCode   int
+	areaCode    int
+}
+
+func (r *legacyCityRecord) GetCountryCode() string { return r.countryCode }
+func (r *legacyCityRecord) GetRegionCode() string  { return r.regionCode }
+func (r *legacyCityRecord) GetCityName() string    { return r.cityName }
+func (r *legacyCityRecord) GetPostalCode() string  { return r.postalCode }
+func (r *legacyCityRecord) GetLatitude() float64   { return r.latitude }
+func (r *legacyCityRecord) GetLongitude() float64  { return r.longitude }
+func (r *legacyCityRecord) GetMetroCode() int      { return r.metroCode }
+func (r *legacyCityRecord) GetAreaCode() int       { return r.areaCode }
+
+func openCityTestDatabase(t *testing.T) *maxminddb.Reader {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	testPath := filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", "GeoIP2-City-Test.mmdb")
+
+	maxmindDB, err := maxminddb.Open(testPath)
+	require.NoError(t, err)
+	return maxmindDB
+}
+
+func TestCityType_DatabaseType(t *testing.T) {
+	assert.EqualValues(t, geodbtools.DatabaseTypeCity, cityType{}.DatabaseType())
+}
+
+func TestCityType_NewReader(t *testing.T) {
+	maxmindDB := openCityTestDatabase(t)
+	defer maxmindDB.Close()
+
+	reader, err := cityType{}.NewReader(maxmindDB)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &cityReader{
+		r: maxmindDB,
+	}, reader)
+}
+
+func TestCityType_NewWriter(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := cityType{}.NewWriter(nil, geodbtools.IPVersionUndefined)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	for _, typeID := range []DatabaseTypeID{DatabaseTypeIDGeoLite2City, DatabaseTypeIDGeoIP2City} {
+		t.Run(string(typeID), func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			w, err := cityType{typeID}.NewWriter(buf, geodbtools.IPVersion6)
+			assert.NoError(t, err)
+			if assert.NotNil(t, w) && assert.IsType(t, &writer{}, w) {
+				wr := w.(*writer)
+				assert.EqualValues(t, buf, wr.w)
+				assert.EqualValues(t, typeID, wr.typeID)
+				assert.EqualValues(t, geodbtools.IPVersion6, wr.ipVersion)
+				assert.EqualValues(t, RecordSizeAuto, wr.recordSize)
+			}
+		})
+	}
+}
+
+func TestCityRecordData(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		data, err := cityRecordData(&countryRecord{})
+		assert.Nil(t, data)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		data, err := cityRecordData(&cityRecord{})
+		assert.Nil(t, data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("LegacyRecord", func(t *testing.T) {
+		data, err := cityRecordData(&legacyCityRecord{
+			countryCode: "US",
+			regionCode:  "WA",
+			cityName:    "Milton",
+			postalCode:  "98354",
+			latitude:    47.2513,
+			longitude:   -122.3149,
+			metroCode:   819,
+			areaCode:    253,
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"city": map[string]interface{}{
+				"names": map[string]string{
+					"en": "Milton",
+				},
+			},
+			"country": map[string]interface{}{
+				"iso_code": "US",
+			},
+			"location": map[string]interface{}{
+				"latitude":   47.2513,
+				"longitude":  -122.3149,
+				"metro_code": uint16(819),
+			},
+			"postal": map[string]interface{}{
+				"code": "98354",
+			},
+			"subdivisions": []interface{}{
+				map[string]interface{}{
+					"iso_code": "WA",
+				},
+			},
+		}, data)
+	})
+
+	t.Run("CityRecord", func(t *testing.T) {
+		rec := &cityRecord{}
+		rec.City.GeoNameID = 2655045
+		rec.City.Names = map[string]string{"en": "Boxford", "de": "Boxford"}
+		rec.Continent.Code = "EU"
+		rec.Continent.GeoNameID = 6255148
+		rec.Country = geoNameEntity{GeoNameID: 2635167, ISOCode: "GB", Names: map[string]string{"en": "United Kingdom"}}
+		rec.Location.AccuracyRadius = 100
+		rec.Location.Latitude = 51.75
+		rec.Location.Longitude = -1.25
+		rec.Location.TimeZone = "Europe/London"
+		rec.Postal.Code = "OX1"
+		rec.RegisteredCountry = geoNameEntity{ISOCode: "FR"}
+		rec.RepresentedCountry.ISOCode = "US"
+		rec.RepresentedCountry.Type = "military"
+		rec.Subdivisions = []geoNameEntity{
+			{GeoNameID: 6269131, ISOCode: "ENG", Names: map[string]string{"en": "England"}},
+			{ISOCode: "WBK"},
+		}
+
+		data, err := cityRecordData(rec)
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"city": map[string]interface{}{
+				"geoname_id": uint32(2655045),
+				"names":      map[string]string{"en": "Boxford", "de": "Boxford"},
+			},
+			"continent": map[string]interface{}{
+				"code":       "EU",
+				"geoname_id": uint32(6255148),
+			},
+			"country": map[string]interface{}{
+				"geoname_id": uint32(2635167),
+				"iso_code":   "GB",
+				"names":      map[string]string{"en": "United Kingdom"},
+			},
+			"location": map[string]interface{}{
+				"accuracy_radius": uint16(100),
+				"latitude":        51.75,
+				"longitude":       -1.25,
+				"time_zone":       "Europe/London",
+			},
+			"postal": map[string]interface{}{
+				"code": "OX1",
+			},
+			"registered_country": map[string]interface{}{
+				"iso_code": "FR",
+			},
+			"represented_country": map[string]interface{}{
+				"iso_code": "US",
+				"type":     "military",
+			},
+			"subdivisions": []interface{}{
+				map[string]interface{}{
+					"geoname_id": uint32(6269131),
+					"iso_code":   "ENG",
+					"names":      map[string]string{"en": "England"},
+				},
+				map[string]interface{}{
+					"iso_code": "WBK",
+				},
+			},
+		}, data)
+	})
+}
+
+func TestCityReader_RecordTree(t *testing.T) {
+	maxmindDB := openCityTestDatabase(t)
+	defer maxmindDB.Close()
+
+	reader := &cityReader{
+		r: maxmindDB,
+	}
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersionUndefined)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		tree, err := reader.RecordTree(geodbtools.IPVersion4)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			var treeRecords []string
+			for _, record := range tree.Records() {
+				treeRecords = append(treeRecords, record.String())
+			}
+
+			assert.Contains(t, treeRecords, "2.125.160.216/29: country code GB, region ENG, city Boxford, postal code OX1, location 51.7500,-1.2500")
+			assert.Contains(t, treeRecords, "216.160.83.56/29: country code US, region WA, city Milton, postal code 98354, location 47.2513,-122.3149")
+		}
+	})
+}
+
+func TestCityReader_LookupIP(t *testing.T) {
+	maxmindDB := openCityTestDatabase(t)
+	defer maxmindDB.Close()
+
+	reader := &cityReader{
+		r: maxmindDB,
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		record, err := reader.LookupIP(net.ParseIP("202.196.224.1"))
+		assert.NoError(t, err)
+		if assert.IsType(t, &cityRecord{}, record) {
+			rec := record.(*cityRecord)
+			assert.EqualValues(t, "PH", rec.GetCountryCode())
+			assert.EqualValues(t, "AS", rec.GetContinentCode())
+			assert.EqualValues(t, "PH", rec.GetRegisteredCountryCode())
+			assert.EqualValues(t, "US", rec.GetRepresentedCountryCode())
+			assert.EqualValues(t, "military", rec.GetRepresentedCountryType())
+			assert.EqualValues(t, "34021", rec.GetPostalCode())
+			assert.EqualValues(t, 13, rec.GetLatitude())
+			assert.EqualValues(t, 122, rec.GetLongitude())
+			assert.EqualValues(t, 121, rec.GetAccuracyRadius())
+			assert.EqualValues(t, "Asia/Manila", rec.GetTimeZone())
+			assert.EqualValues(t, "Philippines", rec.Country.Names["en"])
+		}
+	})
+
+	t.Run("LookupFailure", func(t *testing.T) {
+		record, err := reader.LookupIP(nil)
+		assert.Nil(t, record)
+		assert.Error(t, err)
+	})
+}
+
+func TestCityType_RoundTrip(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "GeoIP2-City-Test.mmdb")
+	sourceTree, err := newSearchTree(buf, mmdbReader.Metadata)
+	require.NoError(t, err)
+
+	sourceReader := &cityReader{
+		r:    mmdbReader,
+		tree: sourceTree,
+	}
+
+	for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+		tree, err := sourceReader.RecordTree(ipVersion)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := cityType{DatabaseTypeIDGeoLite2City}.NewWriter(buf, ipVersion)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{
+			Description: "test",
+		}, tree))
+
+		reader, meta, err := format{}.NewReaderAt(geodbtools.NewReaderSourceWrapper(bytes.NewReader(buf.Bytes()), int64(buf.Len())))
+		require.NoError(t, err)
+		assert.EqualValues(t, geodbtools.DatabaseTypeCity, meta.Type)
+		assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+
+		// all information is retained
+		for _, ip := range []string{"81.2.69.160", "2.125.160.216", "216.160.83.56", "202.196.224.1"} {
+			expectedRecord, err := sourceReader.LookupIP(net.ParseIP(ip))
+			require.NoError(t, err)
+
+			record, err := reader.LookupIP(net.ParseIP(ip))
+			require.NoError(t, err)
+			assert.EqualValues(t, expectedRecord, record)
+		}
+	}
+}
@@ -1,18 +1,23 @@
 package mmdbformat
 
 import (
-	"errors"
 	"io"
 	"net"
+	"net/netip"
 
 	"github.com/anexia-it/geodbtools"
 	"github.com/oschwald/maxminddb-golang"
 )
 
-var _ geodbtools.Reader = (*countryReader)(nil)
+var _ searchTreeReader = (*countryReader)(nil)
 
 type countryReader struct {
-	r *maxminddb.Reader
+	r    *maxminddb.Reader
+	tree *searchTree
+}
+
+func (r *countryReader) setSearchTree(tree *searchTree) {
+	r.tree = tree
 }
 
 func (r *countryReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbtools.RecordTree, err error) {
@@ -23,22 +28,34 @@ func (r *countryReader) RecordTree(ipVersion geodbtools.IPVersion) (tree *geodbt
 	return
 }
 
-func (r *countryReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
-	rec := &countryRecord{}
+// Networks walks the search tree, reporting all networks matching the given filter
+func (r *countryReader) Networks(filter geodbtools.NetworkFilter) geodbtools.NetworkIterator {
+	return networks(r, r.r, r.tree, filter, func() Record {
+		return &countryRecord{}
+	})
+}
 
-	if err = r.r.Lookup(ip, rec); err != nil {
-		return
-	}
-	rec.network = &net.IPNet{
-		IP:   ip,
-		Mask: net.CIDRMask(len(ip)*8, len(ip)*8),
-	}
+// LookupIP looks up the given IP address using LookupNetwork
+func (r *countryReader) LookupIP(ip net.IP) (record geodbtools.Record, err error) {
+	addr, _ := netip.AddrFromSlice(ip)
+	record, _, err = r.LookupNetwork(addr)
+	return
+}
 
-	record = rec
+// LookupAddr looks up the given IP address using LookupNetwork
+func (r *countryReader) LookupAddr(addr netip.Addr) (record geodbtools.Record, err error) {
+	record, _, err = r.LookupNetwork(addr)
 	return
 }
 
+// LookupNetwork walks the search tree, reporting the network the record has been found in
+func (r *countryReader) LookupNetwork(addr netip.Addr) (record geodbtools.Record, prefix netip.Prefix, err error) {
+	return lookupRecord(r.r, r.tree, addr, &countryRecord{})
+}
+
+// countryType implements the country database types, writing databases of the type it has been registered for
 type countryType struct {
+	typeID DatabaseTypeID
 }
 
 func (countryType) DatabaseType() geodbtools.DatabaseType {
@@ -52,15 +69,55 @@ func (countryType) NewReader(dbReader *maxminddb.Reader) (reader geodbtools.Read
 	return
 }
 
-func (countryType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
-	err = errors.New("not implemented")
+func (t countryType) NewWriter(w io.Writer, ipVersion geodbtools.IPVersion) (writer geodbtools.Writer, err error) {
+	return NewWriter(w, t.typeID, ipVersion, RecordSizeAuto, countryRecordData)
+}
+
+// countryRecordData returns the data section value for a country record.
+// Additional information is taken from the optional record interfaces the record implements.
+func countryRecordData(record geodbtools.Record) (data map[string]interface{}, err error) {
+	if _, ok := record.(geodbtools.CountryRecord); !ok {
+		err = geodbtools.ErrUnsupportedRecordType
+		return
+	}
+
+	data = make(map[string]interface{})
+	setCountryData(data, record)
+
+	if len(data) == 0 {
+		data = nil
+	}
 	return
 }
 
+// setCountryData sets the country, continent, registered country and represented country sections of the data map,
+// as far as the record implements the respective record interfaces
+func setCountryData(data map[string]interface{}, record geodbtools.Record) {
+	if countryRecord, ok := record.(geodbtools.CountryRecord); ok {
+		setDataValue(data, "country", "iso_code", countryRecord.GetCountryCode())
+	}
+
+	if countryNameRecord, ok := record.(geodbtools.CountryNameRecord); ok && countryNameRecord.GetCountryName() != "" {
+		setDataValue(data, "country", "names", map[string]string{
+			"en": countryNameRecord.GetCountryName(),
+		})
+	}
+
+	if continentRecord, ok := record.(geodbtools.ContinentRecord); ok {
+		setDataValue(data, "continent", "code", continentRecord.GetContinentCode())
+	}
+
+	if registeredCountryRecord, ok := record.(geodbtools.RegisteredCountryRecord); ok {
+		setDataValue(data, "registered_country", "iso_code", registeredCountryRecord.GetRegisteredCountryCode())
+	}
+
+	if representedCountryRecord, ok := record.(geodbtools.RepresentedCountryRecord); ok {
+		setDataValue(data, "represented_country", "iso_code", representedCountryRecord.GetRepresentedCountryCode())
+		setDataValue(data, "represented_country", "type", representedCountryRecord.GetRepresentedCountryType())
+	}
+}
+
 func init() {
-	MustRegisterType(DatabaseTypeIDGeoLite2Country, countryType{})
-	MustRegisterType(DatabaseTypeIDGeoIP2Country, countryType{})
-	// TODO: remove the two registrations below once we also support city database
-	MustRegisterType(DatabaseTypeIDGeoLite2City, countryType{})
-	MustRegisterType(DatabaseTypeIDGeoIP2City, countryType{})
+	MustRegisterType(DatabaseTypeIDGeoLite2Country, countryType{DatabaseTypeIDGeoLite2Country})
+	MustRegisterType(DatabaseTypeIDGeoIP2Country, countryType{DatabaseTypeIDGeoIP2Country})
 }
@@ -3,9 +3,11 @@ package mmdbformat
 import (
 	"bytes"
 	"net"
+	"net/netip"
 	"path/filepath"
 	"runtime"
 	"testing"
+	"time"
 
 	"github.com/anexia-it/geodbtools"
 	"github.com/oschwald/maxminddb-golang"
@@ -18,10 +20,124 @@ func TestCountryType_DatabaseType(t *testing.T) {
 }
 
 func TestCountryType_NewWriter(t *testing.T) {
-	buf := bytes.NewBufferString("")
-	w, err := countryType{}.NewWriter(buf, geodbtools.IPVersion4)
-	assert.Nil(t, w)
-	assert.EqualError(t, err, "not implemented")
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := countryType{}.NewWriter(nil, geodbtools.IPVersionUndefined)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	for _, typeID := range []DatabaseTypeID{DatabaseTypeIDGeoLite2Country, DatabaseTypeIDGeoIP2Country} {
+		t.Run(string(typeID), func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			w, err := countryType{typeID}.NewWriter(buf, geodbtools.IPVersion4)
+			assert.NoError(t, err)
+			if assert.NotNil(t, w) && assert.IsType(t, &writer{}, w) {
+				wr := w.(*writer)
+				assert.EqualValues(t, buf, wr.w)
+				assert.EqualValues(t, typeID, wr.typeID)
+				assert.EqualValues(t, geodbtools.IPVersion4, wr.ipVersion)
+				assert.EqualValues(t, RecordSizeAuto, wr.recordSize)
+			}
+		})
+	}
+
+	t.Run("GeoIP2Country", func(t *testing.T) {
+		dbType, err := LookupTypeByDatabaseType(DatabaseTypeIDGeoIP2Country)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := dbType.NewWriter(buf, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(geodbtools.Metadata{BuildTime: time.Now()}, nil))
+
+		db, err := maxminddb.FromBytes(buf.Bytes())
+		require.NoError(t, err)
+		assert.EqualValues(t, DatabaseTypeIDGeoIP2Country, db.Metadata.DatabaseType)
+	})
+}
+
+// geoCountryRecord implements a country record holding all optional country information
+type geoCountryRecord struct {
+	networkRecord
+	countryCode, countryName, continentCode       string
+	registeredCountryCode, representedCountryCode string
+	representedCountryType                        string
+}
+
+func (r *geoCountryRecord) GetCountryCode() string            { return r.countryCode }
+func (r *geoCountryRecord) GetCountryName() string            { return r.countryName }
+func (r *geoCountryRecord) GetContinentCode() string          { return r.continentCode }
+func (r *geoCountryRecord) GetRegisteredCountryCode() string  { return r.registeredCountryCode }
+func (r *geoCountryRecord) GetRepresentedCountryCode() string { return r.representedCountryCode }
+func (r *geoCountryRecord) GetRepresentedCountryType() string { return r.representedCountryType }
+
+func TestCountryRecordData(t *testing.T) {
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		data, err := countryRecordData(&networkRecord{})
+		assert.Nil(t, data)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedRecordType.Error())
+	})
+
+	t.Run("EmptyCountryCode", func(t *testing.T) {
+		data, err := countryRecordData(&countryRecord{})
+		assert.Nil(t, data)
+		assert.NoError(t, err)
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		record := &countryRecord{}
+		record.Country.ISOCode = "AT"
+
+		data, err := countryRecordData(record)
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"country": map[string]interface{}{
+				"iso_code": "AT",
+			},
+		}, data)
+	})
+
+	t.Run("AdditionalInformation", func(t *testing.T) {
+		data, err := countryRecordData(&geoCountryRecord{
+			countryCode:            "DE",
+			countryName:            "Germany",
+			continentCode:          "EU",
+			registeredCountryCode:  "AT",
+			representedCountryCode: "US",
+			representedCountryType: "military",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"continent": map[string]interface{}{
+				"code": "EU",
+			},
+			"country": map[string]interface{}{
+				"iso_code": "DE",
+				"names": map[string]string{
+					"en": "Germany",
+				},
+			},
+			"registered_country": map[string]interface{}{
+				"iso_code": "AT",
+			},
+			"represented_country": map[string]interface{}{
+				"iso_code": "US",
+				"type":     "military",
+			},
+		}, data)
+	})
+
+	t.Run("RegisteredCountryOnly", func(t *testing.T) {
+		data, err := countryRecordData(&geoCountryRecord{
+			registeredCountryCode: "US",
+		})
+		assert.NoError(t, err)
+		assert.EqualValues(t, map[string]interface{}{
+			"registered_country": map[string]interface{}{
+				"iso_code": "US",
+			},
+		}, data)
+	})
 }
 
 func TestCountryType_NewReader(t *testing.T) {
@@ -186,32 +302,45 @@ func TestCountryReader_RecordTree(t *testing.T) {
 }
 
 func TestCountryReader_LookupIP(t *testing.T) {
-	t.Run("OK", func(t *testing.T) {
-		_, testFilename, _, ok := runtime.Caller(0)
-		require.True(t, ok)
+	buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+	tree, err := newSearchTree(buf, mmdbReader.Metadata)
+	require.NoError(t, err)
 
-		testPath := filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", "MaxMind-DB-test-ipv4-24.mmdb")
+	reader := &countryReader{
+		r:    mmdbReader,
+		tree: tree,
+	}
 
-		maxmindDB, err := maxminddb.Open(testPath)
-		require.NoError(t, err)
-		defer maxmindDB.Close()
-
-		reader := &countryReader{
-			r: maxmindDB,
+	t.Run("OK", func(t *testing.T) {
+		for ip, expectedPrefix := range map[string]string{
+			"1.1.1.32": "1.1.1.32/32",
+			"1.1.1.3":  "1.1.1.2/31",
+			"1.1.1.20": "1.1.1.16/28",
+		} {
+			expectedRecord := &countryRecord{}
+			expectedRecord.SetPrefix(netip.MustParsePrefix(expectedPrefix))
+
+			record, err := reader.LookupIP(net.ParseIP(ip))
+			assert.NoError(t, err, ip)
+			assert.EqualValues(t, expectedRecord, record, ip)
 		}
+	})
 
-		_, expectedNetwork, err := net.ParseCIDR("1.1.1.32/32")
-		require.NoError(t, err)
-
-		expectedRecord := &countryRecord{
-			network: expectedNetwork,
-		}
+	t.Run("NotFound", func(t *testing.T) {
+		expectedRecord := &countryRecord{}
+		expectedRecord.SetPrefix(netip.MustParsePrefix("1.1.1.33/32"))
 
-		record, err := reader.LookupIP(expectedNetwork.IP)
+		record, err := reader.LookupIP(net.ParseIP("1.1.1.33"))
 		assert.NoError(t, err)
 		assert.EqualValues(t, expectedRecord, record)
 	})
 
+	t.Run("IPv6InIPv4Database", func(t *testing.T) {
+		record, err := reader.LookupIP(net.ParseIP("::1"))
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+
 	t.Run("LookupFailure", func(t *testing.T) {
 		_, testFilename, _, ok := runtime.Caller(0)
 		require.True(t, ok)
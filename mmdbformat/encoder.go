@@ -0,0 +1,149 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// dataType represents a MMDB data section type
+type dataType byte
+
+const (
+	dataTypeExtended dataType = iota
+	dataTypePointer
+	dataTypeString
+	dataTypeFloat64
+	dataTypeBytes
+	dataTypeUint16
+	dataTypeUint32
+	dataTypeMap
+	dataTypeInt32
+	dataTypeUint64
+	dataTypeUint128
+	dataTypeArray
+	dataTypeContainer
+	dataTypeEndMarker
+	dataTypeBool
+	dataTypeFloat32
+)
+
+// encodeValue appends the MMDB data section representation of the given value to buf
+func encodeValue(buf *bytes.Buffer, v interface{}) (err error) {
+	switch value := v.(type) {
+	case string:
+		writeControl(buf, dataTypeString, len(value))
+		buf.WriteString(value)
+	case []byte:
+		writeControl(buf, dataTypeBytes, len(value))
+		buf.Write(value)
+	case bool:
+		size := 0
+		if value {
+			size = 1
+		}
+		writeControl(buf, dataTypeBool, size)
+	case uint16:
+		writeUint(buf, dataTypeUint16, uint64(value))
+	case uint32:
+		writeUint(buf, dataTypeUint32, uint64(value))
+	case uint64:
+		writeUint(buf, dataTypeUint64, value)
+	case int32:
+		b := make([]byte, 4)
+		binary.BigEndian.PutUint32(b, uint32(value))
+		if value >= 0 {
+			b = bytes.TrimLeft(b, "\x00")
+		}
+		writeControl(buf, dataTypeInt32, len(b))
+		buf.Write(b)
+	case float32:
+		writeControl(buf, dataTypeFloat32, 4)
+		err = binary.Write(buf, binary.BigEndian, math.Float32bits(value))
+	case float64:
+		writeControl(buf, dataTypeFloat64, 8)
+		err = binary.Write(buf, binary.BigEndian, math.Float64bits(value))
+	case []string:
+		writeControl(buf, dataTypeArray, len(value))
+		for _, element := range value {
+			if err = encodeValue(buf, element); err != nil {
+				return
+			}
+		}
+	case []interface{}:
+		writeControl(buf, dataTypeArray, len(value))
+		for _, element := range value {
+			if err = encodeValue(buf, element); err != nil {
+				return
+			}
+		}
+	case map[string]string:
+		m := make(map[string]interface{}, len(value))
+		for k, v := range value {
+			m[k] = v
+		}
+		err = encodeValue(buf, m)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(value))
+		for k := range value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		writeControl(buf, dataTypeMap, len(keys))
+		for _, k := range keys {
+			if err = encodeValue(buf, k); err != nil {
+				return
+			} else if err = encodeValue(buf, value[k]); err != nil {
+				return
+			}
+		}
+	default:
+		err = fmt.Errorf("unsupported data type %T", v)
+	}
+
+	return
+}
+
+// writeUint writes an unsigned integer of the given type, using the minimum number of bytes required
+func writeUint(buf *bytes.Buffer, t dataType, value uint64) {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, value)
+	b = bytes.TrimLeft(b, "\x00")
+
+	writeControl(buf, t, len(b))
+	buf.Write(b)
+}
+
+// writeControl writes the control byte(s) for the given type and payload size
+func writeControl(buf *bytes.Buffer, t dataType, size int) {
+	var sizeBytes []byte
+	var sizeBits byte
+
+	switch {
+	case size < 29:
+		sizeBits = byte(size)
+	case size < 285:
+		sizeBits = 29
+		sizeBytes = []byte{byte(size - 29)}
+	case size < 65821:
+		sizeBits = 30
+		size -= 285
+		sizeBytes = []byte{byte(size >> 8), byte(size)}
+	default:
+		sizeBits = 31
+		size -= 65821
+		sizeBytes = []byte{byte(size >> 16), byte(size >> 8), byte(size)}
+	}
+
+	if t > dataTypeMap {
+		buf.WriteByte(byte(dataTypeExtended)<<5 | sizeBits)
+		buf.WriteByte(byte(t - 7))
+	} else {
+		buf.WriteByte(byte(t)<<5 | sizeBits)
+	}
+
+	buf.Write(sizeBytes)
+}
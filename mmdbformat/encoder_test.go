@@ -0,0 +1,116 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeValue(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Value    interface{}
+		Expected []byte
+	}{
+		{"EmptyString", "", []byte{0x40}},
+		{"String", "abc", []byte{0x43, 'a', 'b', 'c'}},
+		{"Bytes", []byte{0x01, 0x02}, []byte{0x82, 0x01, 0x02}},
+		{"BoolFalse", false, []byte{0x00, 0x07}},
+		{"BoolTrue", true, []byte{0x01, 0x07}},
+		{"Uint16Zero", uint16(0), []byte{0xa0}},
+		{"Uint16", uint16(0x1234), []byte{0xa2, 0x12, 0x34}},
+		{"Uint32", uint32(0x0100), []byte{0xc2, 0x01, 0x00}},
+		{"Uint64", uint64(1), []byte{0x01, 0x02, 0x01}},
+		{"Int32Positive", int32(1), []byte{0x01, 0x01, 0x01}},
+		{"Int32Negative", int32(-1), []byte{0x04, 0x01, 0xff, 0xff, 0xff, 0xff}},
+		{"Float32", float32(1), []byte{0x04, 0x08, 0x3f, 0x80, 0x00, 0x00}},
+		{"Float64", float64(1), []byte{0x68, 0x3f, 0xf0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"StringArray", []string{"a"}, []byte{0x01, 0x04, 0x41, 'a'}},
+		{"Array", []interface{}{uint16(1)}, []byte{0x01, 0x04, 0xa1, 0x01}},
+		{"StringMap", map[string]string{"b": "2", "a": "1"}, []byte{0xe2, 0x41, 'a', 0x41, '1', 0x41, 'b', 0x41, '2'}},
+		{"Map", map[string]interface{}{"a": uint16(1)}, []byte{0xe1, 0x41, 'a', 0xa1, 0x01}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			assert.NoError(t, encodeValue(buf, testCase.Value))
+			assert.EqualValues(t, testCase.Expected, buf.Bytes())
+		})
+	}
+
+	t.Run("UnsupportedType", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		assert.EqualError(t, encodeValue(buf, 1), "unsupported data type int")
+	})
+
+	t.Run("UnsupportedNestedType", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		assert.EqualError(t, encodeValue(buf, map[string]interface{}{"a": 1}), "unsupported data type int")
+		assert.EqualError(t, encodeValue(buf, []interface{}{1}), "unsupported data type int")
+	})
+}
+
+func TestWriteControl(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Size     int
+		Expected []byte
+	}{
+		{"Size28", 28, []byte{0x5c}},
+		{"Size29", 29, []byte{0x5d, 0x00}},
+		{"Size284", 284, []byte{0x5d, 0xff}},
+		{"Size285", 285, []byte{0x5e, 0x00, 0x00}},
+		{"Size65820", 65820, []byte{0x5e, 0xff, 0xff}},
+		{"Size65821", 65821, []byte{0x5f, 0x00, 0x00, 0x00}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Name, func(t *testing.T) {
+			buf := bytes.NewBufferString("")
+			writeControl(buf, dataTypeString, testCase.Size)
+			assert.EqualValues(t, testCase.Expected, buf.Bytes())
+		})
+	}
+
+	t.Run("LongStringRoundTrip", func(t *testing.T) {
+		for _, size := range []int{29, 285, 65821} {
+			value := strings.Repeat("x", size)
+
+			buf := bytes.NewBufferString("")
+			require.NoError(t, encodeValue(buf, map[string]interface{}{"value": value}))
+
+			db := writeRawDatabase(t, buf.Bytes())
+			var decoded struct {
+				Value string `maxminddb:"value"`
+			}
+			require.NoError(t, db.Decode(0, &decoded))
+			assert.EqualValues(t, value, decoded.Value)
+		}
+	})
+}
+
+// writeRawDatabase returns a reader for a minimal IPv4 database with the given data section
+func writeRawDatabase(t *testing.T, data []byte) *maxminddb.Reader {
+	buf := bytes.NewBuffer(encodeNode(RecordSize24, 1, 1))
+	buf.Write(make([]byte, dataSectionSeparatorSize))
+	buf.Write(data)
+	buf.Write(metadataStartMarker)
+	require.NoError(t, encodeValue(buf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(0),
+		"database_type":               "Test",
+		"ip_version":                  uint16(4),
+		"node_count":                  uint32(1),
+		"record_size":                 uint16(24),
+	}))
+
+	db, err := maxminddb.FromBytes(buf.Bytes())
+	require.NoError(t, err)
+	return db
+}
@@ -19,9 +19,15 @@ func (format) FormatName() string {
 }
 
 func (format) NewReaderAt(r geodbtools.ReaderSource) (reader geodbtools.Reader, meta geodbtools.Metadata, err error) {
-	buf := make([]byte, r.Size())
-	if _, err = r.ReadAt(buf, 0); err != nil {
-		return
+	// sources providing a byte view are used directly, all others are copied into memory
+	var buf []byte
+	if bytesSource, ok := r.(geodbtools.BytesReaderSource); ok {
+		buf = bytesSource.Bytes()
+	} else {
+		buf = make([]byte, r.Size())
+		if _, err = r.ReadAt(buf, 0); err != nil {
+			return
+		}
 	}
 
 	var mmdbReader *maxminddb.Reader
@@ -38,6 +44,16 @@ func (format) NewReaderAt(r geodbtools.ReaderSource) (reader geodbtools.Reader,
 		return
 	}
 
+	// readers supporting native lookups walk the search tree directly
+	if treeReader, ok := reader.(searchTreeReader); ok {
+		var tree *searchTree
+		if tree, err = newSearchTree(buf, mmdbReader.Metadata); err != nil {
+			reader = nil
+			return
+		}
+		treeReader.setSearchTree(tree)
+	}
+
 	buildTime := time.Unix(int64(mmdbReader.Metadata.BuildEpoch), 0)
 	meta = geodbtools.Metadata{
 		Type:               t.DatabaseType(),
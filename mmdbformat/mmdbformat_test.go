@@ -3,6 +3,7 @@ package mmdbformat
 import (
 	"bytes"
 	"errors"
+	"net"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -189,6 +190,25 @@ func TestFormat_NewReaderAt(t *testing.T) {
 	})
 }
 
+func TestFormat_NewReaderAt_BytesReaderSource(t *testing.T) {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	testPath := filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", "GeoIP2-Country-Test.mmdb")
+
+	src, err := geodbtools.NewMmapReaderSource(testPath)
+	require.NoError(t, err)
+	defer src.Close()
+
+	reader, meta, err := format{}.NewReaderAt(src)
+	require.NoError(t, err)
+	assert.EqualValues(t, geodbtools.DatabaseTypeCountry, meta.Type)
+
+	record, err := reader.LookupIP(net.ParseIP("81.2.69.160"))
+	require.NoError(t, err)
+	assert.EqualValues(t, "GB", record.(geodbtools.CountryRecord).GetCountryCode())
+}
+
 func TestFormat_NewWriter(t *testing.T) {
 	t.Run("TypeNotFoundError", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -3,15 +3,18 @@ package mmdbformat
 import (
 	"fmt"
 	"net"
+	"net/netip"
 
 	"github.com/anexia-it/geodbtools"
 )
 
 var _ geodbtools.CountryRecord = (*countryRecord)(nil)
 var _ Record = (*countryRecord)(nil)
+var _ geodbtools.PrefixRecord = (*countryRecord)(nil)
 
 // countryRecord represents a record with country information
 type countryRecord struct {
+	prefix  netip.Prefix
 	network *net.IPNet
 
 	Country struct {
@@ -20,9 +23,15 @@ type countryRecord struct {
 }
 
 func (r *countryRecord) SetNetwork(network *net.IPNet) {
+	r.prefix = netip.Prefix{}
 	r.network = network
 }
 
+func (r *countryRecord) SetPrefix(prefix netip.Prefix) {
+	r.prefix = prefix.Masked()
+	r.network = geodbtools.IPNetFromPrefix(r.prefix)
+}
+
 func (r *countryRecord) String() string {
 	return fmt.Sprintf("%s: country code %s", r.network, r.Country.ISOCode)
 }
@@ -38,6 +47,227 @@ func (r *countryRecord) GetNetwork() *net.IPNet {
 	return r.network
 }
 
+func (r *countryRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
 func (r *countryRecord) GetCountryCode() string {
 	return r.Country.ISOCode
 }
+
+var _ geodbtools.ASNRecord = (*asnRecord)(nil)
+var _ Record = (*asnRecord)(nil)
+var _ geodbtools.PrefixRecord = (*asnRecord)(nil)
+
+// asnRecord represents a record with autonomous system information
+type asnRecord struct {
+	prefix  netip.Prefix
+	network *net.IPNet
+
+	ASNumber     uint32 `maxminddb:"autonomous_system_number"`
+	Organization string `maxminddb:"autonomous_system_organization"`
+}
+
+func (r *asnRecord) SetNetwork(network *net.IPNet) {
+	r.prefix = netip.Prefix{}
+	r.network = network
+}
+
+func (r *asnRecord) SetPrefix(prefix netip.Prefix) {
+	r.prefix = prefix.Masked()
+	r.network = geodbtools.IPNetFromPrefix(r.prefix)
+}
+
+func (r *asnRecord) String() string {
+	return fmt.Sprintf("%s: AS%d, organization %s", r.network, r.ASNumber, r.Organization)
+}
+
+func (r *asnRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *asnRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
+func (r *asnRecord) GetASNumber() uint32 {
+	return r.ASNumber
+}
+
+func (r *asnRecord) GetOrganization() string {
+	return r.Organization
+}
+
+// geoNameEntity holds the information shared by the geographical entities of a city record
+type geoNameEntity struct {
+	GeoNameID uint32            `maxminddb:"geoname_id"`
+	ISOCode   string            `maxminddb:"iso_code"`
+	Names     map[string]string `maxminddb:"names"`
+}
+
+var _ geodbtools.LocalizedCityRecord = (*cityRecord)(nil)
+var _ geodbtools.CountryNameRecord = (*cityRecord)(nil)
+var _ geodbtools.RegionRecord = (*cityRecord)(nil)
+var _ geodbtools.SubdivisionRecord = (*cityRecord)(nil)
+var _ geodbtools.PostalCodeRecord = (*cityRecord)(nil)
+var _ geodbtools.LocationRecord = (*cityRecord)(nil)
+var _ geodbtools.AccuracyRadiusRecord = (*cityRecord)(nil)
+var _ geodbtools.TimeZoneRecord = (*cityRecord)(nil)
+var _ geodbtools.MetroCodeRecord = (*cityRecord)(nil)
+var _ geodbtools.ContinentRecord = (*cityRecord)(nil)
+var _ geodbtools.RegisteredCountryRecord = (*cityRecord)(nil)
+var _ geodbtools.RepresentedCountryRecord = (*cityRecord)(nil)
+var _ Record = (*cityRecord)(nil)
+var _ geodbtools.PrefixRecord = (*cityRecord)(nil)
+
+// cityRecord represents a record with city information
+type cityRecord struct {
+	prefix  netip.Prefix
+	network *net.IPNet
+
+	City struct {
+		GeoNameID uint32            `maxminddb:"geoname_id"`
+		Names     map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+
+	Continent struct {
+		Code      string            `maxminddb:"code"`
+		GeoNameID uint32            `maxminddb:"geoname_id"`
+		Names     map[string]string `maxminddb:"names"`
+	} `maxminddb:"continent"`
+
+	Country geoNameEntity `maxminddb:"country"`
+
+	Location struct {
+		AccuracyRadius uint16  `maxminddb:"accuracy_radius"`
+		Latitude       float64 `maxminddb:"latitude"`
+		Longitude      float64 `maxminddb:"longitude"`
+		MetroCode      uint16  `maxminddb:"metro_code"`
+		TimeZone       string  `maxminddb:"time_zone"`
+	} `maxminddb:"location"`
+
+	Postal struct {
+		Code string `maxminddb:"code"`
+	} `maxminddb:"postal"`
+
+	RegisteredCountry geoNameEntity `maxminddb:"registered_country"`
+
+	RepresentedCountry struct {
+		GeoNameID uint32            `maxminddb:"geoname_id"`
+		ISOCode   string            `maxminddb:"iso_code"`
+		Names     map[string]string `maxminddb:"names"`
+		Type      string            `maxminddb:"type"`
+	} `maxminddb:"represented_country"`
+
+	Subdivisions []geoNameEntity `maxminddb:"subdivisions"`
+}
+
+func (r *cityRecord) SetNetwork(network *net.IPNet) {
+	r.prefix = netip.Prefix{}
+	r.network = network
+}
+
+func (r *cityRecord) SetPrefix(prefix netip.Prefix) {
+	r.prefix = prefix.Masked()
+	r.network = geodbtools.IPNetFromPrefix(r.prefix)
+}
+
+func (r *cityRecord) String() string {
+	return fmt.Sprintf("%s: country code %s, region %s, city %s, postal code %s, location %.4f,%.4f",
+		r.network, r.Country.ISOCode, r.GetRegionCode(), r.GetCityName(), r.Postal.Code, r.Location.Latitude, r.Location.Longitude)
+}
+
+func (r *cityRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func (r *cityRecord) GetPrefix() netip.Prefix {
+	if r.prefix.IsValid() {
+		return r.prefix
+	}
+	return geodbtools.PrefixFromIPNet(r.network)
+}
+
+func (r *cityRecord) GetCountryCode() string {
+	return r.Country.ISOCode
+}
+
+// GetCountryName returns the english name of the country
+func (r *cityRecord) GetCountryName() string {
+	return r.Country.Names["en"]
+}
+
+// GetCityName returns the english name of the city
+func (r *cityRecord) GetCityName() string {
+	return r.City.Names["en"]
+}
+
+func (r *cityRecord) GetCityNames() map[string]string {
+	return r.City.Names
+}
+
+// GetRegionCode returns the ISO code of the largest subdivision
+func (r *cityRecord) GetRegionCode() string {
+	if len(r.Subdivisions) == 0 {
+		return ""
+	}
+	return r.Subdivisions[0].ISOCode
+}
+
+func (r *cityRecord) GetSubdivisionCodes() (codes []string) {
+	for _, subdivision := range r.Subdivisions {
+		codes = append(codes, subdivision.ISOCode)
+	}
+	return
+}
+
+func (r *cityRecord) GetPostalCode() string {
+	return r.Postal.Code
+}
+
+func (r *cityRecord) GetLatitude() float64 {
+	return r.Location.Latitude
+}
+
+func (r *cityRecord) GetLongitude() float64 {
+	return r.Location.Longitude
+}
+
+func (r *cityRecord) GetAccuracyRadius() uint {
+	return uint(r.Location.AccuracyRadius)
+}
+
+func (r *cityRecord) GetTimeZone() string {
+	return r.Location.TimeZone
+}
+
+func (r *cityRecord) GetMetroCode() int {
+	return int(r.Location.MetroCode)
+}
+
+// GetAreaCode always returns 0, as area codes are not part of MMDB databases
+func (r *cityRecord) GetAreaCode() int {
+	return 0
+}
+
+func (r *cityRecord) GetContinentCode() string {
+	return r.Continent.Code
+}
+
+func (r *cityRecord) GetRegisteredCountryCode() string {
+	return r.RegisteredCountry.ISOCode
+}
+
+func (r *cityRecord) GetRepresentedCountryCode() string {
+	return r.RepresentedCountry.ISOCode
+}
+
+func (r *cityRecord) GetRepresentedCountryType() string {
+	return r.RepresentedCountry.Type
+}
@@ -67,3 +67,102 @@ func TestCountryRecord_SetNetwork(t *testing.T) {
 	rec.SetNetwork(network)
 	assert.EqualValues(t, network, rec.network)
 }
+
+func TestASNRecord_Getters(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+
+	rec := &asnRecord{
+		ASNumber:     64512,
+		Organization: "Test Organization",
+	}
+	rec.SetNetwork(network)
+
+	assert.EqualValues(t, network, rec.GetNetwork())
+	assert.EqualValues(t, 64512, rec.GetASNumber())
+	assert.EqualValues(t, "Test Organization", rec.GetOrganization())
+}
+
+func TestASNRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+	rec := &asnRecord{
+		network:      network,
+		ASNumber:     64512,
+		Organization: "Test Organization",
+	}
+
+	assert.EqualValues(t, "127.0.0.127/32: AS64512, organization Test Organization", rec.String())
+}
+
+func TestCityRecord_Getters(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.0/8")
+	require.NoError(t, err)
+
+	t.Run("Empty", func(t *testing.T) {
+		rec := &cityRecord{}
+		assert.EqualValues(t, "", rec.GetCityName())
+		assert.EqualValues(t, "", rec.GetRegionCode())
+		assert.Nil(t, rec.GetSubdivisionCodes())
+		assert.EqualValues(t, 0, rec.GetAccuracyRadius())
+		assert.EqualValues(t, 0, rec.GetMetroCode())
+		assert.EqualValues(t, 0, rec.GetAreaCode())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		rec := &cityRecord{}
+		rec.SetNetwork(network)
+		rec.City.Names = map[string]string{"en": "Vienna", "de": "Wien"}
+		rec.Continent.Code = "EU"
+		rec.Country.ISOCode = "AT"
+		rec.Country.Names = map[string]string{"en": "Austria", "de": "Österreich"}
+		rec.Location.AccuracyRadius = 20
+		rec.Location.Latitude = 48.2
+		rec.Location.Longitude = 16.3667
+		rec.Location.MetroCode = 42
+		rec.Location.TimeZone = "Europe/Vienna"
+		rec.Postal.Code = "1010"
+		rec.RegisteredCountry.ISOCode = "DE"
+		rec.RepresentedCountry.ISOCode = "US"
+		rec.RepresentedCountry.Type = "military"
+		rec.Subdivisions = []geoNameEntity{
+			{ISOCode: "9"},
+			{ISOCode: "X"},
+		}
+
+		assert.EqualValues(t, network, rec.GetNetwork())
+		assert.EqualValues(t, "AT", rec.GetCountryCode())
+		assert.EqualValues(t, "Austria", rec.GetCountryName())
+		assert.EqualValues(t, "Vienna", rec.GetCityName())
+		assert.EqualValues(t, map[string]string{"en": "Vienna", "de": "Wien"}, rec.GetCityNames())
+		assert.EqualValues(t, "9", rec.GetRegionCode())
+		assert.EqualValues(t, []string{"9", "X"}, rec.GetSubdivisionCodes())
+		assert.EqualValues(t, "1010", rec.GetPostalCode())
+		assert.EqualValues(t, 48.2, rec.GetLatitude())
+		assert.EqualValues(t, 16.3667, rec.GetLongitude())
+		assert.EqualValues(t, 20, rec.GetAccuracyRadius())
+		assert.EqualValues(t, "Europe/Vienna", rec.GetTimeZone())
+		assert.EqualValues(t, 42, rec.GetMetroCode())
+		assert.EqualValues(t, 0, rec.GetAreaCode())
+		assert.EqualValues(t, "EU", rec.GetContinentCode())
+		assert.EqualValues(t, "DE", rec.GetRegisteredCountryCode())
+		assert.EqualValues(t, "US", rec.GetRepresentedCountryCode())
+		assert.EqualValues(t, "military", rec.GetRepresentedCountryType())
+	})
+}
+
+func TestCityRecord_String(t *testing.T) {
+	_, network, err := net.ParseCIDR("127.0.0.127/32")
+	require.NoError(t, err)
+	rec := &cityRecord{
+		network: network,
+	}
+	rec.City.Names = map[string]string{"en": "Vienna"}
+	rec.Country.ISOCode = "AT"
+	rec.Location.Latitude = 48.2
+	rec.Location.Longitude = 16.3667
+	rec.Postal.Code = "1010"
+	rec.Subdivisions = []geoNameEntity{{ISOCode: "9"}}
+
+	assert.EqualValues(t, "127.0.0.127/32: country code AT, region 9, city Vienna, postal code 1010, location 48.2000,16.3667", rec.String())
+}
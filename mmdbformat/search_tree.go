@@ -0,0 +1,235 @@
+package mmdbformat
+
+import (
+	"net"
+	"net/netip"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// searchTree provides native access to the search tree of a database, without converting addresses to net.IP
+type searchTree struct {
+	buffer     []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+
+	// ipv4Start holds the node IPv4 addresses start at inside IPv6 trees
+	ipv4Start uint
+}
+
+// newSearchTree returns the search tree stored inside the given database buffer
+func newSearchTree(buffer []byte, metadata maxminddb.Metadata) (t *searchTree, err error) {
+	tree := &searchTree{
+		buffer:     buffer,
+		nodeCount:  metadata.NodeCount,
+		recordSize: metadata.RecordSize,
+		ipVersion:  metadata.IPVersion,
+	}
+
+	if tree.recordSize != 24 && tree.recordSize != 28 && tree.recordSize != 32 {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	} else if tree.nodeCount*tree.recordSize/4 > uint(len(buffer)) {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	if tree.ipVersion == 6 {
+		// IPv4 addresses are stored inside ::/96
+		for i := 0; i < 96 && tree.ipv4Start < tree.nodeCount; i++ {
+			if tree.ipv4Start, err = tree.readNode(tree.ipv4Start, 0); err != nil {
+				return
+			}
+		}
+	}
+
+	t = tree
+	return
+}
+
+// readNode reads the record with the given index (0 for left, 1 for right) of the node with the given number
+func (t *searchTree) readNode(node uint, index uint) (value uint, err error) {
+	offset := node * t.recordSize / 4
+	if node >= t.nodeCount || offset+t.recordSize/4 > uint(len(t.buffer)) {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	b := t.buffer[offset:]
+	switch t.recordSize {
+	case 24:
+		b = b[index*3:]
+		value = uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		if index == 0 {
+			value = uint(b[3]&0xf0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		} else {
+			value = uint(b[3]&0x0f)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+		}
+	case 32:
+		b = b[index*4:]
+		value = uint(b[0])<<24 | uint(b[1])<<16 | uint(b[2])<<8 | uint(b[3])
+	}
+	return
+}
+
+// lookup walks the search tree for the given address, returning the data section offset of its record and the
+// prefix the record was found for
+func (t *searchTree) lookup(addr netip.Addr) (offset uintptr, prefix netip.Prefix, err error) {
+	if addr = addr.Unmap(); !addr.IsValid() || (addr.Is6() && t.ipVersion != 6) {
+		err = geodbtools.ErrRecordNotFound
+		return
+	}
+
+	ip := addr.As16()
+	bitCount := uint(addr.BitLen())
+	bitOffset := 128 - bitCount
+
+	var node uint
+	if addr.Is4() {
+		node = t.ipv4Start
+	}
+
+	var depth uint
+	for ; depth < bitCount && node < t.nodeCount; depth++ {
+		bit := bitOffset + depth
+		if node, err = t.readNode(node, uint(ip[bit>>3]>>(7-bit&7))&1); err != nil {
+			return
+		}
+	}
+
+	if node == t.nodeCount {
+		// no record for this network, the prefix of the empty network is reported nonetheless
+		prefix = netip.PrefixFrom(addr, int(depth)).Masked()
+		err = geodbtools.ErrRecordNotFound
+		return
+	} else if node < t.nodeCount {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	if offset, err = t.dataOffset(node); err != nil {
+		return
+	}
+	prefix = netip.PrefixFrom(addr, int(depth)).Masked()
+	return
+}
+
+// dataOffset returns the data section offset referenced by the given search tree value
+func (t *searchTree) dataOffset(value uint) (offset uintptr, err error) {
+	resolved := value - t.nodeCount - dataSectionSeparatorSize
+	if value < t.nodeCount+dataSectionSeparatorSize || resolved >= uint(len(t.buffer)) {
+		err = geodbtools.ErrDatabaseInvalid
+		return
+	}
+
+	offset = uintptr(resolved)
+	return
+}
+
+// searchTreeReader describes a reader able to look up addresses using a searchTree
+type searchTreeReader interface {
+	geodbtools.AddrReader
+	geodbtools.NetworkReader
+	geodbtools.IterableReader
+
+	// setSearchTree sets the search tree used for lookups
+	setSearchTree(tree *searchTree)
+}
+
+// lookupRecord looks up the given address using the given search tree, decoding the record found into rec and
+// returning the prefix of the network containing the address.
+// Addresses inside networks without data are reported with an empty record, along with the prefix of the empty
+// network.
+// Without a search tree, the address is looked up using the given maxminddb reader, which does not report the
+// containing network. The address itself is reported as network in that case.
+func lookupRecord(dbReader *maxminddb.Reader, tree *searchTree, addr netip.Addr, rec Record) (record geodbtools.Record, prefix netip.Prefix, err error) {
+	found := true
+	var offset uintptr
+	if tree != nil {
+		if offset, prefix, err = tree.lookup(addr); err == geodbtools.ErrRecordNotFound && prefix.IsValid() {
+			found = false
+			err = nil
+		} else if err != nil {
+			return
+		}
+	} else if !addr.IsValid() {
+		err = geodbtools.ErrRecordNotFound
+		return
+	} else if offset, err = dbReader.LookupOffset(net.IP(addr.AsSlice())); err != nil {
+		return
+	} else {
+		found = offset != maxminddb.NotFound
+		addr = addr.Unmap()
+		prefix = netip.PrefixFrom(addr, addr.BitLen())
+	}
+
+	if found {
+		if err = dbReader.Decode(offset, rec); err != nil {
+			prefix = netip.Prefix{}
+			return
+		}
+	}
+	rec.SetPrefix(prefix)
+
+	record = rec
+	return
+}
+
+var _ geodbtools.SearchTree = (*recordSearchTree)(nil)
+
+// recordSearchTree provides a searchTree to geodbtools.NewSearchTreeIterator, decoding leaves using a record factory
+type recordSearchTree struct {
+	*searchTree
+	dbReader *maxminddb.Reader
+	factory  RecordFactory
+}
+
+func (t *recordSearchTree) IPVersion() geodbtools.IPVersion {
+	return geodbtools.IPVersion(t.ipVersion)
+}
+
+func (t *recordSearchTree) NodeCount() uint {
+	return t.nodeCount
+}
+
+func (t *recordSearchTree) ReadNode(node uint, index uint) (value uint, err error) {
+	return t.readNode(node, index)
+}
+
+func (t *recordSearchTree) ReadLeaf(value uint, prefix netip.Prefix) (record geodbtools.Record, err error) {
+	if value == t.nodeCount {
+		// no record for this network
+		return
+	}
+
+	var offset uintptr
+	if offset, err = t.dataOffset(value); err != nil {
+		return
+	}
+
+	rec := t.factory()
+	if err = t.dbReader.Decode(offset, rec); err != nil {
+		return
+	}
+	rec.SetPrefix(prefix)
+
+	record = rec
+	return
+}
+
+// networks returns an iterator over the networks matching the given filter, walking the given search tree.
+// Without a search tree, the networks are taken from the reader's RecordTree.
+func networks(reader geodbtools.Reader, dbReader *maxminddb.Reader, tree *searchTree, filter geodbtools.NetworkFilter, factory RecordFactory) geodbtools.NetworkIterator {
+	if tree == nil {
+		return geodbtools.RecordTreeNetworks(reader, filter)
+	}
+
+	return geodbtools.NewSearchTreeIterator(&recordSearchTree{
+		searchTree: tree,
+		dbReader:   dbReader,
+		factory:    factory,
+	}, filter)
+}
@@ -0,0 +1,341 @@
+package mmdbformat
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/anexia-it/geodbtools"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDatabase returns the contents of the test database with the given name
+func testDatabase(t *testing.T, name string) (buf []byte, mmdbReader *maxminddb.Reader) {
+	_, testFilename, _, ok := runtime.Caller(0)
+	require.True(t, ok)
+
+	buf, err := ioutil.ReadFile(filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", name))
+	require.NoError(t, err)
+
+	mmdbReader, err = maxminddb.FromBytes(buf)
+	require.NoError(t, err)
+	return
+}
+
+func TestNewSearchTree(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+
+	t.Run("InvalidRecordSize", func(t *testing.T) {
+		metadata := mmdbReader.Metadata
+		metadata.RecordSize = 16
+
+		tree, err := newSearchTree(buf, metadata)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("InvalidNodeCount", func(t *testing.T) {
+		metadata := mmdbReader.Metadata
+		metadata.NodeCount = uint(len(buf))
+
+		tree, err := newSearchTree(buf, metadata)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		tree, err := newSearchTree(buf, mmdbReader.Metadata)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			assert.EqualValues(t, mmdbReader.Metadata.NodeCount, tree.nodeCount)
+			assert.EqualValues(t, 24, tree.recordSize)
+			assert.EqualValues(t, 4, tree.ipVersion)
+			assert.EqualValues(t, 0, tree.ipv4Start)
+		}
+	})
+}
+
+func TestSearchTree_lookup(t *testing.T) {
+	ipv4Prefixes := map[string]string{
+		"1.1.1.1":  "1.1.1.1/32",
+		"1.1.1.3":  "1.1.1.2/31",
+		"1.1.1.7":  "1.1.1.4/30",
+		"1.1.1.15": "1.1.1.8/29",
+		"1.1.1.17": "1.1.1.16/28",
+		"1.1.1.32": "1.1.1.32/32",
+	}
+	ipv6Prefixes := map[string]string{
+		"::1:ffff:ffff": "::1:ffff:ffff/128",
+		"::2:0:1":       "::2:0:0/122",
+		"::2:0:41":      "::2:0:40/124",
+		"::2:0:51":      "::2:0:50/125",
+		"::2:0:59":      "::2:0:58/127",
+	}
+	notFound := []string{"1.1.1.33", "::2:0:60", "2001:db8::1"}
+
+	for _, name := range []string{"ipv4", "ipv6", "mixed"} {
+		for _, recordSize := range []string{"24", "28", "32"} {
+			t.Run(name+"-"+recordSize, func(t *testing.T) {
+				buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-"+name+"-"+recordSize+".mmdb")
+
+				tree, err := newSearchTree(buf, mmdbReader.Metadata)
+				require.NoError(t, err)
+
+				prefixes := map[string]string{}
+				if name != "ipv6" {
+					for ip, prefix := range ipv4Prefixes {
+						prefixes[ip] = prefix
+					}
+				}
+				if name != "ipv4" {
+					for ip, prefix := range ipv6Prefixes {
+						prefixes[ip] = prefix
+					}
+				}
+
+				for ip, expectedPrefix := range prefixes {
+					expectedOffset, err := mmdbReader.LookupOffset(net.ParseIP(ip))
+					require.NoError(t, err, ip)
+
+					offset, prefix, err := tree.lookup(netip.MustParseAddr(ip))
+					if assert.NoError(t, err, ip) {
+						assert.EqualValues(t, expectedOffset, offset, ip)
+						assert.EqualValues(t, expectedPrefix, prefix.String(), ip)
+					}
+				}
+
+				for _, ip := range notFound {
+					_, _, err := tree.lookup(netip.MustParseAddr(ip))
+					assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error(), ip)
+				}
+			})
+		}
+	}
+
+	t.Run("IPv4MappedIPv6", func(t *testing.T) {
+		buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+
+		tree, err := newSearchTree(buf, mmdbReader.Metadata)
+		require.NoError(t, err)
+
+		_, prefix, err := tree.lookup(netip.MustParseAddr("::ffff:1.1.1.3"))
+		assert.NoError(t, err)
+		assert.EqualValues(t, netip.MustParsePrefix("1.1.1.2/31"), prefix)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+
+		tree, err := newSearchTree(buf, mmdbReader.Metadata)
+		require.NoError(t, err)
+
+		_, _, err = tree.lookup(netip.Addr{})
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+
+}
+
+func TestSearchTree_readNode(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+
+	tree, err := newSearchTree(buf, mmdbReader.Metadata)
+	require.NoError(t, err)
+
+	_, err = tree.readNode(tree.nodeCount, 0)
+	assert.EqualError(t, err, geodbtools.ErrDatabaseInvalid.Error())
+}
+
+func TestLookupAddr(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "GeoIP2-Country-Test.mmdb")
+	addr := netip.MustParseAddr("81.2.69.160")
+
+	t.Run("SearchTree", func(t *testing.T) {
+		tree, err := newSearchTree(buf, mmdbReader.Metadata)
+		require.NoError(t, err)
+
+		reader := &countryReader{
+			r:    mmdbReader,
+			tree: tree,
+		}
+
+		record, err := reader.LookupAddr(addr)
+		require.NoError(t, err)
+		if assert.IsType(t, &countryRecord{}, record) {
+			rec := record.(*countryRecord)
+			assert.EqualValues(t, "GB", rec.GetCountryCode())
+			assert.True(t, rec.GetPrefix().Contains(addr))
+			assert.EqualValues(t, geodbtools.IPNetFromPrefix(rec.GetPrefix()), rec.GetNetwork())
+		}
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		reader := &countryReader{
+			r: mmdbReader,
+		}
+
+		record, err := reader.LookupAddr(addr)
+		require.NoError(t, err)
+		assert.EqualValues(t, "GB", record.(geodbtools.CountryRecord).GetCountryCode())
+	})
+
+	t.Run("FallbackInvalidAddr", func(t *testing.T) {
+		reader := &countryReader{
+			r: mmdbReader,
+		}
+
+		record, err := reader.LookupAddr(netip.Addr{})
+		assert.Nil(t, record)
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+}
+
+func TestLookupNetwork(t *testing.T) {
+	buf, mmdbReader := testDatabase(t, "GeoIP2-Country-Test.mmdb")
+	tree, err := newSearchTree(buf, mmdbReader.Metadata)
+	require.NoError(t, err)
+
+	reader := &countryReader{
+		r:    mmdbReader,
+		tree: tree,
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		addr := netip.MustParseAddr("81.2.69.160")
+
+		record, prefix, err := geodbtools.LookupNetwork(reader, addr)
+		require.NoError(t, err)
+		assert.EqualValues(t, "GB", record.(geodbtools.CountryRecord).GetCountryCode())
+		assert.True(t, prefix.Contains(addr))
+		assert.True(t, prefix.Bits() < 32)
+		assert.EqualValues(t, prefix, geodbtools.RecordPrefix(record))
+	})
+
+	t.Run("IPv4MappedAddr", func(t *testing.T) {
+		_, prefix, err := geodbtools.LookupNetwork(reader, netip.MustParseAddr("::ffff:81.2.69.160"))
+		require.NoError(t, err)
+		assert.True(t, prefix.Addr().Is4())
+		assert.True(t, prefix.Contains(netip.MustParseAddr("81.2.69.160")))
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		addr := netip.MustParseAddr("2001:db8::1")
+
+		record, prefix, err := geodbtools.LookupNetwork(reader, addr)
+		require.NoError(t, err)
+		assert.Empty(t, record.(geodbtools.CountryRecord).GetCountryCode())
+		assert.True(t, prefix.Contains(addr))
+		assert.EqualValues(t, prefix, geodbtools.RecordPrefix(record))
+	})
+
+	t.Run("InvalidAddr", func(t *testing.T) {
+		record, prefix, err := geodbtools.LookupNetwork(reader, netip.Addr{})
+		assert.Nil(t, record)
+		assert.False(t, prefix.IsValid())
+		assert.EqualError(t, err, geodbtools.ErrRecordNotFound.Error())
+	})
+}
+
+// networkPrefixes returns the prefixes of all records reported by the given iterator
+func networkPrefixes(t *testing.T, it geodbtools.NetworkIterator) (prefixes []string) {
+	for it.Next() {
+		prefixes = append(prefixes, geodbtools.RecordPrefix(it.Record()).String())
+	}
+	require.NoError(t, it.Err())
+	return
+}
+
+func TestNetworks(t *testing.T) {
+	ipv4Networks := []string{"1.1.1.1/32", "1.1.1.2/31", "1.1.1.4/30", "1.1.1.8/29", "1.1.1.16/28", "1.1.1.32/32"}
+
+	for _, recordSize := range []int{24, 28, 32} {
+		t.Run(fmt.Sprintf("IPv4-%d", recordSize), func(t *testing.T) {
+			buf, mmdbReader := testDatabase(t, fmt.Sprintf("MaxMind-DB-test-ipv4-%d.mmdb", recordSize))
+			tree, err := newSearchTree(buf, mmdbReader.Metadata)
+			require.NoError(t, err)
+
+			reader := &countryReader{
+				r:    mmdbReader,
+				tree: tree,
+			}
+
+			assert.EqualValues(t, ipv4Networks, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{})))
+			assert.EqualValues(t, ipv4Networks[:3], networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+				Prefix: netip.MustParsePrefix("1.1.1.0/29"),
+			})))
+
+			it := reader.Networks(geodbtools.NetworkFilter{
+				IPVersion: geodbtools.IPVersion6,
+			})
+			assert.False(t, it.Next())
+			assert.EqualError(t, it.Err(), geodbtools.ErrUnsupportedIPVersion.Error())
+		})
+
+		t.Run(fmt.Sprintf("Mixed-%d", recordSize), func(t *testing.T) {
+			buf, mmdbReader := testDatabase(t, fmt.Sprintf("MaxMind-DB-test-mixed-%d.mmdb", recordSize))
+			tree, err := newSearchTree(buf, mmdbReader.Metadata)
+			require.NoError(t, err)
+
+			reader := &countryReader{
+				r:    mmdbReader,
+				tree: tree,
+			}
+
+			// the aliases of the IPv4 sub-tree are not reported
+			assert.EqualValues(t, []string{
+				"::101:101/128", "::101:102/127", "::101:104/126", "::101:108/125", "::101:110/124", "::101:120/128",
+				"::1:ffff:ffff/128", "::2:0:0/122", "::2:0:40/124", "::2:0:50/125", "::2:0:58/127",
+			}, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{})))
+
+			assert.EqualValues(t, ipv4Networks, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+				IPVersion: geodbtools.IPVersion4,
+			})))
+			assert.EqualValues(t, ipv4Networks[4:5], networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+				Prefix: netip.MustParsePrefix("1.1.1.16/28"),
+			})))
+			assert.EqualValues(t, []string{"::2:0:40/124", "::2:0:50/125", "::2:0:58/127"}, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+				Prefix: netip.MustParsePrefix("::2:0:40/123"),
+			})))
+			assert.Empty(t, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+				Prefix: netip.MustParsePrefix("::ffff:0:0/96"),
+			})))
+		})
+	}
+
+	t.Run("Records", func(t *testing.T) {
+		buf, mmdbReader := testDatabase(t, "GeoIP2-Country-Test.mmdb")
+		tree, err := newSearchTree(buf, mmdbReader.Metadata)
+		require.NoError(t, err)
+
+		reader := &countryReader{
+			r:    mmdbReader,
+			tree: tree,
+		}
+
+		it := reader.Networks(geodbtools.NetworkFilter{
+			Prefix: netip.MustParsePrefix("81.2.69.160/32"),
+		})
+		require.True(t, it.Next())
+		assert.EqualValues(t, "GB", it.Record().(geodbtools.CountryRecord).GetCountryCode())
+		assert.True(t, geodbtools.RecordPrefix(it.Record()).Contains(netip.MustParseAddr("81.2.69.160")))
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		_, mmdbReader := testDatabase(t, "MaxMind-DB-test-ipv4-24.mmdb")
+
+		reader := &countryReader{
+			r: mmdbReader,
+		}
+
+		assert.EqualValues(t, ipv4Networks, networkPrefixes(t, reader.Networks(geodbtools.NetworkFilter{
+			IPVersion: geodbtools.IPVersion4,
+		})))
+	})
+}
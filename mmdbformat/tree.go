@@ -2,8 +2,8 @@ package mmdbformat
 
 import (
 	"net"
+	"net/netip"
 
-	"github.com/anexia-it/bitmap"
 	"github.com/anexia-it/geodbtools"
 	"github.com/oschwald/maxminddb-golang"
 )
@@ -14,6 +14,9 @@ type Record interface {
 
 	// SetNetwork sets the network of the record
 	SetNetwork(network *net.IPNet)
+
+	// SetPrefix sets the network of the record, given as netip.Prefix
+	SetPrefix(prefix netip.Prefix)
 }
 
 // RecordFactory defines the function type that returns a new record
@@ -21,21 +24,13 @@ type RecordFactory func() Record
 
 // BuildRecordTree builds a record tree
 func BuildRecordTree(reader *maxminddb.Reader, ipVersion geodbtools.IPVersion, factory RecordFactory) (tree *geodbtools.RecordTree, err error) {
-	var maxDepth uint
-	var belongsRightFunc geodbtools.RecordBelongsRightFunc
-
 	switch ipVersion {
 	case geodbtools.IPVersion6:
 		if reader.Metadata.IPVersion != 6 {
 			err = geodbtools.ErrUnsupportedIPVersion
 			return
 		}
-
-		maxDepth = 127
-		belongsRightFunc = geodbtools.RecordBelongsRightIPv6
 	case geodbtools.IPVersion4:
-		maxDepth = 31
-		belongsRightFunc = bitmap.IsSet
 	default:
 		err = geodbtools.ErrUnsupportedIPVersion
 		return
@@ -43,7 +38,7 @@ func BuildRecordTree(reader *maxminddb.Reader, ipVersion geodbtools.IPVersion, f
 	}
 
 	networks := reader.Networks()
-	records := make([]geodbtools.Record, 0, reader.Metadata.NodeCount)
+	tree = &geodbtools.RecordTree{}
 
 	for networks.Next() {
 		record := factory()
@@ -57,9 +52,31 @@ func BuildRecordTree(reader *maxminddb.Reader, ipVersion geodbtools.IPVersion, f
 		if ipVersion == geodbtools.IPVersion4 && network.IP.To4() == nil {
 			continue
 		}
-		records = append(records, record)
+		if err = tree.Insert(treePrefix(record, ipVersion), record); err != nil {
+			tree = nil
+			return
+		}
 	}
 
-	tree, err = geodbtools.NewRecordTree(maxDepth, records, belongsRightFunc)
+	if err = networks.Err(); err != nil {
+		tree = nil
+	}
 	return
 }
+
+// treePrefix returns the prefix a record is stored at inside the RecordTree of the given IP version.
+// IPv4 networks of IPv6 trees are stored inside ::/96, IPv4-mapped networks of IPv4 trees are unmapped.
+func treePrefix(record geodbtools.Record, ipVersion geodbtools.IPVersion) netip.Prefix {
+	prefix := geodbtools.RecordPrefix(record)
+
+	switch {
+	case ipVersion == geodbtools.IPVersion4 && prefix.Addr().Is4In6() && prefix.Bits() >= 96:
+		return netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+	case ipVersion == geodbtools.IPVersion6 && prefix.Addr().Is4():
+		var b [16]byte
+		a4 := prefix.Addr().As4()
+		copy(b[12:], a4[:])
+		return netip.PrefixFrom(netip.AddrFrom16(b), prefix.Bits()+96)
+	}
+	return prefix
+}
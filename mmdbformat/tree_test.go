@@ -2,6 +2,7 @@ package mmdbformat
 
 import (
 	"net"
+	"net/netip"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -153,3 +154,24 @@ func TestBuildRecordTree(t *testing.T) {
 		})
 	})
 }
+
+func TestTreePrefix(t *testing.T) {
+	testCases := map[string]struct {
+		prefix    string
+		ipVersion geodbtools.IPVersion
+		expected  string
+	}{
+		"IPv4":           {"1.2.3.0/24", geodbtools.IPVersion4, "1.2.3.0/24"},
+		"IPv4Mapped":     {"::ffff:1.2.3.0/120", geodbtools.IPVersion4, "1.2.3.0/24"},
+		"IPv4InIPv6Tree": {"1.2.3.0/24", geodbtools.IPVersion6, "::102:300/120"},
+		"IPv6":           {"2001:db8::/32", geodbtools.IPVersion6, "2001:db8::/32"},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			record := &countryRecord{}
+			record.SetPrefix(netip.MustParsePrefix(testCase.prefix))
+			assert.EqualValues(t, testCase.expected, treePrefix(record, testCase.ipVersion).String())
+		})
+	}
+}
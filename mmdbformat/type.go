@@ -22,6 +22,11 @@ const (
 	DatabaseTypeIDGeoLite2City DatabaseTypeID = "GeoLite2-City"
 	// DatabaseTypeIDGeoIP2City defines the database type of GeoIP2-City databases
 	DatabaseTypeIDGeoIP2City DatabaseTypeID = "GeoIP2-City"
+
+	// DatabaseTypeIDGeoLite2ASN defines the database type of GeoLite2-ASN databases
+	DatabaseTypeIDGeoLite2ASN DatabaseTypeID = "GeoLite2-ASN"
+	// DatabaseTypeIDGeoIP2ASN defines the database type of GeoIP2-ASN databases
+	DatabaseTypeIDGeoIP2ASN DatabaseTypeID = "GeoIP2-ASN"
 )
 
 // Type describes a database type
@@ -39,6 +44,9 @@ type Type interface {
 var typeRegistryMu sync.RWMutex
 var typeRegistry = map[DatabaseTypeID]Type{}
 
+// typeRegistrySeq holds the registration sequence number of each type ID
+var typeRegistrySeq = map[DatabaseTypeID]int{}
+
 var (
 	// ErrTypeRegistered indicates that the database type has already been registered
 	ErrTypeRegistered = errors.New("database type is registered")
@@ -58,6 +66,7 @@ func RegisterType(typeID DatabaseTypeID, t Type) (err error) {
 	}
 
 	typeRegistry[typeID] = t
+	typeRegistrySeq[typeID] = len(typeRegistrySeq)
 	return
 }
 
@@ -68,20 +77,25 @@ func MustRegisterType(typeID DatabaseTypeID, t Type) {
 	}
 }
 
-// LookupType retrieves the type for a given geodbtools.DatabaseType string
+// LookupType retrieves the type for a given geodbtools.DatabaseType string.
+// If multiple type IDs are registered for the database type, the one registered first is returned.
 func LookupType(dbType geodbtools.DatabaseType) (t Type, typeID DatabaseTypeID, err error) {
 	typeRegistryMu.RLock()
 	defer typeRegistryMu.RUnlock()
 
-	for typeID, t = range typeRegistry {
-		if t.DatabaseType() == dbType {
-			return
+	for candidateID, candidate := range typeRegistry {
+		if candidate.DatabaseType() != dbType {
+			continue
+		}
+
+		if t == nil || typeRegistrySeq[candidateID] < typeRegistrySeq[typeID] {
+			t, typeID = candidate, candidateID
 		}
 	}
 
-	t = nil
-	typeID = ""
-	err = ErrTypeNotFound
+	if t == nil {
+		err = ErrTypeNotFound
+	}
 	return
 }
 
@@ -152,6 +152,16 @@ func TestLookupType(t *testing.T) {
 
 		assert.EqualValues(t, "test", typeID)
 	})
+
+	t.Run("RegisteredFirst", func(t *testing.T) {
+		// the result does not depend on the iteration order of the registry
+		for i := 0; i < 10; i++ {
+			dbType, typeID, err := LookupType(geodbtools.DatabaseTypeCountry)
+			assert.NoError(t, err)
+			assert.EqualValues(t, countryType{DatabaseTypeIDGeoLite2Country}, dbType)
+			assert.EqualValues(t, DatabaseTypeIDGeoLite2Country, typeID)
+		}
+	})
 }
 
 func TestLookupTypeByDatabaseType(t *testing.T) {
@@ -0,0 +1,402 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/anexia-it/geodbtools"
+)
+
+var (
+	// ErrRecordSizeUnsupported indicates that the requested record size is not supported
+	ErrRecordSizeUnsupported = errors.New("unsupported record size")
+	// ErrRecordSizeTooSmall indicates that the search tree and data section do not fit the requested record size
+	ErrRecordSizeTooSmall = errors.New("record size too small for database")
+	// ErrTreeTooDeep indicates that the record tree is deeper than the number of bits of the IP version
+	ErrTreeTooDeep = errors.New("record tree too deep for IP version")
+	// ErrNetworkOutOfRange indicates that the network of a record is not part of the address space of the IP version
+	ErrNetworkOutOfRange = errors.New("network out of range for IP version")
+)
+
+// ipv4MappedPrefix holds the first 96 bits of IPv4-mapped IPv6 addresses (::ffff:0:0/96)
+var ipv4MappedPrefix = []byte{0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0xff, 0xff}
+
+// RecordSize defines the size of a search tree record in bits
+type RecordSize uint
+
+const (
+	// RecordSizeAuto selects the smallest record size the database fits into
+	RecordSizeAuto RecordSize = 0
+	// RecordSize24 defines 24-bit records
+	RecordSize24 RecordSize = 24
+	// RecordSize28 defines 28-bit records
+	RecordSize28 RecordSize = 28
+	// RecordSize32 defines 32-bit records
+	RecordSize32 RecordSize = 32
+)
+
+const dataSectionSeparatorSize = 16
+
+var metadataStartMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// RecordDataFunc returns the data section value for a given record.
+// A nil value causes the network represented by the record to be written without data.
+type RecordDataFunc func(record geodbtools.Record) (data map[string]interface{}, err error)
+
+// writerNode represents a node of the search tree during writing
+type writerNode struct {
+	children [2]*writerNode
+
+	// isLeaf indicates that the node points to the data section
+	isLeaf bool
+	// value holds the node number for internal nodes and the data section offset for leaf nodes
+	value uint32
+}
+
+var _ geodbtools.Writer = (*writer)(nil)
+
+type writer struct {
+	w          io.Writer
+	typeID     DatabaseTypeID
+	ipVersion  geodbtools.IPVersion
+	recordSize RecordSize
+	dataFunc   RecordDataFunc
+
+	bitCount    uint
+	data        *bytes.Buffer
+	dataOffsets map[string]uint32
+}
+
+func (w *writer) WriteDatabase(meta geodbtools.Metadata, tree *geodbtools.RecordTree) (err error) {
+	w.data = bytes.NewBufferString("")
+	w.dataOffsets = make(map[string]uint32)
+
+	var root *writerNode
+	if root, err = w.convertNode(tree, 0); err != nil {
+		return
+	} else if root == nil {
+		root = &writerNode{}
+	}
+
+	if w.ipVersion == geodbtools.IPVersion6 {
+		aliasIPv4(root)
+	}
+
+	nodes := numberNodes(root)
+	nodeCount := uint32(len(nodes))
+
+	recordSize := w.recordSize
+	maxValue := uint64(nodeCount) + dataSectionSeparatorSize + uint64(w.data.Len())
+	if recordSize == RecordSizeAuto {
+		for _, recordSize = range []RecordSize{RecordSize24, RecordSize28, RecordSize32} {
+			if maxValue < 1<<recordSize {
+				break
+			}
+		}
+	}
+
+	if maxValue >= 1<<recordSize {
+		err = ErrRecordSizeTooSmall
+		return
+	}
+
+	for _, node := range nodes {
+		var values [2]uint32
+		for i, child := range node.children {
+			switch {
+			case child == nil:
+				values[i] = nodeCount
+			case child.isLeaf:
+				values[i] = nodeCount + dataSectionSeparatorSize + child.value
+			default:
+				values[i] = child.value
+			}
+		}
+
+		if _, err = w.w.Write(encodeNode(recordSize, values[0], values[1])); err != nil {
+			return
+		}
+	}
+
+	if _, err = w.w.Write(make([]byte, dataSectionSeparatorSize)); err != nil {
+		return
+	}
+
+	if _, err = w.w.Write(w.data.Bytes()); err != nil {
+		return
+	}
+
+	metaBuf := bytes.NewBufferString("")
+	metaBuf.Write(metadataStartMarker)
+	if err = encodeValue(metaBuf, map[string]interface{}{
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"build_epoch":                 uint64(meta.BuildTime.Unix()),
+		"database_type":               string(w.typeID),
+		"description": map[string]string{
+			"en": meta.Description,
+		},
+		"ip_version":  uint16(w.ipVersion),
+		"languages":   []string{},
+		"node_count":  nodeCount,
+		"record_size": uint16(recordSize),
+	}); err != nil {
+		return
+	}
+
+	_, err = w.w.Write(metaBuf.Bytes())
+	return
+}
+
+// convertNode converts the given record tree into the search tree representation used for writing
+func (w *writer) convertNode(tree *geodbtools.RecordTree, depth uint) (node *writerNode, err error) {
+	if tree == nil {
+		return
+	}
+
+	if leaf := tree.Leaf(); leaf != nil {
+		if depth == 0 {
+			return w.rootLeafNode(leaf, tree.Covering())
+		}
+		return w.leafNode(leaf, depth, tree.Covering())
+	}
+
+	if depth >= w.bitCount {
+		err = ErrTreeTooDeep
+		return
+	}
+
+	var left, right *writerNode
+	if left, err = w.convertNode(tree.Left(), depth+1); err != nil {
+		return
+	} else if right, err = w.convertNode(tree.Right(), depth+1); err != nil {
+		return
+	}
+
+	if left == nil && right == nil {
+		return
+	}
+
+	node = &writerNode{
+		children: [2]*writerNode{left, right},
+	}
+	return
+}
+
+// leafNode returns the node for a record at the given depth.
+// If the record's network is more specific than the depth and the record does not cover the whole network of the
+// leaf, the required nodes are inserted, so that the record only covers its own network.
+func (w *writer) leafNode(record geodbtools.Record, depth uint, covering bool) (node *writerNode, err error) {
+	var data map[string]interface{}
+	if data, err = w.dataFunc(record); err != nil || data == nil {
+		return
+	}
+
+	var ip net.IP
+	var prefixLength uint
+	if !covering {
+		if ip, prefixLength, err = networkBits(record.GetNetwork(), w.bitCount); err != nil {
+			return
+		}
+	}
+
+	buf := bytes.NewBufferString("")
+	if err = encodeValue(buf, data); err != nil {
+		return
+	}
+
+	offset, exists := w.dataOffsets[buf.String()]
+	if !exists {
+		offset = uint32(w.data.Len())
+		w.dataOffsets[buf.String()] = offset
+		w.data.Write(buf.Bytes())
+	}
+
+	node = &writerNode{
+		isLeaf: true,
+		value:  offset,
+	}
+
+	for bit := prefixLength; bit > depth; bit-- {
+		parent := &writerNode{}
+		parent.children[ipBit(ip, bit-1)] = node
+		node = parent
+	}
+
+	return
+}
+
+// rootLeafNode returns the root node for a tree consisting of a single leaf, e.g. holding a record for /0.
+// As the root of the search tree cannot point to the data section itself, the leaf is expanded to depth 1.
+func (w *writer) rootLeafNode(record geodbtools.Record, covering bool) (node *writerNode, err error) {
+	var leaf *writerNode
+	if leaf, err = w.leafNode(record, 1, covering); err != nil || leaf == nil {
+		return
+	}
+
+	var ip net.IP
+	var prefixLength uint
+	if !covering {
+		if ip, prefixLength, err = networkBits(record.GetNetwork(), w.bitCount); err != nil {
+			return
+		}
+	}
+
+	node = &writerNode{}
+	if prefixLength == 0 {
+		node.children = [2]*writerNode{leaf, leaf}
+	} else {
+		node.children[ipBit(ip, 0)] = leaf
+	}
+	return
+}
+
+// networkBits returns the IP address and prefix length of the given network, relative to the given bit count.
+// IPv6 networks are only accepted for IPv4 trees if they are part of ::/96 or ::ffff:0:0/96.
+func networkBits(network *net.IPNet, bitCount uint) (ip net.IP, prefixLength uint, err error) {
+	if network == nil {
+		return
+	}
+
+	ones, bits := network.Mask.Size()
+	ip = network.IP
+	if bits == 0 || len(ip)*8 != bits {
+		return nil, 0, nil
+	}
+
+	switch {
+	case uint(bits) == bitCount:
+	case bitCount == 32 && ones >= 96 && (bytes.Equal(ip[:12], make([]byte, 12)) || bytes.Equal(ip[:12], ipv4MappedPrefix)):
+		ip = ip[12:]
+		ones -= 96
+	case bitCount == 128 && bits == 32:
+		ip = append(make(net.IP, 12), ip...)
+		ones += 96
+	default:
+		return nil, 0, ErrNetworkOutOfRange
+	}
+
+	prefixLength = uint(ones)
+	return
+}
+
+// ipBit returns the bit at the given position of the IP address, starting with the most significant bit
+func ipBit(ip net.IP, bit uint) int {
+	return int(ip[bit>>3]>>(7-(bit&7))) & 1
+}
+
+// aliasIPv4 makes the IPv4 sub-tree (::/96) reachable via ::ffff:0:0/96 and 2002::/16
+func aliasIPv4(root *writerNode) {
+	ipv4Node := root
+	for i := 0; i < 96; i++ {
+		if ipv4Node == nil || ipv4Node.isLeaf {
+			return
+		}
+		ipv4Node = ipv4Node.children[0]
+	}
+
+	if ipv4Node == nil {
+		return
+	}
+
+	// ::ffff:0:0/96
+	aliasNode(root, append(make(net.IP, 10), 0xff, 0xff), 96, ipv4Node)
+	// 2002::/16
+	aliasNode(root, net.IP{0x20, 0x02}, 16, ipv4Node)
+}
+
+// aliasNode points the given prefix to the target node, unless the prefix already holds data
+func aliasNode(root *writerNode, prefix net.IP, prefixLength uint, target *writerNode) {
+	node := root
+	for bit := uint(0); bit < prefixLength-1; bit++ {
+		child := &node.children[ipBit(prefix, bit)]
+		if *child == nil {
+			*child = &writerNode{}
+		} else if (*child).isLeaf {
+			return
+		}
+		node = *child
+	}
+
+	if child := &node.children[ipBit(prefix, prefixLength-1)]; *child == nil {
+		*child = target
+	}
+}
+
+// numberNodes assigns node numbers in breadth-first order and returns the non-leaf nodes in that order
+func numberNodes(root *writerNode) (nodes []*writerNode) {
+	visited := map[*writerNode]bool{
+		root: true,
+	}
+
+	queue := []*writerNode{root}
+	for len(queue) > 0 {
+		cur := queue[0]
+		queue = queue[1:]
+
+		cur.value = uint32(len(nodes))
+		nodes = append(nodes, cur)
+
+		for _, child := range cur.children {
+			if child != nil && !child.isLeaf && !visited[child] {
+				visited[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	return
+}
+
+// encodeNode encodes a search tree node given its left and right record values
+func encodeNode(recordSize RecordSize, left, right uint32) []byte {
+	switch recordSize {
+	case RecordSize24:
+		return []byte{
+			byte(left >> 16), byte(left >> 8), byte(left),
+			byte(right >> 16), byte(right >> 8), byte(right),
+		}
+	case RecordSize28:
+		return []byte{
+			byte(left >> 16), byte(left >> 8), byte(left),
+			byte((left>>24)&0x0f)<<4 | byte((right>>24)&0x0f),
+			byte(right >> 16), byte(right >> 8), byte(right),
+		}
+	}
+
+	return []byte{
+		byte(left >> 24), byte(left >> 16), byte(left >> 8), byte(left),
+		byte(right >> 24), byte(right >> 16), byte(right >> 8), byte(right),
+	}
+}
+
+// NewWriter returns a new writer instance, writing databases of the given type and IP version.
+// The data section contents for each record are obtained via the given RecordDataFunc.
+func NewWriter(w io.Writer, typeID DatabaseTypeID, ipVersion geodbtools.IPVersion, recordSize RecordSize, dataFunc RecordDataFunc) (geodbtools.Writer, error) {
+	var bitCount uint
+	switch ipVersion {
+	case geodbtools.IPVersion4:
+		bitCount = 32
+	case geodbtools.IPVersion6:
+		bitCount = 128
+	default:
+		return nil, geodbtools.ErrUnsupportedIPVersion
+	}
+
+	switch recordSize {
+	case RecordSizeAuto, RecordSize24, RecordSize28, RecordSize32:
+	default:
+		return nil, ErrRecordSizeUnsupported
+	}
+
+	return &writer{
+		w:          w,
+		typeID:     typeID,
+		ipVersion:  ipVersion,
+		recordSize: recordSize,
+		dataFunc:   dataFunc,
+		bitCount:   bitCount,
+	}, nil
+}
@@ -0,0 +1,513 @@
+package mmdbformat
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/netip"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anexia-it/bitmap"
+	"github.com/anexia-it/geodbtools"
+	"github.com/anexia-it/geodbtools/geoip2csvformat"
+	"github.com/oschwald/maxminddb-golang"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testCountryRecord(t *testing.T, cidr string, countryCode string) *countryRecord {
+	_, network, err := net.ParseCIDR(cidr)
+	require.NoError(t, err)
+
+	record := &countryRecord{
+		network: network,
+	}
+	record.Country.ISOCode = countryCode
+	return record
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(b []byte) (int, error) {
+	return 0, w.err
+}
+
+type networkRecord struct {
+	network *net.IPNet
+}
+
+func (r *networkRecord) String() string {
+	return r.network.String()
+}
+
+func (r *networkRecord) GetNetwork() *net.IPNet {
+	return r.network
+}
+
+func TestNewWriter(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		w, err := NewWriter(nil, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersionUndefined, RecordSizeAuto, countryRecordData)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, geodbtools.ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("UnsupportedRecordSize", func(t *testing.T) {
+		w, err := NewWriter(nil, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSize(16), countryRecordData)
+		assert.Nil(t, w)
+		assert.EqualError(t, err, ErrRecordSizeUnsupported.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion6, RecordSize28, countryRecordData)
+		assert.NoError(t, err)
+		if assert.NotNil(t, w) && assert.IsType(t, &writer{}, w) {
+			wr := w.(*writer)
+			assert.EqualValues(t, buf, wr.w)
+			assert.EqualValues(t, DatabaseTypeIDGeoLite2Country, wr.typeID)
+			assert.EqualValues(t, geodbtools.IPVersion6, wr.ipVersion)
+			assert.EqualValues(t, RecordSize28, wr.recordSize)
+			assert.EqualValues(t, 128, wr.bitCount)
+		}
+	})
+}
+
+func TestWriter_WriteDatabase(t *testing.T) {
+	buildTime := time.Unix(1546300800, 0)
+	meta := geodbtools.Metadata{
+		Type:        geodbtools.DatabaseTypeCountry,
+		BuildTime:   buildTime,
+		Description: "test database",
+	}
+
+	t.Run("UnsupportedRecordType", func(t *testing.T) {
+		record := &networkRecord{
+			network: &net.IPNet{
+				IP:   net.IP{127, 0, 0, 1},
+				Mask: net.CIDRMask(32, 32),
+			},
+		}
+
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{record}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+		require.NoError(t, err)
+
+		assert.EqualError(t, w.WriteDatabase(meta, tree), geodbtools.ErrUnsupportedRecordType.Error())
+		assert.Empty(t, buf.Bytes())
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		testErr := errors.New("test error")
+		out := &failingWriter{err: testErr}
+
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{
+			testCountryRecord(t, "1.0.0.0/8", "AT"),
+		}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		w, err := NewWriter(out, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+		require.NoError(t, err)
+
+		assert.EqualError(t, w.WriteDatabase(meta, tree), testErr.Error())
+	})
+
+	t.Run("RecordSizeTooSmall", func(t *testing.T) {
+		records := make([]geodbtools.Record, 0, 1024)
+		for i := 0; i < 1024; i++ {
+			records = append(records, testCountryRecord(t, net.IPv4(10, byte(i>>8), byte(i), 0).String()+"/24", "AT"))
+		}
+
+		tree, err := geodbtools.NewRecordTree(31, records, bitmap.IsSet)
+		require.NoError(t, err)
+
+		w, err := NewWriter(bytes.NewBufferString(""), DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSize24, func(record geodbtools.Record) (map[string]interface{}, error) {
+			// unique data for every record, exceeding the 24-bit address space in total
+			return map[string]interface{}{
+				"network": record.GetNetwork().String(),
+				"padding": strings.Repeat("x", 20000),
+			}, nil
+		})
+		require.NoError(t, err)
+
+		assert.EqualError(t, w.WriteDatabase(meta, tree), ErrRecordSizeTooSmall.Error())
+	})
+
+	t.Run("CompactedTree", func(t *testing.T) {
+		tree := &geodbtools.RecordTree{}
+		for _, record := range []*countryRecord{
+			testCountryRecord(t, "1.0.0.0/9", "AT"),
+			testCountryRecord(t, "1.128.0.0/9", "AT"),
+			testCountryRecord(t, "2.0.0.0/8", "DE"),
+		} {
+			require.NoError(t, tree.Insert(geodbtools.RecordPrefix(record), record))
+		}
+		stats := tree.Compact()
+		require.True(t, stats.NodesAfter < stats.NodesBefore)
+
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(meta, tree))
+
+		reader, _, err := format{}.NewReaderAt(&bufferSource{bytes.NewReader(buf.Bytes())})
+		require.NoError(t, err)
+
+		for ip, expectedCountryCode := range map[string]string{
+			"1.0.0.1":   "AT",
+			"1.200.0.1": "AT",
+			"2.1.2.3":   "DE",
+		} {
+			record, err := reader.LookupIP(net.ParseIP(ip))
+			if assert.NoError(t, err, ip) {
+				assert.EqualValues(t, expectedCountryCode, record.(geodbtools.CountryRecord).GetCountryCode(), ip)
+			}
+		}
+	})
+
+	t.Run("RootLeaf", func(t *testing.T) {
+		for _, testCase := range []struct {
+			Name      string
+			IPVersion geodbtools.IPVersion
+			CIDR      string
+			Expected  map[string]interface{}
+		}{
+			{"IPv4", geodbtools.IPVersion4, "0.0.0.0/0", map[string]interface{}{
+				"1.0.0.1":         "AT",
+				"255.255.255.255": "AT",
+			}},
+			{"IPv6", geodbtools.IPVersion6, "::/0", map[string]interface{}{
+				"1.0.0.1":     "AT",
+				"2001:db8::1": "AT",
+				"ffff::1":     "AT",
+			}},
+			{"MoreSpecific", geodbtools.IPVersion4, "1.0.0.0/8", map[string]interface{}{
+				"1.0.0.1":   "AT",
+				"2.0.0.1":   nil,
+				"200.0.0.1": nil,
+			}},
+		} {
+			t.Run(testCase.Name, func(t *testing.T) {
+				tree := &geodbtools.RecordTree{}
+				require.NoError(t, tree.Insert(netip.MustParsePrefix("0.0.0.0/0"), testCountryRecord(t, testCase.CIDR, "AT")))
+				require.NotNil(t, tree.Leaf())
+
+				buf := bytes.NewBufferString("")
+				w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, testCase.IPVersion, RecordSizeAuto, countryRecordData)
+				require.NoError(t, err)
+				require.NoError(t, w.WriteDatabase(meta, tree))
+
+				db, err := maxminddb.FromBytes(buf.Bytes())
+				require.NoError(t, err)
+
+				for ip, expectedCountryCode := range testCase.Expected {
+					var result struct {
+						Country *struct {
+							ISOCode string `maxminddb:"iso_code"`
+						} `maxminddb:"country"`
+					}
+					require.NoError(t, db.Lookup(net.ParseIP(ip), &result))
+					if expectedCountryCode == nil {
+						assert.Nil(t, result.Country, ip)
+					} else if assert.NotNil(t, result.Country, ip) {
+						assert.EqualValues(t, expectedCountryCode, result.Country.ISOCode, ip)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("IPv6NetworksInIPv4Tree", func(t *testing.T) {
+		for _, cidr := range []string{"::1.0.0.0/120", "::ffff:1.0.0.0/120"} {
+			tree := &geodbtools.RecordTree{}
+			require.NoError(t, tree.Insert(netip.MustParsePrefix("1.0.0.0/8"), testCountryRecord(t, cidr, "AT")))
+
+			buf := bytes.NewBufferString("")
+			w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(meta, tree), cidr)
+
+			db, err := maxminddb.FromBytes(buf.Bytes())
+			require.NoError(t, err)
+
+			for ip, expectedCountryCode := range map[string]interface{}{
+				"1.0.0.1": "AT",
+				"1.0.1.1": nil,
+			} {
+				var result struct {
+					Country *struct {
+						ISOCode string `maxminddb:"iso_code"`
+					} `maxminddb:"country"`
+				}
+				require.NoError(t, db.Lookup(net.ParseIP(ip), &result))
+				if expectedCountryCode == nil {
+					assert.Nil(t, result.Country, cidr+" "+ip)
+				} else if assert.NotNil(t, result.Country, cidr+" "+ip) {
+					assert.EqualValues(t, expectedCountryCode, result.Country.ISOCode, cidr+" "+ip)
+				}
+			}
+		}
+	})
+
+	t.Run("NetworkOutOfRange", func(t *testing.T) {
+		tree := &geodbtools.RecordTree{}
+		require.NoError(t, tree.Insert(netip.MustParsePrefix("1.0.0.0/8"), testCountryRecord(t, "2001:db8::100/120", "AT")))
+
+		w, err := NewWriter(bytes.NewBufferString(""), DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+		require.NoError(t, err)
+		assert.EqualError(t, w.WriteDatabase(meta, tree), ErrNetworkOutOfRange.Error())
+	})
+
+	t.Run("EmptyTree", func(t *testing.T) {
+		buf := bytes.NewBufferString("")
+		w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, RecordSizeAuto, countryRecordData)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(meta, nil))
+
+		db, err := maxminddb.FromBytes(buf.Bytes())
+		require.NoError(t, err)
+		assert.EqualValues(t, 1, db.Metadata.NodeCount)
+
+		var result interface{}
+		assert.NoError(t, db.Lookup(net.ParseIP("1.2.3.4"), &result))
+		assert.Nil(t, result)
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(31, []geodbtools.Record{
+			testCountryRecord(t, "1.0.0.0/8", "AT"),
+			testCountryRecord(t, "2.0.0.0/8", "DE"),
+			testCountryRecord(t, "3.0.0.0/8", "AT"),
+			testCountryRecord(t, "4.4.4.4/32", "US"),
+			testCountryRecord(t, "5.0.0.0/8", ""),
+		}, bitmap.IsSet)
+		require.NoError(t, err)
+
+		for _, recordSize := range []RecordSize{RecordSize24, RecordSize28, RecordSize32} {
+			buf := bytes.NewBufferString("")
+			w, err := NewWriter(buf, DatabaseTypeIDGeoLite2Country, geodbtools.IPVersion4, recordSize, countryRecordData)
+			require.NoError(t, err)
+			require.NoError(t, w.WriteDatabase(meta, tree))
+
+			db, err := maxminddb.FromBytes(buf.Bytes())
+			require.NoError(t, err)
+			require.NoError(t, db.Verify())
+
+			assert.EqualValues(t, 2, db.Metadata.BinaryFormatMajorVersion)
+			assert.EqualValues(t, 0, db.Metadata.BinaryFormatMinorVersion)
+			assert.EqualValues(t, buildTime.Unix(), db.Metadata.BuildEpoch)
+			assert.EqualValues(t, DatabaseTypeIDGeoLite2Country, db.Metadata.DatabaseType)
+			assert.EqualValues(t, map[string]string{"en": "test database"}, db.Metadata.Description)
+			assert.EqualValues(t, 4, db.Metadata.IPVersion)
+			assert.EqualValues(t, recordSize, db.Metadata.RecordSize)
+
+			reader, readerMeta, err := format{}.NewReaderAt(&bufferSource{bytes.NewReader(buf.Bytes())})
+			require.NoError(t, err)
+			assert.EqualValues(t, geodbtools.DatabaseTypeCountry, readerMeta.Type)
+			assert.EqualValues(t, geodbtools.IPVersion4, readerMeta.IPVersion)
+			assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+
+			for ip, expectedCountryCode := range map[string]string{
+				"1.255.255.255": "AT",
+				"2.1.2.3":       "DE",
+				"4.4.4.4":       "US",
+				"4.4.4.5":       "",
+				"5.1.2.3":       "",
+				"6.1.2.3":       "",
+			} {
+				record, err := reader.LookupIP(net.ParseIP(ip))
+				if assert.NoError(t, err, ip) {
+					assert.EqualValues(t, expectedCountryCode, record.(geodbtools.CountryRecord).GetCountryCode(), ip)
+				}
+			}
+
+			// identical records share their data section entry
+			var offsetAT1, offsetAT3 uintptr
+			offsetAT1, err = db.LookupOffset(net.ParseIP("1.0.0.1"))
+			require.NoError(t, err)
+			offsetAT3, err = db.LookupOffset(net.ParseIP("3.0.0.1"))
+			require.NoError(t, err)
+			assert.EqualValues(t, offsetAT1, offsetAT3)
+		}
+	})
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		_, testFilename, _, ok := runtime.Caller(0)
+		require.True(t, ok)
+
+		testPath := filepath.Join(filepath.Dir(testFilename), "test-data", "test-data", "GeoIP2-Country-Test.mmdb")
+
+		source, err := geodbtools.NewFileReaderSource(testPath)
+		require.NoError(t, err)
+		defer source.Close()
+
+		sourceReader, sourceMeta, err := format{}.NewReaderAt(source)
+		require.NoError(t, err)
+
+		for _, ipVersion := range []geodbtools.IPVersion{geodbtools.IPVersion4, geodbtools.IPVersion6} {
+			tree, err := sourceReader.RecordTree(ipVersion)
+			require.NoError(t, err)
+			require.NotEmpty(t, tree.Records())
+
+			for _, recordSize := range []RecordSize{RecordSizeAuto, RecordSize24, RecordSize28, RecordSize32} {
+				buf := bytes.NewBufferString("")
+				w, err := NewWriter(buf, DatabaseTypeIDGeoIP2Country, ipVersion, recordSize, countryRecordData)
+				require.NoError(t, err)
+				require.NoError(t, w.WriteDatabase(sourceMeta, tree))
+
+				db, err := maxminddb.FromBytes(buf.Bytes())
+				require.NoError(t, err)
+				require.NoError(t, db.Verify())
+
+				reader, _, err := format{}.NewReaderAt(&bufferSource{bytes.NewReader(buf.Bytes())})
+				require.NoError(t, err)
+				assert.NoError(t, geodbtools.Verify(reader, tree, nil))
+			}
+		}
+	})
+
+	t.Run("RoundTripGeoIP2CSV", func(t *testing.T) {
+		dir := t.TempDir()
+		for name, contents := range map[string]string{
+			"GeoIP2-Country-Locations-en.csv": `geoname_id,locale_code,continent_code,continent_name,country_iso_code,country_name,is_in_european_union
+2782113,en,EU,Europe,AT,Austria,1
+2921044,en,EU,Europe,DE,Germany,1
+6252001,en,NA,North America,US,United States,0
+`,
+			"GeoIP2-Country-Blocks-IPv4.csv": `network,geoname_id,registered_country_geoname_id,represented_country_geoname_id,is_anonymous_proxy,is_satellite_provider
+1.0.0.0/24,2782113,2782113,,0,0
+1.0.1.0/24,2921044,2782113,,0,0
+10.0.0.0/8,,6252001,6252001,1,0
+`,
+		} {
+			require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600))
+		}
+
+		bundle, err := geodbtools.NewDirectoryBundle(dir)
+		require.NoError(t, err)
+		defer bundle.Close()
+
+		sourceReader, sourceMeta, err := geoip2csvformat.NewBundleReader(bundle)
+		require.NoError(t, err)
+
+		tree, err := sourceReader.RecordTree(geodbtools.IPVersion4)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := countryType{DatabaseTypeIDGeoIP2Country}.NewWriter(buf, geodbtools.IPVersion4)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(sourceMeta, tree))
+
+		db, err := maxminddb.FromBytes(buf.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, db.Verify())
+
+		type countryData struct {
+			ISOCode string            `maxminddb:"iso_code"`
+			Names   map[string]string `maxminddb:"names"`
+		}
+		type continentData struct {
+			Code string `maxminddb:"code"`
+		}
+		type geoIP2Country struct {
+			Continent          continentData `maxminddb:"continent"`
+			Country            countryData   `maxminddb:"country"`
+			RegisteredCountry  countryData   `maxminddb:"registered_country"`
+			RepresentedCountry countryData   `maxminddb:"represented_country"`
+		}
+
+		for ip, expected := range map[string]geoIP2Country{
+			"1.0.0.1": {
+				Continent:         continentData{"EU"},
+				Country:           countryData{"AT", map[string]string{"en": "Austria"}},
+				RegisteredCountry: countryData{ISOCode: "AT"},
+			},
+			"1.0.1.1": {
+				Continent:         continentData{"EU"},
+				Country:           countryData{"DE", map[string]string{"en": "Germany"}},
+				RegisteredCountry: countryData{ISOCode: "AT"},
+			},
+			"10.1.2.3": {
+				RegisteredCountry:  countryData{ISOCode: "US"},
+				RepresentedCountry: countryData{ISOCode: "US"},
+			},
+		} {
+			var result geoIP2Country
+			require.NoError(t, db.Lookup(net.ParseIP(ip), &result))
+			assert.EqualValues(t, expected, result, ip)
+		}
+	})
+
+	t.Run("IPv4Aliasing", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(127, []geodbtools.Record{
+			testCountryRecord(t, "::1.0.0.0/104", "AT"),
+			testCountryRecord(t, "::2.0.0.0/104", "DE"),
+			testCountryRecord(t, "2001::/16", "US"),
+		}, geodbtools.RecordBelongsRightIPv6)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := countryType{DatabaseTypeIDGeoLite2Country}.NewWriter(buf, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(meta, tree))
+
+		reader, _, err := format{}.NewReaderAt(&bufferSource{bytes.NewReader(buf.Bytes())})
+		require.NoError(t, err)
+
+		for ip, expectedCountryCode := range map[string]string{
+			"1.2.3.4":          "AT",
+			"::1.2.3.4":        "AT",
+			"::ffff:2.3.4.5":   "DE",
+			"2002:0102:0304::": "AT",
+			"2002:0203:0405::": "DE",
+			"2001:db8::1":      "US",
+			"2003::1":          "",
+		} {
+			record, err := reader.LookupIP(net.ParseIP(ip))
+			if assert.NoError(t, err, ip) {
+				assert.EqualValues(t, expectedCountryCode, record.(geodbtools.CountryRecord).GetCountryCode(), ip)
+			}
+		}
+	})
+
+	t.Run("IPv4AliasingKeepsExistingData", func(t *testing.T) {
+		tree, err := geodbtools.NewRecordTree(127, []geodbtools.Record{
+			testCountryRecord(t, "::1.0.0.0/104", "AT"),
+			testCountryRecord(t, "2002::/16", "US"),
+		}, geodbtools.RecordBelongsRightIPv6)
+		require.NoError(t, err)
+
+		buf := bytes.NewBufferString("")
+		w, err := countryType{DatabaseTypeIDGeoLite2Country}.NewWriter(buf, geodbtools.IPVersion6)
+		require.NoError(t, err)
+		require.NoError(t, w.WriteDatabase(meta, tree))
+
+		reader, _, err := format{}.NewReaderAt(&bufferSource{bytes.NewReader(buf.Bytes())})
+		require.NoError(t, err)
+
+		record, err := reader.LookupIP(net.ParseIP("2002:0102:0304::"))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, "US", record.(geodbtools.CountryRecord).GetCountryCode())
+		}
+
+		record, err = reader.LookupIP(net.ParseIP("::ffff:1.2.3.4"))
+		if assert.NoError(t, err) {
+			assert.EqualValues(t, "AT", record.(geodbtools.CountryRecord).GetCountryCode())
+		}
+	})
+}
+
+func TestEncodeNode(t *testing.T) {
+	assert.EqualValues(t, []byte{0x12, 0x34, 0x56, 0xab, 0xcd, 0xef}, encodeNode(RecordSize24, 0x123456, 0xabcdef))
+	assert.EqualValues(t, []byte{0x12, 0x34, 0x56, 0x7c, 0xab, 0xcd, 0xef}, encodeNode(RecordSize28, 0x7123456, 0xcabcdef))
+	assert.EqualValues(t, []byte{0x81, 0x23, 0x45, 0x67, 0x9a, 0xbc, 0xde, 0xf0}, encodeNode(RecordSize32, 0x81234567, 0x9abcdef0))
+}
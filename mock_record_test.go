@@ -1,5 +1,5 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/anexia-it/geodbtools (interfaces: Record,CountryRecord,CityRecord)
+// Source: github.com/anexia-it/geodbtools (interfaces: Record,CountryRecord,CityRecord,OrganizationRecord,ASNRecord)
 
 // Package geodbtools is a generated GoMock package.
 package geodbtools
@@ -204,3 +204,147 @@ func (mr *MockCityRecordMockRecorder) String() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockCityRecord)(nil).String))
 }
+
+// MockOrganizationRecord is a mock of OrganizationRecord interface
+type MockOrganizationRecord struct {
+	ctrl     *gomock.Controller
+	recorder *MockOrganizationRecordMockRecorder
+}
+
+// MockOrganizationRecordMockRecorder is the mock recorder for MockOrganizationRecord
+type MockOrganizationRecordMockRecorder struct {
+	mock *MockOrganizationRecord
+}
+
+// NewMockOrganizationRecord creates a new mock instance
+func NewMockOrganizationRecord(ctrl *gomock.Controller) *MockOrganizationRecord {
+	mock := &MockOrganizationRecord{ctrl: ctrl}
+	mock.recorder = &MockOrganizationRecordMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockOrganizationRecord) EXPECT() *MockOrganizationRecordMockRecorder {
+	return m.recorder
+}
+
+// GetNetwork mocks base method
+func (m *MockOrganizationRecord) GetNetwork() *net.IPNet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetwork")
+	ret0, _ := ret[0].(*net.IPNet)
+	return ret0
+}
+
+// GetNetwork indicates an expected call of GetNetwork
+func (mr *MockOrganizationRecordMockRecorder) GetNetwork() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetwork", reflect.TypeOf((*MockOrganizationRecord)(nil).GetNetwork))
+}
+
+// GetOrganization mocks base method
+func (m *MockOrganizationRecord) GetOrganization() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrganization")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetOrganization indicates an expected call of GetOrganization
+func (mr *MockOrganizationRecordMockRecorder) GetOrganization() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrganization", reflect.TypeOf((*MockOrganizationRecord)(nil).GetOrganization))
+}
+
+// String mocks base method
+func (m *MockOrganizationRecord) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String
+func (mr *MockOrganizationRecordMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockOrganizationRecord)(nil).String))
+}
+
+// MockASNRecord is a mock of ASNRecord interface
+type MockASNRecord struct {
+	ctrl     *gomock.Controller
+	recorder *MockASNRecordMockRecorder
+}
+
+// MockASNRecordMockRecorder is the mock recorder for MockASNRecord
+type MockASNRecordMockRecorder struct {
+	mock *MockASNRecord
+}
+
+// NewMockASNRecord creates a new mock instance
+func NewMockASNRecord(ctrl *gomock.Controller) *MockASNRecord {
+	mock := &MockASNRecord{ctrl: ctrl}
+	mock.recorder = &MockASNRecordMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use
+func (m *MockASNRecord) EXPECT() *MockASNRecordMockRecorder {
+	return m.recorder
+}
+
+// GetASNumber mocks base method
+func (m *MockASNRecord) GetASNumber() uint32 {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetASNumber")
+	ret0, _ := ret[0].(uint32)
+	return ret0
+}
+
+// GetASNumber indicates an expected call of GetASNumber
+func (mr *MockASNRecordMockRecorder) GetASNumber() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetASNumber", reflect.TypeOf((*MockASNRecord)(nil).GetASNumber))
+}
+
+// GetNetwork mocks base method
+func (m *MockASNRecord) GetNetwork() *net.IPNet {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNetwork")
+	ret0, _ := ret[0].(*net.IPNet)
+	return ret0
+}
+
+// GetNetwork indicates an expected call of GetNetwork
+func (mr *MockASNRecordMockRecorder) GetNetwork() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNetwork", reflect.TypeOf((*MockASNRecord)(nil).GetNetwork))
+}
+
+// GetOrganization mocks base method
+func (m *MockASNRecord) GetOrganization() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetOrganization")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// GetOrganization indicates an expected call of GetOrganization
+func (mr *MockASNRecordMockRecorder) GetOrganization() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetOrganization", reflect.TypeOf((*MockASNRecord)(nil).GetOrganization))
+}
+
+// String mocks base method
+func (m *MockASNRecord) String() string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "String")
+	ret0, _ := ret[0].(string)
+	return ret0
+}
+
+// String indicates an expected call of String
+func (mr *MockASNRecordMockRecorder) String() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "String", reflect.TypeOf((*MockASNRecord)(nil).String))
+}
@@ -0,0 +1,162 @@
+package geodbtools
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"net/netip"
+)
+
+// ErrInvalidRange indicates that an IP address range is invalid
+var ErrInvalidRange = errors.New("invalid IP address range")
+
+// normalizeRange returns both range boundaries in the same representation,
+// using the 4-byte representation for IPv4 addresses
+func normalizeRange(first, last net.IP) (normalizedFirst, normalizedLast net.IP, err error) {
+	if first4, last4 := first.To4(), last.To4(); first4 != nil && last4 != nil {
+		normalizedFirst, normalizedLast = first4, last4
+	} else if first4 == nil && last4 == nil && first.To16() != nil && last.To16() != nil {
+		normalizedFirst, normalizedLast = first.To16(), last.To16()
+	} else {
+		err = ErrInvalidRange
+		return
+	}
+
+	if bytes.Compare(normalizedFirst, normalizedLast) > 0 {
+		normalizedFirst, normalizedLast = nil, nil
+		err = ErrInvalidRange
+	}
+	return
+}
+
+// setHostBits returns a copy of the given IP address with the lowest hostBits bits set
+func setHostBits(ip net.IP, hostBits int) net.IP {
+	result := make(net.IP, len(ip))
+	copy(result, ip)
+
+	for i := len(result) - 1; i >= 0 && hostBits > 0; i-- {
+		if hostBits >= 8 {
+			result[i] = 0xff
+		} else {
+			result[i] |= byte(1<<uint(hostBits)) - 1
+		}
+		hostBits -= 8
+	}
+	return result
+}
+
+// trailingZeroBits returns the number of trailing zero bits of the given IP address
+func trailingZeroBits(ip net.IP) (n int) {
+	for i := len(ip) - 1; i >= 0; i-- {
+		if ip[i] == 0 {
+			n += 8
+			continue
+		}
+
+		for b := ip[i]; b&1 == 0; b >>= 1 {
+			n++
+		}
+		return
+	}
+	return
+}
+
+// NextIP returns the IP address following the given one.
+// nil is returned if the given address is the last address of its address family.
+func NextIP(ip net.IP) (next net.IP) {
+	next = make(net.IP, len(ip))
+	copy(next, ip)
+
+	for i := len(next) - 1; i >= 0; i-- {
+		next[i]++
+		if next[i] != 0 {
+			return
+		}
+	}
+
+	next = nil
+	return
+}
+
+// NetworkLastIP returns the last IP address contained in the given network
+func NetworkLastIP(network *net.IPNet) net.IP {
+	ones, bits := network.Mask.Size()
+	ip := network.IP.Mask(network.Mask)
+	if ip == nil {
+		return nil
+	}
+
+	return setHostBits(ip, bits-ones)
+}
+
+// RangeNetworks returns the smallest list of networks covering the IP address range from first to last (inclusive).
+// IPv4 addresses are represented using their 4-byte representation.
+func RangeNetworks(first, last net.IP) (networks []*net.IPNet, err error) {
+	if first, last, err = normalizeRange(first, last); err != nil {
+		return
+	}
+
+	bits := len(first) * 8
+	current := first
+	for {
+		hostBits := trailingZeroBits(current)
+		if hostBits > bits {
+			hostBits = bits
+		}
+
+		for hostBits > 0 && bytes.Compare(setHostBits(current, hostBits), last) > 0 {
+			hostBits--
+		}
+
+		networks = append(networks, &net.IPNet{
+			IP:   current,
+			Mask: net.CIDRMask(bits-hostBits, bits),
+		})
+
+		networkLast := setHostBits(current, hostBits)
+		if bytes.Equal(networkLast, last) {
+			return
+		}
+		current = NextIP(networkLast)
+	}
+}
+
+// PrefixFromIPNet returns the netip.Prefix representing the given network.
+// IPv4 networks are returned as IPv4 prefixes, regardless of the representation of their IP address.
+// The zero Prefix is returned if the network is nil or invalid.
+func PrefixFromIPNet(network *net.IPNet) (prefix netip.Prefix) {
+	if network == nil {
+		return
+	}
+
+	ones, bits := network.Mask.Size()
+	ip := network.IP
+	switch bits {
+	case 8 * net.IPv4len:
+		ip = ip.To4()
+	case 8 * net.IPv6len:
+		ip = ip.To16()
+	default:
+		return
+	}
+
+	if addr, ok := netip.AddrFromSlice(ip); ok {
+		prefix = netip.PrefixFrom(addr, ones).Masked()
+	}
+	return
+}
+
+// IPNetFromPrefix returns the network represented by the given prefix.
+// nil is returned if the prefix is invalid.
+func IPNetFromPrefix(prefix netip.Prefix) (network *net.IPNet) {
+	if !prefix.IsValid() {
+		return
+	}
+
+	prefix = prefix.Masked()
+	network = &net.IPNet{
+		IP:   prefix.Addr().AsSlice(),
+		Mask: net.CIDRMask(prefix.Bits(), prefix.Addr().BitLen()),
+	}
+	return
+}
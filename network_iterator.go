@@ -0,0 +1,381 @@
+package geodbtools
+
+import (
+	"net/netip"
+)
+
+// NetworkFilter restricts the networks reported by a NetworkIterator
+type NetworkFilter struct {
+	// IPVersion restricts the networks to the given IP version.
+	// The IPv4 networks of IPv6 databases are taken from ::/96 and reported as IPv4 networks.
+	// If IPVersionUndefined is passed, all networks are reported in the database's own representation.
+	IPVersion IPVersion
+
+	// Prefix restricts the networks to the ones overlapping the given prefix, if valid.
+	// Networks containing the prefix are reported with their own prefix.
+	// IPv4 prefixes imply IPVersion4, IPv6 prefixes imply IPVersion6.
+	Prefix netip.Prefix
+}
+
+// normalize returns the IP version and the prefix to filter for.
+// ok is false if the filter cannot match any network.
+func (f NetworkFilter) normalize() (ipVersion IPVersion, prefix netip.Prefix, ok bool) {
+	ipVersion = f.IPVersion
+
+	if prefix = f.Prefix.Masked(); prefix.IsValid() {
+		prefixVersion := IPVersion6
+		if prefix.Addr().Is4() {
+			prefixVersion = IPVersion4
+		}
+
+		if ipVersion == IPVersionUndefined {
+			ipVersion = prefixVersion
+		} else if ipVersion != prefixVersion {
+			return
+		}
+	}
+
+	ok = true
+	return
+}
+
+// matches checks if the given prefix matches the filter
+func (f NetworkFilter) matches(prefix netip.Prefix) bool {
+	ipVersion, filterPrefix, ok := f.normalize()
+	if !ok || !prefix.IsValid() {
+		return false
+	}
+
+	switch ipVersion {
+	case IPVersion4:
+		if !prefix.Addr().Is4() {
+			return false
+		}
+	case IPVersion6:
+		if !prefix.Addr().Is6() {
+			return false
+		}
+	}
+
+	return !filterPrefix.IsValid() || filterPrefix.Overlaps(prefix)
+}
+
+// NetworkIterator iterates over the networks of a database in address order
+type NetworkIterator interface {
+	// Next advances to the next network.
+	// false is returned if there are no more networks, or if an error occurred.
+	Next() bool
+
+	// Record returns the record of the current network
+	Record() Record
+
+	// Err returns the error that stopped the iteration, if any
+	Err() error
+}
+
+// IterableReader describes a Reader that is able to iterate over its networks without building a RecordTree
+type IterableReader interface {
+	Reader
+
+	// Networks returns an iterator over all networks matching the given filter
+	Networks(filter NetworkFilter) NetworkIterator
+}
+
+// Networks returns an iterator over all networks of the given reader that match the given filter.
+// Readers implementing IterableReader iterate over their networks themselves, all other readers are iterated using
+// RecordTreeNetworks.
+func Networks(r Reader, filter NetworkFilter) NetworkIterator {
+	if iterableReader, ok := r.(IterableReader); ok {
+		return iterableReader.Networks(filter)
+	}
+	return RecordTreeNetworks(r, filter)
+}
+
+// RecordTreeNetworks returns an iterator over all networks of the given reader that match the given filter, building
+// the RecordTree of the filter's IP version and iterating it using NewRecordTreeIterator.
+// If the filter does not specify an IP version, the IPv6 tree is used for databases supporting it.
+func RecordTreeNetworks(r Reader, filter NetworkFilter) NetworkIterator {
+	ipVersion, _, ok := filter.normalize()
+	if !ok {
+		return &recordTreeIterator{}
+	}
+
+	var tree *RecordTree
+	var err error
+	if ipVersion == IPVersionUndefined {
+		if tree, err = r.RecordTree(IPVersion6); err == ErrUnsupportedIPVersion {
+			tree, err = r.RecordTree(IPVersion4)
+		}
+	} else {
+		tree, err = r.RecordTree(ipVersion)
+	}
+
+	if err != nil {
+		return &recordTreeIterator{
+			err: err,
+		}
+	}
+	return NewRecordTreeIterator(tree, filter)
+}
+
+var _ NetworkIterator = (*recordTreeIterator)(nil)
+
+// recordTreeIterator implements a NetworkIterator over the records of a RecordTree
+type recordTreeIterator struct {
+	filter NetworkFilter
+	stack  []*RecordTree
+	record Record
+	err    error
+}
+
+// NewRecordTreeIterator returns an iterator over the records of the given tree, matching the given filter
+func NewRecordTreeIterator(tree *RecordTree, filter NetworkFilter) NetworkIterator {
+	it := &recordTreeIterator{
+		filter: filter,
+	}
+
+	if tree != nil {
+		it.stack = append(it.stack, tree)
+	}
+	return it
+}
+
+func (it *recordTreeIterator) Next() bool {
+	it.record = nil
+
+	for len(it.stack) > 0 {
+		t := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		if right := t.Right(); right != nil {
+			it.stack = append(it.stack, right)
+		}
+		if left := t.Left(); left != nil {
+			it.stack = append(it.stack, left)
+		}
+
+		// records inherited from a covering network are only reported for the covering network itself
+		if t.record != nil && !t.inherited && it.filter.matches(RecordPrefix(t.record)) {
+			it.record = t.record
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *recordTreeIterator) Record() Record {
+	return it.record
+}
+
+func (it *recordTreeIterator) Err() error {
+	return it.err
+}
+
+// SearchTree describes the binary search tree stored inside a database, as walked by NewSearchTreeIterator
+type SearchTree interface {
+	// IPVersion returns the IP version of the addresses stored inside the tree
+	IPVersion() IPVersion
+
+	// NodeCount returns the number of nodes of the tree.
+	// Values below the node count reference nodes, all other values reference leaves.
+	NodeCount() uint
+
+	// ReadNode returns the value of the left (index 0) or right (index 1) record of the given node
+	ReadNode(node uint, index uint) (value uint, err error)
+
+	// ReadLeaf returns the record referenced by the given leaf value, representing the given prefix.
+	// A nil record is returned for leaves that do not hold any data.
+	ReadLeaf(value uint, prefix netip.Prefix) (record Record, err error)
+}
+
+// searchTreeEntry holds a search tree value that is yet to be visited, along with the network it represents.
+// IPv4 addresses occupy the first four bytes of addr.
+type searchTreeEntry struct {
+	value uint
+	addr  [16]byte
+	depth uint
+}
+
+var _ NetworkIterator = (*searchTreeIterator)(nil)
+
+// searchTreeIterator implements a NetworkIterator walking a SearchTree depth-first
+type searchTreeIterator struct {
+	tree      SearchTree
+	nodeCount uint
+	bitCount  uint
+
+	// ipv4InIPv6 indicates that the IPv4 networks of an IPv6 tree are iterated
+	ipv4InIPv6 bool
+
+	// ipv4Root holds the node of an IPv6 tree representing ::/96, if hasIPv4Root is set
+	ipv4Root    uint
+	hasIPv4Root bool
+
+	stack  []searchTreeEntry
+	record Record
+	err    error
+}
+
+// NewSearchTreeIterator returns an iterator walking the given search tree in address order, reporting all networks
+// matching the given filter.
+// Memory usage is bounded by the tree's address length, as at most one pending entry per depth is kept.
+// In IPv6 trees, the IPv4 sub-tree is only reported at ::/96, skipping all other networks aliasing it.
+func NewSearchTreeIterator(tree SearchTree, filter NetworkFilter) NetworkIterator {
+	it := &searchTreeIterator{
+		tree:      tree,
+		nodeCount: tree.NodeCount(),
+		bitCount:  32,
+	}
+
+	ipVersion, prefix, ok := filter.normalize()
+	if !ok {
+		return it
+	}
+
+	var target [16]byte
+	var targetBits uint
+	if tree.IPVersion() == IPVersion6 {
+		it.bitCount = 128
+		if it.err = it.findIPv4Root(); it.err != nil {
+			return it
+		}
+
+		if ipVersion == IPVersion4 {
+			// IPv4 networks are stored inside ::/96
+			it.ipv4InIPv6 = true
+			targetBits = 96
+		}
+	} else if ipVersion == IPVersion6 {
+		it.err = ErrUnsupportedIPVersion
+		return it
+	}
+
+	if prefix.IsValid() {
+		if prefix.Addr().Is4() {
+			a4 := prefix.Addr().As4()
+			copy(target[targetBits/8:], a4[:])
+		} else {
+			target = prefix.Addr().As16()
+		}
+		targetBits += uint(prefix.Bits())
+	}
+
+	// descend to the node representing the filter prefix
+	var entry searchTreeEntry
+	for ; entry.depth < targetBits && entry.value < it.nodeCount; entry.depth++ {
+		index := uint(target[entry.depth>>3]>>(7-entry.depth&7)) & 1
+		if index == 1 {
+			entry.addr[entry.depth>>3] |= 0x80 >> (entry.depth & 7)
+		}
+
+		if entry.value, it.err = tree.ReadNode(entry.value, index); it.err != nil {
+			return it
+		}
+
+		if it.isAlias(searchTreeEntry{value: entry.value, addr: entry.addr, depth: entry.depth + 1}) {
+			// the filter prefix lies inside an alias of the IPv4 sub-tree
+			return it
+		}
+	}
+
+	it.stack = make([]searchTreeEntry, 1, it.bitCount+1)
+	it.stack[0] = entry
+	return it
+}
+
+// findIPv4Root determines the node representing ::/96 inside an IPv6 tree
+func (it *searchTreeIterator) findIPv4Root() (err error) {
+	var node uint
+	for depth := 0; depth < 96; depth++ {
+		if node >= it.nodeCount {
+			return
+		}
+
+		if node, err = it.tree.ReadNode(node, 0); err != nil {
+			return
+		}
+	}
+
+	it.ipv4Root = node
+	it.hasIPv4Root = node < it.nodeCount
+	return
+}
+
+// isAlias checks if the given entry references the IPv4 sub-tree outside of ::/96
+func (it *searchTreeIterator) isAlias(entry searchTreeEntry) bool {
+	return it.hasIPv4Root && entry.value == it.ipv4Root && (entry.depth != 96 || entry.addr != [16]byte{})
+}
+
+// prefix returns the prefix represented by the given entry
+func (it *searchTreeIterator) prefix(entry searchTreeEntry) netip.Prefix {
+	if it.bitCount == 32 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{entry.addr[0], entry.addr[1], entry.addr[2], entry.addr[3]}), int(entry.depth))
+	} else if it.ipv4InIPv6 {
+		if entry.depth < 96 {
+			// the leaf covers all of the IPv4 address space
+			return netip.PrefixFrom(netip.IPv4Unspecified(), 0)
+		}
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{entry.addr[12], entry.addr[13], entry.addr[14], entry.addr[15]}), int(entry.depth-96))
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(entry.addr), int(entry.depth))
+}
+
+func (it *searchTreeIterator) Next() bool {
+	it.record = nil
+
+	for it.err == nil && len(it.stack) > 0 {
+		entry := it.stack[len(it.stack)-1]
+		it.stack = it.stack[:len(it.stack)-1]
+
+		if entry.value < it.nodeCount {
+			if entry.depth >= it.bitCount {
+				it.err = ErrDatabaseInvalid
+				return false
+			} else if it.isAlias(entry) {
+				continue
+			}
+
+			var left, right uint
+			if left, it.err = it.tree.ReadNode(entry.value, 0); it.err != nil {
+				return false
+			}
+			if right, it.err = it.tree.ReadNode(entry.value, 1); it.err != nil {
+				return false
+			}
+
+			// the right child is pushed first, so the left one is visited first
+			rightEntry := searchTreeEntry{
+				value: right,
+				addr:  entry.addr,
+				depth: entry.depth + 1,
+			}
+			rightEntry.addr[entry.depth>>3] |= 0x80 >> (entry.depth & 7)
+
+			it.stack = append(it.stack, rightEntry, searchTreeEntry{
+				value: left,
+				addr:  entry.addr,
+				depth: entry.depth + 1,
+			})
+			continue
+		}
+
+		var record Record
+		if record, it.err = it.tree.ReadLeaf(entry.value, it.prefix(entry)); it.err != nil {
+			return false
+		} else if record != nil {
+			it.record = record
+			return true
+		}
+	}
+
+	return false
+}
+
+func (it *searchTreeIterator) Record() Record {
+	return it.record
+}
+
+func (it *searchTreeIterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,259 @@
+package geodbtools
+
+import (
+	"errors"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSearchTree implements an in-memory SearchTree.
+// Values at or above the number of nodes reference leaves, the node count itself references an empty leaf.
+type testSearchTree struct {
+	ipVersion IPVersion
+	nodes     [][2]uint
+	readErr   error
+}
+
+func (t *testSearchTree) IPVersion() IPVersion {
+	return t.ipVersion
+}
+
+func (t *testSearchTree) NodeCount() uint {
+	return uint(len(t.nodes))
+}
+
+func (t *testSearchTree) ReadNode(node uint, index uint) (value uint, err error) {
+	if t.readErr != nil {
+		err = t.readErr
+		return
+	}
+	value = t.nodes[node][index]
+	return
+}
+
+func (t *testSearchTree) ReadLeaf(value uint, prefix netip.Prefix) (record Record, err error) {
+	if value == t.NodeCount() {
+		return
+	}
+
+	record = &testPrefixRecord{
+		prefix: prefix,
+	}
+	return
+}
+
+// iteratedPrefixes returns the prefixes of all records reported by the given iterator
+func iteratedPrefixes(t *testing.T, it NetworkIterator) (prefixes []string) {
+	for it.Next() {
+		prefixes = append(prefixes, RecordPrefix(it.Record()).String())
+	}
+	return
+}
+
+func TestNetworkFilter_matches(t *testing.T) {
+	testCases := map[string]struct {
+		filter   NetworkFilter
+		prefix   string
+		expected bool
+	}{
+		"NoFilter":           {NetworkFilter{}, "1.0.0.0/8", true},
+		"IPVersion4":         {NetworkFilter{IPVersion: IPVersion4}, "1.0.0.0/8", true},
+		"IPVersion4Mismatch": {NetworkFilter{IPVersion: IPVersion4}, "2001:db8::/32", false},
+		"IPVersion6":         {NetworkFilter{IPVersion: IPVersion6}, "2001:db8::/32", true},
+		"IPVersion6Mismatch": {NetworkFilter{IPVersion: IPVersion6}, "1.0.0.0/8", false},
+		"Contained":          {NetworkFilter{Prefix: netip.MustParsePrefix("1.0.0.0/8")}, "1.2.0.0/16", true},
+		"Containing":         {NetworkFilter{Prefix: netip.MustParsePrefix("1.2.0.0/16")}, "1.0.0.0/8", true},
+		"Disjoint":           {NetworkFilter{Prefix: netip.MustParsePrefix("1.0.0.0/8")}, "2.0.0.0/8", false},
+		"PrefixVersion":      {NetworkFilter{IPVersion: IPVersion6, Prefix: netip.MustParsePrefix("1.0.0.0/8")}, "1.0.0.0/8", false},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualValues(t, testCase.expected, testCase.filter.matches(netip.MustParsePrefix(testCase.prefix)))
+		})
+	}
+}
+
+func TestNewSearchTreeIterator(t *testing.T) {
+	// 0.0.0.0/1 -> node 1, 128.0.0.0/1 -> leaf
+	// 0.0.0.0/2 -> empty, 64.0.0.0/2 -> node 2
+	// 64.0.0.0/3 -> leaf, 96.0.0.0/3 -> leaf
+	ipv4Tree := &testSearchTree{
+		ipVersion: IPVersion4,
+		nodes: [][2]uint{
+			{1, 4},
+			{3, 2},
+			{5, 6},
+		},
+	}
+
+	t.Run("IPv4", func(t *testing.T) {
+		assert.EqualValues(t, []string{"64.0.0.0/3", "96.0.0.0/3", "128.0.0.0/1"}, iteratedPrefixes(t, NewSearchTreeIterator(ipv4Tree, NetworkFilter{})))
+	})
+
+	t.Run("Prefix", func(t *testing.T) {
+		it := NewSearchTreeIterator(ipv4Tree, NetworkFilter{
+			Prefix: netip.MustParsePrefix("64.0.0.0/2"),
+		})
+		assert.EqualValues(t, []string{"64.0.0.0/3", "96.0.0.0/3"}, iteratedPrefixes(t, it))
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("ContainingPrefix", func(t *testing.T) {
+		it := NewSearchTreeIterator(ipv4Tree, NetworkFilter{
+			Prefix: netip.MustParsePrefix("200.1.0.0/16"),
+		})
+		assert.EqualValues(t, []string{"128.0.0.0/1"}, iteratedPrefixes(t, it))
+	})
+
+	t.Run("EmptyPrefix", func(t *testing.T) {
+		it := NewSearchTreeIterator(ipv4Tree, NetworkFilter{
+			Prefix: netip.MustParsePrefix("10.0.0.0/8"),
+		})
+		assert.Empty(t, iteratedPrefixes(t, it))
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("IPv6InIPv4Tree", func(t *testing.T) {
+		it := NewSearchTreeIterator(ipv4Tree, NetworkFilter{
+			IPVersion: IPVersion6,
+		})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("FilterMismatch", func(t *testing.T) {
+		it := NewSearchTreeIterator(ipv4Tree, NetworkFilter{
+			IPVersion: IPVersion6,
+			Prefix:    netip.MustParsePrefix("1.0.0.0/8"),
+		})
+		assert.False(t, it.Next())
+		assert.NoError(t, it.Err())
+	})
+
+	t.Run("ReadError", func(t *testing.T) {
+		testErr := errors.New("test error")
+		it := NewSearchTreeIterator(&testSearchTree{
+			ipVersion: IPVersion4,
+			nodes:     ipv4Tree.nodes,
+			readErr:   testErr,
+		}, NetworkFilter{})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), testErr.Error())
+	})
+
+	t.Run("TooDeep", func(t *testing.T) {
+		// the left child of the root references the root again
+		it := NewSearchTreeIterator(&testSearchTree{
+			ipVersion: IPVersion4,
+			nodes:     [][2]uint{{0, 1}},
+		}, NetworkFilter{})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), ErrDatabaseInvalid.Error())
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		// nodes 0 to 95 form the path to ::/96, node 96 splits the IPv4 address space.
+		// 8000::/1 aliases the IPv4 sub-tree and is skipped.
+		nodes := make([][2]uint, 97)
+		for i := 0; i < 96; i++ {
+			nodes[i] = [2]uint{uint(i + 1), uint(len(nodes))}
+		}
+		nodes[0][1] = 96
+		nodes[95][1] = uint(len(nodes)) + 1
+		nodes[96] = [2]uint{uint(len(nodes)) + 2, uint(len(nodes)) + 3}
+
+		tree := &testSearchTree{
+			ipVersion: IPVersion6,
+			nodes:     nodes,
+		}
+
+		assert.EqualValues(t, []string{"::/97", "::8000:0/97", "::1:0:0/96"}, iteratedPrefixes(t, NewSearchTreeIterator(tree, NetworkFilter{})))
+		assert.EqualValues(t, []string{"0.0.0.0/1", "128.0.0.0/1"}, iteratedPrefixes(t, NewSearchTreeIterator(tree, NetworkFilter{
+			IPVersion: IPVersion4,
+		})))
+		assert.EqualValues(t, []string{"128.0.0.0/1"}, iteratedPrefixes(t, NewSearchTreeIterator(tree, NetworkFilter{
+			Prefix: netip.MustParsePrefix("200.0.0.0/8"),
+		})))
+		assert.Empty(t, iteratedPrefixes(t, NewSearchTreeIterator(tree, NetworkFilter{
+			Prefix: netip.MustParsePrefix("8000::/16"),
+		})))
+	})
+}
+
+func TestNewRecordTreeIterator(t *testing.T) {
+	var records []Record
+	for _, prefix := range []string{"2.0.0.0/8", "1.0.0.0/8", "1.2.0.0/16"} {
+		records = append(records, &testPrefixRecord{
+			prefix: netip.MustParsePrefix(prefix),
+		})
+	}
+
+	tree, err := NewAddrRecordTree(31, records, AddrBelongsRight)
+	require.NoError(t, err)
+
+	assert.EqualValues(t, []string{"1.0.0.0/8", "1.2.0.0/16", "2.0.0.0/8"}, iteratedPrefixes(t, NewRecordTreeIterator(tree, NetworkFilter{})))
+	assert.EqualValues(t, []string{"2.0.0.0/8"}, iteratedPrefixes(t, NewRecordTreeIterator(tree, NetworkFilter{
+		Prefix: netip.MustParsePrefix("2.0.0.0/16"),
+	})))
+	assert.Empty(t, iteratedPrefixes(t, NewRecordTreeIterator(nil, NetworkFilter{})))
+}
+
+// testIterableReader implements IterableReader, returning a fixed iterator
+type testIterableReader struct {
+	*MockReader
+	it NetworkIterator
+}
+
+func (r *testIterableReader) Networks(filter NetworkFilter) NetworkIterator {
+	return r.it
+}
+
+func TestNetworks(t *testing.T) {
+	t.Run("IterableReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		it := NewRecordTreeIterator(nil, NetworkFilter{})
+		assert.EqualValues(t, it, Networks(&testIterableReader{
+			MockReader: NewMockReader(ctrl),
+			it:         it,
+		}, NetworkFilter{}))
+	})
+
+	t.Run("RecordTree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree, err := NewAddrRecordTree(31, []Record{&testPrefixRecord{
+			prefix: netip.MustParsePrefix("1.0.0.0/8"),
+		}}, AddrBelongsRight)
+		require.NoError(t, err)
+
+		reader := NewMockReader(ctrl)
+		gomock.InOrder(
+			reader.EXPECT().RecordTree(IPVersion6).Return(nil, ErrUnsupportedIPVersion),
+			reader.EXPECT().RecordTree(IPVersion4).Return(tree, nil),
+		)
+
+		assert.EqualValues(t, []string{"1.0.0.0/8"}, iteratedPrefixes(t, Networks(reader, NetworkFilter{})))
+	})
+
+	t.Run("RecordTreeIPVersion", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().RecordTree(IPVersion4).Return(nil, ErrUnsupportedIPVersion)
+
+		it := Networks(reader, NetworkFilter{
+			Prefix: netip.MustParsePrefix("1.0.0.0/8"),
+		})
+		assert.False(t, it.Next())
+		assert.EqualError(t, it.Err(), ErrUnsupportedIPVersion.Error())
+	})
+}
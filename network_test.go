@@ -0,0 +1,173 @@
+package geodbtools
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextIP(t *testing.T) {
+	testCases := []struct {
+		IP       string
+		Expected string
+	}{
+		{"0.0.0.0", "0.0.0.1"},
+		{"1.0.0.255", "1.0.1.0"},
+		{"1.255.255.255", "2.0.0.0"},
+		{"2001:db8::ffff", "2001:db8::1:0"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.IP, func(t *testing.T) {
+			assert.EqualValues(t, net.ParseIP(testCase.Expected).String(), NextIP(net.ParseIP(testCase.IP)).String())
+		})
+	}
+
+	t.Run("LastIPv4", func(t *testing.T) {
+		assert.Nil(t, NextIP(net.ParseIP("255.255.255.255").To4()))
+	})
+
+	t.Run("LastIPv6", func(t *testing.T) {
+		assert.Nil(t, NextIP(net.ParseIP("ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff")))
+	})
+}
+
+func TestNetworkLastIP(t *testing.T) {
+	testCases := []struct {
+		Network  string
+		Expected string
+	}{
+		{"0.0.0.0/0", "255.255.255.255"},
+		{"1.0.0.0/8", "1.255.255.255"},
+		{"1.0.0.0/23", "1.0.1.255"},
+		{"1.2.3.4/32", "1.2.3.4"},
+		{"2001:db8::/32", "2001:db8:ffff:ffff:ffff:ffff:ffff:ffff"},
+		{"2001:db8::/125", "2001:db8::7"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.Network, func(t *testing.T) {
+			_, network, err := net.ParseCIDR(testCase.Network)
+			require.NoError(t, err)
+			assert.EqualValues(t, testCase.Expected, NetworkLastIP(network).String())
+		})
+	}
+
+	t.Run("InvalidNetwork", func(t *testing.T) {
+		assert.Nil(t, NetworkLastIP(&net.IPNet{
+			IP:   net.IP{1, 2, 3},
+			Mask: net.CIDRMask(8, 32),
+		}))
+	})
+}
+
+func TestRangeNetworks(t *testing.T) {
+	t.Run("InvalidRange", func(t *testing.T) {
+		testCases := map[string][]net.IP{
+			"MixedVersions":  {net.ParseIP("1.0.0.0"), net.ParseIP("2001:db8::")},
+			"InvalidIP":      {net.IP{1, 2, 3}, net.ParseIP("1.0.0.0")},
+			"FirstAfterLast": {net.ParseIP("1.0.0.1"), net.ParseIP("1.0.0.0")},
+		}
+
+		for name, testCase := range testCases {
+			t.Run(name, func(t *testing.T) {
+				networks, err := RangeNetworks(testCase[0], testCase[1])
+				assert.Nil(t, networks)
+				assert.EqualError(t, err, ErrInvalidRange.Error())
+			})
+		}
+	})
+
+	testCases := []struct {
+		First    string
+		Last     string
+		Expected []string
+	}{
+		{"0.0.0.0", "255.255.255.255", []string{"0.0.0.0/0"}},
+		{"1.2.3.4", "1.2.3.4", []string{"1.2.3.4/32"}},
+		{"1.0.0.0", "1.0.1.255", []string{"1.0.0.0/23"}},
+		{"1.0.0.1", "1.0.0.6", []string{"1.0.0.1/32", "1.0.0.2/31", "1.0.0.4/31", "1.0.0.6/32"}},
+		{"1.0.0.255", "1.0.2.0", []string{"1.0.0.255/32", "1.0.1.0/24", "1.0.2.0/32"}},
+		{"255.255.255.254", "255.255.255.255", []string{"255.255.255.254/31"}},
+		{"2001:db8::", "2001:db8::1:ffff", []string{"2001:db8::/111"}},
+		{"2001:db8::ffff", "2001:db8::1:0", []string{"2001:db8::ffff/128", "2001:db8::1:0/128"}},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", []string{"::/0"}},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.First+"-"+testCase.Last, func(t *testing.T) {
+			networks, err := RangeNetworks(net.ParseIP(testCase.First), net.ParseIP(testCase.Last))
+			assert.NoError(t, err)
+
+			networkStrings := make([]string, len(networks))
+			for i, network := range networks {
+				networkStrings[i] = network.String()
+			}
+			assert.EqualValues(t, testCase.Expected, networkStrings)
+		})
+	}
+}
+
+func TestPrefixFromIPNet(t *testing.T) {
+	testCases := map[string]struct {
+		Network        *net.IPNet
+		ExpectedPrefix netip.Prefix
+	}{
+		"Nil": {},
+		"IPv4": {
+			Network:        &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)},
+			ExpectedPrefix: netip.MustParsePrefix("10.0.0.0/8"),
+		},
+		"IPv4With16ByteIP": {
+			Network:        &net.IPNet{IP: net.ParseIP("10.0.0.0"), Mask: net.CIDRMask(8, 32)},
+			ExpectedPrefix: netip.MustParsePrefix("10.0.0.0/8"),
+		},
+		"IPv4Unmasked": {
+			Network:        &net.IPNet{IP: net.IP{10, 1, 2, 3}, Mask: net.CIDRMask(8, 32)},
+			ExpectedPrefix: netip.MustParsePrefix("10.0.0.0/8"),
+		},
+		"IPv6": {
+			Network:        &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(32, 128)},
+			ExpectedPrefix: netip.MustParsePrefix("2001:db8::/32"),
+		},
+		"IPv4MappedIPv6": {
+			Network:        &net.IPNet{IP: net.ParseIP("::ffff:10.0.0.0"), Mask: net.CIDRMask(104, 128)},
+			ExpectedPrefix: netip.MustParsePrefix("::ffff:10.0.0.0/104"),
+		},
+		"IPv6WithIPv4Mask": {
+			Network: &net.IPNet{IP: net.ParseIP("2001:db8::"), Mask: net.CIDRMask(8, 32)},
+		},
+		"NonCanonicalMask": {
+			Network: &net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.IPMask{0xff, 0x00, 0xff, 0x00}},
+		},
+	}
+
+	for name, testCase := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualValues(t, testCase.ExpectedPrefix, PrefixFromIPNet(testCase.Network))
+		})
+	}
+}
+
+func TestIPNetFromPrefix(t *testing.T) {
+	t.Run("Invalid", func(t *testing.T) {
+		assert.Nil(t, IPNetFromPrefix(netip.Prefix{}))
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		assert.EqualValues(t, &net.IPNet{
+			IP:   net.IP{10, 0, 0, 0},
+			Mask: net.CIDRMask(8, 32),
+		}, IPNetFromPrefix(netip.MustParsePrefix("10.1.2.3/8")))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		assert.EqualValues(t, &net.IPNet{
+			IP:   net.ParseIP("2001:db8::"),
+			Mask: net.CIDRMask(32, 128),
+		}, IPNetFromPrefix(netip.MustParsePrefix("2001:db8::/32")))
+	})
+}
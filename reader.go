@@ -3,6 +3,7 @@ package geodbtools
 import (
 	"io"
 	"net"
+	"net/netip"
 )
 
 // ReaderSource defines the interface for reader source
@@ -16,6 +17,16 @@ type ReaderSource interface {
 	Close() error
 }
 
+// BytesReaderSource describes a ReaderSource that provides direct access to its underlying data,
+// allowing formats to read from it without copying
+type BytesReaderSource interface {
+	ReaderSource
+
+	// Bytes returns the complete data of the source, or nil if the source has been closed.
+	// The returned slice must not be modified and is only valid until the source is closed.
+	Bytes() []byte
+}
+
 // Reader represents a database reader
 type Reader interface {
 	// RecordTree returns the database's underlying RecordTree instance, selecting the desired IP version.
@@ -25,3 +36,59 @@ type Reader interface {
 	// LookupIP retrieves the record for the given IP address
 	LookupIP(ip net.IP) (record Record, err error)
 }
+
+// AddrReader describes a Reader that looks up netip.Addr values natively
+type AddrReader interface {
+	Reader
+
+	// LookupAddr retrieves the record for the given IP address
+	LookupAddr(addr netip.Addr) (record Record, err error)
+}
+
+// LookupAddr retrieves the record for the given IP address, using the given reader.
+// Readers implementing AddrReader look up the address natively, all other readers are queried using LookupIP.
+func LookupAddr(r Reader, addr netip.Addr) (record Record, err error) {
+	if addrReader, ok := r.(AddrReader); ok {
+		return addrReader.LookupAddr(addr)
+	}
+
+	if !addr.IsValid() {
+		err = ErrRecordNotFound
+		return
+	}
+
+	return r.LookupIP(net.IP(addr.AsSlice()))
+}
+
+// MetadataReader describes a Reader that reports the metadata of its database, which may change over the lifetime
+// of the reader
+type MetadataReader interface {
+	Reader
+
+	// Metadata returns the metadata of the database
+	Metadata() Metadata
+}
+
+// NetworkReader describes a Reader that reports the network an IP address has been found in
+type NetworkReader interface {
+	Reader
+
+	// LookupNetwork retrieves the record for the given IP address, along with the prefix of the network containing
+	// the address
+	LookupNetwork(addr netip.Addr) (record Record, prefix netip.Prefix, err error)
+}
+
+// LookupNetwork retrieves the record for the given IP address, along with the prefix of the network containing the
+// address, using the given reader.
+// Readers implementing NetworkReader report the prefix themselves, all other readers are queried using LookupAddr,
+// taking the prefix from the record found.
+func LookupNetwork(r Reader, addr netip.Addr) (record Record, prefix netip.Prefix, err error) {
+	if networkReader, ok := r.(NetworkReader); ok {
+		return networkReader.LookupNetwork(addr)
+	}
+
+	if record, err = LookupAddr(r, addr); err == nil {
+		prefix = RecordPrefix(record)
+	}
+	return
+}
@@ -1,11 +1,16 @@
 package geodbtools
 
 import (
+	"errors"
 	"io"
+	"sync"
 
 	"github.com/spf13/afero"
 )
 
+// ErrInvalidOffset indicates that a negative offset has been passed to ReadAt
+var ErrInvalidOffset = errors.New("invalid offset")
+
 type readerSourceWrapper struct {
 	io.ReaderAt
 	size int64
@@ -55,3 +60,87 @@ func NewFileReaderSource(path string) (s ReaderSource, err error) {
 	s = NewReaderSourceWrapper(f, size)
 	return
 }
+
+var _ BytesReaderSource = (*bytesReaderSource)(nil)
+
+// bytesReaderSource implements a BytesReaderSource backed by a byte slice
+type bytesReaderSource struct {
+	// mu guards data, which is set to nil once the source has been closed
+	mu   sync.RWMutex
+	data []byte
+	// release is called when the source is closed, if set
+	release func(data []byte) error
+}
+
+func (s *bytesReaderSource) ReadAt(b []byte, off int64) (n int, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.data == nil {
+		err = ErrReaderClosed
+		return
+	} else if off < 0 {
+		err = ErrInvalidOffset
+		return
+	} else if off >= int64(len(s.data)) {
+		err = io.EOF
+		return
+	}
+
+	if n = copy(b, s.data[off:]); n < len(b) {
+		err = io.EOF
+	}
+	return
+}
+
+func (s *bytesReaderSource) Size() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return int64(len(s.data))
+}
+
+// Bytes returns the underlying data, or nil if the source has been closed
+func (s *bytesReaderSource) Bytes() []byte {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.data
+}
+
+// Close releases the underlying data once, waiting for reads in progress to finish.
+// Subsequent reads fail with ErrReaderClosed.
+func (s *bytesReaderSource) Close() (err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.data != nil && s.release != nil {
+		err = s.release(s.data)
+	}
+	s.data = nil
+	return
+}
+
+// NewMemoryReaderSource returns a new BytesReaderSource holding a copy of the contents of the given file, which is
+// read into memory completely. Unlike a memory-mapped source, the returned source is unaffected by later
+// modifications of the file.
+func NewMemoryReaderSource(path string) (s ReaderSource, err error) {
+	var data []byte
+	if data, err = afero.ReadFile(fs, path); err != nil {
+		return
+	}
+
+	s = NewBytesReaderSource(data)
+	return
+}
+
+// NewBytesReaderSource returns a new BytesReaderSource providing the given data.
+// Once the source has been closed, reads fail with ErrReaderClosed and Bytes returns nil. Readers created from the
+// source must not be used after closing it.
+func NewBytesReaderSource(data []byte) ReaderSource {
+	if data == nil {
+		data = []byte{}
+	}
+
+	return &bytesReaderSource{
+		data: data,
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows || appengine
+// +build windows appengine
+
+package geodbtools
+
+// NewMmapReaderSource returns a new BytesReaderSource providing the contents of the given file.
+// Memory-mapping is not supported on this platform, so the file is read into memory completely.
+func NewMmapReaderSource(path string) (s ReaderSource, err error) {
+	return NewMemoryReaderSource(path)
+}
@@ -0,0 +1,50 @@
+//go:build !windows && !appengine
+// +build !windows,!appengine
+
+package geodbtools
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// NewMmapReaderSource returns a new BytesReaderSource providing the contents of the given file, which is mapped into
+// memory read-only. The mapping is shared between all processes mapping the same file and is released when the
+// source is closed.
+//
+// No reader created from the source may be used after the source has been closed: while reads through ReadAt fail
+// with ErrReaderClosed, readers accessing the data returned by Bytes directly would access unmapped memory.
+//
+// As the mapping reflects the current contents of the file, the file must not be modified in place (e.g. using cp or
+// shell redirection) while the source is in use: readers observe partially written data, and accessing data beyond
+// the end of a truncated file raises SIGBUS. Files need to be replaced by writing a new file and renaming it over the
+// old one instead, which leaves the mapped file intact. Use NewMemoryReaderSource for files that might be modified
+// in place.
+func NewMmapReaderSource(path string) (s ReaderSource, err error) {
+	var f *os.File
+	if f, err = os.Open(path); err != nil {
+		return
+	}
+	defer f.Close()
+
+	var info os.FileInfo
+	if info, err = f.Stat(); err != nil {
+		return
+	} else if info.Size() == 0 {
+		// empty files cannot be mapped
+		s = NewBytesReaderSource(nil)
+		return
+	}
+
+	var data []byte
+	if data, err = unix.Mmap(int(f.Fd()), 0, int(info.Size()), unix.PROT_READ, unix.MAP_SHARED); err != nil {
+		return
+	}
+
+	s = &bytesReaderSource{
+		data:    data,
+		release: unix.Munmap,
+	}
+	return
+}
@@ -3,10 +3,16 @@ package geodbtools
 import (
 	"errors"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 //go:generate mockgen -package geodbtools -self_package github.com/anexia-it/geodbtools -destination mock_io_test.go io ReaderAt,Closer
@@ -171,3 +177,176 @@ func TestNewFileReaderSource(t *testing.T) {
 		}
 	})
 }
+
+func TestBytesReaderSource(t *testing.T) {
+	data := []byte{0x01, 0x02, 0x03, 0x04}
+
+	t.Run("ReadAt", func(t *testing.T) {
+		s := NewBytesReaderSource(data)
+
+		testCases := []struct {
+			Name          string
+			Offset        int64
+			Length        int
+			ExpectedBytes []byte
+			ExpectedError error
+		}{
+			{"Complete", 0, 4, []byte{0x01, 0x02, 0x03, 0x04}, nil},
+			{"Partial", 1, 2, []byte{0x02, 0x03}, nil},
+			{"ShortRead", 2, 4, []byte{0x03, 0x04}, io.EOF},
+			{"AtEnd", 4, 1, []byte{}, io.EOF},
+			{"InvalidOffset", -1, 1, []byte{}, ErrInvalidOffset},
+		}
+
+		for _, testCase := range testCases {
+			t.Run(testCase.Name, func(t *testing.T) {
+				b := make([]byte, testCase.Length)
+				n, err := s.ReadAt(b, testCase.Offset)
+				assert.EqualValues(t, testCase.ExpectedBytes, b[:n])
+				assert.EqualValues(t, testCase.ExpectedError, err)
+			})
+		}
+	})
+
+	t.Run("SizeAndBytes", func(t *testing.T) {
+		s := NewBytesReaderSource(data)
+		assert.EqualValues(t, 4, s.Size())
+		if assert.Implements(t, (*BytesReaderSource)(nil), s) {
+			b := s.(BytesReaderSource).Bytes()
+			assert.True(t, &b[0] == &data[0])
+		}
+	})
+
+	t.Run("Nil", func(t *testing.T) {
+		s := NewBytesReaderSource(nil)
+		assert.EqualValues(t, 0, s.Size())
+		assert.NotNil(t, s.(BytesReaderSource).Bytes())
+	})
+
+	t.Run("Close", func(t *testing.T) {
+		var released []byte
+		testErr := errors.New("test error")
+		s := &bytesReaderSource{
+			data: data,
+			release: func(b []byte) error {
+				released = b
+				return testErr
+			},
+		}
+
+		assert.EqualError(t, s.Close(), testErr.Error())
+		assert.EqualValues(t, data, released)
+		assert.Nil(t, s.Bytes())
+
+		// closing again does not release the data twice
+		released = nil
+		assert.NoError(t, s.Close())
+		assert.Nil(t, released)
+
+		n, err := s.ReadAt(make([]byte, 1), 0)
+		assert.EqualValues(t, 0, n)
+		assert.EqualError(t, err, ErrReaderClosed.Error())
+	})
+
+	t.Run("ConcurrentClose", func(t *testing.T) {
+		var releaseCount int32
+		s := &bytesReaderSource{
+			data: data,
+			release: func(b []byte) error {
+				atomic.AddInt32(&releaseCount, 1)
+				return nil
+			},
+		}
+
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				n, err := s.ReadAt(make([]byte, 4), 0)
+				if err != nil {
+					assert.EqualError(t, err, ErrReaderClosed.Error())
+				} else {
+					assert.EqualValues(t, 4, n)
+				}
+			}()
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, s.Close())
+			}()
+		}
+		wg.Wait()
+
+		assert.EqualValues(t, 1, releaseCount)
+	})
+}
+
+func TestNewMemoryReaderSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geodbtools")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("NotFound", func(t *testing.T) {
+		s, err := NewMemoryReaderSource(filepath.Join(dir, "missing"))
+		assert.Nil(t, s)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		data := []byte("test data")
+		path := filepath.Join(dir, "data")
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+		s, err := NewMemoryReaderSource(path)
+		require.NoError(t, err)
+		assert.EqualValues(t, data, s.(BytesReaderSource).Bytes())
+
+		// the source is unaffected by modifications of the file
+		require.NoError(t, ioutil.WriteFile(path, []byte("modified"), 0600))
+		assert.EqualValues(t, len(data), s.Size())
+		assert.EqualValues(t, data, s.(BytesReaderSource).Bytes())
+		assert.NoError(t, s.Close())
+	})
+}
+
+func TestNewMmapReaderSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "geodbtools")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	t.Run("NotFound", func(t *testing.T) {
+		s, err := NewMmapReaderSource(filepath.Join(dir, "missing"))
+		assert.Nil(t, s)
+		assert.True(t, os.IsNotExist(err))
+	})
+
+	t.Run("Empty", func(t *testing.T) {
+		path := filepath.Join(dir, "empty")
+		require.NoError(t, ioutil.WriteFile(path, nil, 0600))
+
+		s, err := NewMmapReaderSource(path)
+		require.NoError(t, err)
+		assert.EqualValues(t, 0, s.Size())
+		assert.NoError(t, s.Close())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		data := []byte("test data")
+		path := filepath.Join(dir, "data")
+		require.NoError(t, ioutil.WriteFile(path, data, 0600))
+
+		s, err := NewMmapReaderSource(path)
+		require.NoError(t, err)
+		assert.EqualValues(t, len(data), s.Size())
+		assert.EqualValues(t, data, s.(BytesReaderSource).Bytes())
+
+		b := make([]byte, 4)
+		n, err := s.ReadAt(b, 5)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 4, n)
+		assert.EqualValues(t, "data", string(b))
+
+		assert.NoError(t, s.Close())
+		assert.Nil(t, s.(BytesReaderSource).Bytes())
+	})
+}
@@ -0,0 +1,117 @@
+package geodbtools
+
+import (
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+//go:generate mockgen -package geodbtools -self_package github.com/anexia-it/geodbtools -destination mock_addr_reader_test.go github.com/anexia-it/geodbtools AddrReader
+//go:generate mockgen -package geodbtools -self_package github.com/anexia-it/geodbtools -destination mock_network_reader_test.go github.com/anexia-it/geodbtools NetworkReader
+
+func TestLookupAddr(t *testing.T) {
+	t.Run("AddrReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		addr := netip.MustParseAddr("127.0.0.1")
+		record := NewMockRecord(ctrl)
+
+		reader := NewMockAddrReader(ctrl)
+		reader.EXPECT().LookupAddr(addr).Return(record, nil)
+
+		result, err := LookupAddr(reader, addr)
+		assert.NoError(t, err)
+		assert.EqualValues(t, record, result)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		testCases := map[string]net.IP{
+			"127.0.0.1":        net.IP{127, 0, 0, 1},
+			"::ffff:127.0.0.1": net.ParseIP("::ffff:127.0.0.1"),
+			"2001:db8::1":      net.ParseIP("2001:db8::1"),
+		}
+
+		for addr, expectedIP := range testCases {
+			t.Run(addr, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				record := NewMockRecord(ctrl)
+
+				reader := NewMockReader(ctrl)
+				reader.EXPECT().LookupIP(expectedIP).Return(record, nil)
+
+				result, err := LookupAddr(reader, netip.MustParseAddr(addr))
+				assert.NoError(t, err)
+				assert.EqualValues(t, record, result)
+			})
+		}
+	})
+
+	t.Run("InvalidAddr", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		result, err := LookupAddr(NewMockReader(ctrl), netip.Addr{})
+		assert.Nil(t, result)
+		assert.EqualError(t, err, ErrRecordNotFound.Error())
+	})
+}
+
+func TestLookupNetwork(t *testing.T) {
+	t.Run("NetworkReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		addr := netip.MustParseAddr("127.0.0.1")
+		prefix := netip.MustParsePrefix("127.0.0.0/8")
+		record := NewMockRecord(ctrl)
+
+		reader := NewMockNetworkReader(ctrl)
+		reader.EXPECT().LookupNetwork(addr).Return(record, prefix, nil)
+
+		result, resultPrefix, err := LookupNetwork(reader, addr)
+		assert.NoError(t, err)
+		assert.EqualValues(t, record, result)
+		assert.EqualValues(t, prefix, resultPrefix)
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		_, network, err := net.ParseCIDR("127.0.0.0/8")
+		require.NoError(t, err)
+
+		record := NewMockRecord(ctrl)
+		record.EXPECT().GetNetwork().Return(network)
+
+		reader := NewMockAddrReader(ctrl)
+		addr := netip.MustParseAddr("127.0.0.1")
+		reader.EXPECT().LookupAddr(addr).Return(record, nil)
+
+		result, prefix, err := LookupNetwork(reader, addr)
+		assert.NoError(t, err)
+		assert.EqualValues(t, record, result)
+		assert.EqualValues(t, netip.MustParsePrefix("127.0.0.0/8"), prefix)
+	})
+
+	t.Run("FallbackError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		reader := NewMockAddrReader(ctrl)
+		addr := netip.MustParseAddr("127.0.0.1")
+		reader.EXPECT().LookupAddr(addr).Return(nil, ErrRecordNotFound)
+
+		result, prefix, err := LookupNetwork(reader, addr)
+		assert.Nil(t, result)
+		assert.False(t, prefix.IsValid())
+		assert.EqualError(t, err, ErrRecordNotFound.Error())
+	})
+}
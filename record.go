@@ -3,6 +3,7 @@ package geodbtools
 import (
 	"fmt"
 	"net"
+	"net/netip"
 
 	"github.com/anexia-it/bitmap"
 )
@@ -15,6 +16,25 @@ type Record interface {
 	GetNetwork() *net.IPNet
 }
 
+// PrefixRecord describes a database record providing its network as netip.Prefix
+type PrefixRecord interface {
+	Record
+
+	// GetPrefix returns the network represented by the record
+	GetPrefix() netip.Prefix
+}
+
+// RecordPrefix returns the network represented by the given record as netip.Prefix.
+// Records implementing PrefixRecord provide the prefix natively, the networks of all other records are converted
+// using PrefixFromIPNet.
+func RecordPrefix(r Record) netip.Prefix {
+	if prefixRecord, ok := r.(PrefixRecord); ok {
+		return prefixRecord.GetPrefix()
+	}
+
+	return PrefixFromIPNet(r.GetNetwork())
+}
+
 // CountryRecord describes a database record holding country-specific information
 type CountryRecord interface {
 	Record
@@ -23,6 +43,14 @@ type CountryRecord interface {
 	GetCountryCode() string
 }
 
+// CountryNameRecord describes a database record holding the name of its country
+type CountryNameRecord interface {
+	CountryRecord
+
+	// GetCountryName returns the English name of the country
+	GetCountryName() string
+}
+
 // CityRecord describes a database record holding city-specific information
 type CityRecord interface {
 	CountryRecord
@@ -31,6 +59,120 @@ type CityRecord interface {
 	GetCityName() string
 }
 
+// LocalizedCityRecord describes a city record holding localized city names
+type LocalizedCityRecord interface {
+	CityRecord
+
+	// GetCityNames returns the names of the city, keyed by locale code
+	GetCityNames() map[string]string
+}
+
+// RegionRecord describes a database record holding region information
+type RegionRecord interface {
+	Record
+
+	// GetRegionCode returns the code of the region (subdivision) inside the country
+	GetRegionCode() string
+}
+
+// SubdivisionRecord describes a database record holding the subdivisions the network is located in
+type SubdivisionRecord interface {
+	Record
+
+	// GetSubdivisionCodes returns the ISO codes of the subdivisions, ordered from the largest to the smallest one
+	GetSubdivisionCodes() []string
+}
+
+// PostalCodeRecord describes a database record holding a postal code
+type PostalCodeRecord interface {
+	Record
+
+	// GetPostalCode returns the postal code
+	GetPostalCode() string
+}
+
+// LocationRecord describes a database record holding geographical coordinates
+type LocationRecord interface {
+	Record
+
+	// GetLatitude returns the latitude
+	GetLatitude() float64
+
+	// GetLongitude returns the longitude
+	GetLongitude() float64
+}
+
+// AccuracyRadiusRecord describes a database record holding the accuracy of its location
+type AccuracyRadiusRecord interface {
+	Record
+
+	// GetAccuracyRadius returns the radius around the location in kilometers
+	GetAccuracyRadius() uint
+}
+
+// TimeZoneRecord describes a database record holding a time zone
+type TimeZoneRecord interface {
+	Record
+
+	// GetTimeZone returns the name of the time zone, as defined by the IANA time zone database
+	GetTimeZone() string
+}
+
+// ContinentRecord describes a database record holding continent information
+type ContinentRecord interface {
+	Record
+
+	// GetContinentCode returns the 2-character continent code
+	GetContinentCode() string
+}
+
+// RegisteredCountryRecord describes a database record holding the country the network is registered in
+type RegisteredCountryRecord interface {
+	Record
+
+	// GetRegisteredCountryCode returns the 2-character ISO country code of the registered country
+	GetRegisteredCountryCode() string
+}
+
+// RepresentedCountryRecord describes a database record holding the country represented by the network's users,
+// e.g. for military bases
+type RepresentedCountryRecord interface {
+	Record
+
+	// GetRepresentedCountryCode returns the 2-character ISO country code of the represented country
+	GetRepresentedCountryCode() string
+
+	// GetRepresentedCountryType returns the type of the representation (e.g. "military")
+	GetRepresentedCountryType() string
+}
+
+// MetroCodeRecord describes a database record holding US metro and area codes
+type MetroCodeRecord interface {
+	Record
+
+	// GetMetroCode returns the metro (DMA) code
+	GetMetroCode() int
+
+	// GetAreaCode returns the telephone area code
+	GetAreaCode() int
+}
+
+// OrganizationRecord describes a database record holding the name of an organization
+type OrganizationRecord interface {
+	Record
+
+	// GetOrganization returns the name of the organization
+	GetOrganization() string
+}
+
+// ASNRecord describes a database record holding autonomous system information
+type ASNRecord interface {
+	OrganizationRecord
+
+	// GetASNumber returns the autonomous system number
+	GetASNumber() uint32
+}
+
 // RecordBelongsRightIPv6 defines the "belongs right" test function for IPv6 addresses
 func RecordBelongsRightIPv6(b []byte, depth uint) bool {
 	if len(b) < 16 {
@@ -0,0 +1,83 @@
+package geodbtools
+
+// RecordFields returns the non-empty fields of the given record, keyed by field name, as provided by the record
+// interfaces it implements. The record's network is not included.
+// An empty map is returned if the record is nil.
+func RecordFields(rec Record) (fields map[string]interface{}) {
+	fields = make(map[string]interface{})
+	if rec == nil {
+		return
+	}
+
+	if t, ok := rec.(CountryRecord); ok && t.GetCountryCode() != "" {
+		fields["country_code"] = t.GetCountryCode()
+	}
+
+	if t, ok := rec.(CountryNameRecord); ok && t.GetCountryName() != "" {
+		fields["country_name"] = t.GetCountryName()
+	}
+
+	if t, ok := rec.(RegisteredCountryRecord); ok && t.GetRegisteredCountryCode() != "" {
+		fields["registered_country_code"] = t.GetRegisteredCountryCode()
+	}
+
+	if t, ok := rec.(RepresentedCountryRecord); ok && t.GetRepresentedCountryCode() != "" {
+		fields["represented_country_code"] = t.GetRepresentedCountryCode()
+		fields["represented_country_type"] = t.GetRepresentedCountryType()
+	}
+
+	if t, ok := rec.(ContinentRecord); ok && t.GetContinentCode() != "" {
+		fields["continent_code"] = t.GetContinentCode()
+	}
+
+	if t, ok := rec.(SubdivisionRecord); ok {
+		if subdivisionCodes := t.GetSubdivisionCodes(); len(subdivisionCodes) > 0 {
+			fields["subdivision_codes"] = subdivisionCodes
+		}
+	} else if t, ok := rec.(RegionRecord); ok && t.GetRegionCode() != "" {
+		fields["region_code"] = t.GetRegionCode()
+	}
+
+	if t, ok := rec.(CityRecord); ok && t.GetCityName() != "" {
+		fields["city_name"] = t.GetCityName()
+	}
+
+	if t, ok := rec.(LocalizedCityRecord); ok && len(t.GetCityNames()) > 0 {
+		fields["city_names"] = t.GetCityNames()
+	}
+
+	if t, ok := rec.(PostalCodeRecord); ok && t.GetPostalCode() != "" {
+		fields["postal_code"] = t.GetPostalCode()
+	}
+
+	if t, ok := rec.(LocationRecord); ok {
+		fields["latitude"] = t.GetLatitude()
+		fields["longitude"] = t.GetLongitude()
+	}
+
+	if t, ok := rec.(AccuracyRadiusRecord); ok && t.GetAccuracyRadius() > 0 {
+		fields["accuracy_radius"] = t.GetAccuracyRadius()
+	}
+
+	if t, ok := rec.(TimeZoneRecord); ok && t.GetTimeZone() != "" {
+		fields["time_zone"] = t.GetTimeZone()
+	}
+
+	if t, ok := rec.(MetroCodeRecord); ok {
+		if t.GetMetroCode() > 0 {
+			fields["metro_code"] = t.GetMetroCode()
+		}
+		if t.GetAreaCode() > 0 {
+			fields["area_code"] = t.GetAreaCode()
+		}
+	}
+
+	if t, ok := rec.(ASNRecord); ok && t.GetASNumber() > 0 {
+		fields["as_number"] = t.GetASNumber()
+	}
+
+	if t, ok := rec.(OrganizationRecord); ok && t.GetOrganization() != "" {
+		fields["organization"] = t.GetOrganization()
+	}
+	return
+}
@@ -0,0 +1,52 @@
+package geodbtools
+
+import (
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordFields(t *testing.T) {
+	t.Run("Nil", func(t *testing.T) {
+		assert.Empty(t, RecordFields(nil))
+	})
+
+	t.Run("CityRecord", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record := NewMockCityRecord(ctrl)
+		record.EXPECT().GetCountryCode().AnyTimes().Return("AT")
+		record.EXPECT().GetCityName().AnyTimes().Return("Klagenfurt")
+
+		assert.EqualValues(t, map[string]interface{}{
+			"country_code": "AT",
+			"city_name":    "Klagenfurt",
+		}, RecordFields(record))
+	})
+
+	t.Run("EmptyFields", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record := NewMockCountryRecord(ctrl)
+		record.EXPECT().GetCountryCode().AnyTimes().Return("")
+
+		assert.Empty(t, RecordFields(record))
+	})
+
+	t.Run("ASNRecord", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record := NewMockASNRecord(ctrl)
+		record.EXPECT().GetASNumber().AnyTimes().Return(uint32(47147))
+		record.EXPECT().GetOrganization().AnyTimes().Return("ANEXIA Internetdienstleistungs GmbH")
+
+		assert.EqualValues(t, map[string]interface{}{
+			"as_number":    uint32(47147),
+			"organization": "ANEXIA Internetdienstleistungs GmbH",
+		}, RecordFields(record))
+	})
+}
@@ -3,8 +3,11 @@ package geodbtools
 import (
 	"bytes"
 	"net"
+	"net/netip"
 	"testing"
 
+	"github.com/anexia-it/bitmap"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -47,3 +50,68 @@ func TestRecordBelongsRightIPv6(t *testing.T) {
 		})
 	})
 }
+
+func TestAddrBelongsRight(t *testing.T) {
+	t.Run("IPv4", func(t *testing.T) {
+		for _, ip := range []string{"0.0.0.0", "128.0.0.1", "10.20.30.40", "255.255.255.254"} {
+			addr := netip.MustParseAddr(ip)
+			b := addr.AsSlice()
+			for depth := uint(0); depth < 32; depth++ {
+				assert.EqualValues(t, bitmap.IsSet(b, depth), AddrBelongsRight(addr, depth), "%s at depth %d", ip, depth)
+			}
+		}
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		for _, ip := range []string{"::", "8000::1", "2001:db8::1234", "::ffff:10.20.30.40"} {
+			addr := netip.MustParseAddr(ip)
+			b := addr.AsSlice()
+			for depth := uint(0); depth < 128; depth++ {
+				assert.EqualValues(t, RecordBelongsRightIPv6(b, depth), AddrBelongsRight(addr, depth), "%s at depth %d", ip, depth)
+			}
+		}
+	})
+
+	t.Run("DepthExceedsIPv4", func(t *testing.T) {
+		assert.False(t, AddrBelongsRight(netip.MustParseAddr("255.255.255.255"), 32))
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		assert.False(t, AddrBelongsRight(netip.Addr{}, 0))
+	})
+}
+
+type testPrefixRecord struct {
+	*MockRecord
+	prefix netip.Prefix
+}
+
+func (r *testPrefixRecord) GetPrefix() netip.Prefix {
+	return r.prefix
+}
+
+func TestRecordPrefix(t *testing.T) {
+	t.Run("PrefixRecord", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		prefix := netip.MustParsePrefix("10.0.0.0/8")
+		assert.EqualValues(t, prefix, RecordPrefix(&testPrefixRecord{
+			MockRecord: NewMockRecord(ctrl),
+			prefix:     prefix,
+		}))
+	})
+
+	t.Run("Fallback", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record := NewMockRecord(ctrl)
+		record.EXPECT().GetNetwork().Return(&net.IPNet{
+			IP:   net.ParseIP("2001:db8::"),
+			Mask: net.CIDRMask(32, 128),
+		})
+
+		assert.EqualValues(t, netip.MustParsePrefix("2001:db8::/32"), RecordPrefix(record))
+	})
+}
@@ -1,24 +1,62 @@
 package geodbtools
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+)
 
 // RecordBelongsRightFunc tests if a given record, given the byte-slice representation of
 // its IP address, belongs into the right sub-tree or not.
 // This function is used during build of a RecordTree.
 type RecordBelongsRightFunc func(b []byte, depth uint) bool
 
-// RecordTree represents the rooted binary tree of records
-// Each node inside the tree is either a leaf, or has two children (left and right)
+// AddrBelongsRightFunc tests if a given record, given the address of its prefix, belongs into the right sub-tree or
+// not.
+// This function is used during build of a RecordTree.
+type AddrBelongsRightFunc func(addr netip.Addr, depth uint) bool
+
+// AddrBelongsRight defines the "belongs right" test function for addresses of both IP versions.
+// The depth counts down from the address' most significant bit, like the depth of RecordBelongsRightFunc.
+func AddrBelongsRight(addr netip.Addr, depth uint) bool {
+	position := addr.BitLen() - 1 - int(depth)
+	if position < 0 {
+		return false
+	}
+
+	var b [16]byte
+	if addr.Is4() {
+		a4 := addr.As4()
+		copy(b[:], a4[:])
+	} else {
+		b = addr.As16()
+	}
+	return b[position>>3]&(0x80>>uint(position&7)) != 0
+}
+
+// ErrInvalidPrefix indicates that a prefix is invalid or does not fit into a RecordTree
+var ErrInvalidPrefix = errors.New("invalid prefix")
+
+// RecordTree represents the rooted binary tree of records, organized as a prefix trie.
+// Each node inside the tree is either a leaf, or has up to two children (left and right).
+// If a record is inserted for a network that contains more specific networks, the node of the network keeps the
+// record, while all leaves below it that are not covered by a more specific network inherit it.
 type RecordTree struct {
-	records []Record
-	left    *RecordTree
-	right   *RecordTree
+	record Record
+	// inherited indicates that the record has been inherited from the record of a covering network
+	inherited bool
+	// aggregated indicates that the leaf's record applies to the whole network the leaf represents, as the leaf
+	// has been merged from leaves holding equal records by Compact
+	aggregated bool
+
+	left  *RecordTree
+	right *RecordTree
 }
 
 // Leaf returns the leaf value of the tree
 func (t *RecordTree) Leaf() Record {
-	if len(t.records) == 1 {
-		return t.records[0]
+	if t.left == nil && t.right == nil {
+		return t.record
 	}
 	return nil
 }
@@ -33,15 +71,341 @@ func (t *RecordTree) Right() *RecordTree {
 	return t.right
 }
 
-// Records returns all records the tree node and its children represent
-func (t *RecordTree) Records() []Record {
-	return t.records
+// Covering reports if the leaf's record applies to the whole network represented by the leaf.
+// This is the case for records inherited from a covering network and for leaves aggregated by Compact, while the
+// record of any other leaf applies to its own network only, which may be more specific than the leaf.
+func (t *RecordTree) Covering() bool {
+	return t.inherited || t.aggregated
+}
+
+// Records returns all records the tree node and its children represent, in address order.
+// The records are enumerated on each call, using WalkRecords.
+func (t *RecordTree) Records() (records []Record) {
+	t.WalkRecords(func(record Record) bool {
+		records = append(records, record)
+		return true
+	})
+	return
+}
+
+// WalkRecords calls the given function for all records the tree node and its children represent, in address order.
+// Records inherited from a covering network are only reported for the covering network itself.
+// The walk is stopped as soon as the function returns false.
+func (t *RecordTree) WalkRecords(fn func(record Record) bool) {
+	stack := []*RecordTree{t}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if node.record != nil && !node.inherited && !fn(node.record) {
+			return
+		}
+
+		if node.right != nil {
+			stack = append(stack, node.right)
+		}
+		if node.left != nil {
+			stack = append(stack, node.left)
+		}
+	}
+}
+
+// prefixBit reports if the bit at the given depth, counting from the most significant bit, is set for the given
+// address bytes. IPv4 addresses occupy the first four bytes.
+func prefixBit(b *[16]byte, depth int) bool {
+	return b[depth>>3]&(0x80>>uint(depth&7)) != 0
+}
+
+// treeAddrBytes returns the address bytes of the given address, as used by prefixBit
+func treeAddrBytes(addr netip.Addr) (b [16]byte) {
+	if addr.Is4() {
+		a4 := addr.As4()
+		copy(b[:], a4[:])
+	} else {
+		b = addr.As16()
+	}
+	return
+}
+
+// prefixPath returns the nodes on the path from the tree's root to the node representing the given prefix.
+// Leaves covering the prefix are split on the way, so that their record is inherited by both halves.
+// Missing nodes are created if create is set, otherwise nil is returned if the prefix is not part of the tree.
+func (t *RecordTree) prefixPath(prefix netip.Prefix, create bool) (path []*RecordTree) {
+	b := treeAddrBytes(prefix.Addr())
+
+	path = make([]*RecordTree, 1, prefix.Bits()+1)
+	path[0] = t
+
+	node := t
+	for depth := 0; depth < prefix.Bits(); depth++ {
+		if node.left == nil && node.right == nil && node.record != nil {
+			// the leaf covers the prefix, both halves inherit its record
+			node.left = &RecordTree{record: node.record, inherited: true}
+			node.right = &RecordTree{record: node.record, inherited: true}
+			node.aggregated = false
+			if node.inherited {
+				node.record = nil
+				node.inherited = false
+			}
+		}
+
+		child := &node.left
+		if prefixBit(&b, depth) {
+			child = &node.right
+		}
+
+		if *child == nil {
+			if !create {
+				return nil
+			}
+			*child = &RecordTree{}
+		}
+
+		node = *child
+		path = append(path, node)
+	}
+	return
+}
+
+// merge cleans up the nodes of the given path, starting at its end
+func merge(path []*RecordTree) {
+	for i := len(path) - 1; i >= 0; i-- {
+		path[i].mergeChildren()
+	}
+}
+
+// mergeChildren removes empty children and merges two leaves inheriting the same record into the node
+func (t *RecordTree) mergeChildren() {
+	if t.left != nil && t.left.isEmpty() {
+		t.left = nil
+	}
+	if t.right != nil && t.right.isEmpty() {
+		t.right = nil
+	}
+
+	left, right := t.left, t.right
+	if left == nil || right == nil || !left.isInheritedLeaf() || !right.isInheritedLeaf() || left.record != right.record {
+		return
+	} else if t.record != nil && t.record != left.record {
+		return
+	}
+
+	if t.record == nil {
+		t.record = left.record
+		t.inherited = true
+	}
+	t.left = nil
+	t.right = nil
 }
 
-// Build builds the sub-tree starting at the given depth, a slice of records and a RecordBelongsRightFunc
+// isEmpty reports if the node neither holds a record nor has any children
+func (t *RecordTree) isEmpty() bool {
+	return t.record == nil && t.left == nil && t.right == nil
+}
+
+// isInheritedLeaf reports if the node is a leaf holding a record inherited from a covering network
+func (t *RecordTree) isInheritedLeaf() bool {
+	return t.left == nil && t.right == nil && t.record != nil && t.inherited
+}
+
+// Insert inserts the given record for the given prefix.
+// An existing record for the same prefix is replaced. Parts of the prefix that are covered by more specific networks
+// already present keep their records, all other parts of the prefix inherit the inserted record.
+// All prefixes inserted into a tree have to share the same IP version.
+// The record should represent the given prefix, as writers of tree-less formats use the record's own network.
+func (t *RecordTree) Insert(prefix netip.Prefix, record Record) (err error) {
+	if prefix = prefix.Masked(); !prefix.IsValid() || record == nil {
+		err = ErrInvalidPrefix
+		return
+	}
+
+	path := t.prefixPath(prefix, true)
+	node := path[len(path)-1]
+	node.record = record
+	node.inherited = false
+	node.aggregated = false
+	if node.left != nil || node.right != nil {
+		node.inherit(record)
+	}
+
+	merge(path)
+	return
+}
+
+// Override sets the given record for the given prefix, replacing all records of the prefix and of any more specific
+// networks inside it. Covering networks keep their records for the parts outside of the prefix.
+func (t *RecordTree) Override(prefix netip.Prefix, record Record) (err error) {
+	return t.override(prefix, record, false)
+}
+
+// override implements Override, marking the node of the prefix as aggregated if covering is set, as the record applies
+// to the whole prefix, regardless of its own network
+func (t *RecordTree) override(prefix netip.Prefix, record Record, covering bool) (err error) {
+	if prefix = prefix.Masked(); !prefix.IsValid() || record == nil {
+		err = ErrInvalidPrefix
+		return
+	}
+
+	path := t.prefixPath(prefix, true)
+	node := path[len(path)-1]
+	node.record = record
+	node.inherited = false
+	node.aggregated = covering
+	node.left = nil
+	node.right = nil
+
+	merge(path)
+	return
+}
+
+// Remove removes the given prefix from the tree, including the records of any more specific networks inside it.
+// Covering networks keep their records for the parts outside of the prefix, the prefix itself does not hold any
+// record afterwards.
+func (t *RecordTree) Remove(prefix netip.Prefix) (err error) {
+	if prefix = prefix.Masked(); !prefix.IsValid() {
+		err = ErrInvalidPrefix
+		return
+	}
+
+	path := t.prefixPath(prefix, false)
+	if path == nil {
+		return
+	}
+
+	node := path[len(path)-1]
+	node.record = nil
+	node.inherited = false
+	node.aggregated = false
+	node.left = nil
+	node.right = nil
+
+	merge(path)
+	return
+}
+
+// WalkNetworks calls the given function for all leaves of the tree holding a record, in address order.
+// The network passed is the record's own network, unless the record has been inherited from a covering network or
+// the leaf has been aggregated by Compact, in which case the network represented by the leaf is passed.
+// The walk is stopped as soon as the function returns false.
+func (t *RecordTree) WalkNetworks(ipVersion IPVersion, fn func(network netip.Prefix, record Record) bool) (err error) {
+	var bitCount int
+	switch ipVersion {
+	case IPVersion4:
+		bitCount = 32
+	case IPVersion6:
+		bitCount = 128
+	default:
+		err = ErrUnsupportedIPVersion
+		return
+	}
+
+	return t.walkNetworks(bitCount, [16]byte{}, 0, fn)
+}
+
+// walkNetworks implements WalkNetworks for a tree node located at the given address bytes and depth
+func (t *RecordTree) walkNetworks(bitCount int, addr [16]byte, depth int, fn func(network netip.Prefix, record Record) bool) (err error) {
+	type entry struct {
+		node  *RecordTree
+		addr  [16]byte
+		depth int
+	}
+
+	stack := []entry{{node: t, addr: addr, depth: depth}}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		if cur.node.left == nil && cur.node.right == nil {
+			if cur.node.record == nil {
+				continue
+			}
+
+			network := RecordPrefix(cur.node.record)
+			if cur.node.inherited || cur.node.aggregated {
+				network = bytesPrefix(cur.addr, bitCount, cur.depth)
+			}
+
+			if !fn(network, cur.node.record) {
+				return
+			}
+			continue
+		} else if cur.depth >= bitCount {
+			err = ErrInvalidPrefix
+			return
+		}
+
+		if cur.node.right != nil {
+			right := entry{node: cur.node.right, addr: cur.addr, depth: cur.depth + 1}
+			right.addr[cur.depth>>3] |= 0x80 >> uint(cur.depth&7)
+			stack = append(stack, right)
+		}
+		if cur.node.left != nil {
+			stack = append(stack, entry{node: cur.node.left, addr: cur.addr, depth: cur.depth + 1})
+		}
+	}
+	return
+}
+
+// bytesPrefix returns the prefix of the given length for the given address bytes.
+// IPv4 addresses occupy the first four bytes.
+func bytesPrefix(b [16]byte, bitCount int, bits int) netip.Prefix {
+	if bitCount == 32 {
+		return netip.PrefixFrom(netip.AddrFrom4([4]byte{b[0], b[1], b[2], b[3]}), bits)
+	}
+	return netip.PrefixFrom(netip.AddrFrom16(b), bits)
+}
+
+// inherit passes the given record on to all leaves of the sub-tree that are not covered by a more specific network
+func (t *RecordTree) inherit(record Record) {
+	for _, child := range []**RecordTree{&t.left, &t.right} {
+		node := *child
+		if node == nil {
+			*child = &RecordTree{record: record, inherited: true}
+		} else if node.left == nil && node.right == nil {
+			if node.record == nil || node.inherited {
+				node.record = record
+				node.inherited = true
+			}
+		} else if node.record == nil {
+			node.inherit(record)
+		}
+	}
+
+	t.mergeChildren()
+}
+
+// NewPrefixRecordTree initializes a new RecordTree, inserting the given records using their prefix as obtained by
+// RecordPrefix. Building the tree takes time proportional to the number of records times the address length.
+func NewPrefixRecordTree(records []Record) (t *RecordTree, err error) {
+	t = &RecordTree{}
+
+	for _, record := range records {
+		if err = t.Insert(RecordPrefix(record), record); err != nil {
+			t = nil
+			return
+		}
+	}
+	return
+}
+
+// Build builds the sub-tree starting at the given depth, a slice of records and a RecordBelongsRightFunc.
+// Records are partitioned until each of them is the only record of its sub-tree, which becomes the record's leaf.
 func (t *RecordTree) Build(depth int, records []Record, belongsRightFn RecordBelongsRightFunc) (err error) {
-	t.records = records
+	return t.build(depth, append([]Record(nil), records...), func(r Record, depth uint) bool {
+		return belongsRightFn(r.GetNetwork().IP, depth)
+	})
+}
+
+// BuildAddr builds the sub-tree starting at the given depth, a slice of records and an AddrBelongsRightFunc.
+// The address of each record's prefix is obtained using RecordPrefix.
+func (t *RecordTree) BuildAddr(depth int, records []Record, belongsRightFn AddrBelongsRightFunc) (err error) {
+	return t.build(depth, append([]Record(nil), records...), func(r Record, depth uint) bool {
+		return belongsRightFn(RecordPrefix(r).Addr(), depth)
+	})
+}
 
+// build builds the sub-tree starting at the given depth, testing records using the given function
+func (t *RecordTree) build(depth int, records []Record, belongsRightFn func(r Record, depth uint) bool) (err error) {
 	if depth < 0 {
 		err = fmt.Errorf("depth<0! #records=%d", len(records))
 		return
@@ -53,39 +417,35 @@ func (t *RecordTree) Build(depth int, records []Record, belongsRightFn RecordBel
 		}
 	}()
 
-	recordsLeft := make([]Record, 0, len(records))
-	recordsRight := make([]Record, 0, len(records))
-
-	for _, r := range records {
-		if belongsRightFn(r.GetNetwork().IP, uint(depth)) {
-			recordsRight = append(recordsRight, r)
-		} else {
-			recordsLeft = append(recordsLeft, r)
+	// partition the records in place, moving records belonging left to the front
+	split := 0
+	for i, r := range records {
+		if !belongsRightFn(r, uint(depth)) {
+			records[split], records[i] = records[i], records[split]
+			split++
 		}
 	}
 
-	if len(recordsLeft) > 0 {
-		t.left = &RecordTree{}
-		if len(recordsLeft) > 1 {
-			if err = t.left.Build(depth-1, recordsLeft, belongsRightFn); err != nil {
-				return
-			}
-		} else {
-			t.left.records = recordsLeft
+	for _, part := range []struct {
+		child   **RecordTree
+		records []Record
+	}{
+		{&t.left, records[:split]},
+		{&t.right, records[split:]},
+	} {
+		if len(part.records) == 0 {
+			continue
 		}
-	}
 
-	if len(recordsRight) > 0 {
-		t.right = &RecordTree{}
-		if len(recordsRight) > 1 {
-			if err = t.right.Build(depth-1, recordsRight, belongsRightFn); err != nil {
+		*part.child = &RecordTree{}
+		if len(part.records) > 1 {
+			if err = (*part.child).build(depth-1, part.records, belongsRightFn); err != nil {
 				return
 			}
 		} else {
-			t.right.records = recordsRight
+			(*part.child).record = part.records[0]
 		}
 	}
-
 	return
 }
 
@@ -98,3 +458,13 @@ func NewRecordTree(maxDepth uint, records []Record, belongsRightFunc RecordBelon
 	}
 	return
 }
+
+// NewAddrRecordTree initializes and builds a new RecordTree, given a slice of records and an AddrBelongsRightFunc
+func NewAddrRecordTree(maxDepth uint, records []Record, belongsRightFunc AddrBelongsRightFunc) (t *RecordTree, err error) {
+	t = &RecordTree{}
+
+	if err = t.BuildAddr(int(maxDepth), records, belongsRightFunc); err != nil {
+		t = nil
+	}
+	return
+}
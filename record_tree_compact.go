@@ -0,0 +1,149 @@
+package geodbtools
+
+import "reflect"
+
+// CompactionStats holds the number of nodes of a RecordTree before and after compaction
+type CompactionStats struct {
+	NodesBefore uint
+	NodesAfter  uint
+}
+
+// NodeCount returns the number of nodes of the tree, including the tree node itself
+func (t *RecordTree) NodeCount() (count uint) {
+	stack := []*RecordTree{t}
+	for len(stack) > 0 {
+		node := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		count++
+
+		if node.left != nil {
+			stack = append(stack, node.left)
+		}
+		if node.right != nil {
+			stack = append(stack, node.right)
+		}
+	}
+	return
+}
+
+// Clone returns a copy of the tree, sharing the records but none of the nodes
+func (t *RecordTree) Clone() *RecordTree {
+	clone := *t
+	if t.left != nil {
+		clone.left = t.left.Clone()
+	}
+	if t.right != nil {
+		clone.right = t.right.Clone()
+	}
+	return &clone
+}
+
+// compactRecordsEqual reports if two records hold the same data, comparing all of their fields as returned by
+// RecordFields using reflect.DeepEqual.
+//
+// RecordsEqual is not used, as it is meant for verifying converted databases and therefore only compares the fields
+// all formats are able to represent: country records are compared by country code only, treating equivalent codes
+// (e.g. "UK" and "GB") as equal, and city records additionally by city name. Collapsing siblings it considers equal
+// would silently replace the postal code, location, names or registered country of the right sibling by those of the
+// left one. Comparing all fields guarantees that no data is lost by compaction.
+func compactRecordsEqual(a, b Record) bool {
+	return a == b || reflect.DeepEqual(RecordFields(a), RecordFields(b))
+}
+
+// leafCovered reports if the record of the given leaf at the given depth applies to the whole network represented by
+// the leaf. This is not the case for leaves whose record belongs to a more specific network, which leaves built using
+// Build may hold.
+func (t *RecordTree) leafCovered(depth int) bool {
+	if t.inherited || t.aggregated {
+		return true
+	}
+
+	prefix := RecordPrefix(t.record)
+	bits := prefix.Bits()
+	if prefix.Addr().Is4() && bits+96 == depth {
+		// IPv4 record inside an IPv6 tree
+		return true
+	}
+	return bits == depth
+}
+
+// Compact collapses sibling leaves holding equal records into their parent. Records are considered equal if all of
+// their fields, as returned by RecordFields, are equal.
+// The resulting leaf keeps the record of the left sibling, which then applies to the whole network represented by the
+// leaf, as reported by Covering. Leaves without a record are never collapsed, as they do not hold any data, and neither
+// are leaves holding the record of a network more specific than the leaf.
+// The tree node itself is never turned into a leaf, as writers require the root of the tree to be a node.
+// The tree is compacted in place, use Clone to keep the original tree.
+func (t *RecordTree) Compact() (stats CompactionStats) {
+	stats.NodesBefore, stats.NodesAfter = 1, 1
+	for _, child := range []*RecordTree{t.left, t.right} {
+		if child != nil {
+			before, after := child.compact(1)
+			stats.NodesBefore += before
+			stats.NodesAfter += after
+		}
+	}
+	return
+}
+
+// compact compacts the sub-tree at the given depth bottom-up, returning its node count before and after compaction
+func (t *RecordTree) compact(depth int) (before, after uint) {
+	before, after = 1, 1
+	for _, child := range []*RecordTree{t.left, t.right} {
+		if child != nil {
+			childBefore, childAfter := child.compact(depth + 1)
+			before += childBefore
+			after += childAfter
+		}
+	}
+
+	left, right := t.left, t.right
+	if left == nil || right == nil || left.Leaf() == nil || right.Leaf() == nil {
+		return
+	} else if !left.leafCovered(depth+1) || !right.leafCovered(depth+1) {
+		return
+	} else if !compactRecordsEqual(left.record, right.record) {
+		return
+	}
+
+	if left.inherited && right.inherited && left.record == right.record {
+		// both halves inherit the same covering record
+		if t.record == nil {
+			t.record = left.record
+			t.inherited = true
+		}
+	} else {
+		if t.record == nil || !compactRecordsEqual(t.record, left.record) {
+			t.record = left.record
+		}
+		t.inherited = false
+		t.aggregated = true
+	}
+
+	t.left = nil
+	t.right = nil
+	after -= 2
+	return
+}
+
+// CompactingWriter describes a Writer that opts in to compaction of the RecordTree prior to writing
+type CompactingWriter interface {
+	Writer
+
+	// CompactTree reports if the record tree should be compacted before it is written
+	CompactTree() bool
+}
+
+// WriteDatabase writes the database using the given writer.
+// If the writer implements CompactingWriter and opts in to compaction, a compacted copy of the tree is written and the
+// compaction's stats are returned, nil is returned otherwise. The given tree is left unchanged.
+func WriteDatabase(w Writer, meta Metadata, tree *RecordTree) (stats *CompactionStats, err error) {
+	if compactingWriter, ok := w.(CompactingWriter); ok && compactingWriter.CompactTree() && tree != nil {
+		tree = tree.Clone()
+		compactionStats := tree.Compact()
+		stats = &compactionStats
+	}
+
+	err = w.WriteDatabase(meta, tree)
+	return
+}
@@ -0,0 +1,326 @@
+package geodbtools
+
+import (
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+// newTestCountryRecord returns a new country record for the given prefix and country code
+func newTestCountryRecord(ctrl *gomock.Controller, prefix string, countryCode string) *testCountryPrefixRecord {
+	record := NewMockCountryRecord(ctrl)
+	record.EXPECT().GetCountryCode().AnyTimes().Return(countryCode)
+
+	return &testCountryPrefixRecord{
+		MockCountryRecord: record,
+		prefix:            netip.MustParsePrefix(prefix),
+	}
+}
+
+// testCountryPrefixRecord implements a CountryRecord providing its network as netip.Prefix
+type testCountryPrefixRecord struct {
+	*MockCountryRecord
+	prefix netip.Prefix
+}
+
+func (r *testCountryPrefixRecord) GetPrefix() netip.Prefix {
+	return r.prefix
+}
+
+// testCityPrefixRecord implements a CityRecord holding a postal code, providing its network as netip.Prefix
+type testCityPrefixRecord struct {
+	prefix      netip.Prefix
+	countryCode string
+	cityName    string
+	postalCode  string
+}
+
+func (r *testCityPrefixRecord) GetNetwork() *net.IPNet {
+	return nil
+}
+
+func (r *testCityPrefixRecord) GetPrefix() netip.Prefix {
+	return r.prefix
+}
+
+func (r *testCityPrefixRecord) GetCountryCode() string {
+	return r.countryCode
+}
+
+func (r *testCityPrefixRecord) GetCityName() string {
+	return r.cityName
+}
+
+func (r *testCityPrefixRecord) GetPostalCode() string {
+	return r.postalCode
+}
+
+func (r *testCityPrefixRecord) String() string {
+	return r.prefix.String() + " " + r.postalCode
+}
+
+func TestRecordTree_Clone(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	tree, err := NewPrefixRecordTree([]Record{
+		newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+		newTestCountryRecord(ctrl, "10.128.0.0/9", "AT"),
+	})
+	assert.NoError(t, err)
+
+	clone := tree.Clone()
+	assert.EqualValues(t, tree, clone)
+	assert.True(t, clone != tree)
+	assert.True(t, clone.Left() != tree.Left())
+
+	nodeCount := tree.NodeCount()
+	clone.Compact()
+	assert.EqualValues(t, nodeCount, tree.NodeCount())
+	assert.EqualValues(t, nodeCount-2, clone.NodeCount())
+}
+
+func TestRecordTree_NodeCount(t *testing.T) {
+	assert.EqualValues(t, 1, (&RecordTree{}).NodeCount())
+	assert.EqualValues(t, 4, (&RecordTree{
+		left: &RecordTree{
+			right: &RecordTree{},
+		},
+		right: &RecordTree{},
+	}).NodeCount())
+}
+
+func TestRecordTree_Compact(t *testing.T) {
+	t.Run("EqualRecords", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r0 := newTestCountryRecord(ctrl, "10.0.0.0/9", "AT")
+		r1 := newTestCountryRecord(ctrl, "10.128.0.0/9", "AT")
+		r2 := newTestCountryRecord(ctrl, "11.0.0.0/8", "DE")
+
+		tree, err := NewPrefixRecordTree([]Record{r0, r1, r2})
+		assert.NoError(t, err)
+
+		nodeCount := tree.NodeCount()
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: nodeCount,
+			NodesAfter:  nodeCount - 2,
+		}, tree.Compact())
+		assert.EqualValues(t, nodeCount-2, tree.NodeCount())
+
+		assert.EqualValues(t, []Record{r0, r2}, tree.Records())
+
+		networks := make(map[string]Record)
+		assert.NoError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			networks[network.String()] = record
+			return true
+		}))
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/8": r0,
+			"11.0.0.0/8": r2,
+		}, networks)
+	})
+
+	t.Run("Cascade", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var records []Record
+		for _, prefix := range []string{"10.0.0.0/10", "10.64.0.0/10", "10.128.0.0/9"} {
+			records = append(records, newTestCountryRecord(ctrl, prefix, "AT"))
+		}
+
+		tree, err := NewPrefixRecordTree(records)
+		assert.NoError(t, err)
+		tree.Compact()
+
+		node := tree.Left().Left().Left().Left().Right().Left().Right().Left()
+		assert.True(t, node.Leaf() == records[0])
+		assert.True(t, node.Covering())
+	})
+
+	t.Run("InheritedRecords", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestCountryRecord(ctrl, "10.0.0.0/8", "AT")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+		assert.NoError(t, tree.Insert(netip.MustParsePrefix("10.1.0.0/16"), newTestCountryRecord(ctrl, "10.1.0.0/16", "AT")))
+		tree.Compact()
+
+		assert.EqualValues(t, []Record{covering}, tree.Records())
+		node := tree.Left().Left().Left().Left().Right().Left().Right().Left()
+		assert.True(t, node.Leaf() == Record(covering))
+	})
+
+	t.Run("DifferentRecords", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree, err := NewPrefixRecordTree([]Record{
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "DE"),
+		})
+		assert.NoError(t, err)
+
+		nodeCount := tree.NodeCount()
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: nodeCount,
+			NodesAfter:  nodeCount,
+		}, tree.Compact())
+	})
+
+	t.Run("CityRecordsDifferingInPostalCode", func(t *testing.T) {
+		tree, err := NewPrefixRecordTree([]Record{
+			&testCityPrefixRecord{prefix: netip.MustParsePrefix("10.0.0.0/9"), countryCode: "AT", cityName: "Vienna", postalCode: "1010"},
+			&testCityPrefixRecord{prefix: netip.MustParsePrefix("10.128.0.0/9"), countryCode: "AT", cityName: "Vienna", postalCode: "1020"},
+		})
+		assert.NoError(t, err)
+
+		nodeCount := tree.NodeCount()
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: nodeCount,
+			NodesAfter:  nodeCount,
+		}, tree.Compact())
+		assert.Len(t, tree.Records(), 2)
+	})
+
+	t.Run("CityRecordsEqual", func(t *testing.T) {
+		tree, err := NewPrefixRecordTree([]Record{
+			&testCityPrefixRecord{prefix: netip.MustParsePrefix("10.0.0.0/9"), countryCode: "AT", cityName: "Vienna", postalCode: "1010"},
+			&testCityPrefixRecord{prefix: netip.MustParsePrefix("10.128.0.0/9"), countryCode: "AT", cityName: "Vienna", postalCode: "1010"},
+		})
+		assert.NoError(t, err)
+
+		nodeCount := tree.NodeCount()
+		assert.EqualValues(t, nodeCount-2, tree.Compact().NodesAfter)
+	})
+
+	t.Run("MoreSpecificLeafRecords", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// partitioning places the records at the depth they differ at, above their own networks
+		tree, err := NewAddrRecordTree(31, []Record{
+			newTestCountryRecord(ctrl, "10.0.0.0/16", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/16", "AT"),
+		}, AddrBelongsRight)
+		assert.NoError(t, err)
+
+		nodeCount := tree.NodeCount()
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: nodeCount,
+			NodesAfter:  nodeCount,
+		}, tree.Compact())
+
+		var networks []string
+		assert.NoError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			networks = append(networks, network.String())
+			return true
+		}))
+		assert.EqualValues(t, []string{"10.0.0.0/16", "10.128.0.0/16"}, networks)
+	})
+
+	t.Run("EmptyLeaves", func(t *testing.T) {
+		tree := &RecordTree{
+			left: &RecordTree{
+				left:  &RecordTree{},
+				right: &RecordTree{},
+			},
+		}
+
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: 4,
+			NodesAfter:  4,
+		}, tree.Compact())
+	})
+
+	t.Run("Root", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree, err := NewPrefixRecordTree([]Record{
+			newTestCountryRecord(ctrl, "0.0.0.0/1", "AT"),
+			newTestCountryRecord(ctrl, "128.0.0.0/1", "AT"),
+		})
+		assert.NoError(t, err)
+
+		assert.EqualValues(t, CompactionStats{
+			NodesBefore: 3,
+			NodesAfter:  3,
+		}, tree.Compact())
+	})
+}
+
+// testCompactingWriter implements a CompactingWriter, recording the tree passed to WriteDatabase
+type testCompactingWriter struct {
+	compact bool
+	tree    *RecordTree
+	err     error
+}
+
+func (w *testCompactingWriter) WriteDatabase(meta Metadata, tree *RecordTree) error {
+	w.tree = tree
+	return w.err
+}
+
+func (w *testCompactingWriter) CompactTree() bool {
+	return w.compact
+}
+
+func TestWriteDatabase(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	newTree := func() *RecordTree {
+		tree, err := NewPrefixRecordTree([]Record{
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "AT"),
+		})
+		assert.NoError(t, err)
+		return tree
+	}
+
+	t.Run("OptIn", func(t *testing.T) {
+		tree := newTree()
+		nodeCount := tree.NodeCount()
+
+		w := &testCompactingWriter{
+			compact: true,
+		}
+		stats, err := WriteDatabase(w, Metadata{}, tree)
+		assert.NoError(t, err)
+		assert.EqualValues(t, &CompactionStats{
+			NodesBefore: nodeCount,
+			NodesAfter:  nodeCount - 2,
+		}, stats)
+		assert.True(t, w.tree != tree)
+		assert.EqualValues(t, nodeCount-2, w.tree.NodeCount())
+		assert.EqualValues(t, nodeCount, tree.NodeCount())
+	})
+
+	t.Run("OptOut", func(t *testing.T) {
+		tree := newTree()
+		nodeCount := tree.NodeCount()
+
+		stats, err := WriteDatabase(&testCompactingWriter{}, Metadata{}, tree)
+		assert.NoError(t, err)
+		assert.Nil(t, stats)
+		assert.EqualValues(t, nodeCount, tree.NodeCount())
+	})
+
+	t.Run("WriteError", func(t *testing.T) {
+		testErr := errors.New("test error")
+
+		_, err := WriteDatabase(&testCompactingWriter{err: testErr}, Metadata{}, newTree())
+		assert.EqualError(t, err, testErr.Error())
+	})
+}
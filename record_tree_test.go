@@ -2,6 +2,7 @@ package geodbtools
 
 import (
 	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/anexia-it/bitmap"
@@ -19,7 +20,7 @@ func TestRecordTree_Leaf(t *testing.T) {
 		r := NewMockRecord(ctrl)
 
 		tree := &RecordTree{
-			records: []Record{r},
+			record: r,
 		}
 
 		assert.EqualValues(t, r, tree.Leaf())
@@ -33,7 +34,12 @@ func TestRecordTree_Leaf(t *testing.T) {
 		r1 := NewMockRecord(ctrl)
 
 		tree := &RecordTree{
-			records: []Record{r0, r1},
+			left: &RecordTree{
+				record: r0,
+			},
+			right: &RecordTree{
+				record: r1,
+			},
 		}
 
 		assert.Nil(t, tree.Leaf())
@@ -48,7 +54,7 @@ func TestRecordTree_Left(t *testing.T) {
 	r := NewMockRecord(ctrl)
 
 	leftTree := &RecordTree{
-		records: []Record{r},
+		record: r,
 	}
 
 	tree := &RecordTree{
@@ -65,7 +71,7 @@ func TestRecordTree_Right(t *testing.T) {
 	r := NewMockRecord(ctrl)
 
 	rightTree := &RecordTree{
-		records: []Record{r},
+		record: r,
 	}
 
 	tree := &RecordTree{
@@ -81,17 +87,398 @@ func TestRecordTree_Records(t *testing.T) {
 
 	r0 := NewMockRecord(ctrl)
 	r1 := NewMockRecord(ctrl)
+	r2 := NewMockRecord(ctrl)
 
-	expectedRecords := []Record{
-		r0,
-		r1,
+	tree := &RecordTree{
+		record: r0,
+		left: &RecordTree{
+			left: &RecordTree{
+				record: r1,
+			},
+			right: &RecordTree{
+				record:    r0,
+				inherited: true,
+			},
+		},
+		right: &RecordTree{
+			record: r2,
+		},
 	}
 
+	assert.EqualValues(t, []Record{r0, r1, r2}, tree.Records())
+	assert.Nil(t, (&RecordTree{}).Records())
+}
+
+func TestRecordTree_WalkRecords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	r0 := NewMockRecord(ctrl)
+	r1 := NewMockRecord(ctrl)
+
 	tree := &RecordTree{
-		records: expectedRecords,
+		left: &RecordTree{
+			record: r0,
+		},
+		right: &RecordTree{
+			record: r1,
+		},
 	}
 
-	assert.EqualValues(t, expectedRecords, tree.Records())
+	var records []Record
+	tree.WalkRecords(func(record Record) bool {
+		records = append(records, record)
+		return false
+	})
+	assert.EqualValues(t, []Record{r0}, records)
+}
+
+// newTestPrefixRecord returns a new testPrefixRecord for the given prefix
+func newTestPrefixRecord(ctrl *gomock.Controller, prefix string) *testPrefixRecord {
+	return &testPrefixRecord{
+		MockRecord: NewMockRecord(ctrl),
+		prefix:     netip.MustParsePrefix(prefix),
+	}
+}
+
+// recordTreeLeaves returns the records of all leaves of the given tree, keyed by their prefix
+func recordTreeLeaves(tree *RecordTree, prefix netip.Prefix, leaves map[string]Record) map[string]Record {
+	if leaves == nil {
+		leaves = make(map[string]Record)
+	}
+
+	if tree.left == nil && tree.right == nil {
+		if tree.record != nil {
+			leaves[prefix.String()] = tree.record
+		}
+		return leaves
+	}
+
+	addr := prefix.Addr().AsSlice()
+	if tree.left != nil {
+		recordTreeLeaves(tree.left, netip.PrefixFrom(prefix.Addr(), prefix.Bits()+1), leaves)
+	}
+	if tree.right != nil {
+		addr[prefix.Bits()>>3] |= 0x80 >> uint(prefix.Bits()&7)
+		rightAddr, _ := netip.AddrFromSlice(addr)
+		recordTreeLeaves(tree.right, netip.PrefixFrom(rightAddr, prefix.Bits()+1), leaves)
+	}
+	return leaves
+}
+
+func TestRecordTree_Insert(t *testing.T) {
+	t.Run("InvalidPrefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree := &RecordTree{}
+		assert.EqualError(t, tree.Insert(netip.Prefix{}, NewMockRecord(ctrl)), ErrInvalidPrefix.Error())
+		assert.EqualError(t, tree.Insert(netip.MustParsePrefix("1.0.0.0/8"), nil), ErrInvalidPrefix.Error())
+	})
+
+	t.Run("Disjoint", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r0 := newTestPrefixRecord(ctrl, "128.0.0.0/1")
+		r1 := newTestPrefixRecord(ctrl, "0.0.0.0/2")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r0.prefix, r0))
+		assert.NoError(t, tree.Insert(r1.prefix, r1))
+
+		assert.EqualValues(t, []Record{r1, r0}, tree.Records())
+		assert.EqualValues(t, map[string]Record{
+			"0.0.0.0/2":   r1,
+			"128.0.0.0/1": r0,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+
+	t.Run("Nested", func(t *testing.T) {
+		for name, order := range map[string][]int{
+			"CoveringFirst":     {0, 1, 2},
+			"CoveringLast":      {2, 1, 0},
+			"CoveringInbetween": {1, 0, 2},
+		} {
+			t.Run(name, func(t *testing.T) {
+				ctrl := gomock.NewController(t)
+				defer ctrl.Finish()
+
+				records := []*testPrefixRecord{
+					newTestPrefixRecord(ctrl, "10.0.0.0/8"),
+					newTestPrefixRecord(ctrl, "10.0.0.0/9"),
+					newTestPrefixRecord(ctrl, "10.192.0.0/10"),
+				}
+
+				tree := &RecordTree{}
+				for _, i := range order {
+					assert.NoError(t, tree.Insert(records[i].prefix, records[i]))
+				}
+
+				assert.EqualValues(t, []Record{records[0], records[1], records[2]}, tree.Records())
+				assert.EqualValues(t, map[string]Record{
+					"10.0.0.0/9":    records[1],
+					"10.128.0.0/10": records[0],
+					"10.192.0.0/10": records[2],
+				}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+			})
+		}
+	})
+
+	t.Run("Replace", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		nested := newTestPrefixRecord(ctrl, "10.0.0.0/9")
+		replacement := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Insert(nested.prefix, nested))
+		assert.NoError(t, tree.Insert(replacement.prefix, replacement))
+
+		assert.EqualValues(t, []Record{replacement, nested}, tree.Records())
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/9":   nested,
+			"10.128.0.0/9": replacement,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := newTestPrefixRecord(ctrl, "2001:db8::/127")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r.prefix, r))
+		assert.EqualValues(t, map[string]Record{
+			"2001:db8::/127": r,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("::/0"), nil))
+	})
+}
+
+func TestRecordTree_Override(t *testing.T) {
+	t.Run("InvalidPrefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree := &RecordTree{}
+		assert.EqualError(t, tree.Override(netip.Prefix{}, NewMockRecord(ctrl)), ErrInvalidPrefix.Error())
+		assert.EqualError(t, tree.Override(netip.MustParsePrefix("1.0.0.0/8"), nil), ErrInvalidPrefix.Error())
+	})
+
+	t.Run("MoreSpecificNetworks", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		nested := newTestPrefixRecord(ctrl, "10.1.0.0/16")
+		override := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Insert(nested.prefix, nested))
+		assert.NoError(t, tree.Override(override.prefix, override))
+
+		assert.EqualValues(t, []Record{override}, tree.Records())
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/8": override,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+
+	t.Run("InsideCoveringNetwork", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		override := newTestPrefixRecord(ctrl, "10.128.0.0/9")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Override(override.prefix, override))
+
+		assert.EqualValues(t, []Record{covering, override}, tree.Records())
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/9":   covering,
+			"10.128.0.0/9": override,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+}
+
+func TestRecordTree_Remove(t *testing.T) {
+	t.Run("InvalidPrefix", func(t *testing.T) {
+		tree := &RecordTree{}
+		assert.EqualError(t, tree.Remove(netip.Prefix{}), ErrInvalidPrefix.Error())
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r.prefix, r))
+		assert.NoError(t, tree.Remove(netip.MustParsePrefix("11.0.0.0/8")))
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/8": r,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+
+	t.Run("Network", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r0 := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		r1 := newTestPrefixRecord(ctrl, "11.0.0.0/8")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r0.prefix, r0))
+		assert.NoError(t, tree.Insert(r1.prefix, r1))
+		assert.NoError(t, tree.Remove(r1.prefix))
+
+		assert.EqualValues(t, []Record{r0}, tree.Records())
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/8": r0,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+
+		// the path to the removed network has been merged
+		assert.Nil(t, tree.prefixPath(r1.prefix, false))
+	})
+
+	t.Run("InsideCoveringNetwork", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r.prefix, r))
+		assert.NoError(t, tree.Remove(netip.MustParsePrefix("10.64.0.0/10")))
+
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/10":  r,
+			"10.128.0.0/9": r,
+		}, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil))
+	})
+
+	t.Run("MergeInherited", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		nested := newTestPrefixRecord(ctrl, "10.1.0.0/16")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Insert(nested.prefix, nested))
+		assert.NoError(t, tree.Override(nested.prefix, covering))
+		assert.NoError(t, tree.Remove(netip.MustParsePrefix("10.1.0.0/16")))
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+
+		// all parts of 10.0.0.0/8 inherit the same record again and are merged
+		node := tree.Left().Left().Left().Left().Right().Left().Right().Left()
+		assert.True(t, node.Leaf() == Record(covering))
+		assert.False(t, node.inherited)
+	})
+}
+
+func TestRecordTree_WalkNetworks(t *testing.T) {
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		tree := &RecordTree{}
+		assert.EqualError(t, tree.WalkNetworks(IPVersionUndefined, nil), ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("IPv4", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		covering := newTestPrefixRecord(ctrl, "10.0.0.0/8")
+		nested := newTestPrefixRecord(ctrl, "10.0.0.0/9")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(covering.prefix, covering))
+		assert.NoError(t, tree.Insert(nested.prefix, nested))
+
+		networks := make(map[string]Record)
+		assert.NoError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			networks[network.String()] = record
+			return true
+		}))
+		assert.EqualValues(t, map[string]Record{
+			"10.0.0.0/9":   nested,
+			"10.128.0.0/9": covering,
+		}, networks)
+
+		var count int
+		assert.NoError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			count++
+			return false
+		}))
+		assert.EqualValues(t, 1, count)
+	})
+
+	t.Run("IPv6", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := newTestPrefixRecord(ctrl, "2001:db8::/32")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r.prefix, r))
+		assert.NoError(t, tree.Remove(netip.MustParsePrefix("2001:db8::/33")))
+
+		var networks []string
+		assert.NoError(t, tree.WalkNetworks(IPVersion6, func(network netip.Prefix, record Record) bool {
+			networks = append(networks, network.String())
+			return true
+		}))
+		assert.EqualValues(t, []string{"2001:db8:8000::/33"}, networks)
+	})
+
+	t.Run("TooDeep", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := newTestPrefixRecord(ctrl, "2001:db8::/64")
+
+		tree := &RecordTree{}
+		assert.NoError(t, tree.Insert(r.prefix, r))
+		assert.EqualError(t, tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			return true
+		}), ErrInvalidPrefix.Error())
+	})
+}
+
+func TestNewPrefixRecordTree(t *testing.T) {
+	t.Run("InvalidPrefix", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r := NewMockRecord(ctrl)
+		r.EXPECT().GetNetwork().Return(nil)
+
+		tree, err := NewPrefixRecordTree([]Record{r})
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, ErrInvalidPrefix.Error())
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		r0 := newTestPrefixRecord(ctrl, "1.2.0.0/16")
+		r1 := newTestPrefixRecord(ctrl, "1.0.0.0/8")
+
+		tree, err := NewPrefixRecordTree([]Record{r0, r1})
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			assert.EqualValues(t, []Record{r1, r0}, tree.Records())
+			assert.EqualValues(t, r0, recordTreeLeaves(tree, netip.MustParsePrefix("0.0.0.0/0"), nil)["1.2.0.0/16"])
+		}
+	})
 }
 
 func TestRecordTree_Build(t *testing.T) {
@@ -114,10 +501,10 @@ func TestRecordTree_Build(t *testing.T) {
 
 		assert.NoError(t, tree.Build(31, []Record{r}, bitmap.IsSet))
 		if assert.NotNil(t, tree.left) {
-			assert.EqualValues(t, []Record{r}, tree.left.records)
+			assert.EqualValues(t, r, tree.left.record)
 		}
 		assert.Nil(t, tree.right)
-		assert.EqualValues(t, []Record{r}, tree.records)
+		assert.EqualValues(t, []Record{r}, tree.Records())
 	})
 
 	t.Run("LeafsBelowRoot", func(t *testing.T) {
@@ -142,14 +529,15 @@ func TestRecordTree_Build(t *testing.T) {
 		tree := &RecordTree{}
 		assert.NoError(t, tree.Build(31, records, bitmap.IsSet))
 
-		if assert.NotNil(t, tree.left) && assert.NotNil(t, tree.left.records) {
-			assert.EqualValues(t, []Record{leftRecord}, tree.left.records)
+		if assert.NotNil(t, tree.left) {
+			assert.EqualValues(t, leftRecord, tree.left.record)
 		}
 
-		if assert.NotNil(t, tree.right) && assert.NotNil(t, tree.right.records) {
-			assert.EqualValues(t, []Record{rightRecord}, tree.right.records)
+		if assert.NotNil(t, tree.right) {
+			assert.EqualValues(t, rightRecord, tree.right.record)
 		}
-		assert.EqualValues(t, records, tree.records)
+		assert.EqualValues(t, []Record{leftRecord, rightRecord}, tree.Records())
+		assert.EqualValues(t, []Record{rightRecord, leftRecord}, records)
 	})
 
 	t.Run("TopLevelPanic", func(t *testing.T) {
@@ -171,7 +559,9 @@ func TestRecordTree_Build(t *testing.T) {
 
 		tree := &RecordTree{}
 		err := tree.Build(31, records, bitmap.IsSet)
-		assert.EqualError(t, err, "recovered from panic: runtime error: index out of range")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "recovered from panic: runtime error: index out of range")
+		}
 	})
 
 	t.Run("LeftPanic", func(t *testing.T) {
@@ -210,7 +600,9 @@ func TestRecordTree_Build(t *testing.T) {
 
 		tree := &RecordTree{}
 		err := tree.Build(31, records, bitmap.IsSet)
-		assert.EqualError(t, err, "recovered from panic: runtime error: index out of range")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "recovered from panic: runtime error: index out of range")
+		}
 	})
 
 	t.Run("RightPanic", func(t *testing.T) {
@@ -249,7 +641,9 @@ func TestRecordTree_Build(t *testing.T) {
 
 		tree := &RecordTree{}
 		err := tree.Build(31, records, bitmap.IsSet)
-		assert.EqualError(t, err, "recovered from panic: runtime error: index out of range")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "recovered from panic: runtime error: index out of range")
+		}
 	})
 
 	t.Run("TwoLevels", func(t *testing.T) {
@@ -288,29 +682,20 @@ func TestRecordTree_Build(t *testing.T) {
 		}
 
 		expectedTree := &RecordTree{
-			records: records,
 			left: &RecordTree{
-				records: []Record{
-					leftLeftRecord,
-					leftRightRecord,
-				},
 				left: &RecordTree{
-					records: []Record{leftLeftRecord},
+					record: leftLeftRecord,
 				},
 				right: &RecordTree{
-					records: []Record{leftRightRecord},
+					record: leftRightRecord,
 				},
 			},
 			right: &RecordTree{
-				records: []Record{
-					rightRightRecord,
-					rightLeftRecord,
-				},
 				left: &RecordTree{
-					records: []Record{rightLeftRecord},
+					record: rightLeftRecord,
 				},
 				right: &RecordTree{
-					records: []Record{rightRightRecord},
+					record: rightRightRecord,
 				},
 			},
 		}
@@ -327,7 +712,7 @@ func TestNewRecordTree(t *testing.T) {
 		tree, err := NewRecordTree(31, nil, nil)
 		assert.NoError(t, err)
 		if assert.NotNil(t, tree) {
-			assert.Nil(t, tree.records)
+			assert.Nil(t, tree.record)
 			assert.Nil(t, tree.left)
 			assert.Nil(t, tree.right)
 		}
@@ -354,7 +739,9 @@ func TestNewRecordTree(t *testing.T) {
 
 		tree, err := NewRecordTree(31, records, bitmap.IsSet)
 		assert.Nil(t, tree)
-		assert.EqualError(t, err, "recovered from panic: runtime error: index out of range")
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "recovered from panic: runtime error: index out of range")
+		}
 	})
 
 	t.Run("OK", func(t *testing.T) {
@@ -380,13 +767,73 @@ func TestNewRecordTree(t *testing.T) {
 		assert.NoError(t, err)
 		if assert.NotNil(t, tree) {
 
-			assert.EqualValues(t, records, tree.records)
-			if assert.NotNil(t, tree.left) && assert.NotNil(t, tree.left.records) {
-				assert.EqualValues(t, []Record{leftRecord}, tree.left.records)
+			assert.EqualValues(t, []Record{leftRecord, rightRecord}, tree.Records())
+			if assert.NotNil(t, tree.left) {
+				assert.EqualValues(t, leftRecord, tree.left.record)
+			}
+
+			if assert.NotNil(t, tree.right) {
+				assert.EqualValues(t, rightRecord, tree.right.record)
+			}
+		}
+	})
+}
+
+func TestNewAddrRecordTree(t *testing.T) {
+	t.Run("EmptyRecords", func(t *testing.T) {
+		tree, err := NewAddrRecordTree(31, nil, AddrBelongsRight)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			assert.Nil(t, tree.record)
+			assert.Nil(t, tree.left)
+			assert.Nil(t, tree.right)
+		}
+	})
+
+	t.Run("DepthExceeded", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		records := []Record{
+			&testPrefixRecord{MockRecord: NewMockRecord(ctrl), prefix: netip.MustParsePrefix("10.0.0.0/32")},
+			&testPrefixRecord{MockRecord: NewMockRecord(ctrl), prefix: netip.MustParsePrefix("10.0.0.0/32")},
+		}
+
+		tree, err := NewAddrRecordTree(31, records, AddrBelongsRight)
+		assert.Nil(t, tree)
+		assert.EqualError(t, err, "depth<0! #records=2")
+	})
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		leftRecord := &testPrefixRecord{
+			MockRecord: NewMockRecord(ctrl),
+			prefix:     netip.MustParsePrefix("0.0.0.0/1"),
+		}
+
+		rightRecord := NewMockRecord(ctrl)
+		rightRecord.EXPECT().GetNetwork().Return(&net.IPNet{
+			IP:   net.IP{0x80, 0x00, 0x00, 0x00},
+			Mask: net.CIDRMask(1, 32),
+		})
+
+		records := []Record{
+			rightRecord,
+			leftRecord,
+		}
+
+		tree, err := NewAddrRecordTree(31, records, AddrBelongsRight)
+		assert.NoError(t, err)
+		if assert.NotNil(t, tree) {
+			assert.EqualValues(t, []Record{leftRecord, rightRecord}, tree.Records())
+			if assert.NotNil(t, tree.left) {
+				assert.EqualValues(t, leftRecord, tree.left.record)
 			}
 
-			if assert.NotNil(t, tree.right) && assert.NotNil(t, tree.right.records) {
-				assert.EqualValues(t, []Record{rightRecord}, tree.right.records)
+			if assert.NotNil(t, tree.right) {
+				assert.EqualValues(t, rightRecord, tree.right.record)
 			}
 		}
 	})
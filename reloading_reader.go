@@ -0,0 +1,366 @@
+package geodbtools
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ReloadEvent describes a reload of the database of a ReloadingReader
+type ReloadEvent struct {
+	// Path holds the path of the database file
+	Path string
+
+	// OldMetadata holds the metadata of the database that has been replaced, or was kept if the reload failed
+	OldMetadata Metadata
+
+	// NewMetadata holds the metadata of the database that has been loaded, zero if the reload failed
+	NewMetadata Metadata
+
+	// Err holds the error that caused the reload to fail, if any. The previous database is kept in this case.
+	Err error
+}
+
+// ReloadOptions holds the options of a ReloadingReader.
+//
+// Database files should be updated by writing the new version to a temporary file and renaming it over the watched
+// path. Although each version is copied into memory, so that lookups are not affected by modifications of the file,
+// a file rewritten in place might be loaded while being written. Such a version is only detected if it fails to open
+// or is rejected by Validate; the final contents are loaded once the file's modification time changes again.
+type ReloadOptions struct {
+	// Format holds the format of the database. The format is detected on each load if nil.
+	Format Format
+
+	// PollInterval holds the interval at which the database file is checked for changes.
+	// Watching the file is disabled if zero.
+	PollInterval time.Duration
+
+	// Signals holds the signals triggering a reload, e.g. syscall.SIGHUP
+	Signals []os.Signal
+
+	// Validate, if not nil, is called for each newly opened database before it replaces the current one.
+	// The database is rejected if an error is returned.
+	Validate func(reader Reader, meta Metadata) error
+
+	// Events, if not nil, receives an event for each reload, including failed ones.
+	// Events are sent synchronously, so the channel needs to be consumed.
+	Events chan<- *ReloadEvent
+}
+
+// readerGeneration holds a single loaded version of the database of a ReloadingReader
+type readerGeneration struct {
+	// mu is held for reading during lookups and for writing while the generation is retired
+	mu      sync.RWMutex
+	retired bool
+
+	source ReaderSource
+	reader Reader
+	meta   Metadata
+	info   os.FileInfo
+}
+
+// retire closes the source of the generation, once all lookups using it have finished
+func (g *readerGeneration) retire() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.retired = true
+	return g.source.Close()
+}
+
+var _ AddrReader = (*ReloadingReader)(nil)
+var _ NetworkReader = (*ReloadingReader)(nil)
+var _ BatchReader = (*ReloadingReader)(nil)
+var _ MetadataReader = (*ReloadingReader)(nil)
+
+// ReloadingReader implements a Reader whose database file is reloaded when it changes on disk or on request.
+// New versions of the database are read into memory, opened and validated before atomically replacing the current
+// one. Lookups that are in progress during a reload finish using the previous version, whose ReaderSource is closed
+// afterwards.
+type ReloadingReader struct {
+	path    string
+	options ReloadOptions
+
+	current  atomic.Pointer[readerGeneration]
+	reloadMu sync.Mutex
+	// checked holds the file info of the last version the reader attempted to load
+	checked os.FileInfo
+
+	done      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewReloadingReader opens the database file at the given path, returning a reader that reloads the database as
+// configured by the given options.
+// The reader needs to be closed to stop watching the file and release the database.
+func NewReloadingReader(path string, options ReloadOptions) (r *ReloadingReader, err error) {
+	r = &ReloadingReader{
+		path:    path,
+		options: options,
+		done:    make(chan struct{}),
+	}
+
+	var g *readerGeneration
+	if g, err = r.load(); err != nil {
+		r = nil
+		return
+	}
+	r.current.Store(g)
+	r.checked = g.info
+
+	if options.PollInterval > 0 || len(options.Signals) > 0 {
+		r.wg.Add(1)
+		go r.watch()
+	}
+	return
+}
+
+// load opens and validates the database file
+func (r *ReloadingReader) load() (g *readerGeneration, err error) {
+	g = &readerGeneration{}
+	if g.info, err = os.Stat(r.path); err != nil {
+		return
+	}
+
+	// the database is copied rather than memory-mapped, as the file is expected to change while in use
+	if g.source, err = NewMemoryReaderSource(r.path); err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			g.source.Close()
+		}
+	}()
+
+	format := r.options.Format
+	if format == nil {
+		if format, err = DetectFormat(g.source); err != nil {
+			return
+		}
+	}
+
+	if g.reader, g.meta, err = format.NewReaderAt(g.source); err != nil {
+		return
+	}
+
+	if r.options.Validate != nil {
+		err = r.options.Validate(g.reader, g.meta)
+	}
+	return
+}
+
+// watch reloads the database whenever the file changes or one of the configured signals is received
+func (r *ReloadingReader) watch() {
+	defer r.wg.Done()
+
+	var ticks <-chan time.Time
+	if r.options.PollInterval > 0 {
+		ticker := time.NewTicker(r.options.PollInterval)
+		defer ticker.Stop()
+		ticks = ticker.C
+	}
+
+	var signals chan os.Signal
+	if len(r.options.Signals) > 0 {
+		signals = make(chan os.Signal, 1)
+		signal.Notify(signals, r.options.Signals...)
+		defer signal.Stop(signals)
+	}
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticks:
+			r.reloadChanged()
+		case <-signals:
+			r.Reload()
+		}
+	}
+}
+
+// fileChanged returns whether the given file info describes a different version of the file than the given one
+func fileChanged(old, info os.FileInfo) bool {
+	return !os.SameFile(old, info) || !old.ModTime().Equal(info.ModTime()) || old.Size() != info.Size()
+}
+
+// reloadChanged reloads the database if the file has changed since the last attempt to load it
+func (r *ReloadingReader) reloadChanged() {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		// the file might be in the process of being replaced
+		return
+	}
+
+	r.reloadMu.Lock()
+	changed := fileChanged(r.checked, info)
+	r.reloadMu.Unlock()
+
+	if changed {
+		r.Reload()
+	}
+}
+
+// Reload opens and validates the current version of the database file and, if successful, replaces the database.
+// The database of the previous version is closed before Reload returns, after all lookups using it have finished.
+// A ReloadEvent is emitted regardless of the outcome. If the new database is of a different type than the current
+// one, ErrDatabaseTypeMismatch is returned.
+func (r *ReloadingReader) Reload() (err error) {
+	r.reloadMu.Lock()
+	defer r.reloadMu.Unlock()
+
+	old := r.current.Load()
+	if old == nil {
+		err = ErrReaderClosed
+		return
+	}
+
+	event := &ReloadEvent{
+		Path:        r.path,
+		OldMetadata: old.meta,
+	}
+	defer func() {
+		event.Err = err
+		r.emit(event)
+	}()
+
+	var g *readerGeneration
+	if g, err = r.load(); g.info != nil {
+		r.checked = g.info
+	}
+	if err != nil {
+		return
+	}
+
+	if g.meta.Type != old.meta.Type {
+		g.source.Close()
+		err = ErrDatabaseTypeMismatch
+		return
+	}
+
+	r.current.Store(g)
+	event.NewMetadata = g.meta
+
+	err = old.retire()
+	return
+}
+
+// emit sends the given event, unless the reader is being closed
+func (r *ReloadingReader) emit(event *ReloadEvent) {
+	if r.options.Events == nil {
+		return
+	}
+
+	select {
+	case r.options.Events <- event:
+	case <-r.done:
+	}
+}
+
+// acquire returns the current generation, which must be released by calling its mu.RUnlock method
+func (r *ReloadingReader) acquire() (g *readerGeneration, err error) {
+	for {
+		if g = r.current.Load(); g == nil {
+			err = ErrReaderClosed
+			return
+		}
+
+		g.mu.RLock()
+		if !g.retired {
+			return
+		}
+		// the generation has been replaced in the meantime
+		g.mu.RUnlock()
+	}
+}
+
+// Metadata returns the metadata of the current database
+func (r *ReloadingReader) Metadata() (meta Metadata) {
+	if g := r.current.Load(); g != nil {
+		meta = g.meta
+	}
+	return
+}
+
+// RecordTree returns the RecordTree of the current database
+func (r *ReloadingReader) RecordTree(ipVersion IPVersion) (tree *RecordTree, err error) {
+	var g *readerGeneration
+	if g, err = r.acquire(); err != nil {
+		return
+	}
+	defer g.mu.RUnlock()
+
+	return g.reader.RecordTree(ipVersion)
+}
+
+// LookupIP retrieves the record for the given IP address from the current database
+func (r *ReloadingReader) LookupIP(ip net.IP) (record Record, err error) {
+	var g *readerGeneration
+	if g, err = r.acquire(); err != nil {
+		return
+	}
+	defer g.mu.RUnlock()
+
+	return g.reader.LookupIP(ip)
+}
+
+// LookupAddr retrieves the record for the given IP address from the current database
+func (r *ReloadingReader) LookupAddr(addr netip.Addr) (record Record, err error) {
+	var g *readerGeneration
+	if g, err = r.acquire(); err != nil {
+		return
+	}
+	defer g.mu.RUnlock()
+
+	return LookupAddr(g.reader, addr)
+}
+
+// LookupNetwork retrieves the record for the given IP address from the current database, along with the prefix of
+// the network containing the address
+func (r *ReloadingReader) LookupNetwork(addr netip.Addr) (record Record, prefix netip.Prefix, err error) {
+	var g *readerGeneration
+	if g, err = r.acquire(); err != nil {
+		return
+	}
+	defer g.mu.RUnlock()
+
+	return LookupNetwork(g.reader, addr)
+}
+
+// LookupIPs retrieves the records for the given IP addresses. All addresses are looked up in the same version of
+// the database.
+func (r *ReloadingReader) LookupIPs(ips []net.IP) (results []LookupResult) {
+	g, err := r.acquire()
+	if err != nil {
+		results = make([]LookupResult, len(ips))
+		for i, ip := range ips {
+			results[i].IP = ip
+			results[i].Err = err
+		}
+		return
+	}
+	defer g.mu.RUnlock()
+
+	return LookupIPs(g.reader, ips)
+}
+
+// Close stops watching the database file and closes the current database, after all lookups using it have finished.
+// Lookups started afterwards fail with ErrReaderClosed.
+func (r *ReloadingReader) Close() (err error) {
+	r.closeOnce.Do(func() {
+		close(r.done)
+		r.wg.Wait()
+
+		r.reloadMu.Lock()
+		defer r.reloadMu.Unlock()
+		if g := r.current.Swap(nil); g != nil {
+			err = g.retire()
+		}
+	})
+	return
+}
@@ -0,0 +1,329 @@
+package geodbtools
+
+import (
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testReloadFormat returns a format reading databases consisting of "<type> <description>", returning the reader
+// registered for the description
+func testReloadFormat(ctrl *gomock.Controller, readers map[string]Reader) Format {
+	format := NewMockFormat(ctrl)
+	format.EXPECT().NewReaderAt(gomock.Any()).AnyTimes().DoAndReturn(func(source ReaderSource) (Reader, Metadata, error) {
+		data := make([]byte, source.Size())
+		if _, err := source.ReadAt(data, 0); err != nil {
+			return nil, Metadata{}, err
+		}
+
+		fields := strings.SplitN(string(data), " ", 2)
+		if len(fields) != 2 {
+			return nil, Metadata{}, ErrDatabaseInvalid
+		}
+
+		return readers[fields[1]], Metadata{
+			Type:        DatabaseType(fields[0]),
+			Description: fields[1],
+		}, nil
+	})
+	return format
+}
+
+// testReloadReader returns a reader returning the given record for all lookups
+func testReloadReader(ctrl *gomock.Controller, record Record) *MockReader {
+	reader := NewMockReader(ctrl)
+	reader.EXPECT().LookupIP(gomock.Any()).AnyTimes().Return(record, nil)
+	return reader
+}
+
+// writeTestDatabase writes the given database contents to the given path
+func writeTestDatabase(t *testing.T, path, contents string) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+}
+
+func TestReloadingReader(t *testing.T) {
+	ip := net.ParseIP("192.0.2.1")
+
+	t.Run("Reload", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record1, record2 := NewMockRecord(ctrl), NewMockRecord(ctrl)
+		format := testReloadFormat(ctrl, map[string]Reader{
+			"v1": testReloadReader(ctrl, record1),
+			"v2": testReloadReader(ctrl, record2),
+		})
+
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, "country v1")
+
+		events := make(chan *ReloadEvent, 1)
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format: format,
+			Events: events,
+		})
+		require.NoError(t, err)
+		defer r.Close()
+
+		record, err := r.LookupIP(ip)
+		assert.NoError(t, err)
+		assert.True(t, record == record1)
+		assert.EqualValues(t, "v1", r.Metadata().Description)
+
+		writeTestDatabase(t, path, "country v2")
+		require.NoError(t, r.Reload())
+
+		event := <-events
+		assert.EqualValues(t, path, event.Path)
+		assert.EqualValues(t, "v1", event.OldMetadata.Description)
+		assert.EqualValues(t, "v2", event.NewMetadata.Description)
+		assert.NoError(t, event.Err)
+
+		record, err = r.LookupIP(ip)
+		assert.NoError(t, err)
+		assert.True(t, record == record2)
+		assert.EqualValues(t, "v2", r.Metadata().Description)
+	})
+
+	t.Run("InFlightLookup", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record1, record2 := NewMockRecord(ctrl), NewMockRecord(ctrl)
+		lookupStarted := make(chan struct{})
+		finishLookup := make(chan struct{})
+		reader1 := NewMockReader(ctrl)
+		reader1.EXPECT().LookupIP(gomock.Any()).DoAndReturn(func(ip net.IP) (Record, error) {
+			close(lookupStarted)
+			<-finishLookup
+			return record1, nil
+		})
+
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, "country v1")
+
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format: testReloadFormat(ctrl, map[string]Reader{
+				"v1": reader1,
+				"v2": testReloadReader(ctrl, record2),
+			}),
+		})
+		require.NoError(t, err)
+		defer r.Close()
+
+		lookupDone := make(chan Record)
+		go func() {
+			record, _ := r.LookupIP(ip)
+			lookupDone <- record
+		}()
+		<-lookupStarted
+
+		writeTestDatabase(t, path, "country v2")
+		reloadDone := make(chan error)
+		go func() {
+			reloadDone <- r.Reload()
+		}()
+
+		// the new database is used as soon as it has been swapped in, while the old one is kept open
+		for r.Metadata().Description != "v2" {
+			time.Sleep(time.Millisecond)
+		}
+		record, err := r.LookupIP(ip)
+		assert.NoError(t, err)
+		assert.True(t, record == record2)
+
+		select {
+		case <-reloadDone:
+			t.Fatal("reload finished before in-flight lookup")
+		default:
+		}
+
+		close(finishLookup)
+		assert.True(t, <-lookupDone == record1)
+		assert.NoError(t, <-reloadDone)
+	})
+
+	t.Run("Rejected", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		record1 := NewMockRecord(ctrl)
+		validationErr := errors.New("validation error")
+
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, "country v1")
+
+		events := make(chan *ReloadEvent, 1)
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format: testReloadFormat(ctrl, map[string]Reader{
+				"v1": testReloadReader(ctrl, record1),
+			}),
+			Validate: func(reader Reader, meta Metadata) error {
+				if meta.Description == "invalid" {
+					return validationErr
+				}
+				return nil
+			},
+			Events: events,
+		})
+		require.NoError(t, err)
+		defer r.Close()
+
+		for _, testCase := range []struct {
+			contents    string
+			expectedErr error
+		}{
+			{"country invalid", validationErr},
+			{"asn v2", ErrDatabaseTypeMismatch},
+			{"corrupt", ErrDatabaseInvalid},
+		} {
+			writeTestDatabase(t, path, testCase.contents)
+			assert.EqualError(t, r.Reload(), testCase.expectedErr.Error())
+
+			event := <-events
+			assert.EqualError(t, event.Err, testCase.expectedErr.Error())
+			assert.EqualValues(t, "v1", event.OldMetadata.Description)
+			assert.EqualValues(t, Metadata{}, event.NewMetadata)
+
+			record, err := r.LookupIP(ip)
+			assert.NoError(t, err)
+			assert.True(t, record == record1)
+		}
+	})
+
+	t.Run("WatchFile", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, "country v1")
+
+		events := make(chan *ReloadEvent)
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format: testReloadFormat(ctrl, map[string]Reader{
+				"v1":        NewMockReader(ctrl),
+				"version 2": NewMockReader(ctrl),
+			}),
+			PollInterval: 5 * time.Millisecond,
+			Events:       events,
+		})
+		require.NoError(t, err)
+		defer r.Close()
+
+		// replace the file, as done by deployments
+		newPath := path + ".new"
+		writeTestDatabase(t, newPath, "country version 2")
+		require.NoError(t, os.Rename(newPath, path))
+
+		select {
+		case event := <-events:
+			assert.NoError(t, event.Err)
+			assert.EqualValues(t, "v1", event.OldMetadata.Description)
+			assert.EqualValues(t, "version 2", event.NewMetadata.Description)
+		case <-time.After(5 * time.Second):
+			t.Fatal("database has not been reloaded")
+		}
+
+		// unchanged files are not reloaded again
+		select {
+		case event := <-events:
+			t.Fatalf("unexpected reload: %v", event)
+		case <-time.After(50 * time.Millisecond):
+		}
+	})
+
+	t.Run("InPlaceRewrite", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		// the database spans multiple pages, so that truncating the file would invalidate pages of a memory mapping
+		contents := "country v1 " + strings.Repeat("a", 64*1024)
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, contents)
+
+		// the reader returned by the format verifies the contents of its source on each lookup
+		record := NewMockRecord(ctrl)
+		format := NewMockFormat(ctrl)
+		format.EXPECT().NewReaderAt(gomock.Any()).DoAndReturn(func(source ReaderSource) (Reader, Metadata, error) {
+			reader := NewMockReader(ctrl)
+			reader.EXPECT().LookupIP(gomock.Any()).AnyTimes().DoAndReturn(func(ip net.IP) (Record, error) {
+				data := make([]byte, source.Size())
+				if _, err := source.ReadAt(data, 0); err != nil {
+					return nil, err
+				} else if string(data) != contents {
+					return nil, ErrDatabaseInvalid
+				}
+				return record, nil
+			})
+			return reader, Metadata{Type: DatabaseTypeCountry}, nil
+		})
+
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format: format,
+		})
+		require.NoError(t, err)
+		defer r.Close()
+
+		rewriteDone := make(chan struct{})
+		go func() {
+			defer close(rewriteDone)
+			for i := 0; i < 100; i++ {
+				data := "country v2"
+				if i%2 != 0 {
+					data = "country v3 " + strings.Repeat("b", 64*1024)
+				}
+				assert.NoError(t, ioutil.WriteFile(path, []byte(data), 0600))
+			}
+		}()
+
+		for done := false; !done; {
+			select {
+			case <-rewriteDone:
+				done = true
+			default:
+			}
+
+			result, err := r.LookupIP(ip)
+			require.NoError(t, err)
+			require.True(t, result == record)
+		}
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		path := filepath.Join(t.TempDir(), "test.db")
+		writeTestDatabase(t, path, "country v1")
+
+		r, err := NewReloadingReader(path, ReloadOptions{
+			Format:       testReloadFormat(ctrl, map[string]Reader{"v1": NewMockReader(ctrl)}),
+			PollInterval: time.Millisecond,
+		})
+		require.NoError(t, err)
+		require.NoError(t, r.Close())
+		assert.NoError(t, r.Close())
+
+		_, err = r.LookupIP(ip)
+		assert.EqualError(t, err, ErrReaderClosed.Error())
+		results := r.LookupIPs([]net.IP{ip})
+		if assert.Len(t, results, 1) {
+			assert.EqualError(t, results[0].Err, ErrReaderClosed.Error())
+		}
+		assert.EqualError(t, r.Reload(), ErrReaderClosed.Error())
+	})
+
+	t.Run("OpenError", func(t *testing.T) {
+		_, err := NewReloadingReader(filepath.Join(t.TempDir(), "missing.db"), ReloadOptions{})
+		assert.True(t, os.IsNotExist(err))
+	})
+}
@@ -21,7 +21,7 @@ then
 fi
 
 echo '> install golint'
-go get -u golang.org/x/lint/golint
+GO111MODULE=on go install golang.org/x/lint/golint@v0.0.0-20210508222113-6edffad5e616
 
 EXIT_STATUS=0
 for pkg in ${PKG_LIST}
@@ -21,7 +21,7 @@ then
 fi
 
 echo '> install staticcheck'
-go get -u honnef.co/go/tools/cmd/staticcheck
+GO111MODULE=on go install honnef.co/go/tools/cmd/staticcheck@v0.4.7
 
 EXIT_STATUS=0
 for pkg in ${PKG_LIST}
@@ -1,7 +1,9 @@
 package geodbtools
 
 import (
+	"context"
 	"fmt"
+	"net/netip"
 	"sync"
 
 	"go.uber.org/multierr"
@@ -93,6 +95,175 @@ func Verify(reader Reader, root *RecordTree, progress chan<- *VerificationProgre
 	return
 }
 
+// RangeVerificationError describes an address range whose record differs from the expected one
+type RangeVerificationError struct {
+	// First holds the first address of the range
+	First netip.Addr
+
+	// Last holds the last address of the range
+	Last netip.Addr
+
+	// ExpectedRecord holds the expected record, nil if the range is not expected to be covered
+	ExpectedRecord Record
+
+	// Record holds the record found for the range, nil if the range is not covered
+	Record Record
+}
+
+// Missing checks if the range is expected to be covered, but is not
+func (e *RangeVerificationError) Missing() bool {
+	return e.Record == nil
+}
+
+// Extra checks if the range is covered, but is not expected to be
+func (e *RangeVerificationError) Extra() bool {
+	return e.ExpectedRecord == nil
+}
+
+func (e *RangeVerificationError) Error() string {
+	switch {
+	case e.Missing():
+		return fmt.Sprintf("missing record %s for range %s-%s", e.ExpectedRecord, e.First, e.Last)
+	case e.Extra():
+		return fmt.Sprintf("unexpected record %s for range %s-%s", e.Record, e.First, e.Last)
+	}
+
+	return fmt.Sprintf("expected record %s for range %s-%s, received record %s", e.ExpectedRecord, e.First, e.Last, e.Record)
+}
+
+// VerifyRanges tests if a given reader is equivalent to the given tree across the whole address space of the given IP
+// version.
+// Unlike Verify, which only looks up the first address of each expected record, the networks of both the tree and the
+// reader are walked in address order, as done by Diff. Each differing range is reported as RangeVerificationError,
+// including the ranges only covered by the reader.
+// Records holding none of the data compared by RecordsEqual are treated as not covering their network, as some formats
+// are unable to leave networks uncovered.
+// IPv4-mapped IPv6 addresses (::ffff:0:0/96) are not verified, as formats either alias them to the IPv4 networks
+// or do not hold them at all, resolving them as IPv4 addresses instead.
+// Progress is reported in terms of the networks of the tree.
+func VerifyRanges(reader Reader, root *RecordTree, ipVersion IPVersion, progress chan<- *VerificationProgress) (err error) {
+	var verificationErr error
+	if _, _, err = verifyRanges(context.Background(), reader, root, ipVersion, progress, func(rangeErr *RangeVerificationError) bool {
+		verificationErr = multierr.Append(verificationErr, rangeErr)
+		return true
+	}); err != nil {
+		return
+	}
+
+	err = verificationErr
+	return
+}
+
+// verifyRanges implements VerifyRanges, calling the given function for each differing range until it returns false.
+// The number of networks of the tree is returned, along with the number of networks checked.
+func verifyRanges(ctx context.Context, reader Reader, root *RecordTree, ipVersion IPVersion, progress chan<- *VerificationProgress, fn func(rangeErr *RangeVerificationError) bool) (networks, checked int, err error) {
+	var prefix netip.Prefix
+	if _, prefix, err = diffFilter(NetworkFilter{IPVersion: ipVersion}); err != nil {
+		return
+	}
+
+	filter := NetworkFilter{
+		IPVersion: ipVersion,
+		Prefix:    prefix,
+	}
+
+	expected := newTreeNetworkSource(root, ipVersion, prefix)
+	expected.progress = progress
+	networks = len(expected.networks)
+
+	defer func() {
+		checked = expected.index
+	}()
+
+	err = diffSources(&contextNetworkSource{networkSource: expected, ctx: ctx}, readerNetworkSource(reader, filter), prefix, func(r DiffRange) bool {
+		if isEmptyRecord(r.Old) {
+			r.Old = nil
+		}
+		if isEmptyRecord(r.New) {
+			r.New = nil
+		}
+
+		if r.Old == nil && r.New == nil {
+			return true
+		}
+
+		for _, r := range excludeIPv4MappedRange(r) {
+			if !fn(&RangeVerificationError{
+				First:          r.First,
+				Last:           r.Last,
+				ExpectedRecord: r.Old,
+				Record:         r.New,
+			}) {
+				return false
+			}
+		}
+		return true
+	})
+	return
+}
+
+// contextNetworkSource stops the given source once its context is done
+type contextNetworkSource struct {
+	networkSource
+	ctx context.Context
+}
+
+func (s *contextNetworkSource) next() (prefix netip.Prefix, record Record, ok bool) {
+	if s.ctx.Err() != nil {
+		return
+	}
+	return s.networkSource.next()
+}
+
+func (s *contextNetworkSource) err() error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+	return s.networkSource.err()
+}
+
+// ipv4MappedPrefix holds the prefix of the IPv4-mapped IPv6 addresses
+var ipv4MappedPrefix = netip.MustParsePrefix("::ffff:0:0/96")
+
+// excludeIPv4MappedRange returns the parts of the given range outside of ipv4MappedPrefix
+func excludeIPv4MappedRange(r DiffRange) (ranges []DiffRange) {
+	mappedFirst, mappedLast := ipv4MappedPrefix.Addr(), prefixLastAddr(ipv4MappedPrefix)
+	if !r.First.Is6() || r.Last.Less(mappedFirst) || mappedLast.Less(r.First) {
+		return []DiffRange{r}
+	}
+
+	if r.First.Less(mappedFirst) {
+		before := r
+		before.Last = mappedFirst.Prev()
+		ranges = append(ranges, before)
+	}
+
+	if mappedLast.Less(r.Last) {
+		after := r
+		after.First = mappedLast.Next()
+		ranges = append(ranges, after)
+	}
+	return
+}
+
+// isEmptyRecord checks if the given record holds none of the data compared by RecordsEqual
+func isEmptyRecord(r Record) bool {
+	switch record := r.(type) {
+	case nil:
+		return true
+	case CityRecord:
+		return record.GetCountryCode() == "" && record.GetCityName() == ""
+	case CountryRecord:
+		return record.GetCountryCode() == ""
+	case ASNRecord:
+		return record.GetASNumber() == 0 && record.GetOrganization() == ""
+	case OrganizationRecord:
+		return record.GetOrganization() == ""
+	}
+
+	return false
+}
+
 // RecordsEqual checks if two records are equal
 func RecordsEqual(a, b Record) bool {
 	switch recordA := a.(type) {
@@ -100,6 +271,10 @@ func RecordsEqual(a, b Record) bool {
 		return CityRecordsEqual(recordA, b)
 	case CountryRecord:
 		return CountryRecordsEqual(recordA, b)
+	case ASNRecord:
+		return ASNRecordsEqual(recordA, b)
+	case OrganizationRecord:
+		return OrganizationRecordsEqual(recordA, b)
 	}
 
 	return false
@@ -128,3 +303,27 @@ func CityRecordsEqual(a CityRecord, b Record) bool {
 
 	return a.GetCityName() == recordB.GetCityName()
 }
+
+// OrganizationRecordsEqual checks if two OrganizationRecord instances are equal
+func OrganizationRecordsEqual(a OrganizationRecord, b Record) bool {
+	recordB, isOrganizationRecord := b.(OrganizationRecord)
+	if !isOrganizationRecord {
+		return false
+	}
+
+	return a.GetOrganization() == recordB.GetOrganization()
+}
+
+// ASNRecordsEqual checks if two ASNRecord instances are equal
+func ASNRecordsEqual(a ASNRecord, b Record) bool {
+	recordB, isASNRecord := b.(ASNRecord)
+	if !isASNRecord {
+		return false
+	}
+
+	if a.GetASNumber() != recordB.GetASNumber() {
+		return false
+	}
+
+	return OrganizationRecordsEqual(a, recordB)
+}
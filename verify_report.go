@@ -0,0 +1,292 @@
+package geodbtools
+
+import (
+	"context"
+	"net/netip"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// DefaultVerificationSamples defines the number of failures kept as examples if VerifyOptions.MaxSamples is zero
+var DefaultVerificationSamples = 10
+
+// VerifyOptions holds the options of VerifyContext and VerifyRangesContext
+type VerifyOptions struct {
+	// Workers holds the number of lookups run concurrently by VerifyContext, runtime.NumCPU() if zero.
+	// The reader has to be safe for concurrent use if more than one worker is used.
+	Workers int
+
+	// MaxFailures stops the verification once the given number of failures has been found, if greater than zero
+	MaxFailures int
+
+	// MaxSamples holds the number of failures kept as examples, DefaultVerificationSamples if zero.
+	// No examples are kept if negative.
+	MaxSamples int
+
+	// Progress optionally receives progress reports
+	Progress chan<- *VerificationProgress
+}
+
+// VerificationMismatch holds the number of failures for a pair of expected and actual country codes
+type VerificationMismatch struct {
+	// ExpectedCountryCode holds the expected country code, empty if the expected record has none
+	ExpectedCountryCode string `json:"expected_country_code"`
+
+	// CountryCode holds the country code found, empty if the record found has none or no record has been found
+	CountryCode string `json:"country_code"`
+
+	// Count holds the number of failures
+	Count int `json:"count"`
+}
+
+// VerificationSample holds an example of a verification failure
+type VerificationSample struct {
+	// First holds the first address of the failing network or range
+	First netip.Addr
+
+	// Last holds the last address of the failing network or range
+	Last netip.Addr
+
+	// Err holds the failure, either a *VerificationError or a *RangeVerificationError
+	Err error
+}
+
+// VerificationReport holds the results of VerifyContext and VerifyRangesContext
+type VerificationReport struct {
+	// TotalRecords holds the number of records, or networks, of the expected tree
+	TotalRecords int `json:"total_records"`
+
+	// CheckedRecords holds the number of records, or networks, that have been checked
+	CheckedRecords int `json:"checked_records"`
+
+	// Failures holds the number of failures found
+	Failures int `json:"failures"`
+
+	// LookupErrors holds the number of failures caused by lookup errors
+	LookupErrors int `json:"lookup_errors"`
+
+	// MissingRanges holds the number of ranges not covered by the reader, as reported by VerifyRangesContext
+	MissingRanges int `json:"missing_ranges"`
+
+	// ExtraRanges holds the number of ranges only covered by the reader, as reported by VerifyRangesContext
+	ExtraRanges int `json:"extra_ranges"`
+
+	// Stopped indicates that the verification has been stopped after reaching VerifyOptions.MaxFailures
+	Stopped bool `json:"stopped"`
+
+	// Mismatches holds the failures grouped by expected and actual country code, most frequent first.
+	// Lookup errors are not included.
+	Mismatches []*VerificationMismatch `json:"mismatches"`
+
+	// Samples holds examples of the failures found, in address order
+	Samples []*VerificationSample `json:"-"`
+
+	maxSamples  int
+	maxFailures int
+	mismatches  map[[2]string]*VerificationMismatch
+}
+
+// newVerificationReport returns a new, empty report for the given options
+func newVerificationReport(opts VerifyOptions) *VerificationReport {
+	maxSamples := opts.MaxSamples
+	if maxSamples == 0 {
+		maxSamples = DefaultVerificationSamples
+	}
+
+	return &VerificationReport{
+		Mismatches:  []*VerificationMismatch{},
+		maxSamples:  maxSamples,
+		maxFailures: opts.MaxFailures,
+		mismatches:  make(map[[2]string]*VerificationMismatch),
+	}
+}
+
+// addFailure adds a failure to the report.
+// false is returned if the maximum number of failures has been reached.
+func (r *VerificationReport) addFailure(sample *VerificationSample, expectedRecord, record Record) bool {
+	r.Failures++
+
+	if rangeErr, ok := sample.Err.(*RangeVerificationError); ok && rangeErr.Missing() {
+		r.MissingRanges++
+	} else if ok && rangeErr.Extra() {
+		r.ExtraRanges++
+	}
+
+	if verificationErr, ok := sample.Err.(*VerificationError); ok && verificationErr.LookupError != nil {
+		r.LookupErrors++
+	} else {
+		key := [2]string{diffCountryCode(expectedRecord), diffCountryCode(record)}
+		mismatch, exists := r.mismatches[key]
+		if !exists {
+			mismatch = &VerificationMismatch{
+				ExpectedCountryCode: key[0],
+				CountryCode:         key[1],
+			}
+			r.mismatches[key] = mismatch
+			r.Mismatches = append(r.Mismatches, mismatch)
+		}
+		mismatch.Count++
+	}
+
+	if r.maxSamples > 0 {
+		// keep the samples with the lowest addresses, as failures may be found in any order
+		index := sort.Search(len(r.Samples), func(i int) bool {
+			return sample.First.Less(r.Samples[i].First)
+		})
+		if index < r.maxSamples {
+			r.Samples = append(r.Samples, nil)
+			copy(r.Samples[index+1:], r.Samples[index:])
+			r.Samples[index] = sample
+
+			if len(r.Samples) > r.maxSamples {
+				r.Samples = r.Samples[:r.maxSamples]
+			}
+		}
+	}
+
+	if r.maxFailures > 0 && r.Failures >= r.maxFailures {
+		r.Stopped = true
+		return false
+	}
+	return true
+}
+
+// finish sorts the mismatches of the report
+func (r *VerificationReport) finish() {
+	sort.SliceStable(r.Mismatches, func(i, j int) bool {
+		a, b := r.Mismatches[i], r.Mismatches[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		} else if a.ExpectedCountryCode != b.ExpectedCountryCode {
+			return a.ExpectedCountryCode < b.ExpectedCountryCode
+		}
+		return a.CountryCode < b.CountryCode
+	})
+}
+
+// VerifyContext tests if a given reader contains all records defined by the given tree, as done by Verify.
+// The lookups are run by a pool of workers and failures are collected in a report instead of an error.
+// A final progress report is always sent, even if the tree holds no records.
+// The verification is stopped as soon as the given context is done, returning the report so far along with the
+// context's error.
+func VerifyContext(ctx context.Context, reader Reader, root *RecordTree, opts VerifyOptions) (report *VerificationReport, err error) {
+	expectedRecords := root.Records()
+
+	report = newVerificationReport(opts)
+	report.TotalRecords = len(expectedRecords)
+
+	workers := opts.Workers
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	indexes := make(chan int)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for index := range indexes {
+				expectedRecord := expectedRecords[index]
+
+				var sample *VerificationSample
+				var record Record
+				if network := expectedRecord.GetNetwork(); network != nil {
+					var lookupErr error
+					if record, lookupErr = reader.LookupIP(network.IP); lookupErr != nil {
+						sample = &VerificationSample{
+							Err: &VerificationError{
+								ExpectedRecord: expectedRecord,
+								LookupError:    lookupErr,
+							},
+						}
+					} else if !RecordsEqual(expectedRecord, record) {
+						sample = &VerificationSample{
+							Err: &VerificationError{
+								ExpectedRecord: expectedRecord,
+								Record:         record,
+							},
+						}
+					}
+
+					if sample != nil {
+						prefix := PrefixFromIPNet(network)
+						sample.First, sample.Last = prefix.Masked().Addr(), prefixLastAddr(prefix)
+					}
+				}
+
+				mu.Lock()
+				if workerCtx.Err() == nil {
+					report.CheckedRecords++
+					if sample != nil && !report.addFailure(sample, expectedRecord, record) {
+						cancel()
+					}
+
+					if opts.Progress != nil {
+						opts.Progress <- &VerificationProgress{
+							TotalRecords:   report.TotalRecords,
+							CheckedRecords: report.CheckedRecords,
+						}
+					}
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+dispatch:
+	for index := range expectedRecords {
+		select {
+		case <-workerCtx.Done():
+			break dispatch
+		case indexes <- index:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	if opts.Progress != nil {
+		opts.Progress <- &VerificationProgress{
+			TotalRecords:   report.TotalRecords,
+			CheckedRecords: report.CheckedRecords,
+		}
+	}
+
+	report.finish()
+	err = ctx.Err()
+	return
+}
+
+// VerifyRangesContext tests if a given reader is equivalent to the given tree across the whole address space of the
+// given IP version, as done by VerifyRanges.
+// Failures are collected in a report instead of an error. VerifyOptions.Workers is not used, as both databases are
+// walked in address order.
+// The verification is stopped as soon as the given context is done, returning the report so far along with the
+// context's error.
+func VerifyRangesContext(ctx context.Context, reader Reader, root *RecordTree, ipVersion IPVersion, opts VerifyOptions) (report *VerificationReport, err error) {
+	report = newVerificationReport(opts)
+	report.TotalRecords, report.CheckedRecords, err = verifyRanges(ctx, reader, root, ipVersion, opts.Progress, func(rangeErr *RangeVerificationError) bool {
+		return report.addFailure(&VerificationSample{
+			First: rangeErr.First,
+			Last:  rangeErr.Last,
+			Err:   rangeErr,
+		}, rangeErr.ExpectedRecord, rangeErr.Record)
+	})
+
+	if opts.Progress != nil {
+		opts.Progress <- &VerificationProgress{
+			TotalRecords:   report.TotalRecords,
+			CheckedRecords: report.CheckedRecords,
+		}
+	}
+
+	report.finish()
+	return
+}
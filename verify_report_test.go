@@ -0,0 +1,289 @@
+package geodbtools
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/netip"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestVerifyRecord returns a new country record for the given prefix and country code, also providing its network
+// as net.IPNet
+func newTestVerifyRecord(ctrl *gomock.Controller, prefix string, countryCode string) *testCountryPrefixRecord {
+	record := newTestCountryRecord(ctrl, prefix, countryCode)
+	record.EXPECT().GetNetwork().AnyTimes().Return(&net.IPNet{
+		IP:   net.IP(record.prefix.Addr().AsSlice()),
+		Mask: net.CIDRMask(record.prefix.Bits(), record.prefix.Addr().BitLen()),
+	})
+	record.EXPECT().String().AnyTimes().Return(prefix + " " + countryCode)
+	return record
+}
+
+// testVerifyReader returns a reader looking up the records of the given tree
+func testVerifyReader(ctrl *gomock.Controller, tree *RecordTree, lookupErrs map[string]error) Reader {
+	reader := NewMockReader(ctrl)
+	reader.EXPECT().LookupIP(gomock.Any()).AnyTimes().DoAndReturn(func(ip net.IP) (Record, error) {
+		if err := lookupErrs[ip.String()]; err != nil {
+			return nil, err
+		}
+
+		addr, _ := netip.AddrFromSlice(ip)
+		var found Record
+		tree.WalkNetworks(IPVersion4, func(network netip.Prefix, record Record) bool {
+			if network.Contains(addr.Unmap()) {
+				found = record
+				return false
+			}
+			return true
+		})
+
+		if found == nil {
+			return nil, ErrRecordNotFound
+		}
+		return found, nil
+	})
+	return reader
+}
+
+func TestVerifyContext(t *testing.T) {
+	newTree := func(t *testing.T, records ...Record) *RecordTree {
+		tree, err := NewPrefixRecordTree(records)
+		require.NoError(t, err)
+		return tree
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree := newTree(t,
+			newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "12.0.0.0/8", "DE"),
+		)
+
+		progress := make(chan *VerificationProgress, 8)
+		report, err := VerifyContext(context.Background(), testVerifyReader(ctrl, tree, nil), tree, VerifyOptions{
+			Workers:  2,
+			Progress: progress,
+		})
+		close(progress)
+		assert.NoError(t, err)
+
+		assert.EqualValues(t, 2, report.TotalRecords)
+		assert.EqualValues(t, 2, report.CheckedRecords)
+		assert.EqualValues(t, 0, report.Failures)
+		assert.Empty(t, report.Mismatches)
+		assert.Empty(t, report.Samples)
+
+		var reports int
+		var lastReport *VerificationProgress
+		for report := range progress {
+			reports++
+			lastReport = report
+		}
+		// one report per record, followed by the final report
+		assert.EqualValues(t, 3, reports)
+		if assert.NotNil(t, lastReport) {
+			assert.EqualValues(t, 2, lastReport.TotalRecords)
+			assert.EqualValues(t, 2, lastReport.CheckedRecords)
+		}
+	})
+
+	t.Run("EmptyTree", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		progress := make(chan *VerificationProgress, 8)
+		report, err := VerifyContext(context.Background(), NewMockReader(ctrl), newTree(t), VerifyOptions{
+			Progress: progress,
+		})
+		close(progress)
+		assert.NoError(t, err)
+		assert.EqualValues(t, 0, report.TotalRecords)
+
+		var reports []*VerificationProgress
+		for report := range progress {
+			reports = append(reports, report)
+		}
+		assert.EqualValues(t, []*VerificationProgress{{}}, reports)
+	})
+
+	t.Run("Failures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		expected := newTree(t,
+			newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "11.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "12.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "13.0.0.0/8", "US"),
+			newTestVerifyRecord(ctrl, "14.0.0.0/8", "US"),
+		)
+		actual := newTree(t,
+			newTestVerifyRecord(ctrl, "10.0.0.0/8", "DE"),
+			newTestVerifyRecord(ctrl, "11.0.0.0/8", "DE"),
+			newTestVerifyRecord(ctrl, "12.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "13.0.0.0/8", "CA"),
+		)
+		testErr := errors.New("test error")
+
+		report, err := VerifyContext(context.Background(), testVerifyReader(ctrl, actual, map[string]error{"14.0.0.0": testErr}), expected, VerifyOptions{
+			Workers:    3,
+			MaxSamples: 2,
+		})
+		assert.NoError(t, err)
+
+		assert.EqualValues(t, 5, report.TotalRecords)
+		assert.EqualValues(t, 5, report.CheckedRecords)
+		assert.EqualValues(t, 4, report.Failures)
+		assert.EqualValues(t, 1, report.LookupErrors)
+		assert.False(t, report.Stopped)
+		assert.EqualValues(t, []*VerificationMismatch{
+			{ExpectedCountryCode: "AT", CountryCode: "DE", Count: 2},
+			{ExpectedCountryCode: "US", CountryCode: "CA", Count: 1},
+		}, report.Mismatches)
+
+		if assert.Len(t, report.Samples, 2) {
+			assert.EqualValues(t, netip.MustParseAddr("10.0.0.0"), report.Samples[0].First)
+			assert.EqualValues(t, netip.MustParseAddr("10.255.255.255"), report.Samples[0].Last)
+			assert.EqualError(t, report.Samples[0].Err, "expected record 10.0.0.0/8 AT, received record 10.0.0.0/8 DE")
+			assert.EqualValues(t, netip.MustParseAddr("11.0.0.0"), report.Samples[1].First)
+		}
+	})
+
+	t.Run("MaxFailures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		var expectedRecords, actualRecords []Record
+		for _, prefix := range []string{"10.0.0.0/8", "11.0.0.0/8", "12.0.0.0/8", "13.0.0.0/8"} {
+			expectedRecords = append(expectedRecords, newTestVerifyRecord(ctrl, prefix, "AT"))
+			actualRecords = append(actualRecords, newTestVerifyRecord(ctrl, prefix, "DE"))
+		}
+
+		report, err := VerifyContext(context.Background(), testVerifyReader(ctrl, newTree(t, actualRecords...), nil), newTree(t, expectedRecords...), VerifyOptions{
+			Workers:     1,
+			MaxFailures: 2,
+		})
+		assert.NoError(t, err)
+		assert.True(t, report.Stopped)
+		assert.EqualValues(t, 2, report.Failures)
+		assert.EqualValues(t, 2, report.CheckedRecords)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		tree := newTree(t, newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT"))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		progress := make(chan *VerificationProgress, 8)
+		report, err := VerifyContext(ctx, NewMockReader(ctrl), tree, VerifyOptions{
+			Progress: progress,
+		})
+		close(progress)
+		assert.EqualError(t, err, context.Canceled.Error())
+		if assert.NotNil(t, report) {
+			assert.EqualValues(t, 1, report.TotalRecords)
+			assert.EqualValues(t, 0, report.CheckedRecords)
+		}
+
+		var reports []*VerificationProgress
+		for report := range progress {
+			reports = append(reports, report)
+		}
+		assert.EqualValues(t, []*VerificationProgress{{TotalRecords: 1}}, reports)
+	})
+}
+
+func TestVerifyRangesContext(t *testing.T) {
+	t.Run("Failures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root, err := NewPrefixRecordTree([]Record{
+			newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "12.0.0.0/8", "US"),
+		})
+		require.NoError(t, err)
+
+		reader := testDiffReader(t, ctrl,
+			newTestVerifyRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestVerifyRecord(ctrl, "10.128.0.0/9", "DE"),
+			newTestVerifyRecord(ctrl, "11.0.0.0/8", "DE"),
+		)
+
+		report, err := VerifyRangesContext(context.Background(), reader, root, IPVersion4, VerifyOptions{})
+		assert.NoError(t, err)
+
+		assert.EqualValues(t, 2, report.TotalRecords)
+		assert.EqualValues(t, 2, report.CheckedRecords)
+		assert.EqualValues(t, 3, report.Failures)
+		assert.EqualValues(t, 1, report.MissingRanges)
+		assert.EqualValues(t, 1, report.ExtraRanges)
+		assert.EqualValues(t, []*VerificationMismatch{
+			{ExpectedCountryCode: "", CountryCode: "DE", Count: 1},
+			{ExpectedCountryCode: "AT", CountryCode: "DE", Count: 1},
+			{ExpectedCountryCode: "US", CountryCode: "", Count: 1},
+		}, report.Mismatches)
+
+		if assert.Len(t, report.Samples, 3) {
+			for i, first := range []string{"10.128.0.0", "11.0.0.0", "12.0.0.0"} {
+				assert.EqualValues(t, netip.MustParseAddr(first), report.Samples[i].First)
+				assert.IsType(t, &RangeVerificationError{}, report.Samples[i].Err)
+			}
+		}
+	})
+
+	t.Run("MaxFailures", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root, err := NewPrefixRecordTree([]Record{
+			newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestVerifyRecord(ctrl, "12.0.0.0/8", "US"),
+		})
+		require.NoError(t, err)
+
+		report, err := VerifyRangesContext(context.Background(), testDiffReader(t, ctrl), root, IPVersion4, VerifyOptions{
+			MaxFailures: 1,
+		})
+		assert.NoError(t, err)
+		assert.True(t, report.Stopped)
+		assert.EqualValues(t, 1, report.Failures)
+	})
+
+	t.Run("Cancelled", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root, err := NewPrefixRecordTree([]Record{newTestVerifyRecord(ctrl, "10.0.0.0/8", "AT")})
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		progress := make(chan *VerificationProgress, 8)
+		_, err = VerifyRangesContext(ctx, testDiffReader(t, ctrl), root, IPVersion4, VerifyOptions{
+			Progress: progress,
+		})
+		close(progress)
+		assert.EqualError(t, err, context.Canceled.Error())
+
+		var reports []*VerificationProgress
+		for report := range progress {
+			reports = append(reports, report)
+		}
+		if assert.NotEmpty(t, reports) {
+			assert.EqualValues(t, 1, reports[len(reports)-1].TotalRecords)
+		}
+	})
+}
@@ -2,7 +2,9 @@ package geodbtools
 
 import (
 	"errors"
+	"fmt"
 	"net"
+	"net/netip"
 	"testing"
 
 	"github.com/golang/mock/gomock"
@@ -121,9 +123,7 @@ func TestVerify(t *testing.T) {
 
 			reader := NewMockReader(ctrl)
 			root := &RecordTree{
-				records: []Record{
-					record,
-				},
+				record: record,
 			}
 
 			assert.NoError(t, Verify(reader, root, nil))
@@ -145,9 +145,7 @@ func TestVerify(t *testing.T) {
 			reader := NewMockReader(ctrl)
 			reader.EXPECT().LookupIP(network.IP).Return(nil, testErr)
 			root := &RecordTree{
-				records: []Record{
-					record,
-				},
+				record: record,
 			}
 
 			assert.EqualError(t, Verify(reader, root, nil), "expected record mockRecord, received error test error")
@@ -170,9 +168,7 @@ func TestVerify(t *testing.T) {
 			reader := NewMockReader(ctrl)
 			reader.EXPECT().LookupIP(network.IP).Return(expectedRecord, nil)
 			root := &RecordTree{
-				records: []Record{
-					record,
-				},
+				record: record,
 			}
 
 			errs := multierr.Errors(Verify(reader, root, nil))
@@ -196,9 +192,7 @@ func TestVerify(t *testing.T) {
 			reader := NewMockReader(ctrl)
 			reader.EXPECT().LookupIP(network.IP).Return(record, nil)
 			root := &RecordTree{
-				records: []Record{
-					record,
-				},
+				record: record,
 			}
 
 			assert.NoError(t, Verify(reader, root, nil))
@@ -222,9 +216,11 @@ func TestVerify(t *testing.T) {
 			reader := NewMockReader(ctrl)
 			reader.EXPECT().LookupIP(network.IP).Return(record1, nil)
 			root := &RecordTree{
-				records: []Record{
-					record0,
-					record1,
+				left: &RecordTree{
+					record: record0,
+				},
+				right: &RecordTree{
+					record: record1,
 				},
 			}
 
@@ -253,9 +249,11 @@ func TestVerify(t *testing.T) {
 			reader.EXPECT().LookupIP(network0.IP).Return(nil, errors.New("test error"))
 			reader.EXPECT().LookupIP(network1.IP).Return(record1, nil)
 			root := &RecordTree{
-				records: []Record{
-					record0,
-					record1,
+				left: &RecordTree{
+					record: record0,
+				},
+				right: &RecordTree{
+					record: record1,
 				},
 			}
 
@@ -290,9 +288,11 @@ func TestVerify(t *testing.T) {
 			reader.EXPECT().LookupIP(network0.IP).Return(expectedRecord0, nil)
 			reader.EXPECT().LookupIP(network1.IP).Return(record1, nil)
 			root := &RecordTree{
-				records: []Record{
-					record0,
-					record1,
+				left: &RecordTree{
+					record: record0,
+				},
+				right: &RecordTree{
+					record: record1,
 				},
 			}
 
@@ -324,9 +324,11 @@ func TestVerify(t *testing.T) {
 			reader.EXPECT().LookupIP(network0.IP).Return(record0, nil)
 			reader.EXPECT().LookupIP(network1.IP).Return(record1, nil)
 			root := &RecordTree{
-				records: []Record{
-					record0,
-					record1,
+				left: &RecordTree{
+					record: record0,
+				},
+				right: &RecordTree{
+					record: record1,
 				},
 			}
 
@@ -357,9 +359,11 @@ func TestVerify(t *testing.T) {
 			reader.EXPECT().LookupIP(network0.IP).Return(record0, nil)
 			reader.EXPECT().LookupIP(network1.IP).Return(record1, nil)
 			root := &RecordTree{
-				records: []Record{
-					record0,
-					record1,
+				left: &RecordTree{
+					record: record0,
+				},
+				right: &RecordTree{
+					record: record1,
 				},
 			}
 
@@ -367,7 +371,7 @@ func TestVerify(t *testing.T) {
 			defer close(progress)
 			assert.NoError(t, Verify(reader, root, progress))
 
-			for i := 0; i <= len(root.records); i++ {
+			for i := 0; i <= len(root.Records()); i++ {
 				select {
 				case report := <-progress:
 					if assert.NotNil(t, report) {
@@ -386,9 +390,7 @@ func TestVerify(t *testing.T) {
 			defer ctrl.Finish()
 
 			reader := NewMockReader(ctrl)
-			root := &RecordTree{
-				records: []Record{},
-			}
+			root := &RecordTree{}
 
 			progress := make(chan *VerificationProgress, 8)
 			defer close(progress)
@@ -408,6 +410,197 @@ func TestVerify(t *testing.T) {
 	})
 }
 
+func TestRangeVerificationError_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	expectedRecord := NewMockRecord(ctrl)
+	expectedRecord.EXPECT().String().AnyTimes().Return("expectedRecord")
+	record := NewMockRecord(ctrl)
+	record.EXPECT().String().AnyTimes().Return("testRecord")
+
+	first, last := netip.MustParseAddr("10.0.0.0"), netip.MustParseAddr("10.0.0.255")
+
+	t.Run("Missing", func(t *testing.T) {
+		err := &RangeVerificationError{First: first, Last: last, ExpectedRecord: expectedRecord}
+		assert.True(t, err.Missing())
+		assert.False(t, err.Extra())
+		assert.EqualValues(t, "missing record expectedRecord for range 10.0.0.0-10.0.0.255", err.Error())
+	})
+
+	t.Run("Extra", func(t *testing.T) {
+		err := &RangeVerificationError{First: first, Last: last, Record: record}
+		assert.False(t, err.Missing())
+		assert.True(t, err.Extra())
+		assert.EqualValues(t, "unexpected record testRecord for range 10.0.0.0-10.0.0.255", err.Error())
+	})
+
+	t.Run("Mismatch", func(t *testing.T) {
+		err := &RangeVerificationError{First: first, Last: last, ExpectedRecord: expectedRecord, Record: record}
+		assert.False(t, err.Missing())
+		assert.False(t, err.Extra())
+		assert.EqualValues(t, "expected record expectedRecord for range 10.0.0.0-10.0.0.255, received record testRecord", err.Error())
+	})
+}
+
+func TestVerifyRanges(t *testing.T) {
+	newTree := func(t *testing.T, records ...Record) *RecordTree {
+		tree, err := NewPrefixRecordTree(records)
+		require.NoError(t, err)
+		return tree
+	}
+
+	t.Run("OK", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+		reader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "AT"),
+		)
+
+		assert.NoError(t, VerifyRanges(reader, root, IPVersion4, nil))
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t,
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "12.0.0.0/8", "US"),
+		)
+		reader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/9", "AT"),
+			newTestCountryRecord(ctrl, "10.128.0.0/9", "DE"),
+			newTestCountryRecord(ctrl, "11.0.0.0/8", "DE"),
+		)
+
+		errs := multierr.Errors(VerifyRanges(reader, root, IPVersion4, nil))
+		if assert.Len(t, errs, 3) {
+			var ranges []string
+			for _, err := range errs {
+				if rangeErr, ok := err.(*RangeVerificationError); assert.True(t, ok) {
+					ranges = append(ranges, fmt.Sprintf("%s-%s %v %v", rangeErr.First, rangeErr.Last, rangeErr.Missing(), rangeErr.Extra()))
+				}
+			}
+
+			assert.EqualValues(t, []string{
+				"10.128.0.0-10.255.255.255 false false",
+				"11.0.0.0-11.255.255.255 false true",
+				"12.0.0.0-12.255.255.255 true false",
+			}, ranges)
+		}
+	})
+
+	t.Run("EmptyRecords", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+		reader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "0.0.0.0/5", ""),
+			newTestCountryRecord(ctrl, "8.0.0.0/7", ""),
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "11.0.0.0/8", ""),
+		)
+
+		assert.NoError(t, VerifyRanges(reader, root, IPVersion4, nil))
+	})
+
+	t.Run("IPv4Mapped", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t,
+			newTestCountryRecord(ctrl, "::ffff:10.0.0.0/104", "AT"),
+			newTestCountryRecord(ctrl, "::ffff:255.255.255.255/128", "AT"),
+			newTestCountryRecord(ctrl, "::1:0:0:0/128", "AT"),
+		)
+		reader := testDiffReader(t, ctrl)
+
+		errs := multierr.Errors(VerifyRanges(reader, root, IPVersion6, nil))
+		if assert.Len(t, errs, 1) {
+			if rangeErr, ok := errs[0].(*RangeVerificationError); assert.True(t, ok) {
+				assert.True(t, rangeErr.Missing())
+				assert.EqualValues(t, netip.MustParseAddr("::1:0:0:0"), rangeErr.First)
+				assert.EqualValues(t, netip.MustParseAddr("::1:0:0:0"), rangeErr.Last)
+			}
+		}
+	})
+
+	t.Run("RecordTreeReader", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+
+		// overlapping records, as in patched trees
+		readerTree := newTree(t, newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"))
+		require.NoError(t, readerTree.Insert(netip.MustParsePrefix("10.1.0.0/16"), newTestCountryRecord(ctrl, "10.1.0.0/16", "DE")))
+
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().RecordTree(IPVersion4).Return(readerTree, nil)
+
+		errs := multierr.Errors(VerifyRanges(reader, root, IPVersion4, nil))
+		if assert.Len(t, errs, 1) {
+			if rangeErr, ok := errs[0].(*RangeVerificationError); assert.True(t, ok) {
+				assert.EqualValues(t, netip.MustParseAddr("10.1.0.0"), rangeErr.First)
+				assert.EqualValues(t, netip.MustParseAddr("10.1.255.255"), rangeErr.Last)
+			}
+		}
+	})
+
+	t.Run("RecordTreeError", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		testErr := errors.New("test error")
+		reader := NewMockReader(ctrl)
+		reader.EXPECT().RecordTree(IPVersion4).Return(nil, testErr)
+
+		assert.EqualError(t, VerifyRanges(reader, &RecordTree{}, IPVersion4, nil), testErr.Error())
+	})
+
+	t.Run("UnsupportedIPVersion", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		assert.EqualError(t, VerifyRanges(NewMockReader(ctrl), &RecordTree{}, IPVersionUndefined, nil), ErrUnsupportedIPVersion.Error())
+	})
+
+	t.Run("ProgressReport", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		root := newTree(t,
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "12.0.0.0/8", "US"),
+		)
+		reader := testDiffReader(t, ctrl,
+			newTestCountryRecord(ctrl, "10.0.0.0/8", "AT"),
+			newTestCountryRecord(ctrl, "12.0.0.0/8", "US"),
+		)
+
+		progress := make(chan *VerificationProgress, 8)
+		defer close(progress)
+		assert.NoError(t, VerifyRanges(reader, root, IPVersion4, progress))
+
+		for i := 0; i <= 2; i++ {
+			select {
+			case report := <-progress:
+				if assert.NotNil(t, report) {
+					assert.EqualValuesf(t, i, report.CheckedRecords, "CheckedRecords incorrect #%d", i)
+					assert.EqualValuesf(t, 2, report.TotalRecords, "TotalRecords incorrect #%d", i)
+				}
+			default:
+				require.FailNowf(t, "progress report missing", "#%d missing", i)
+			}
+		}
+	})
+}
+
 func TestCountryRecordsEqual(t *testing.T) {
 	t.Run("BNotCountryRecord", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
@@ -605,6 +798,60 @@ func TestRecordsEqual(t *testing.T) {
 		})
 	})
 
+	t.Run("ASNRecord", func(t *testing.T) {
+		t.Run("Equal", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			a := NewMockASNRecord(ctrl)
+			a.EXPECT().GetASNumber().Return(uint32(64512))
+			a.EXPECT().GetOrganization().Return("test organization")
+			b := NewMockASNRecord(ctrl)
+			b.EXPECT().GetASNumber().Return(uint32(64512))
+			b.EXPECT().GetOrganization().Return("test organization")
+
+			assert.True(t, RecordsEqual(a, b))
+		})
+
+		t.Run("NotEqual", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			a := NewMockASNRecord(ctrl)
+			a.EXPECT().GetASNumber().Return(uint32(64512))
+			b := NewMockASNRecord(ctrl)
+			b.EXPECT().GetASNumber().Return(uint32(64513))
+
+			assert.False(t, RecordsEqual(a, b))
+		})
+	})
+
+	t.Run("OrganizationRecord", func(t *testing.T) {
+		t.Run("Equal", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			a := NewMockOrganizationRecord(ctrl)
+			a.EXPECT().GetOrganization().Return("test organization")
+			b := NewMockOrganizationRecord(ctrl)
+			b.EXPECT().GetOrganization().Return("test organization")
+
+			assert.True(t, RecordsEqual(a, b))
+		})
+
+		t.Run("NotEqual", func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			a := NewMockOrganizationRecord(ctrl)
+			a.EXPECT().GetOrganization().Return("test organization")
+			b := NewMockOrganizationRecord(ctrl)
+			b.EXPECT().GetOrganization().Return("test organization 2")
+
+			assert.False(t, RecordsEqual(a, b))
+		})
+	})
+
 	t.Run("OtherRecord", func(t *testing.T) {
 		ctrl := gomock.NewController(t)
 		defer ctrl.Finish()
@@ -615,3 +862,91 @@ func TestRecordsEqual(t *testing.T) {
 		assert.False(t, RecordsEqual(a, b))
 	})
 }
+
+func TestOrganizationRecordsEqual(t *testing.T) {
+	t.Run("BNotOrganizationRecord", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockOrganizationRecord(ctrl)
+		b := NewMockRecord(ctrl)
+
+		assert.False(t, OrganizationRecordsEqual(a, b))
+	})
+
+	t.Run("Equal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockOrganizationRecord(ctrl)
+		a.EXPECT().GetOrganization().Return("test organization")
+		b := NewMockOrganizationRecord(ctrl)
+		b.EXPECT().GetOrganization().Return("test organization")
+
+		assert.True(t, OrganizationRecordsEqual(a, b))
+	})
+
+	t.Run("NotEqual", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockOrganizationRecord(ctrl)
+		a.EXPECT().GetOrganization().Return("test organization")
+		b := NewMockOrganizationRecord(ctrl)
+		b.EXPECT().GetOrganization().Return("other organization")
+
+		assert.False(t, OrganizationRecordsEqual(a, b))
+	})
+}
+
+func TestASNRecordsEqual(t *testing.T) {
+	t.Run("BNotASNRecord", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockASNRecord(ctrl)
+		b := NewMockOrganizationRecord(ctrl)
+
+		assert.False(t, ASNRecordsEqual(a, b))
+	})
+
+	t.Run("Equal", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockASNRecord(ctrl)
+		a.EXPECT().GetASNumber().Return(uint32(64512))
+		a.EXPECT().GetOrganization().Return("test organization")
+		b := NewMockASNRecord(ctrl)
+		b.EXPECT().GetASNumber().Return(uint32(64512))
+		b.EXPECT().GetOrganization().Return("test organization")
+
+		assert.True(t, ASNRecordsEqual(a, b))
+	})
+
+	t.Run("ASNumberMismatch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockASNRecord(ctrl)
+		a.EXPECT().GetASNumber().Return(uint32(64512))
+		b := NewMockASNRecord(ctrl)
+		b.EXPECT().GetASNumber().Return(uint32(64513))
+
+		assert.False(t, ASNRecordsEqual(a, b))
+	})
+
+	t.Run("OrganizationMismatch", func(t *testing.T) {
+		ctrl := gomock.NewController(t)
+		defer ctrl.Finish()
+
+		a := NewMockASNRecord(ctrl)
+		a.EXPECT().GetASNumber().Return(uint32(64512))
+		a.EXPECT().GetOrganization().Return("test organization")
+		b := NewMockASNRecord(ctrl)
+		b.EXPECT().GetASNumber().Return(uint32(64512))
+		b.EXPECT().GetOrganization().Return("other organization")
+
+		assert.False(t, ASNRecordsEqual(a, b))
+	})
+}